@@ -0,0 +1,382 @@
+// Package arc 实现自适应替换缓存（ARC），接口与 lru.Cache 相同，可以作为 geecache 的淘汰策略。
+// ARC 同时维护按最近访问和按访问频率排列的两个链表，并根据被淘汰后又很快被访问的键（幽灵条目）
+// 自动调整两者所占的比例，不需要像分段 LRU 那样手工选择各段的大小。
+package arc
+
+import (
+	"container/list"
+	"math/rand"
+	"time"
+
+	"testProject/cache/clock"
+	"testProject/cache/lru"
+)
+
+// Cache 是按字节计算容量的 ARC 缓存。T1 保存只被访问过一次的条目，T2 保存被访问过至少两次的条目；
+// B1、B2 是分别从 T1、T2 淘汰的条目留下的幽灵记录，只有键和大小，不计入 Bytes。
+// 幽灵条目被再次写入时说明对应的链表太小，T1 的目标大小 p 随之增大或减小。
+// Cache 不是并发安全的。
+type Cache struct {
+	maxBytes int64 //允许使用的最大内存，0 表示不限制
+	p        int64 //T1 的目标大小，在 0 到 maxBytes 之间自适应调整
+
+	t1, t2           *list.List //常驻条目，元素为 *entry
+	b1, b2           *list.List //幽灵条目，元素为 *ghost
+	t1bytes, t2bytes int64
+	b1bytes, b2bytes int64
+
+	cache  map[string]*list.Element //常驻条目
+	ghosts map[string]*list.Element //幽灵条目
+
+	evictions int64 //因超出容量而被淘汰的条目数量
+	clock     clock.Clock
+	listeners []lru.EvictionListener //淘汰监听器，按注册顺序依次调用
+}
+
+// Option 用于在创建 Cache 时定制其行为。
+type Option func(*Cache)
+
+// WithClock 设置 Cache 记录条目写入时间和判断过期使用的时钟，默认使用真实时间。
+func WithClock(c clock.Clock) Option {
+	return func(cache *Cache) {
+		cache.clock = c
+	}
+}
+
+type entry struct {
+	key      string
+	value    lru.Value
+	added    time.Time //条目写入（或最近一次被覆盖）的时间
+	expires  time.Time //条目的过期时间，零值表示永不过期
+	frequent bool      //条目位于 T2
+}
+
+// expired 报告条目在 now 时是否已经过期。
+func (e *entry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// ghost 是被淘汰的条目留下的记录。
+type ghost struct {
+	key      string
+	size     int64 //条目被淘汰时占用的字节数
+	frequent bool  //记录位于 B2
+}
+
+// New 创建一个 Cache，onEvicted 不为 nil 时会被注册为第一个淘汰监听器。
+func New(maxBytes int64, onEvicted func(string, lru.Value), opts ...Option) *Cache {
+	c := &Cache{
+		maxBytes: maxBytes,
+		t1:       list.New(),
+		t2:       list.New(),
+		b1:       list.New(),
+		b2:       list.New(),
+		cache:    make(map[string]*list.Element),
+		ghosts:   make(map[string]*list.Element),
+		clock:    clock.Real,
+	}
+	if onEvicted != nil {
+		c.AddEvictionListener(onEvicted)
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// cost 返回一个条目占用的字节数。
+func cost(key string, value lru.Value) int64 {
+	return int64(len(key)) + int64(value.Len())
+}
+
+// hit 记录一次访问：T1 中的条目移到 T2，T2 中的条目移到 T2 的队首。
+func (c *Cache) hit(ele *list.Element) {
+	e := ele.Value.(*entry)
+	if e.frequent {
+		c.t2.MoveToFront(ele)
+		return
+	}
+	n := cost(e.key, e.value)
+	c.t1.Remove(ele)
+	c.t1bytes -= n
+	e.frequent = true
+	c.cache[e.key] = c.t2.PushFront(e)
+	c.t2bytes += n
+}
+
+// Get 返回 key 对应的值并记录一次访问。已经过期的条目在这里被惰性删除，视为未命中。
+func (c *Cache) Get(key string) (value lru.Value, ok bool) {
+	ele, ok := c.cache[key]
+	if !ok {
+		return nil, false
+	}
+	e := ele.Value.(*entry)
+	if e.expired(c.clock.Now()) {
+		c.removeElement(ele)
+		return nil, false
+	}
+	c.hit(ele)
+	return e.value, true
+}
+
+// Peek 返回 key 对应的值，但不记录访问，不影响条目的淘汰顺序。
+// 已经过期的条目视为不存在，但不会在这里被删除。
+func (c *Cache) Peek(key string) (value lru.Value, ok bool) {
+	if ele, ok := c.cache[key]; ok {
+		if e := ele.Value.(*entry); !e.expired(c.clock.Now()) {
+			return e.value, true
+		}
+	}
+	return nil, false
+}
+
+// Contains 报告 key 是否在缓存中且尚未过期，不影响条目的淘汰顺序。
+func (c *Cache) Contains(key string) bool {
+	_, ok := c.Peek(key)
+	return ok
+}
+
+// Expiration 返回 key 对应条目的过期时间，零值表示永不过期。
+// 它不影响条目的淘汰顺序；条目不存在或已经过期时 ok 为 false。
+func (c *Cache) Expiration(key string) (expires time.Time, ok bool) {
+	if ele, ok := c.cache[key]; ok {
+		if e := ele.Value.(*entry); !e.expired(c.clock.Now()) {
+			return e.expires, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Add 将一个永不过期的键值对添加或更新到缓存中。
+func (c *Cache) Add(key string, value lru.Value) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire 将一个键值对添加或更新到缓存中，条目在 expires 之后过期，零值表示永不过期。
+// 覆盖已有的键计为一次访问；键有幽灵记录时先据此调整 T1 的目标大小，条目直接进入 T2。
+func (c *Cache) AddWithExpire(key string, value lru.Value, expires time.Time) {
+	now := c.clock.Now()
+	if ele, ok := c.cache[key]; ok {
+		e := ele.Value.(*entry)
+		delta := int64(value.Len()) - int64(e.value.Len())
+		if e.frequent {
+			c.t2bytes += delta
+		} else {
+			c.t1bytes += delta
+		}
+		e.value, e.added, e.expires = value, now, expires
+		c.hit(ele)
+		c.fit(false)
+		return
+	}
+
+	e := &entry{key: key, value: value, added: now, expires: expires}
+	n := cost(key, value)
+	fromB2 := false
+	if gele, ok := c.ghosts[key]; ok {
+		g := gele.Value.(*ghost)
+		fromB2 = g.frequent
+		if fromB2 {
+			// 最近从 T2 淘汰的键又被访问，说明 T2 太小，缩小 T1 的目标大小
+			c.p = max(c.p-adapt(n, c.b1bytes, c.b2bytes), 0)
+		} else {
+			// 最近从 T1 淘汰的键又被访问，说明 T1 太小
+			c.p = min(c.p+adapt(n, c.b2bytes, c.b1bytes), c.maxBytes)
+		}
+		c.removeGhost(gele)
+		e.frequent = true
+		c.cache[key] = c.t2.PushFront(e)
+		c.t2bytes += n
+	} else {
+		c.cache[key] = c.t1.PushFront(e)
+		c.t1bytes += n
+	}
+	c.fit(fromB2)
+}
+
+// adapt 返回一次幽灵命中时 p 的调整量：至少是条目的大小，另一个幽灵链表越大调整越多。
+func adapt(n, other, self int64) int64 {
+	if self > 0 && other > self {
+		return n * other / self
+	}
+	return n
+}
+
+// fit 淘汰常驻条目直到不超过容量，并限制幽灵记录的总大小。
+func (c *Cache) fit(fromB2 bool) {
+	if c.maxBytes == 0 {
+		return
+	}
+	for c.t1bytes+c.t2bytes > c.maxBytes {
+		c.replace(fromB2)
+	}
+	for c.b1.Len() > 0 && c.t1bytes+c.b1bytes > c.maxBytes {
+		c.removeGhost(c.b1.Back())
+	}
+	for c.b2.Len() > 0 && c.t1bytes+c.t2bytes+c.b1bytes+c.b2bytes > 2*c.maxBytes {
+		c.removeGhost(c.b2.Back())
+	}
+}
+
+// victim 返回 ARC 下一个淘汰的常驻条目：T1 超过目标大小时淘汰 T1 的队尾，否则淘汰 T2 的队尾。
+func (c *Cache) victim(fromB2 bool) *list.Element {
+	if c.t1.Len() > 0 && (c.t1bytes > c.p || (fromB2 && c.t1bytes == c.p) || c.t2.Len() == 0) {
+		return c.t1.Back()
+	}
+	return c.t2.Back()
+}
+
+// replace 淘汰一个常驻条目，并为它留下幽灵记录。
+func (c *Cache) replace(fromB2 bool) {
+	ele := c.victim(fromB2)
+	if ele == nil {
+		return
+	}
+	e := ele.Value.(*entry)
+	c.removeElement(ele)
+	c.evictions++
+	g := &ghost{key: e.key, size: cost(e.key, e.value), frequent: e.frequent}
+	if g.frequent {
+		c.ghosts[g.key] = c.b2.PushFront(g)
+		c.b2bytes += g.size
+	} else {
+		c.ghosts[g.key] = c.b1.PushFront(g)
+		c.b1bytes += g.size
+	}
+}
+
+// RemoveOldest 按 ARC 的规则淘汰一个条目。
+func (c *Cache) RemoveOldest() {
+	c.replace(false)
+}
+
+// Victim 返回下一个将被 RemoveOldest 淘汰的条目的键，缓存为空时 ok 为 false。
+func (c *Cache) Victim() (key string, ok bool) {
+	if ele := c.victim(false); ele != nil {
+		return ele.Value.(*entry).key, true
+	}
+	return "", false
+}
+
+// Remove 从缓存中删除 key 对应的条目，返回条目是否存在。被删除的条目同样会通知淘汰监听器，
+// 但不留下幽灵记录。
+func (c *Cache) Remove(key string) bool {
+	if ele, ok := c.cache[key]; ok {
+		c.removeElement(ele)
+		return true
+	}
+	return false
+}
+
+// RemoveExpired 删除所有已经过期的条目并返回删除的数量，被删除的条目会通知淘汰监听器。
+func (c *Cache) RemoveExpired() int {
+	now := c.clock.Now()
+	var expired []*list.Element
+	for _, ele := range c.cache {
+		if ele.Value.(*entry).expired(now) {
+			expired = append(expired, ele)
+		}
+	}
+	for _, ele := range expired {
+		c.removeElement(ele)
+	}
+	return len(expired)
+}
+
+// removeElement 从缓存中删除一个常驻条目，并通知淘汰监听器。
+func (c *Cache) removeElement(ele *list.Element) {
+	e := ele.Value.(*entry)
+	if e.frequent {
+		c.t2.Remove(ele)
+		c.t2bytes -= cost(e.key, e.value)
+	} else {
+		c.t1.Remove(ele)
+		c.t1bytes -= cost(e.key, e.value)
+	}
+	delete(c.cache, e.key)
+	for _, fn := range c.listeners {
+		fn(e.key, e.value)
+	}
+}
+
+// AddEvictionListener 注册一个淘汰监听器，条目被淘汰或删除时所有监听器按注册顺序被调用。
+func (c *Cache) AddEvictionListener(fn lru.EvictionListener) {
+	c.listeners = append(c.listeners, fn)
+}
+
+// removeGhost 删除一条幽灵记录。
+func (c *Cache) removeGhost(ele *list.Element) {
+	g := ele.Value.(*ghost)
+	if g.frequent {
+		c.b2.Remove(ele)
+		c.b2bytes -= g.size
+	} else {
+		c.b1.Remove(ele)
+		c.b1bytes -= g.size
+	}
+	delete(c.ghosts, g.key)
+}
+
+// Target 返回 T1 当前的目标大小。它随访问模式在 0 到 maxBytes 之间变化：
+// 越大说明最近访问越重要，越小说明访问频率越重要。
+func (c *Cache) Target() int64 {
+	return c.p
+}
+
+// Evictions 返回因超出容量而被淘汰的条目数量，不包括过期和被显式删除的条目。
+func (c *Cache) Evictions() int64 {
+	return c.evictions
+}
+
+// Len 返回缓存中常驻条目的数量。
+func (c *Cache) Len() int {
+	return len(c.cache)
+}
+
+// Bytes 返回常驻条目的键和值的长度之和，幽灵记录不计入。
+func (c *Cache) Bytes() int64 {
+	return c.t1bytes + c.t2bytes
+}
+
+// Resize 调整允许使用的最大内存，并立即淘汰条目直到不超过新的限制，0 表示不限制。
+func (c *Cache) Resize(maxBytes int64) {
+	c.maxBytes = maxBytes
+	c.p = min(c.p, maxBytes)
+	c.fit(false)
+}
+
+// Sample 随机返回最多 n 个未过期的条目，不影响条目的淘汰顺序。
+func (c *Cache) Sample(n int) []lru.EntryInfo {
+	if n <= 0 {
+		return nil
+	}
+	sample := make([]lru.EntryInfo, 0, n)
+	now := c.clock.Now()
+	i := 0
+	for _, ele := range c.cache {
+		e := ele.Value.(*entry)
+		if e.expired(now) {
+			continue
+		}
+		info := lru.EntryInfo{Key: e.key, Value: e.value, Added: e.added, Expires: e.expires}
+		if i < n {
+			sample = append(sample, info)
+		} else if j := rand.Intn(i + 1); j < n {
+			sample[j] = info
+		}
+		i++
+	}
+	return sample
+}
+
+// Scan 与 lru.Cache.Scan 相同，结果可以用 lru.MergeScans 与其他缓存的结果合并。
+func (c *Cache) Scan(cursor uint64, prefix string, count int) (keys []string, next uint64) {
+	now := c.clock.Now()
+	return lru.ScanKeys(cursor, prefix, count, func(yield func(string) bool) {
+		for key, ele := range c.cache {
+			if !ele.Value.(*entry).expired(now) && !yield(key) {
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package arc
+
+import (
+	"testing"
+	"time"
+
+	"testProject/cache/clock"
+)
+
+type String string
+
+func (d String) Len() int {
+	return len(d)
+}
+
+// 测试被访问过两次的条目不会被一次性扫描挤出缓存
+func TestScanResistance(t *testing.T) {
+	c := New(int64(8), nil)
+	c.Add("a", String("1"))
+	c.Add("b", String("2"))
+	c.Get("a")
+	c.Get("b")
+	for _, k := range []string{"c", "d", "e", "f", "g"} {
+		c.Add(k, String("x"))
+	}
+	if !c.Contains("a") || !c.Contains("b") || c.Len() != 4 || c.Bytes() != 8 {
+		t.Fatalf("frequent entries evicted by a scan: Len = %d, Bytes = %d", c.Len(), c.Bytes())
+	}
+	if c.Evictions() != 3 {
+		t.Fatalf("Evictions = %d, want 3", c.Evictions())
+	}
+}
+
+// 测试从 T1 淘汰的键很快又被写入时，T1 的目标大小增大，该键直接进入 T2
+func TestAdapt(t *testing.T) {
+	c := New(int64(8), nil)
+	c.Add("a", String("1"))
+	c.Add("b", String("2"))
+	c.Get("a")
+	c.Get("b")
+	for _, k := range []string{"c", "d", "e", "f"} {
+		c.Add(k, String("x"))
+	}
+	if c.Contains("c") || c.Target() != 0 {
+		t.Fatalf("c still cached or Target = %d", c.Target())
+	}
+	c.Add("c", String("x")) // c 在 B1 中留有幽灵记录
+	if c.Target() != 2 || !c.Contains("c") || c.Contains("e") {
+		t.Fatalf("Target = %d after a ghost hit, want 2", c.Target())
+	}
+	if key, _ := c.Victim(); key != "a" {
+		t.Fatalf("Victim = %q, want a: T1 is within its target size", key)
+	}
+}
+
+// 测试过期的条目视为未命中并被删除
+func TestExpiration(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := New(0, nil, WithClock(clk))
+	c.AddWithExpire("k", String("v"), clk.Now().Add(time.Second))
+	c.Add("forever", String("v"))
+	clk.Advance(time.Second)
+	if c.Contains("k") || c.RemoveExpired() != 1 || c.Len() != 1 || c.Bytes() != int64(len("forever")+1) {
+		t.Fatalf("expired entry still present, Len = %d", c.Len())
+	}
+}
@@ -0,0 +1,66 @@
+// Package bloom 实现并发安全的布隆过滤器，用于在访问数据源之前快速排除一定不存在的键。
+package bloom
+
+import (
+	"hash/fnv"
+	"math"
+	"sync/atomic"
+)
+
+// Filter 是布隆过滤器：MayContain 返回 false 时键一定没有被加入过，
+// 返回 true 时键可能被加入过，误判的概率由创建时的参数决定。所有方法都可以并发调用。
+type Filter struct {
+	bits []atomic.Uint64 // 位数组，按 64 位分组
+	m    uint64          // 位数组的长度
+	k    int             // 每个键设置的位数
+}
+
+// New 创建一个预计存放 n 个键、误判率约为 p 的布隆过滤器。
+// 实际加入的键远多于 n 时误判率会明显升高。
+func New(n int, p float64) *Filter {
+	if n < 1 {
+		n = 1
+	}
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+	// 最优的位数 m = -n·ln(p)/(ln2)²，哈希函数个数 k = (m/n)·ln2。
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k := int(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	words := (m + 63) / 64
+	return &Filter{bits: make([]atomic.Uint64, words), m: words * 64, k: k}
+}
+
+// Add 把 key 加入过滤器。
+func (f *Filter) Add(key string) {
+	h1, h2 := hashes(key)
+	for i := 0; i < f.k; i++ {
+		bit := (h1 + uint64(i)*h2) % f.m
+		f.bits[bit/64].Or(1 << (bit % 64))
+	}
+}
+
+// MayContain 报告 key 是否可能被加入过，返回 false 时一定没有加入过。
+func (f *Filter) MayContain(key string) bool {
+	h1, h2 := hashes(key)
+	for i := 0; i < f.k; i++ {
+		bit := (h1 + uint64(i)*h2) % f.m
+		if f.bits[bit/64].Load()&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hashes 返回用于双重哈希的两个哈希值，第 i 个位置为 h1 + i·h2。
+func hashes(key string) (h1, h2 uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	h1 = sum & math.MaxUint32
+	h2 = sum>>32 | 1 // 保证 h2 为奇数，各个位置不会重合
+	return h1, h2
+}
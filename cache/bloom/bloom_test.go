@@ -0,0 +1,29 @@
+package bloom
+
+import (
+	"strconv"
+	"testing"
+)
+
+// 加入过的键一定返回 true，误判率接近预期
+func TestFilter(t *testing.T) {
+	const n = 10000
+	f := New(n, 0.01)
+	for i := 0; i < n; i++ {
+		f.Add("key" + strconv.Itoa(i))
+	}
+	for i := 0; i < n; i++ {
+		if !f.MayContain("key" + strconv.Itoa(i)) {
+			t.Fatalf("added key%d is reported missing", i)
+		}
+	}
+	falsePositives := 0
+	for i := 0; i < n; i++ {
+		if f.MayContain("other" + strconv.Itoa(i)) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / n; rate > 0.03 {
+		t.Fatalf("false positive rate %.3f, want about 0.01", rate)
+	}
+}
@@ -0,0 +1,196 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 是所有与时间相关的行为（过期时间、后台清理、提前刷新、统计时间戳等）使用的时间来源。
+// 默认使用真实时间，测试中可以替换为 Fake，从而无需 sleep 就能确定性地测试过期逻辑。
+type Clock interface {
+	// Now 返回当前时间。
+	Now() time.Time
+	// NewTicker 返回一个每隔 d 触发一次的 Ticker。
+	NewTicker(d time.Duration) Ticker
+	// AfterFunc 在 d 之后调用 f，返回的 Timer 可以在调用之前取消。
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer 是 time.AfterFunc 返回的 *time.Timer 的抽象。
+type Timer interface {
+	// Stop 取消还没有调用的函数，取消成功时返回 true。
+	Stop() bool
+}
+
+// Ticker 是 time.Ticker 的抽象。
+type Ticker interface {
+	// C 返回接收触发时间的通道。
+	C() <-chan time.Time
+	// Stop 停止 Ticker，之后不会再触发。
+	Stop()
+}
+
+// Real 是使用真实时间的 Clock。
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+type realTicker struct{ t *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.t.C }
+func (t realTicker) Stop()               { t.t.Stop() }
+
+// Fake 是只在调用 Advance 或 Set 时才前进的 Clock，用于测试。
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+	timers  []*fakeTimer
+}
+
+// NewFake 创建一个当前时间为 now 的 Fake。
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now 返回 Fake 的当前时间。
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// NewTicker 返回一个在 Fake 时间前进时触发的 Ticker。
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTicker{c: make(chan time.Time, 1), d: d, next: f.now.Add(d)}
+	f.tickers = append(f.tickers, t)
+	return t
+}
+
+// AfterFunc 返回一个在 Fake 时间前进到 d 之后时调用 f 的 Timer。
+// f 在推进时间的 Advance 或 Set 返回之前、在调用它们的 goroutine 中执行。
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{when: f.now.Add(d), f: fn}
+	f.timers = append(f.timers, t)
+	return t
+}
+
+// Advance 让 Fake 的时间前进 d，并触发期间到期的 Ticker 和 Timer。
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	f.mu.Unlock()
+	f.fire()
+}
+
+// Set 把 Fake 的时间设置为 t，并触发期间到期的 Ticker 和 Timer。
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	f.now = t
+	f.mu.Unlock()
+	f.fire()
+}
+
+// fire 触发所有已经到期的 Ticker 和 Timer。与 time.Ticker 一样，接收方来不及处理时丢弃多余的触发。
+// Timer 的函数在释放锁之后调用，它可以再使用 Fake。
+func (f *Fake) fire() {
+	f.mu.Lock()
+	var due []*fakeTimer
+	pending := f.timers[:0]
+	for _, t := range f.timers {
+		if t.when.After(f.now) {
+			pending = append(pending, t)
+		} else {
+			due = append(due, t)
+		}
+	}
+	f.timers = pending
+	f.fireTickers()
+	f.mu.Unlock()
+	for _, t := range due {
+		if t.take() {
+			t.f()
+		}
+	}
+}
+
+// fireTickers 触发所有已经到期的 Ticker。调用方需要持有 f.mu。
+func (f *Fake) fireTickers() {
+	active := f.tickers[:0]
+	for _, t := range f.tickers {
+		if t.stopped() {
+			continue
+		}
+		active = append(active, t)
+		for !t.next.After(f.now) {
+			select {
+			case t.c <- t.next:
+			default:
+			}
+			t.next = t.next.Add(t.d)
+		}
+	}
+	f.tickers = active
+}
+
+type fakeTicker struct {
+	mu   sync.Mutex
+	c    chan time.Time
+	d    time.Duration
+	next time.Time
+	stop bool
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+
+func (t *fakeTicker) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stop = true
+}
+
+func (t *fakeTicker) stopped() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stop
+}
+
+type fakeTimer struct {
+	mu   sync.Mutex
+	when time.Time
+	f    func()
+	done bool // 已经调用或者已经取消
+}
+
+// Stop 取消还没有调用的函数。
+func (t *fakeTimer) Stop() bool {
+	return t.take()
+}
+
+// take 把 Timer 标记为已经结束，只有第一次调用返回 true。
+func (t *fakeTimer) take() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		return false
+	}
+	t.done = true
+	return true
+}
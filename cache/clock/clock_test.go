@@ -0,0 +1,63 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+// 测试 Fake 的 Ticker 只在时间前进到间隔之后触发，停止之后不再触发
+func TestFakeAdvance(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+	ticker := f.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	f.Advance(500 * time.Millisecond)
+	select {
+	case <-ticker.C():
+		t.Fatal("ticker fired before its interval")
+	default:
+	}
+
+	f.Advance(500 * time.Millisecond)
+	select {
+	case got := <-ticker.C():
+		if !got.Equal(start.Add(time.Second)) {
+			t.Fatalf("tick at %v, want %v", got, start.Add(time.Second))
+		}
+	default:
+		t.Fatal("ticker did not fire after its interval")
+	}
+	if got := f.Now(); !got.Equal(start.Add(time.Second)) {
+		t.Fatalf("Now() = %v, want %v", got, start.Add(time.Second))
+	}
+
+	ticker.Stop()
+	f.Advance(time.Minute)
+	select {
+	case <-ticker.C():
+		t.Fatal("stopped ticker fired")
+	default:
+	}
+}
+
+// 测试 Fake 的 AfterFunc 只在时间前进到期之后调用函数，取消之后不再调用
+func TestFakeAfterFunc(t *testing.T) {
+	f := NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	calls := 0
+	f.AfterFunc(time.Second, func() { calls++ })
+	stopped := f.AfterFunc(time.Second, func() { t.Fatal("stopped timer fired") })
+	if !stopped.Stop() || stopped.Stop() {
+		t.Fatal("Stop should succeed exactly once")
+	}
+
+	f.Advance(999 * time.Millisecond)
+	if calls != 0 {
+		t.Fatal("timer fired before it was due")
+	}
+	f.Advance(time.Millisecond)
+	f.Advance(time.Minute)
+	if calls != 1 {
+		t.Fatalf("timer fired %d times, want once", calls)
+	}
+}
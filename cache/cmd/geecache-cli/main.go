@@ -0,0 +1,280 @@
+// geecache-cli 是运维缓存集群的命令行工具，用于排查线上问题。
+//
+// 用法：
+//
+//	geecache-cli [flags] get <group> <key>
+//	geecache-cli [flags] set <group> <key> <value>   # value 为 "-" 时从标准输入读取
+//	geecache-cli [flags] del <group> <key>
+//	geecache-cli [flags] stats [group]
+//	geecache-cli [flags] keys <group> [prefix]
+//	geecache-cli [flags] flush <group>
+//	geecache-cli [flags] freeze <group>
+//	geecache-cli [flags] unfreeze <group>
+//
+// get、set 和 del 通过节点间协议访问集群，按一致性哈希找到键的所有者；
+// stats、keys、flush、freeze 和 unfreeze 通过管理接口（见 geecache.AdminHandler）依次访问每个节点。
+// -peers 可以是单个节点，也可以是集群中的所有节点。
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"testProject/cache/geecache"
+	"testProject/cache/geecache/client"
+)
+
+// options 是所有子命令共享的命令行参数。
+type options struct {
+	peers      []string
+	basePath   string
+	token      string
+	adminPath  string
+	adminToken string
+	format     string
+	limit      int
+	timeout    time.Duration
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "geecache-cli:", err)
+		os.Exit(1)
+	}
+}
+
+// run 解析命令行参数并执行子命令，结果写到 stdout。
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("geecache-cli", flag.ContinueOnError)
+	var o options
+	var peers string
+	fs.StringVar(&peers, "peers", os.Getenv("GEECACHE_PEERS"), "Comma-separated node addresses, e.g. http://10.0.0.1:8001 (default $GEECACHE_PEERS)")
+	fs.StringVar(&o.basePath, "base-path", "/_geecache/", "Base path of the peer protocol")
+	fs.StringVar(&o.token, "token", os.Getenv("GEECACHE_TOKEN"), "Cluster auth token (default $GEECACHE_TOKEN)")
+	fs.StringVar(&o.adminPath, "admin-path", "/_geecache/admin/", "Base path of the admin API")
+	fs.StringVar(&o.adminToken, "admin-token", os.Getenv("GEECACHE_ADMIN_TOKEN"), "Admin API token (default $GEECACHE_ADMIN_TOKEN)")
+	fs.StringVar(&o.format, "format", "raw", "Output format: raw, hex or json")
+	fs.IntVar(&o.limit, "limit", 100, "Maximum number of keys listed per node")
+	fs.DurationVar(&o.timeout, "timeout", 10*time.Second, "Timeout of the whole command")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	for _, p := range strings.Split(peers, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			o.peers = append(o.peers, strings.TrimSuffix(p, "/"))
+		}
+	}
+	if len(o.peers) == 0 {
+		return errors.New("no peers, set -peers or $GEECACHE_PEERS")
+	}
+	switch o.format {
+	case "raw", "hex", "json":
+	default:
+		return fmt.Errorf("unknown format %q, want raw, hex or json", o.format)
+	}
+	if fs.NArg() == 0 {
+		return errors.New("missing command: get, set, del, stats, keys, flush, freeze or unfreeze")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+	defer cancel()
+	cmd, rest := fs.Arg(0), fs.Args()[1:]
+	switch cmd {
+	case "get":
+		if len(rest) != 2 {
+			return errors.New("usage: get <group> <key>")
+		}
+		return get(ctx, o, rest[0], rest[1], stdout)
+	case "set":
+		if len(rest) != 3 {
+			return errors.New("usage: set <group> <key> <value|->")
+		}
+		value := []byte(rest[2])
+		if rest[2] == "-" {
+			var err error
+			if value, err = io.ReadAll(stdin); err != nil {
+				return err
+			}
+		}
+		_, err := o.client().Set(ctx, rest[0], rest[1], value)
+		return err
+	case "del":
+		if len(rest) != 2 {
+			return errors.New("usage: del <group> <key>")
+		}
+		return o.client().Remove(ctx, rest[0], rest[1])
+	case "stats":
+		if len(rest) > 1 {
+			return errors.New("usage: stats [group]")
+		}
+		group := ""
+		if len(rest) == 1 {
+			group = rest[0]
+		}
+		return stats(ctx, o, group, stdout)
+	case "keys":
+		if len(rest) < 1 || len(rest) > 2 {
+			return errors.New("usage: keys <group> [prefix]")
+		}
+		prefix := ""
+		if len(rest) == 2 {
+			prefix = rest[1]
+		}
+		return keys(ctx, o, rest[0], prefix, stdout)
+	case "flush", "freeze", "unfreeze":
+		if len(rest) != 1 {
+			return fmt.Errorf("usage: %s <group>", cmd)
+		}
+		for _, peer := range o.peers {
+			if err := o.admin(ctx, peer, http.MethodPost, cmd, url.Values{"group": {rest[0]}}, nil); err != nil {
+				return fmt.Errorf("%s: %v", peer, err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown command %q", cmd)
+}
+
+// client 创建通过节点间协议访问集群的客户端。
+func (o options) client() *client.Client {
+	opts := []geecache.HTTPPoolOption{geecache.WithBasePath(o.basePath)}
+	if o.token != "" {
+		opts = append(opts, geecache.WithAuthToken(o.token))
+	}
+	return client.New(o.peers, opts...)
+}
+
+// admin 向节点 peer 的管理接口发起请求，响应是 JSON 时解码到 out 中。
+func (o options) admin(ctx context.Context, peer, method, endpoint string, query url.Values, out interface{}) error {
+	u := peer + o.adminPath + endpoint + "?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return err
+	}
+	if o.adminToken != "" {
+		req.Header.Set("Authorization", "Bearer "+o.adminToken)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("server returned: %v: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// get 读取一个键并按输出格式写出它的值。
+func get(ctx context.Context, o options, group, key string, w io.Writer) error {
+	value, err := o.client().Get(ctx, group, key)
+	if err != nil {
+		return err
+	}
+	switch o.format {
+	case "hex":
+		_, err = io.WriteString(w, hex.Dump(value))
+	case "json":
+		out := struct {
+			Group    string `json:"group"`
+			Key      string `json:"key"`
+			Size     int    `json:"size"`
+			Value    string `json:"value"`
+			Encoding string `json:"encoding,omitempty"` // 值不是合法的 UTF-8 时为 "base64"
+		}{Group: group, Key: key, Size: len(value), Value: string(value)}
+		if !utf8.Valid(value) {
+			out.Value = base64.StdEncoding.EncodeToString(value)
+			out.Encoding = "base64"
+		}
+		err = writeJSON(w, out)
+	default:
+		_, err = w.Write(value)
+	}
+	return err
+}
+
+// stats 依次查询每个节点的运行统计。
+func stats(ctx context.Context, o options, group string, w io.Writer) error {
+	all := make(map[string]map[string]geecache.CacheStats, len(o.peers)) // 节点 -> 缓存组 -> 统计
+	for _, peer := range o.peers {
+		perGroup := make(map[string]geecache.CacheStats)
+		if group != "" {
+			var s geecache.CacheStats
+			if err := o.admin(ctx, peer, http.MethodGet, "stats", url.Values{"group": {group}}, &s); err != nil {
+				return fmt.Errorf("%s: %v", peer, err)
+			}
+			perGroup[group] = s
+		} else if err := o.admin(ctx, peer, http.MethodGet, "stats", nil, &perGroup); err != nil {
+			return fmt.Errorf("%s: %v", peer, err)
+		}
+		all[peer] = perGroup
+	}
+	if o.format == "json" {
+		return writeJSON(w, all)
+	}
+	fmt.Fprintf(w, "%-24s %-16s %10s %10s %10s %10s %10s %12s\n", "NODE", "GROUP", "GETS", "HITS", "LOADS", "ERRORS", "ITEMS", "BYTES")
+	for _, peer := range o.peers {
+		names := make([]string, 0, len(all[peer]))
+		for name := range all[peer] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			s := all[peer][name]
+			fmt.Fprintf(w, "%-24s %-16s %10d %10d %10d %10d %10d %12d\n", peer, name, s.Gets, s.Hits, s.Loads, s.LoadErrors, s.Items, s.Bytes)
+		}
+	}
+	return nil
+}
+
+// keys 列出每个节点上缓存组中以 prefix 开头的键，每个节点最多 o.limit 个。
+func keys(ctx context.Context, o options, group, prefix string, w io.Writer) error {
+	all := make(map[string][]string, len(o.peers))
+	for _, peer := range o.peers {
+		var page struct {
+			Keys []string `json:"keys"`
+			Next uint64   `json:"next"`
+		}
+		query := url.Values{"group": {group}, "prefix": {prefix}, "limit": {strconv.Itoa(o.limit)}}
+		if err := o.admin(ctx, peer, http.MethodGet, "keys", query, &page); err != nil {
+			return fmt.Errorf("%s: %v", peer, err)
+		}
+		all[peer] = page.Keys
+	}
+	if o.format == "json" {
+		return writeJSON(w, all)
+	}
+	for _, peer := range o.peers {
+		for _, key := range all[peer] {
+			if o.format == "hex" {
+				key = hex.EncodeToString([]byte(key))
+			}
+			fmt.Fprintf(w, "%s\t%s\n", peer, key)
+		}
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
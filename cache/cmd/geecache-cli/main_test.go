@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"testProject/cache/geecache"
+)
+
+// 测试各个子命令访问一个同时提供节点服务和管理接口的节点
+func TestCommands(t *testing.T) {
+	stored := map[string]string{"Tom": "630"}
+	g := geecache.NewGroup("cli", 0, geecache.GetterFunc(func(key string) ([]byte, error) {
+		if v, ok := stored[key]; ok {
+			return []byte(v), nil
+		}
+		return nil, geecache.ErrNotFound
+	}))
+
+	mux := http.NewServeMux()
+	mux.Handle("/cache/", geecache.NewHTTPPool("http://node", geecache.WithBasePath("/cache/"), geecache.WithAuthToken("peer")))
+	mux.Handle("/cache/admin/", geecache.AdminHandler(geecache.WithAdminBasePath("/cache/admin/"), geecache.WithAdminToken("admin")))
+	srv := httptest.NewUnstartedServer(mux)
+	srv.Config.Protocols = geecache.PeerProtocols()
+	srv.Start()
+	defer srv.Close()
+
+	cli := func(stdin string, args ...string) string {
+		t.Helper()
+		flags := []string{"-peers", srv.URL, "-base-path", "/cache/", "-token", "peer", "-admin-path", "/cache/admin/", "-admin-token", "admin"}
+		var out bytes.Buffer
+		if err := run(append(flags, args...), strings.NewReader(stdin), &out); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		return out.String()
+	}
+
+	if out := cli("", "get", "cli", "Tom"); out != "630" {
+		t.Fatalf("get = %q", out)
+	}
+	cli("589", "set", "cli", "Jack", "-")
+	if out := cli("", "-format", "hex", "get", "cli", "Jack"); !strings.Contains(out, "35 38 39") {
+		t.Fatalf("get -format hex = %q", out)
+	}
+
+	var stats map[string]map[string]geecache.CacheStats
+	if err := json.Unmarshal([]byte(cli("", "-format", "json", "stats", "cli")), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if s := stats[srv.URL]["cli"]; s.Items != 2 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if out := cli("", "keys", "cli", "J"); out != srv.URL+"\tJack\n" {
+		t.Fatalf("keys = %q", out)
+	}
+
+	cli("", "del", "cli", "Jack")
+	cli("", "flush", "cli")
+	if g.Len() != 0 {
+		t.Fatalf("Len() = %d after del and flush", g.Len())
+	}
+	if cli("", "freeze", "cli"); !g.Frozen() {
+		t.Fatal("freeze did not freeze the group")
+	}
+	if cli("", "unfreeze", "cli"); g.Frozen() {
+		t.Fatal("unfreeze did not unfreeze the group")
+	}
+
+	var out bytes.Buffer
+	if err := run([]string{"-peers", srv.URL, "-admin-path", "/cache/admin/", "stats"}, nil, &out); err == nil {
+		t.Fatal("stats without the admin token should fail")
+	}
+}
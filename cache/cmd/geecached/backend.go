@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+
+	"testProject/cache/geecache"
+)
+
+// newGetter 根据数据源配置创建缓存组的 Getter。
+func newGetter(b BackendConfig) geecache.Getter {
+	switch b.Type {
+	case "http":
+		return httpBackend(b)
+	default:
+		return commandBackend(b)
+	}
+}
+
+// httpBackend 通过 GET 请求从 HTTP 服务加载数据，响应体就是值。
+// 404 表示数据源中不存在该键，其他非 200 的状态码视为加载失败。
+func httpBackend(b BackendConfig) geecache.Getter {
+	client := &http.Client{Timeout: b.Timeout}
+	return geecache.ContextGetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		u := strings.ReplaceAll(b.URL, "{key}", url.QueryEscape(key))
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		switch res.StatusCode {
+		case http.StatusOK:
+			return io.ReadAll(res.Body)
+		case http.StatusNotFound:
+			return nil, geecache.ErrNotFound
+		}
+		return nil, fmt.Errorf("backend returned: %v", res.Status)
+	})
+}
+
+// commandBackend 执行命令加载数据，键作为最后一个参数传入，命令的标准输出就是值。
+// 命令以非 0 状态退出时视为加载失败，错误中包含标准错误的内容。
+func commandBackend(b BackendConfig) geecache.Getter {
+	return geecache.ContextGetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(ctx, b.Timeout)
+		defer cancel()
+		args := append(b.Command[1:len(b.Command):len(b.Command)], key)
+		cmd := exec.CommandContext(ctx, b.Command[0], args...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v: %s", b.Command[0], err, bytes.TrimSpace(stderr.Bytes()))
+		}
+		return out, nil
+	})
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config 是 geecached 的配置文件，使用 YAML 或 JSON 编写（JSON 是 YAML 的子集）。
+type Config struct {
+	Listen     string        `yaml:"listen"`      // 节点服务监听的地址，例如 ":8001"
+	Self       string        `yaml:"self"`        // 本节点在集群中的地址，例如 "http://10.0.0.1:8001"，必须出现在 Peers 中
+	Peers      []string      `yaml:"peers"`       // 集群中所有节点的地址，包括本节点
+	AuthToken  string        `yaml:"auth_token"`  // 节点间通信使用的认证令牌，为空时不认证
+	AdminToken string        `yaml:"admin_token"` // 管理接口的认证令牌，为空时不挂载管理接口
+	API        string        `yaml:"api"`         // 对外读取接口监听的地址，为空时不启动
+	LogLevel   string        `yaml:"log_level"`   // 日志级别：debug、info 或 error，默认为 info
+	TLS        *TLSConfig    `yaml:"tls"`         // 节点间的双向 TLS，为空时使用明文
+	Groups     []GroupConfig `yaml:"groups"`      // 本节点提供的缓存组
+}
+
+// TLSConfig 是节点间双向 TLS 使用的证书文件。
+type TLSConfig struct {
+	Cert string `yaml:"cert"` // 本节点的证书
+	Key  string `yaml:"key"`  // 本节点的私钥
+	CA   string `yaml:"ca"`   // 用于验证对端证书的 CA 证书
+}
+
+// GroupConfig 描述一个缓存组。
+type GroupConfig struct {
+	Name        string        `yaml:"name"`
+	CacheBytes  int64         `yaml:"cache_bytes"`  // 主缓存的最大内存，0 表示不限制
+	TTL         time.Duration `yaml:"ttl"`          // 条目的有效期，例如 "5m"，0 表示永不过期
+	NegativeTTL time.Duration `yaml:"negative_ttl"` // 数据源报告不存在的键在负缓存中保留的时间
+	Shards      int           `yaml:"shards"`       // 主缓存的分片数量
+	Backend     BackendConfig `yaml:"backend"`      // 缓存未命中时加载数据的数据源
+}
+
+// BackendConfig 描述缓存组的数据源。
+type BackendConfig struct {
+	Type    string        `yaml:"type"`    // "http" 或 "command"
+	URL     string        `yaml:"url"`     // http：请求的地址，其中的 {key} 替换为转义后的键
+	Command []string      `yaml:"command"` // command：执行的命令及其参数，键作为最后一个参数传入
+	Timeout time.Duration `yaml:"timeout"` // 每次加载的超时时间，默认为 10 秒
+}
+
+// defaultBackendTimeout 是数据源没有设置超时时间时使用的超时时间。
+const defaultBackendTimeout = 10 * time.Second
+
+// LoadConfig 读取并校验 path 处的配置文件。
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig 解析并校验 YAML 或 JSON 格式的配置。
+func ParseConfig(data []byte) (*Config, error) {
+	cfg := &Config{LogLevel: "info"}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing config: %v", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// validate 检查配置中的必填项，并为数据源填充默认的超时时间。
+func (c *Config) validate() error {
+	if c.Listen == "" {
+		return errors.New("config: listen is required")
+	}
+	if c.Self == "" {
+		return errors.New("config: self is required")
+	}
+	if len(c.Peers) == 0 {
+		c.Peers = []string{c.Self} // 单节点部署
+	}
+	found := false
+	for _, p := range c.Peers {
+		found = found || p == c.Self
+	}
+	if !found {
+		return fmt.Errorf("config: self %q is not in peers", c.Self)
+	}
+	if len(c.Groups) == 0 {
+		return errors.New("config: at least one group is required")
+	}
+	names := make(map[string]bool)
+	for i := range c.Groups {
+		g := &c.Groups[i]
+		if g.Name == "" {
+			return fmt.Errorf("config: groups[%d]: name is required", i)
+		}
+		if names[g.Name] {
+			return fmt.Errorf("config: duplicate group %q", g.Name)
+		}
+		names[g.Name] = true
+		if err := g.Backend.validate(); err != nil {
+			return fmt.Errorf("config: group %q: %v", g.Name, err)
+		}
+	}
+	return nil
+}
+
+func (b *BackendConfig) validate() error {
+	switch b.Type {
+	case "http":
+		if b.URL == "" {
+			return errors.New("http backend requires url")
+		}
+	case "command":
+		if len(b.Command) == 0 {
+			return errors.New("command backend requires command")
+		}
+	default:
+		return fmt.Errorf("unknown backend type %q, want http or command", b.Type)
+	}
+	if b.Timeout <= 0 {
+		b.Timeout = defaultBackendTimeout
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"testProject/cache/geecache"
+)
+
+// 测试解析 YAML 和 JSON 配置，以及校验失败的情况
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`
+listen: ":8001"
+self: "http://localhost:8001"
+groups:
+  - name: scores
+    cache_bytes: 1024
+    ttl: 5m
+    backend:
+      type: http
+      url: "http://db/{key}"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := cfg.Groups[0]
+	if g.TTL != 5*time.Minute || g.CacheBytes != 1024 || g.Backend.Timeout != defaultBackendTimeout {
+		t.Fatalf("group = %+v", g)
+	}
+	if len(cfg.Peers) != 1 || cfg.Peers[0] != cfg.Self || cfg.LogLevel != "info" {
+		t.Fatalf("defaults not applied: %+v", cfg)
+	}
+
+	cfg, err = ParseConfig([]byte(`{"listen": ":8001", "self": "a", "peers": ["a", "b"],
+		"groups": [{"name": "users", "backend": {"type": "command", "command": ["cat"]}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Peers) != 2 || cfg.Groups[0].Backend.Command[0] != "cat" {
+		t.Fatalf("json config = %+v", cfg)
+	}
+
+	for _, bad := range []string{
+		`self: a`,
+		`{"listen": ":1", "self": "a", "peers": ["b"], "groups": [{"name": "g", "backend": {"type": "http", "url": "u"}}]}`,
+		`{"listen": ":1", "self": "a", "groups": [{"name": "g", "backend": {"type": "ftp"}}]}`,
+		`{"listen": ":1", "self": "a", "groups": [{"name": "g", "backend": {"type": "http"}}]}`,
+	} {
+		if _, err := ParseConfig([]byte(bad)); err == nil {
+			t.Fatalf("ParseConfig(%s) should fail", bad)
+		}
+	}
+}
+
+// 测试 HTTP 和命令数据源
+func TestBackends(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if k := r.URL.Query().Get("k"); k == "a b" {
+			w.Write([]byte("value of " + k))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	getter := newGetter(BackendConfig{Type: "http", URL: srv.URL + "/?k={key}", Timeout: time.Second})
+	cg := getter.(geecache.ContextGetter)
+	if v, err := cg.GetContext(context.Background(), "a b"); err != nil || string(v) != "value of a b" {
+		t.Fatalf("http backend = %q, %v", v, err)
+	}
+	if _, err := cg.GetContext(context.Background(), "missing"); !errors.Is(err, geecache.ErrNotFound) {
+		t.Fatalf("http backend error = %v, want ErrNotFound", err)
+	}
+
+	getter = newGetter(BackendConfig{Type: "command", Command: []string{"echo", "-n"}, Timeout: time.Second})
+	if v, err := getter.Get("Tom"); err != nil || string(v) != "Tom" {
+		t.Fatalf("command backend = %q, %v", v, err)
+	}
+	getter = newGetter(BackendConfig{Type: "command", Command: []string{"false"}, Timeout: time.Second})
+	if _, err := getter.Get("Tom"); err == nil || !strings.Contains(err.Error(), "false") {
+		t.Fatalf("command backend error = %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+// geecached 根据配置文件运行一个 geecache 节点，部署时不需要再编写 Go 代码。
+//
+// 用法：
+//
+//	geecached -config geecached.yaml
+//
+// 配置文件示例（也可以使用 JSON）：
+//
+//	listen: ":8001"
+//	self: "http://10.0.0.1:8001"
+//	peers: ["http://10.0.0.1:8001", "http://10.0.0.2:8001"]
+//	admin_token: "change-me"
+//	api: ":9999"
+//	groups:
+//	  - name: scores
+//	    cache_bytes: 67108864
+//	    ttl: 5m
+//	    negative_ttl: 30s
+//	    backend:
+//	      type: http
+//	      url: "http://db.internal/scores?key={key}"
+//	  - name: users
+//	    cache_bytes: 33554432
+//	    backend:
+//	      type: command
+//	      command: ["/usr/local/bin/lookup-user"]
+//
+// 收到 SIGINT 或 SIGTERM 时停止接受新请求，等待正在处理的请求完成后退出。
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"testProject/cache/geecache"
+)
+
+// shutdownTimeout 是退出时等待正在处理的请求完成的最长时间。
+const shutdownTimeout = 30 * time.Second
+
+func main() {
+	var configPath string
+	flag.StringVar(&configPath, "config", "geecached.yaml", "Path of the YAML or JSON config file")
+	flag.Parse()
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := run(cfg); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run 按配置启动节点，一直运行到收到退出信号。
+func run(cfg *Config) error {
+	level, err := geecache.ParseLogLevel(cfg.LogLevel)
+	if err != nil {
+		return err
+	}
+	geecache.SetLogLevel(level)
+
+	pool, err := newPool(cfg)
+	if err != nil {
+		return err
+	}
+	for _, gc := range cfg.Groups {
+		newGroup(gc).RegisterPeers(pool)
+	}
+
+	var opts []geecache.ServerOption
+	if cfg.AdminToken != "" {
+		opts = append(opts, geecache.WithAdmin(geecache.AdminHandler(geecache.WithAdminToken(cfg.AdminToken))))
+	}
+	server := geecache.NewServer(cfg.Listen, pool, opts...)
+	if err := server.Start(); err != nil {
+		return err
+	}
+	log.Printf("geecached is running at %s (%s), %d groups", cfg.Listen, cfg.Self, len(cfg.Groups))
+
+	var api *http.Server
+	if cfg.API != "" {
+		api = &http.Server{Addr: cfg.API, Handler: apiHandler(), ReadHeaderTimeout: 5 * time.Second}
+		go func() {
+			if err := api.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("api server on %s stopped: %v", cfg.API, err)
+			}
+		}()
+		log.Println("api server is running at", cfg.API)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	log.Println("shutting down")
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if api != nil {
+		api.Shutdown(ctx)
+	}
+	return server.Shutdown(ctx)
+}
+
+// newPool 创建本节点的 HTTPPool，配置了 TLS 时节点之间使用双向 TLS 通信。
+func newPool(cfg *Config) (*geecache.HTTPPool, error) {
+	var opts []geecache.HTTPPoolOption
+	if cfg.AuthToken != "" {
+		opts = append(opts, geecache.WithAuthToken(cfg.AuthToken))
+	}
+	var pool *geecache.HTTPPool
+	if cfg.TLS != nil {
+		tlsConfig, err := geecache.LoadMutualTLSConfig(cfg.TLS.Cert, cfg.TLS.Key, cfg.TLS.CA)
+		if err != nil {
+			return nil, err
+		}
+		pool = geecache.NewHTTPPoolTLS(cfg.Self, tlsConfig, opts...)
+	} else {
+		pool = geecache.NewHTTPPool(cfg.Self, opts...)
+	}
+	pool.Set(cfg.Peers...)
+	return pool, nil
+}
+
+// newGroup 按配置创建缓存组。
+func newGroup(gc GroupConfig) *geecache.Group {
+	var opts []geecache.GroupOption
+	if gc.TTL > 0 {
+		opts = append(opts, geecache.WithExpiration(gc.TTL))
+	}
+	if gc.NegativeTTL > 0 {
+		opts = append(opts, geecache.WithNegativeTTL(gc.NegativeTTL))
+	}
+	if gc.Shards > 1 {
+		opts = append(opts, geecache.WithShards(gc.Shards))
+	}
+	return geecache.NewGroup(gc.Name, gc.CacheBytes, newGetter(gc.Backend), opts...)
+}
+
+// apiHandler 返回对外的读取接口：GET /api?group=&key= 返回值，/metrics 导出指标。
+func apiHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		g := geecache.GetGroup(q.Get("group"))
+		if g == nil {
+			http.Error(w, "no such group: "+q.Get("group"), http.StatusNotFound)
+			return
+		}
+		view, err := g.GetContext(r.Context(), q.Get("key"))
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, geecache.ErrNotFound) {
+				status = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(view.ByteSlice())
+	})
+	mux.Handle("/metrics", geecache.MetricsHandler())
+	return mux
+}
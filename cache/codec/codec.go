@@ -0,0 +1,124 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec 负责把值编码为缓存中保存的字节，以及把字节解码回值。
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// 内置编解码器的名称。
+const (
+	JSON    = "json"
+	Gob     = "gob"
+	Proto   = "proto"
+	Msgpack = "msgpack"
+)
+
+var (
+	mu     sync.RWMutex             // 用于保护 codecs 映射的读写锁
+	codecs = make(map[string]Codec) // 按名称注册的编解码器
+)
+
+func init() {
+	Register(JSON, jsonCodec{})
+	Register(Gob, gobCodec{})
+	Register(Proto, protoCodec{})
+	Register(Msgpack, msgpackCodec{})
+}
+
+// Register 以 name 注册一个编解码器，同名的编解码器会被替换，可用于注册自定义编解码器。
+func Register(name string, c Codec) {
+	if c == nil {
+		panic("codec: Register nil codec " + name)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	codecs[name] = c
+}
+
+// Get 返回以 name 注册的编解码器，没有找到时 ok 为 false。
+func Get(name string) (c Codec, ok bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok = codecs[name]
+	return c, ok
+}
+
+// MustGet 与 Get 相同，但在没有找到编解码器时 panic，适用于初始化阶段的配置。
+func MustGet(name string) Codec {
+	c, ok := Get(name)
+	if !ok {
+		panic(fmt.Sprintf("codec: unknown codec %q", name))
+	}
+	return c
+}
+
+// Names 返回所有已注册的编解码器名称，按字母顺序排列。
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// jsonCodec 使用 encoding/json 编解码。
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// gobCodec 使用 encoding/gob 编解码，每个值单独编码，包含完整的类型信息。
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// protoCodec 使用 protobuf 编解码，值必须实现 proto.Message。
+type protoCodec struct{}
+
+func (protoCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("codec: %T is not a proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (protoCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("codec: %T is not a proto.Message", v)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+// msgpackCodec 使用 MessagePack 编解码。
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error)      { return msgpack.Marshal(v) }
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error { return msgpack.Unmarshal(data, v) }
@@ -0,0 +1,52 @@
+package codec
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type score struct {
+	Name  string
+	Score int
+}
+
+// 内置编解码器都能把值编码后再原样解码
+func TestBuiltinCodecs(t *testing.T) {
+	for _, name := range []string{JSON, Gob, Msgpack} {
+		c := MustGet(name)
+		data, err := c.Marshal(score{"Tom", 630})
+		if err != nil {
+			t.Fatalf("%s: Marshal: %v", name, err)
+		}
+		var got score
+		if err := c.Unmarshal(data, &got); err != nil || got != (score{"Tom", 630}) {
+			t.Fatalf("%s: Unmarshal = %+v, %v", name, got, err)
+		}
+	}
+
+	c := MustGet(Proto)
+	data, err := c.Marshal(wrapperspb.String("Tom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &wrapperspb.StringValue{}
+	if err := c.Unmarshal(data, got); err != nil || got.GetValue() != "Tom" {
+		t.Fatalf("proto: Unmarshal = %v, %v", got, err)
+	}
+	if _, err := c.Marshal(score{}); err == nil {
+		t.Fatal("proto codec should reject values that are not proto.Message")
+	}
+}
+
+// 可以按名称注册并取回自定义编解码器
+func TestRegister(t *testing.T) {
+	Register("custom", jsonCodec{})
+	if c, ok := Get("custom"); !ok || c != (jsonCodec{}) {
+		t.Fatalf("Get(custom) = %v, %v", c, ok)
+	}
+	if !reflect.DeepEqual(Names(), []string{"custom", Gob, JSON, Msgpack, Proto}) {
+		t.Fatalf("Names() = %v", Names())
+	}
+}
@@ -4,17 +4,27 @@ import (
 	"hash/crc32"
 	"sort"
 	"strconv"
+
+	"github.com/cespare/xxhash/v2"
 )
 
 // Hash 函数将字节数组映射为一个无符号 32 位整数。
 type Hash func(data []byte) uint32
 
+// XXHash 是基于 xxHash64 的 Hash，取低 32 位。它比默认的 CRC32 更快，
+// 对前缀相同、只有末尾几个字节不同的键（例如虚拟节点名 "0host"、"1host"）也分布得更均匀。
+// 集群中的所有节点必须使用相同的散列函数。
+func XXHash(data []byte) uint32 {
+	return uint32(xxhash.Sum64(data))
+}
+
 // Map 结构体包含了所有散列过的键。
 type Map struct {
 	hash     Hash           // 散列函数
 	replicas int            // 虚拟节点的数量
 	keys     []int          // 按顺序排序的虚拟节点的哈希值
 	hashMap  map[int]string // 虚拟节点的哈希值到真实节点的映射
+	weights  map[string]int // 真实节点的权重，节点的虚拟节点数量是 replicas * 权重
 }
 
 // New 创建一个 Map 实例。
@@ -24,31 +34,84 @@ func New(replicas int, fn Hash) *Map {
 		replicas: replicas,
 		hash:     fn,
 		hashMap:  make(map[int]string),
+		weights:  make(map[string]int),
 	}
 	// 如果没有指定散列函数，使用默认的 CRC32 校验和函数。
-	if m.hash == nil {
-		m.hash = crc32.ChecksumIEEE
-	}
+	m.hash = defaultHash(fn)
 	return m
 }
 
+// defaultHash 返回 fn，fn 为 nil 时返回 CRC32 校验和函数。
+func defaultHash(fn Hash) Hash {
+	if fn == nil {
+		return crc32.ChecksumIEEE
+	}
+	return fn
+}
+
+// Add 添加权重为 1 的节点，见 AddWeighted。
 func (m *Map) Add(keys ...string) {
 	// 遍历传入的节点（键）列表。
 	for _, key := range keys {
-		// 为每个节点（键）创建多个虚拟节点（副本），并为每个虚拟节点计算哈希值。
-		for i := 0; i < m.replicas; i++ {
-			// 计算虚拟节点的哈希值，将虚拟节点的索引和节点键组合后进行哈希计算。
-			hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
+		m.add(key, 1)
+	}
+	// 对 keys 列表中的虚拟节点哈希值进行排序，以便进行二分查找。
+	sort.Ints(m.keys)
+}
+
+// AddWeighted 添加一个权重为 weight 的节点，它有 replicas * weight 个虚拟节点，
+// 分到的键的数量大致与权重成正比，适合配置不同的机器混合部署的集群。weight 不大于 0 时按 1 处理。
+// 权重为 1 的节点与 Add 添加的节点完全相同；节点已经在环中时先 Remove 再添加才能改变其权重。
+func (m *Map) AddWeighted(key string, weight int) {
+	m.add(key, max(weight, 1))
+	sort.Ints(m.keys)
+}
+
+// add 为节点 key 创建 replicas * weight 个虚拟节点，调用方负责之后对 keys 排序。
+func (m *Map) add(key string, weight int) {
+	m.weights[key] = weight
+	// 为每个节点（键）创建多个虚拟节点（副本），并为每个虚拟节点计算哈希值。
+	// 虚拟节点的编号从 0 开始，权重更大的节点只是多出更大的编号，权重为 1 的部分与 Add 相同。
+	for i := 0; i < m.replicas*weight; i++ {
+		// 计算虚拟节点的哈希值，将虚拟节点的索引和节点键组合后进行哈希计算。
+		hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
 
-			// 将虚拟节点的哈希值添加到 keys 列表中，以便后续查找。
-			m.keys = append(m.keys, hash)
+		// 将虚拟节点的哈希值添加到 keys 列表中，以便后续查找。
+		m.keys = append(m.keys, hash)
 
-			// 在 hashMap 中建立虚拟节点的哈希值到实际节点的映射。
-			m.hashMap[hash] = key
+		// 在 hashMap 中建立虚拟节点的哈希值到实际节点的映射。
+		m.hashMap[hash] = key
+	}
+}
+
+// Remove 从哈希环中删除节点 key 的所有虚拟节点，其他节点的虚拟节点保持不变，
+// 原来属于 key 的键会落到环上的下一个节点。key 不在环中时什么也不做。
+func (m *Map) Remove(key string) {
+	weight, ok := m.weights[key]
+	if !ok {
+		return
+	}
+	delete(m.weights, key)
+	removed := false
+	for i := 0; i < m.replicas*weight; i++ {
+		hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
+		// 只删除仍然属于 key 的虚拟节点，哈希冲突时该位置可能已经属于其他节点。
+		if m.hashMap[hash] == key {
+			delete(m.hashMap, hash)
+			removed = true
 		}
 	}
-	// 对 keys 列表中的虚拟节点哈希值进行排序，以便进行二分查找。
-	sort.Ints(m.keys)
+	if !removed {
+		return
+	}
+	// keys 原本有序，原地过滤掉已经删除的虚拟节点后仍然有序，无需重新排序。
+	keys := m.keys[:0]
+	for _, hash := range m.keys {
+		if _, ok := m.hashMap[hash]; ok {
+			keys = append(keys, hash)
+		}
+	}
+	m.keys = keys
 }
 
 // Get 方法用于根据给定的键（key）查找对应的节点。
@@ -69,3 +132,25 @@ func (m *Map) Get(key string) string {
 	// 通过模运算找到最终映射的节点。
 	return m.hashMap[m.keys[idx%len(m.keys)]]
 }
+
+// GetN 返回从 key 在环上的位置开始顺时针遇到的最多 n 个不同的真实节点，第一个就是 Get 返回的节点。
+// 所有者不可用时，可以依次尝试之后的节点，所有节点对同一个 key 得到的顺序相同。
+func (m *Map) GetN(key string, n int) []string {
+	if len(m.keys) == 0 || n <= 0 {
+		return nil
+	}
+	hash := int(m.hash([]byte(key)))
+	idx := sort.Search(len(m.keys), func(i int) bool {
+		return m.keys[i] >= hash
+	})
+	var nodes []string
+	seen := make(map[string]bool)
+	for i := 0; i < len(m.keys) && len(nodes) < n; i++ {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
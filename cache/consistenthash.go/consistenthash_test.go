@@ -1,6 +1,8 @@
 package consistenthashgo
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
 	"strconv"
 	"testing"
 )
@@ -41,3 +43,203 @@ func TestHashing(t *testing.T) {
 	}
 
 }
+
+// 测试删除节点后，原来属于它的键落到环上的下一个节点，其他键不受影响
+func TestRemove(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	// 虚拟节点：2, 4, 6, 12, 14, 16, 22, 24, 26
+	hash.Add("6", "4", "2")
+
+	hash.Remove("4")
+	testCases := map[string]string{
+		"2":  "2",
+		"3":  "6", // 原来属于 4
+		"23": "6", // 原来属于 4
+		"27": "2",
+	}
+	for k, v := range testCases {
+		if hash.Get(k) != v {
+			t.Errorf("Asking for %s, should have yielded %s", k, v)
+		}
+	}
+
+	hash.Remove("4") // 删除不在环中的节点不影响其他节点
+	hash.Remove("6")
+	hash.Remove("2")
+	if got := hash.Get("1"); got != "" {
+		t.Errorf("empty ring should yield no node, got %s", got)
+	}
+}
+
+// 测试 GetN 按环上的顺序返回不同的节点
+func TestGetN(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	// 虚拟节点：2, 4, 6, 12, 14, 16, 22, 24, 26
+	hash.Add("6", "4", "2")
+	if got := hash.GetN("3", 2); len(got) != 2 || got[0] != "4" || got[1] != "6" {
+		t.Errorf("GetN(3, 2) = %v, want [4 6]", got)
+	}
+	if got := hash.GetN("27", 5); len(got) != 3 || got[0] != "2" || got[1] != "4" || got[2] != "6" {
+		t.Errorf("GetN(27, 5) = %v, want [2 4 6]", got)
+	}
+}
+
+// 测试节点分到的键的数量与权重成正比，删除加权节点时删除它的所有虚拟节点
+func TestAddWeighted(t *testing.T) {
+	// CRC32 对只差一个字符的虚拟节点名称分布不够均匀，这里使用 SHA-256 以便检验比例。
+	hash := New(100, func(data []byte) uint32 {
+		sum := sha256.Sum256(data)
+		return binary.BigEndian.Uint32(sum[:])
+	})
+	hash.AddWeighted("big", 3)
+	hash.Add("small")
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		counts[hash.Get(strconv.Itoa(i))]++
+	}
+	if share := float64(counts["big"]) / 10000; share < 0.65 || share > 0.85 {
+		t.Errorf("big node owns %.2f of the keys, want about 0.75", share)
+	}
+
+	hash.Remove("big")
+	if len(hash.keys) != 100 {
+		t.Fatalf("%d virtual nodes left after removing big, want 100", len(hash.keys))
+	}
+	if got := hash.Get("any"); got != "small" {
+		t.Errorf("Get after removing big = %q, want small", got)
+	}
+}
+
+// strategies 是测试和基准测试比较的所有策略
+var strategies = []struct {
+	name string
+	new  StrategyFunc
+}{
+	{"ring", RingStrategy},
+	{"rendezvous", RendezvousStrategy},
+	{"jump", JumpStrategy},
+	{"maglev", MaglevStrategy},
+}
+
+// 测试所有策略的共同约定：GetN 的第一个节点就是 Get 的结果，节点不重复，加权节点分到更多的键
+func TestStrategies(t *testing.T) {
+	for _, s := range strategies {
+		t.Run(s.name, func(t *testing.T) {
+			m := s.new(50, nil)
+			if m.Get("k") != "" || m.GetN("k", 2) != nil {
+				t.Fatal("an empty strategy should return no nodes")
+			}
+			m.Add("c", "a", "b")
+			for i := 0; i < 100; i++ {
+				key := strconv.Itoa(i)
+				nodes := m.GetN(key, 5)
+				if len(nodes) != 3 || nodes[0] != m.Get(key) || nodes[0] == nodes[1] || nodes[1] == nodes[2] || nodes[0] == nodes[2] {
+					t.Fatalf("GetN(%q, 5) = %v, Get = %q", key, nodes, m.Get(key))
+				}
+			}
+			m.Remove("b")
+			for i := 0; i < 100; i++ {
+				if m.Get(strconv.Itoa(i)) == "b" {
+					t.Fatal("removed node still owns keys")
+				}
+			}
+			if s.name == "ring" {
+				return // CRC32 下哈希环的比例不稳定，见 TestAddWeighted
+			}
+			m.AddWeighted("b", 3)
+			counts := make(map[string]int)
+			for i := 0; i < 10000; i++ {
+				counts[m.Get("key"+strconv.Itoa(i))]++
+			}
+			if share := float64(counts["b"]) / 10000; share < 0.5 || share > 0.7 {
+				t.Errorf("node with weight 3 of 5 owns %.2f of the keys, want about 0.6", share)
+			}
+		})
+	}
+}
+
+// 测试查找表大小不是质数时向上取到质数，填表不会在部分槽位上无限循环
+func TestMaglevNonPrimeSize(t *testing.T) {
+	for _, size := range []int{6, 100, 1000} {
+		m := NewMaglev(size, nil)
+		m.Add("a", "b", "c")
+		if m.size < size || len(m.table) != m.size {
+			t.Fatalf("NewMaglev(%d): size = %d, table = %d", size, m.size, len(m.table))
+		}
+		for _, slot := range m.table {
+			if slot < 0 {
+				t.Fatalf("NewMaglev(%d): table has an empty slot", size)
+			}
+		}
+	}
+	if m := NewMaglev(100, nil); m.size != 101 {
+		t.Errorf("NewMaglev(100).size = %d, want 101", m.size)
+	}
+}
+
+// 比较各个策略的查找开销，以及分布质量：max/mean 是分到最多键的节点与平均值之比，
+// moved% 是删除一个节点时改变所有者的键的比例（理想值是 1/节点数）。
+func BenchmarkStrategies(b *testing.B) {
+	keys := make([]string, 100000)
+	for i := range keys {
+		keys[i] = "key" + strconv.Itoa(i)
+	}
+	for _, s := range strategies {
+		for _, n := range []int{10, 100} {
+			b.Run(s.name+"/nodes="+strconv.Itoa(n), func(b *testing.B) {
+				nodes := make([]string, n)
+				for i := range nodes {
+					nodes[i] = "10.0.0." + strconv.Itoa(i) + ":8001"
+				}
+				m := s.new(50, nil)
+				m.Add(nodes...)
+				counts := make(map[string]int)
+				before := make([]string, len(keys))
+				for i, key := range keys {
+					before[i] = m.Get(key)
+					counts[before[i]]++
+				}
+				most := 0
+				for _, c := range counts {
+					most = max(most, c)
+				}
+				m.Remove(nodes[n/2])
+				moved := 0
+				for i, key := range keys {
+					if m.Get(key) != before[i] {
+						moved++
+					}
+				}
+				m.Add(nodes[n/2])
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					m.Get(keys[i%len(keys)])
+				}
+				b.ReportMetric(float64(most)*float64(n)/float64(len(keys)), "max/mean")
+				b.ReportMetric(float64(moved)*100/float64(len(keys)), "moved%")
+			})
+		}
+	}
+}
+
+// 测试使用 XXHash 时每个节点分到的键数量大致相同
+func TestXXHashDistribution(t *testing.T) {
+	m := New(50, XXHash)
+	m.Add("http://10.0.0.1:8001", "http://10.0.0.2:8001", "http://10.0.0.3:8001")
+	counts := make(map[string]int)
+	for i := 0; i < 30000; i++ {
+		counts[m.Get("key"+strconv.Itoa(i))]++
+	}
+	for node, n := range counts {
+		if n < 7000 || n > 13000 {
+			t.Errorf("%s got %d of 30000 keys, want about 10000", node, n)
+		}
+	}
+}
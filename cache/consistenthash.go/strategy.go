@@ -0,0 +1,408 @@
+package consistenthashgo
+
+import (
+	"math"
+	"slices"
+)
+
+// Strategy 把键映射到节点。Map（哈希环）是默认的实现，Rendezvous、Jump 和 Maglev 是其他可选的实现，
+// 它们在分布的均匀程度、查找的开销以及节点变化时需要迁移的键的比例上各有取舍。
+// 同一个集群中的所有节点必须使用相同的策略、散列函数和节点权重。Strategy 不是并发安全的。
+type Strategy interface {
+	// Add 添加权重为 1 的节点。
+	Add(nodes ...string)
+	// AddWeighted 添加一个权重为 weight 的节点，分到的键的数量大致与权重成正比。weight 不大于 0 时按 1 处理。
+	AddWeighted(node string, weight int)
+	// Remove 删除节点，节点不存在时什么也不做。
+	Remove(node string)
+	// Get 返回 key 所属的节点，没有节点时返回空字符串。
+	Get(key string) string
+	// GetN 返回 key 的最多 n 个不同的候选节点，第一个就是 Get 返回的节点，所有节点对同一个 key 得到的顺序相同。
+	GetN(key string, n int) []string
+}
+
+// StrategyFunc 创建一个空的 Strategy，replicas 和 fn 是调用方配置的虚拟节点数量和散列函数，
+// 不使用虚拟节点的策略忽略 replicas，fn 为 nil 时使用 CRC32。
+type StrategyFunc func(replicas int, fn Hash) Strategy
+
+// RingStrategy 使用带虚拟节点的哈希环，见 Map。
+func RingStrategy(replicas int, fn Hash) Strategy {
+	return New(replicas, fn)
+}
+
+// RendezvousStrategy 使用最高随机权重（HRW）哈希，见 Rendezvous。
+func RendezvousStrategy(replicas int, fn Hash) Strategy {
+	return NewRendezvous(fn)
+}
+
+// JumpStrategy 使用跳跃一致性哈希，见 Jump。
+func JumpStrategy(replicas int, fn Hash) Strategy {
+	return NewJump(fn)
+}
+
+// MaglevStrategy 使用 DefaultMaglevTableSize 大小的 Maglev 查找表，见 Maglev。
+func MaglevStrategy(replicas int, fn Hash) Strategy {
+	return NewMaglev(DefaultMaglevTableSize, fn)
+}
+
+var (
+	_ Strategy = (*Map)(nil)
+	_ Strategy = (*Rendezvous)(nil)
+	_ Strategy = (*Jump)(nil)
+	_ Strategy = (*Maglev)(nil)
+)
+
+// mix64 是 splitmix64 的终结函数，把 32 位散列值扩展为各位充分混合的 64 位值。
+// CRC32 这样的线性散列函数对相近的输入给出相近的结果，直接用来打分或者跳跃分布会很不均匀。
+func mix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// weightedNodes 是按名称排序的带权重的节点集合，各个策略用它保存节点。
+// 排序保证节点以不同的顺序加入时所有节点得到相同的结果。
+type weightedNodes struct {
+	names   []string
+	weights []int
+}
+
+// add 添加或更新节点的权重。
+func (w *weightedNodes) add(node string, weight int) {
+	i, found := slices.BinarySearch(w.names, node)
+	if found {
+		w.weights[i] = weight
+		return
+	}
+	w.names = slices.Insert(w.names, i, node)
+	w.weights = slices.Insert(w.weights, i, weight)
+}
+
+// remove 删除节点，返回节点是否存在。
+func (w *weightedNodes) remove(node string) bool {
+	i, found := slices.BinarySearch(w.names, node)
+	if !found {
+		return false
+	}
+	w.names = slices.Delete(w.names, i, i+1)
+	w.weights = slices.Delete(w.weights, i, i+1)
+	return true
+}
+
+// unitFloat 把 64 位散列值映射到 (0, 1) 之间的浮点数。
+func unitFloat(x uint64) float64 {
+	return (float64(x>>11) + 0.5) / (1 << 53)
+}
+
+// Rendezvous 是最高随机权重（HRW）哈希：对每个节点计算 key 与节点组合的随机分数，分数最高的节点是所有者。
+// 分布均匀且不需要虚拟节点，删除一个节点只会迁移它自己的键，GetN 就是分数最高的 n 个节点。
+// 代价是每次查找都要对所有节点打分，查找开销与节点数量成正比，适合节点不多的集群。
+type Rendezvous struct {
+	hash   Hash
+	nodes  weightedNodes
+	hashes []uint64 // 每个节点名称的散列值，与 nodes 对应
+}
+
+// NewRendezvous 创建一个 Rendezvous，fn 为 nil 时使用 CRC32。
+func NewRendezvous(fn Hash) *Rendezvous {
+	return &Rendezvous{hash: defaultHash(fn)}
+}
+
+// Add 添加权重为 1 的节点。
+func (r *Rendezvous) Add(nodes ...string) {
+	for _, node := range nodes {
+		r.nodes.add(node, 1)
+	}
+	r.rehash()
+}
+
+// AddWeighted 添加一个权重为 weight 的节点。加权使用对数方法：分数为 -weight/ln(u)，u 是 (0, 1) 之间的随机数。
+func (r *Rendezvous) AddWeighted(node string, weight int) {
+	r.nodes.add(node, max(weight, 1))
+	r.rehash()
+}
+
+// Remove 删除节点。
+func (r *Rendezvous) Remove(node string) {
+	if r.nodes.remove(node) {
+		r.rehash()
+	}
+}
+
+// rehash 重新计算所有节点名称的散列值。
+func (r *Rendezvous) rehash() {
+	r.hashes = r.hashes[:0]
+	for _, node := range r.nodes.names {
+		r.hashes = append(r.hashes, mix64(uint64(r.hash([]byte(node)))))
+	}
+}
+
+// score 返回第 i 个节点对散列值为 h 的键的分数。
+func (r *Rendezvous) score(i int, h uint64) float64 {
+	u := unitFloat(mix64(r.hashes[i] ^ h))
+	return -float64(r.nodes.weights[i]) / math.Log(u)
+}
+
+// Get 返回分数最高的节点。
+func (r *Rendezvous) Get(key string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	h := mix64(uint64(r.hash([]byte(key))))
+	best, bestScore := 0, r.score(0, h)
+	for i := 1; i < len(r.hashes); i++ {
+		if s := r.score(i, h); s > bestScore {
+			best, bestScore = i, s
+		}
+	}
+	return r.nodes.names[best]
+}
+
+// GetN 按分数从高到低返回最多 n 个节点。
+func (r *Rendezvous) GetN(key string, n int) []string {
+	if len(r.hashes) == 0 || n <= 0 {
+		return nil
+	}
+	h := mix64(uint64(r.hash([]byte(key))))
+	order := make([]int, len(r.hashes))
+	scores := make([]float64, len(r.hashes))
+	for i := range order {
+		order[i], scores[i] = i, r.score(i, h)
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		if scores[a] > scores[b] {
+			return -1
+		}
+		if scores[a] < scores[b] {
+			return 1
+		}
+		return 0
+	})
+	nodes := make([]string, 0, min(n, len(order)))
+	for _, i := range order[:min(n, len(order))] {
+		nodes = append(nodes, r.nodes.names[i])
+	}
+	return nodes
+}
+
+// Jump 是跳跃一致性哈希（Lamping & Veach）：不需要任何查找表，O(ln n) 时间把键映射到 n 个桶之一，
+// 分布几乎完全均匀，桶的数量从 n 增加到 n+1 时只有 1/(n+1) 的键迁移。
+// 桶按节点名称排序后依次分配，权重为 w 的节点占 w 个相邻的桶。只有名称排在最后的节点加入或离开时迁移才是最少的，
+// 其他位置的节点变化会让它之后的桶整体移动，适合节点很少变化、或者新节点的名称总是排在最后的集群。
+type Jump struct {
+	hash    Hash
+	nodes   weightedNodes
+	buckets []string // 每个桶所属的节点
+}
+
+// NewJump 创建一个 Jump，fn 为 nil 时使用 CRC32。
+func NewJump(fn Hash) *Jump {
+	return &Jump{hash: defaultHash(fn)}
+}
+
+// Add 添加权重为 1 的节点。
+func (j *Jump) Add(nodes ...string) {
+	for _, node := range nodes {
+		j.nodes.add(node, 1)
+	}
+	j.rebuild()
+}
+
+// AddWeighted 添加一个权重为 weight 的节点，它占 weight 个桶。
+func (j *Jump) AddWeighted(node string, weight int) {
+	j.nodes.add(node, max(weight, 1))
+	j.rebuild()
+}
+
+// Remove 删除节点。
+func (j *Jump) Remove(node string) {
+	if j.nodes.remove(node) {
+		j.rebuild()
+	}
+}
+
+// rebuild 按节点的顺序和权重重新分配桶。
+func (j *Jump) rebuild() {
+	j.buckets = j.buckets[:0]
+	for i, node := range j.nodes.names {
+		for w := 0; w < j.nodes.weights[i]; w++ {
+			j.buckets = append(j.buckets, node)
+		}
+	}
+}
+
+// jumpHash 返回 key 所在的桶，0 <= 结果 < buckets。
+func jumpHash(key uint64, buckets int) int {
+	b, next := -1, 0
+	for next < buckets {
+		b = next
+		key = key*2862933555777941757 + 1
+		next = int(float64(b+1) * (float64(1<<31) / float64((key>>33)+1)))
+	}
+	return b
+}
+
+// Get 返回 key 所在的桶所属的节点。
+func (j *Jump) Get(key string) string {
+	if len(j.buckets) == 0 {
+		return ""
+	}
+	return j.buckets[jumpHash(mix64(uint64(j.hash([]byte(key)))), len(j.buckets))]
+}
+
+// GetN 返回最多 n 个不同的节点：第一个是 Get 的结果，之后依次对 key 重新散列，跳过已经选中的节点。
+func (j *Jump) GetN(key string, n int) []string {
+	if len(j.buckets) == 0 || n <= 0 {
+		return nil
+	}
+	n = min(n, len(j.nodes.names))
+	h := mix64(uint64(j.hash([]byte(key))))
+	nodes := make([]string, 0, n)
+	// 每次重新散列都可能落到已经选中的节点上，尝试的次数设置上限，最后按名称顺序补足。
+	for i := 0; len(nodes) < n && i < 64*n; i++ {
+		if node := j.buckets[jumpHash(h, len(j.buckets))]; !slices.Contains(nodes, node) {
+			nodes = append(nodes, node)
+		}
+		h = mix64(h)
+	}
+	for _, node := range j.nodes.names {
+		if len(nodes) == n {
+			break
+		}
+		if !slices.Contains(nodes, node) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// DefaultMaglevTableSize 是 MaglevStrategy 使用的查找表大小，它是质数，适合最多几百个节点的集群。
+const DefaultMaglevTableSize = 65537
+
+// Maglev 是 Google Maglev 负载均衡器使用的一致性哈希：每个节点按自己的排列轮流占据查找表中的槽位，
+// 查找只需要一次散列和一次数组访问，各节点分到的槽位数量最多相差一个，分布非常均匀。
+// 代价是每次增删节点都要重建整个查找表，节点变化时迁移的键比哈希环略多。
+// 权重为 w 的节点每一轮占据 w 个槽位。
+type Maglev struct {
+	hash  Hash
+	size  int // 查找表的大小，应当是远大于节点数量的质数
+	nodes weightedNodes
+	table []int // 每个槽位所属的节点在 nodes 中的下标
+}
+
+// NewMaglev 创建一个查找表大小为 size 的 Maglev，size 应当远大于节点数量（至少 100 倍），
+// 不是质数时向上取到下一个质数，小于 2 时使用 DefaultMaglevTableSize。fn 为 nil 时使用 CRC32。
+func NewMaglev(size int, fn Hash) *Maglev {
+	if size < 2 {
+		size = DefaultMaglevTableSize
+	}
+	return &Maglev{hash: defaultHash(fn), size: nextPrime(size)}
+}
+
+// nextPrime 返回不小于 n 的最小质数。
+// 查找表的大小必须是质数，每个节点的排列才能遍历所有槽位；否则 skip 与大小有公因数时排列只覆盖一部分槽位，
+// 填表会在已经占满的槽位上无限循环。
+func nextPrime(n int) int {
+	for ; ; n++ {
+		prime := n >= 2
+		for d := 2; d*d <= n; d++ {
+			if n%d == 0 {
+				prime = false
+				break
+			}
+		}
+		if prime {
+			return n
+		}
+	}
+}
+
+// Add 添加权重为 1 的节点。
+func (m *Maglev) Add(nodes ...string) {
+	for _, node := range nodes {
+		m.nodes.add(node, 1)
+	}
+	m.populate()
+}
+
+// AddWeighted 添加一个权重为 weight 的节点。
+func (m *Maglev) AddWeighted(node string, weight int) {
+	m.nodes.add(node, max(weight, 1))
+	m.populate()
+}
+
+// Remove 删除节点。
+func (m *Maglev) Remove(node string) {
+	if m.nodes.remove(node) {
+		m.populate()
+	}
+}
+
+// populate 重建查找表：每个节点的排列由 offset 和 skip 决定，节点依次取自己排列中下一个空闲的槽位，直到填满。
+func (m *Maglev) populate() {
+	n := len(m.nodes.names)
+	if n == 0 {
+		m.table = nil
+		return
+	}
+	size := uint64(m.size)
+	offsets := make([]uint64, n)
+	skips := make([]uint64, n)
+	next := make([]uint64, n)
+	for i, node := range m.nodes.names {
+		h := mix64(uint64(m.hash([]byte(node))))
+		offsets[i] = h % size
+		skips[i] = (h>>32)%(size-1) + 1
+	}
+	table := make([]int, m.size)
+	for i := range table {
+		table[i] = -1
+	}
+	for filled := 0; ; {
+		for i := 0; i < n; i++ {
+			for w := 0; w < m.nodes.weights[i]; w++ {
+				c := (offsets[i] + next[i]*skips[i]) % size
+				for table[c] >= 0 {
+					next[i]++
+					c = (offsets[i] + next[i]*skips[i]) % size
+				}
+				table[c] = i
+				next[i]++
+				if filled++; filled == m.size {
+					m.table = table
+					return
+				}
+			}
+		}
+	}
+}
+
+// Get 返回 key 所在槽位所属的节点。
+func (m *Maglev) Get(key string) string {
+	if len(m.table) == 0 {
+		return ""
+	}
+	return m.nodes.names[m.table[m.slot(key)]]
+}
+
+// slot 返回 key 在查找表中的槽位。
+func (m *Maglev) slot(key string) int {
+	return int(mix64(uint64(m.hash([]byte(key)))) % uint64(m.size))
+}
+
+// GetN 从 key 的槽位开始依次向后查找，返回最多 n 个不同的节点。
+func (m *Maglev) GetN(key string, n int) []string {
+	if len(m.table) == 0 || n <= 0 {
+		return nil
+	}
+	n = min(n, len(m.nodes.names))
+	nodes := make([]string, 0, n)
+	start := m.slot(key)
+	for i := 0; i < m.size && len(nodes) < n; i++ {
+		if node := m.nodes.names[m.table[(start+i)%m.size]]; !slices.Contains(nodes, node) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,124 @@
+// Package consul 基于 Consul 实现节点注册和发现：节点把自己注册为带健康检查的服务实例，
+// 并通过阻塞查询订阅该服务通过健康检查的实例，实例变化时自动更新节点列表。
+package consul
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+
+	"testProject/cache/discovery"
+)
+
+// defaultHealthPath 是 HTTPPool 使用默认 basePath 时的健康检查路径。
+const defaultHealthPath = "/_geecache/_health"
+
+// Registration 描述本节点注册到 Consul 的服务实例。
+type Registration struct {
+	Service string // 服务名，所有节点使用相同的服务名
+	ID      string // 实例 ID，默认为 "<Service>-<Address>:<Port>"
+	Address string // 本节点的主机名或 IP
+	Port    int    // 本节点的端口
+	// HealthURL 是 Consul 用于健康检查的 HTTP 地址，默认为 http://<Address>:<Port>/_geecache/_health。
+	HealthURL string
+	// CheckInterval 是健康检查的间隔，默认为 10 秒。
+	CheckInterval time.Duration
+	// DeregisterAfter 是健康检查持续失败多久之后 Consul 自动注销该实例，默认为 1 分钟。
+	DeregisterAfter time.Duration
+}
+
+// Register 把本节点注册为 Consul 服务实例并附带 HTTP 健康检查，返回的 deregister 用于在正常关闭时注销。
+// 节点异常退出时，Consul 会在健康检查持续失败 DeregisterAfter 之后自动注销它。
+func Register(client *api.Client, r Registration) (deregister func() error, err error) {
+	hostport := net.JoinHostPort(r.Address, strconv.Itoa(r.Port))
+	if r.ID == "" {
+		r.ID = r.Service + "-" + hostport
+	}
+	if r.HealthURL == "" {
+		r.HealthURL = "http://" + hostport + defaultHealthPath
+	}
+	if r.CheckInterval <= 0 {
+		r.CheckInterval = 10 * time.Second
+	}
+	if r.DeregisterAfter <= 0 {
+		r.DeregisterAfter = time.Minute
+	}
+	err = client.Agent().ServiceRegister(&api.AgentServiceRegistration{
+		ID:      r.ID,
+		Name:    r.Service,
+		Address: r.Address,
+		Port:    r.Port,
+		Check: &api.AgentServiceCheck{
+			HTTP:                           r.HealthURL,
+			Interval:                       r.CheckInterval.String(),
+			Timeout:                        r.CheckInterval.String(),
+			DeregisterCriticalServiceAfter: r.DeregisterAfter.String(),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("consul: register %s: %v", r.ID, err)
+	}
+	return func() error {
+		return client.Agent().ServiceDeregister(r.ID)
+	}, nil
+}
+
+// retryInterval 是查询 Consul 失败之后重试之前的等待时间。
+const retryInterval = time.Second
+
+// Watch 通过阻塞查询订阅 service 中通过健康检查的实例，实例列表变化时用完整的节点列表调用 set。
+// 节点地址为 "<scheme>://<地址>:<端口>"，scheme 为空时使用 "http"。
+// 查询失败时等待一段时间后重试，保留上一次的节点列表。Watch 一直阻塞到 ctx 结束，返回 ctx 的错误。
+func Watch(ctx context.Context, client *api.Client, service string, scheme string, set discovery.Setter) error {
+	if scheme == "" {
+		scheme = "http"
+	}
+	var index uint64
+	var last []string
+	for {
+		opts := (&api.QueryOptions{WaitIndex: index}).WithContext(ctx)
+		entries, meta, err := client.Health().Service(service, "", true, opts)
+		if err != nil {
+			timer := time.NewTimer(retryInterval)
+			select {
+			case <-timer.C:
+				continue
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			}
+		}
+		if meta.LastIndex < index {
+			index = 0 // Consul 的索引回退（例如重启）时重新开始
+		} else {
+			index = meta.LastIndex
+		}
+
+		peers := peerList(entries, scheme)
+		if !slices.Equal(peers, last) {
+			set(peers...)
+			last = peers
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// peerList 把服务实例转换为有序的节点地址列表。实例没有单独设置地址时使用所在 Consul 节点的地址。
+func peerList(entries []*api.ServiceEntry, scheme string) []string {
+	set := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		addr := e.Service.Address
+		if addr == "" {
+			addr = e.Node.Address
+		}
+		set[scheme+"://"+net.JoinHostPort(addr, strconv.Itoa(e.Service.Port))] = true
+	}
+	return discovery.Sorted(set)
+}
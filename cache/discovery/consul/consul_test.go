@@ -0,0 +1,71 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// 测试 Watch 用通过健康检查的实例更新节点列表，实例没有单独的地址时使用节点地址
+func TestWatch(t *testing.T) {
+	var registered, deregistered string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/agent/service/register":
+			var reg api.AgentServiceRegistration
+			json.NewDecoder(r.Body).Decode(&reg)
+			registered = reg.Check.HTTP
+		case strings.HasPrefix(r.URL.Path, "/v1/agent/service/deregister/"):
+			deregistered = strings.TrimPrefix(r.URL.Path, "/v1/agent/service/deregister/")
+		case r.URL.Path == "/v1/health/service/geecache":
+			if r.URL.Query().Get("index") == "7" {
+				<-r.Context().Done() // 实例没有变化，阻塞查询一直等待
+				return
+			}
+			w.Header().Set("X-Consul-Index", "7")
+			fmt.Fprint(w, `[
+				{"Node": {"Address": "10.0.0.2"}, "Service": {"Port": 8002}},
+				{"Node": {"Address": "10.0.0.9"}, "Service": {"Address": "10.0.0.1", "Port": 8001}}
+			]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	client, err := api.NewClient(&api.Config{Address: strings.TrimPrefix(srv.URL, "http://")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deregister, err := Register(client, Registration{Service: "geecache", Address: "10.0.0.1", Port: 8001})
+	if err != nil || registered != "http://10.0.0.1:8001/_geecache/_health" {
+		t.Fatalf("Register: check %q, %v", registered, err)
+	}
+	if err := deregister(); err != nil || deregistered != "geecache-10.0.0.1:8001" {
+		t.Fatalf("deregister: %q, %v", deregistered, err)
+	}
+
+	got := make(chan []string, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- Watch(ctx, client, "geecache", "", func(peers ...string) { got <- peers }) }()
+	select {
+	case peers := <-got:
+		if len(peers) != 2 || peers[0] != "http://10.0.0.1:8001" || peers[1] != "http://10.0.0.2:8002" {
+			t.Fatalf("peers = %v", peers)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch did not set peers")
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Watch returned %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,21 @@
+// Package discovery 定义动态节点发现的公共部分，各种注册中心的实现位于子包中。
+// 发现到的节点列表变化时，实现会用完整的节点列表调用 Setter，例如：
+//
+//	pool := geecache.NewHTTPPool(self)
+//	go etcd.Watch(ctx, client, "/geecache/nodes/", pool.Set)
+package discovery
+
+import "sort"
+
+// Setter 接收最新的完整节点列表，geecache.HTTPPool 的 Set 方法可以直接作为 Setter 使用。
+type Setter func(peers ...string)
+
+// Sorted 返回 set 中的节点组成的有序列表，用于以确定的顺序调用 Setter。
+func Sorted(set map[string]bool) []string {
+	peers := make([]string, 0, len(set))
+	for peer := range set {
+		peers = append(peers, peer)
+	}
+	sort.Strings(peers)
+	return peers
+}
@@ -0,0 +1,113 @@
+// Package dns 通过定期解析 DNS 名称发现节点，适用于 Kubernetes 的 headless service：
+// headless service 的 A 记录是所有就绪 Pod 的地址，SRV 记录还包含端口，
+// 缓存以 StatefulSet 运行时不需要额外的注册中心或 sidecar。
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"testProject/cache/clock"
+	"testProject/cache/discovery"
+)
+
+// Resolver 是 Watch 使用的 DNS 解析器，*net.Resolver 实现了该接口。
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// Config 描述要解析的 DNS 名称以及如何把解析结果转换为节点地址。
+type Config struct {
+	// Name 是要解析的名称，例如 "geecache.default.svc.cluster.local"。
+	Name string
+	// SRV 为 true 时按 SRV 记录解析，节点地址使用记录中的目标和端口；否则按 A/AAAA 记录解析并使用 Port。
+	SRV bool
+	// Port 是按 A/AAAA 记录解析时节点的端口。
+	Port int
+	// Scheme 是节点地址的协议前缀，默认为 "http"。gRPC 传输层不需要前缀时设为 "-"。
+	Scheme string
+	// Interval 是两次解析之间的间隔，默认为 10 秒。
+	Interval time.Duration
+	// Resolver 是使用的解析器，默认为 net.DefaultResolver。
+	Resolver Resolver
+	// Clock 是定时解析使用的时钟，默认使用真实时间。
+	Clock clock.Clock
+}
+
+// Watch 立即解析一次 cfg.Name，之后每隔 cfg.Interval 解析一次，节点列表与上一次不同时用完整的列表调用 set。
+// 解析失败时保留上一次的节点列表，下一次继续解析，避免 DNS 的短暂故障清空节点列表。
+// Watch 一直阻塞到 ctx 结束，返回 ctx 的错误。
+func Watch(ctx context.Context, cfg Config, set discovery.Setter) error {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	if cfg.Resolver == nil {
+		cfg.Resolver = net.DefaultResolver
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = clock.Real
+	}
+	ticker := cfg.Clock.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	var last []string
+	for {
+		if peers, err := Resolve(ctx, cfg); err == nil && !slices.Equal(peers, last) {
+			set(peers...)
+			last = peers
+		}
+		select {
+		case <-ticker.C():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Resolve 解析一次 cfg.Name，返回有序的节点地址列表。解析结果为空时返回错误。
+func Resolve(ctx context.Context, cfg Config) ([]string, error) {
+	if cfg.Resolver == nil {
+		cfg.Resolver = net.DefaultResolver
+	}
+	set := make(map[string]bool)
+	if cfg.SRV {
+		_, records, err := cfg.Resolver.LookupSRV(ctx, "", "", cfg.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range records {
+			set[address(cfg.Scheme, strings.TrimSuffix(r.Target, "."), int(r.Port))] = true
+		}
+	} else {
+		hosts, err := cfg.Resolver.LookupHost(ctx, cfg.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, host := range hosts {
+			set[address(cfg.Scheme, host, cfg.Port)] = true
+		}
+	}
+	if len(set) == 0 {
+		return nil, fmt.Errorf("dns: no records for %s", cfg.Name)
+	}
+	return discovery.Sorted(set), nil
+}
+
+// address 把主机和端口组合为节点地址。
+func address(scheme, host string, port int) string {
+	hostport := net.JoinHostPort(host, strconv.Itoa(port))
+	switch scheme {
+	case "":
+		return "http://" + hostport
+	case "-":
+		return hostport
+	default:
+		return scheme + "://" + hostport
+	}
+}
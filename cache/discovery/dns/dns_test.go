@@ -0,0 +1,68 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"testProject/cache/clock"
+)
+
+// fakeResolver 返回预先设置的 A 记录
+type fakeResolver struct {
+	mu    sync.Mutex
+	hosts []string
+	err   error
+}
+
+func (r *fakeResolver) set(hosts []string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hosts, r.err = hosts, err
+}
+
+func (r *fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.hosts, r.err
+}
+
+func (r *fakeResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	return "", nil, errors.New("not supported")
+}
+
+// 测试节点列表变化时才调用 set，解析失败时保留上一次的列表
+func TestWatch(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	r := &fakeResolver{hosts: []string{"10.0.0.2", "10.0.0.1"}}
+	updates := make(chan []string, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Watch(ctx, Config{Name: "geecache", Port: 8001, Interval: time.Second, Resolver: r, Clock: fake},
+		func(peers ...string) { updates <- peers })
+
+	next := func() []string {
+		select {
+		case peers := <-updates:
+			return peers
+		case <-time.After(time.Second):
+			t.Fatal("no update")
+			return nil
+		}
+	}
+	if got, want := next(), []string{"http://10.0.0.1:8001", "http://10.0.0.2:8001"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("first update = %v, want %v", got, want)
+	}
+
+	r.set(nil, errors.New("temporary failure"))
+	fake.Advance(time.Second)
+	r.set([]string{"10.0.0.1"}, nil)
+	fake.Advance(time.Second)
+	if got, want := next(), []string{"http://10.0.0.1:8001"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("second update = %v, want %v", got, want)
+	}
+}
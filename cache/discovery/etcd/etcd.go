@@ -0,0 +1,102 @@
+// Package etcd 基于 etcd 实现节点注册和发现：每个节点在同一个前缀下注册自己的地址，
+// 并监听该前缀的变化，节点加入或离开时自动更新节点列表。
+package etcd
+
+import (
+	"context"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"testProject/cache/discovery"
+)
+
+// Client 是 Watch 使用的 etcd 客户端，*clientv3.Client 实现了该接口。
+type Client interface {
+	Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error)
+	Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan
+}
+
+// Registration 是本节点在 etcd 中的注册，由 Register 返回。
+type Registration struct {
+	client *clientv3.Client
+	lease  clientv3.LeaseID
+	cancel context.CancelFunc // 停止后台续约
+}
+
+// Register 在 etcd 中以 prefix+self 为键、self 为值注册本节点。键绑定一个 ttl 秒的租约并在后台自动续约，
+// 节点异常退出、无法续约时键会在租约到期后被删除，其他节点随之把它移出节点列表。
+// 正常关闭时应当调用 Deregister 立即注销。
+func Register(ctx context.Context, client *clientv3.Client, prefix, self string, ttl int64) (*Registration, error) {
+	lease, err := client.Grant(ctx, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := client.Put(ctx, prefix+self, self, clientv3.WithLease(lease.ID)); err != nil {
+		return nil, err
+	}
+	kaCtx, cancel := context.WithCancel(context.Background())
+	responses, err := client.KeepAlive(kaCtx, lease.ID)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	go func() {
+		for range responses {
+			// 续约响应不需要处理，但必须被读取，否则 KeepAlive 会阻塞
+		}
+	}()
+	return &Registration{client: client, lease: lease.ID, cancel: cancel}, nil
+}
+
+// Deregister 停止续约并撤销租约，本节点的键会被立即删除。
+func (r *Registration) Deregister(ctx context.Context) error {
+	r.cancel()
+	_, err := r.client.Revoke(ctx, r.lease)
+	return err
+}
+
+// Watch 读取 prefix 下注册的所有节点并调用 set，之后监听 prefix 的变化，每次变化后都用完整的节点列表调用 set。
+// 监听中断（例如历史版本被压缩）时会重新读取全部节点。Watch 一直阻塞到 ctx 结束，返回 ctx 的错误；
+// 读取节点列表失败时返回该错误。
+func Watch(ctx context.Context, client Client, prefix string, set discovery.Setter) error {
+	for {
+		res, err := client.Get(ctx, prefix, clientv3.WithPrefix())
+		if err != nil {
+			return err
+		}
+		nodes := make(map[string]string, len(res.Kvs)) // 键到节点地址
+		for _, kv := range res.Kvs {
+			nodes[string(kv.Key)] = string(kv.Value)
+		}
+		set(peerList(nodes)...)
+
+		// 从读取时的版本之后开始监听，不会漏掉读取和监听之间发生的变化。
+		events := client.Watch(ctx, prefix, clientv3.WithPrefix(), clientv3.WithRev(res.Header.Revision+1))
+		for wr := range events {
+			if wr.Err() != nil {
+				break // 重新读取全部节点
+			}
+			for _, ev := range wr.Events {
+				switch ev.Type {
+				case clientv3.EventTypePut:
+					nodes[string(ev.Kv.Key)] = string(ev.Kv.Value)
+				case clientv3.EventTypeDelete:
+					delete(nodes, string(ev.Kv.Key))
+				}
+			}
+			set(peerList(nodes)...)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// peerList 返回 nodes 中所有节点地址组成的有序列表。
+func peerList(nodes map[string]string) []string {
+	set := make(map[string]bool, len(nodes))
+	for _, addr := range nodes {
+		set[addr] = true
+	}
+	return discovery.Sorted(set)
+}
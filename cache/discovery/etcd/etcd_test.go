@@ -0,0 +1,90 @@
+package etcd
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// fakeClient 保存一组键值，Watch 返回的通道由测试发送变化
+type fakeClient struct {
+	mu      sync.Mutex
+	kvs     map[string]string
+	watches chan chan clientv3.WatchResponse
+}
+
+func (c *fakeClient) set(kvs map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.kvs = kvs
+}
+
+func (c *fakeClient) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res := &clientv3.GetResponse{Header: &pb.ResponseHeader{Revision: 1}}
+	for k, v := range c.kvs {
+		res.Kvs = append(res.Kvs, &mvccpb.KeyValue{Key: []byte(k), Value: []byte(v)})
+	}
+	return res, nil
+}
+
+func (c *fakeClient) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	ch := make(chan clientv3.WatchResponse)
+	go func() {
+		<-ctx.Done()
+		close(ch) // 与真实的客户端一样，ctx 结束时关闭通道
+	}()
+	c.watches <- ch
+	return ch
+}
+
+// event 返回一个键值变化事件
+func event(typ mvccpb.Event_EventType, key, value string) *clientv3.Event {
+	return &clientv3.Event{Type: typ, Kv: &mvccpb.KeyValue{Key: []byte(key), Value: []byte(value)}}
+}
+
+// 测试 Watch 按节点的加入和离开更新节点列表，监听中断时重新读取全部节点
+func TestWatch(t *testing.T) {
+	c := &fakeClient{kvs: map[string]string{"/n/a": "http://a"}, watches: make(chan chan clientv3.WatchResponse)}
+	updates := make(chan []string, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- Watch(ctx, c, "/n/", func(peers ...string) { updates <- peers }) }()
+
+	next := func(want ...string) {
+		t.Helper()
+		select {
+		case got := <-updates:
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("peers = %v, want %v", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no update, want %v", want)
+		}
+	}
+	next("http://a")
+
+	w := <-c.watches
+	w <- clientv3.WatchResponse{Events: []*clientv3.Event{event(mvccpb.PUT, "/n/b", "http://b")}}
+	next("http://a", "http://b")
+	w <- clientv3.WatchResponse{Events: []*clientv3.Event{event(mvccpb.DELETE, "/n/a", "")}}
+	next("http://b")
+
+	// 历史版本被压缩时重新读取全部节点，再重新监听
+	c.set(map[string]string{"/n/c": "http://c"})
+	w <- clientv3.WatchResponse{CompactRevision: 2}
+	next("http://c")
+	<-c.watches
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Watch returned %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,137 @@
+// Package gossip 基于 hashicorp/memberlist 的 gossip 协议实现节点发现，适用于没有外部注册中心的集群。
+// 节点通过种子地址加入集群，每个节点在元数据中携带自己的缓存服务地址，
+// 节点加入、离开或被判定为失效时，用最新的节点列表调用 Setter。
+package gossip
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/memberlist"
+
+	"testProject/cache/discovery"
+)
+
+// Config 是加入 gossip 集群的配置。
+type Config struct {
+	// Peer 是本节点的缓存服务地址（例如 "http://10.0.0.1:8001"），通过节点元数据传播给其他节点。
+	Peer string
+	// Seeds 是已知集群成员的 gossip 地址（"host:port"），为空时本节点作为集群的第一个节点启动。
+	Seeds []string
+	// Memberlist 是 memberlist 的基础配置，为 nil 时使用 memberlist.DefaultLANConfig()。
+	// 其中的 Name 为空时使用 Peer；Delegate 和 Events 会被覆盖。
+	Memberlist *memberlist.Config
+}
+
+// Cluster 是本节点在 gossip 集群中的成员身份。
+type Cluster struct {
+	list *memberlist.Memberlist
+	set  discovery.Setter
+
+	mu    sync.Mutex
+	peers map[string]string // 节点名 -> 缓存服务地址
+}
+
+// Join 启动本节点的 gossip 成员协议并通过 Seeds 加入集群，本节点会立即出现在节点列表中。
+// 之后节点列表的每次变化都会用完整的节点列表调用 set。
+// 所有种子都无法连接时返回错误；Seeds 为空时不会出错。
+func Join(cfg Config, set discovery.Setter) (*Cluster, error) {
+	if cfg.Peer == "" {
+		return nil, fmt.Errorf("gossip: empty peer address")
+	}
+	c := &Cluster{set: set, peers: make(map[string]string)}
+	conf := cfg.Memberlist
+	if conf == nil {
+		conf = memberlist.DefaultLANConfig()
+	}
+	if conf.Name == "" {
+		conf.Name = cfg.Peer
+	}
+	conf.Delegate = &delegate{meta: []byte(cfg.Peer)}
+	conf.Events = c
+
+	list, err := memberlist.Create(conf)
+	if err != nil {
+		return nil, fmt.Errorf("gossip: %v", err)
+	}
+	c.list = list
+	if len(cfg.Seeds) > 0 {
+		if _, err := list.Join(cfg.Seeds); err != nil {
+			list.Shutdown()
+			return nil, fmt.Errorf("gossip: join %v: %v", cfg.Seeds, err)
+		}
+	}
+	return c, nil
+}
+
+// Addr 返回本节点的 gossip 地址（"host:port"），其他节点可以把它作为种子。
+func (c *Cluster) Addr() string {
+	return c.list.LocalNode().Address()
+}
+
+// Peers 返回当前集群中所有节点的缓存服务地址，按字典序排列。
+func (c *Cluster) Peers() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sortedLocked()
+}
+
+// Leave 通知其他节点本节点正常离开集群，最多等待 timeout，然后停止 gossip 协议。
+func (c *Cluster) Leave(timeout time.Duration) error {
+	err := c.list.Leave(timeout)
+	if serr := c.list.Shutdown(); err == nil {
+		err = serr
+	}
+	return err
+}
+
+// NotifyJoin 实现 memberlist.EventDelegate，节点加入集群时调用。
+func (c *Cluster) NotifyJoin(n *memberlist.Node) { c.update(n.Name, string(n.Meta)) }
+
+// NotifyLeave 实现 memberlist.EventDelegate，节点离开集群或被判定为失效时调用。
+func (c *Cluster) NotifyLeave(n *memberlist.Node) { c.update(n.Name, "") }
+
+// NotifyUpdate 实现 memberlist.EventDelegate，节点的元数据变化时调用。
+func (c *Cluster) NotifyUpdate(n *memberlist.Node) { c.update(n.Name, string(n.Meta)) }
+
+// update 记录节点 name 的缓存服务地址，peer 为空表示节点已经离开，节点列表变化时调用 set。
+// memberlist 调用事件回调时持有其内部的锁，因此这里维护自己的节点表，而不是调用 Members。
+func (c *Cluster) update(name, peer string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.peers[name] == peer {
+		return
+	}
+	if peer == "" {
+		delete(c.peers, name)
+	} else {
+		c.peers[name] = peer
+	}
+	c.set(c.sortedLocked()...)
+}
+
+// sortedLocked 返回有序的节点列表，调用方需要持有 c.mu。
+func (c *Cluster) sortedLocked() []string {
+	set := make(map[string]bool, len(c.peers))
+	for _, peer := range c.peers {
+		set[peer] = true
+	}
+	return discovery.Sorted(set)
+}
+
+// delegate 实现 memberlist.Delegate，只通过节点元数据传播缓存服务地址，不使用用户消息和状态同步。
+type delegate struct {
+	meta []byte
+}
+
+func (d *delegate) NodeMeta(limit int) []byte {
+	if len(d.meta) > limit {
+		return nil
+	}
+	return d.meta
+}
+func (d *delegate) NotifyMsg([]byte)                           {}
+func (d *delegate) GetBroadcasts(overhead, limit int) [][]byte { return nil }
+func (d *delegate) LocalState(join bool) []byte                { return nil }
+func (d *delegate) MergeRemoteState(buf []byte, join bool)     {}
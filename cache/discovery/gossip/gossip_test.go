@@ -0,0 +1,83 @@
+package gossip
+
+import (
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/memberlist"
+)
+
+// localConfig 返回在本机随机端口上监听、不输出日志的 memberlist 配置。
+func localConfig(name string) *memberlist.Config {
+	conf := memberlist.DefaultLocalConfig()
+	conf.Name = name
+	conf.BindAddr = "127.0.0.1"
+	conf.BindPort = 0
+	conf.Logger = log.New(io.Discard, "", 0)
+	return conf
+}
+
+// recorder 记录 Setter 收到的最新节点列表。
+type recorder struct {
+	mu    sync.Mutex
+	peers []string
+}
+
+func (r *recorder) set(peers ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.peers = peers
+}
+
+// wait 等待最新的节点列表变为 n 个节点。
+func (r *recorder) wait(t *testing.T, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		r.mu.Lock()
+		peers := r.peers
+		r.mu.Unlock()
+		if len(peers) == n {
+			return peers
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("peers = %v, want %d peers", peers, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// 测试节点通过种子加入集群后双方都能看到对方，节点离开后从列表中删除
+func TestJoinLeave(t *testing.T) {
+	var r1, r2 recorder
+	c1, err := Join(Config{Peer: "http://a:8001", Memberlist: localConfig("a")}, r1.set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1.Leave(time.Second)
+	if peers := r1.wait(t, 1); peers[0] != "http://a:8001" {
+		t.Fatalf("peers = %v", peers)
+	}
+
+	c2, err := Join(Config{Peer: "http://b:8002", Seeds: []string{c1.Addr()}, Memberlist: localConfig("b")}, r2.set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peers := r1.wait(t, 2); peers[0] != "http://a:8001" || peers[1] != "http://b:8002" {
+		t.Fatalf("peers = %v", peers)
+	}
+	r2.wait(t, 2)
+
+	if err := c2.Leave(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if peers := r1.wait(t, 1); peers[0] != "http://a:8001" {
+		t.Fatalf("peers after leave = %v", peers)
+	}
+	if got := c1.Peers(); len(got) != 1 {
+		t.Fatalf("Peers() = %v", got)
+	}
+}
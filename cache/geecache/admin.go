@@ -0,0 +1,266 @@
+package geecache
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultAdminPath 是管理接口默认挂载的路径，位于节点服务的 basePath 之下，但由单独的 Handler 处理。
+const defaultAdminPath = "/_geecache/admin/"
+
+// defaultAdminKeysLimit 是 keys 接口没有指定 limit 时每次最多返回的键数量。
+const defaultAdminKeysLimit = 100
+
+// adminHandler 提供运维使用的管理接口，见 AdminHandler。
+type adminHandler struct {
+	basePath string
+	token    string    // 访问管理接口需要的令牌，为空时不检查
+	registry *Registry // 管理的缓存组所在的注册表
+}
+
+// AdminOption 用于在创建管理接口时定制其配置。
+type AdminOption func(*adminHandler)
+
+// WithAdminToken 设置访问管理接口需要的令牌，请求需要在 Authorization 请求头中携带 "Bearer <token>"。
+// 管理接口可以清空缓存、读取缓存的值，应当使用与节点间通信（WithAuthToken）不同的令牌。
+func WithAdminToken(token string) AdminOption {
+	return func(h *adminHandler) {
+		h.token = token
+	}
+}
+
+// WithAdminBasePath 设置管理接口挂载的路径，默认为 "/_geecache/admin/"。
+func WithAdminBasePath(path string) AdminOption {
+	return func(h *adminHandler) {
+		h.basePath = path
+	}
+}
+
+// WithAdminRegistry 设置管理接口管理的缓存组所在的注册表，默认为 DefaultRegistry。
+func WithAdminRegistry(r *Registry) AdminOption {
+	return func(h *adminHandler) {
+		h.registry = r
+	}
+}
+
+// AdminHandler 返回提供管理接口的 http.Handler，用于调试和运维：
+//
+//	GET  <basePath>stats[?group=]                      各缓存组的运行统计（JSON）
+//	POST <basePath>flush?group=                        清空本节点上缓存组的所有条目
+//	POST <basePath>freeze?group=                       把本节点上的缓存组切换到只读维护模式，见 Group.Freeze
+//	POST <basePath>unfreeze?group=                     解除本节点上缓存组的只读维护模式
+//	GET  <basePath>keys?group=[&prefix=&cursor=&limit=] 分页列出缓存组中的键
+//	GET  <basePath>entry?group=&key=                   查看本节点缓存中的一个条目，不会触发加载
+//	GET  <basePath>loglevel                            查询 StdLogger 的日志级别
+//	POST <basePath>loglevel?level=                     修改 StdLogger 的日志级别
+//
+// 管理接口与节点服务分开认证，可以与 HTTPPool 挂载在同一个服务上，
+// 例如 mux.Handle("/_geecache/admin/", geecache.AdminHandler(geecache.WithAdminToken(token)))，
+// 或者通过 WithAdmin 挂载到 NewServer 创建的服务上。
+func AdminHandler(opts ...AdminOption) http.Handler {
+	h := &adminHandler{basePath: defaultAdminPath, registry: DefaultRegistry}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// WithAdmin 把管理接口 h（通常由 AdminHandler 创建）挂载到 NewServer 创建的服务上。
+// h 由 AdminHandler 创建时挂载到它的 basePath（见 WithAdminBasePath），否则挂载到 "/_geecache/admin/"。
+func WithAdmin(h http.Handler) ServerOption {
+	path := defaultAdminPath
+	if ah, ok := h.(*adminHandler); ok {
+		path = ah.basePath
+	}
+	return func(s *http.Server) {
+		s.Handler.(*http.ServeMux).Handle(path, h)
+	}
+}
+
+// ServeHTTP 按路径和方法分发管理接口的请求。
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, h.basePath) {
+		http.NotFound(w, r)
+		return
+	}
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	switch r.URL.Path[len(h.basePath):] {
+	case "stats":
+		if allowMethods(w, r, http.MethodGet) {
+			h.serveStats(w, q.Get("group"))
+		}
+	case "flush":
+		if allowMethods(w, r, http.MethodPost) {
+			if g := h.group(w, q.Get("group")); g != nil {
+				h.serveFlush(w, g)
+			}
+		}
+	case "freeze", "unfreeze":
+		if allowMethods(w, r, http.MethodPost) {
+			if g := h.group(w, q.Get("group")); g != nil {
+				h.serveFreeze(w, g, r.URL.Path[len(h.basePath):] == "freeze")
+			}
+		}
+	case "keys":
+		if allowMethods(w, r, http.MethodGet) {
+			if g := h.group(w, q.Get("group")); g != nil {
+				h.serveKeys(w, r, g)
+			}
+		}
+	case "entry":
+		if allowMethods(w, r, http.MethodGet) {
+			if g := h.group(w, q.Get("group")); g != nil {
+				h.serveEntry(w, g, q.Get("key"))
+			}
+		}
+	case "loglevel":
+		if allowMethods(w, r, http.MethodGet, http.MethodPost) {
+			h.serveLogLevel(w, r)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// authorized 报告请求是否携带了正确的管理令牌，没有设置令牌时所有请求都通过。
+func (h *adminHandler) authorized(r *http.Request) bool {
+	if h.token == "" {
+		return true
+	}
+	got := r.Header.Get("Authorization")
+	want := "Bearer " + h.token
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// group 返回名为 name 的缓存组，缓存组不存在时写出 404 并返回 nil。
+func (h *adminHandler) group(w http.ResponseWriter, name string) *Group {
+	g := h.registry.Get(name)
+	if g == nil {
+		http.Error(w, "no such group: "+name, http.StatusNotFound)
+	}
+	return g
+}
+
+// writeJSON 以 JSON 写出 v。
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// serveStats 返回缓存组 name 的运行统计，name 为空时返回所有缓存组的统计，以组名为键。
+func (h *adminHandler) serveStats(w http.ResponseWriter, name string) {
+	if name != "" {
+		if g := h.group(w, name); g != nil {
+			writeJSON(w, g.Stats())
+		}
+		return
+	}
+	stats := make(map[string]CacheStats)
+	for _, g := range h.registry.list() {
+		stats[g.name] = g.Stats()
+	}
+	writeJSON(w, stats)
+}
+
+// serveFlush 清空本节点上缓存组 g 的所有条目，响应头中返回一致性令牌。
+func (h *adminHandler) serveFlush(w http.ResponseWriter, g *Group) {
+	token, err := g.Flush()
+	if errors.Is(err, ErrFrozen) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.Header().Set(tokenHeader, string(token))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveFreeze 把本节点上的缓存组 g 切换到（frozen 为 true）或者解除只读维护模式。
+func (h *adminHandler) serveFreeze(w http.ResponseWriter, g *Group, frozen bool) {
+	if frozen {
+		g.Freeze()
+	} else {
+		g.Unfreeze()
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// adminKeys 是 keys 接口的响应，Next 为 0 表示遍历结束。
+type adminKeys struct {
+	Keys []string `json:"keys"`
+	Next uint64   `json:"next"`
+}
+
+// serveKeys 按游标分页返回缓存组 g 中的键，见 Group.Scan。
+func (h *adminHandler) serveKeys(w http.ResponseWriter, r *http.Request, g *Group) {
+	q := r.URL.Query()
+	limit := defaultAdminKeysLimit
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			http.Error(w, "bad limit: "+s, http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	var cursor uint64
+	if s := q.Get("cursor"); s != "" {
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			http.Error(w, "bad cursor: "+s, http.StatusBadRequest)
+			return
+		}
+		cursor = n
+	}
+	keys, next := g.Scan(cursor, q.Get("prefix"), limit)
+	if keys == nil {
+		keys = []string{} // 没有键时返回空数组而不是 null
+	}
+	writeJSON(w, adminKeys{Keys: keys, Next: next})
+}
+
+// adminEntry 是 entry 接口的响应，Value 以 base64 编码。
+type adminEntry struct {
+	Key     string `json:"key"`
+	Size    int    `json:"size"`
+	Version string `json:"version"`
+	TTL     string `json:"ttl,omitempty"` // 剩余有效期，条目不会过期时省略
+	Value   []byte `json:"value"`
+}
+
+// serveEntry 返回本节点缓存中 key 对应条目的元数据和值，不会触发加载，也不影响淘汰顺序。
+func (h *adminHandler) serveEntry(w http.ResponseWriter, g *Group, key string) {
+	v, meta, ok := g.entry(key)
+	if !ok {
+		http.Error(w, ErrNotCached.Error(), http.StatusNotFound)
+		return
+	}
+	e := adminEntry{Key: key, Size: meta.Size, Version: meta.Version, Value: v.ByteSlice()}
+	if meta.TTL > 0 {
+		e.TTL = meta.TTL.Round(time.Millisecond).String()
+	}
+	writeJSON(w, e)
+}
+
+// serveLogLevel 返回 StdLogger 当前的日志级别，POST 请求先把日志级别改为 level 参数指定的级别。
+func (h *adminHandler) serveLogLevel(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		l, err := ParseLogLevel(r.URL.Query().Get("level"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		SetLogLevel(l)
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(GetLogLevel().String()))
+}
@@ -0,0 +1,25 @@
+package geecache
+
+import "testProject/cache/bloom"
+
+// WithBloomFilter 让缓存组在访问数据源之前先查询布隆过滤器 f，过滤器确定不存在的键直接返回 ErrNotFound，
+// 大量请求根本不可能存在的键时不会穿透到数据源。
+// f 需要由调用方预先写入数据源中已有的键，之后通过 Set 写入的键会自动加入 f；
+// 绕过 Set 直接写入数据源的键必须由调用方自己调用 f.Add，否则在过滤器中查不到，会被当作不存在。
+func WithBloomFilter(f *bloom.Filter) GroupOption {
+	return func(g *Group) {
+		g.bloom = f
+	}
+}
+
+// admit 报告是否应该为 key 访问数据源。没有设置布隆过滤器时总是返回 true。
+func (g *Group) admit(key string) bool {
+	return g.bloom == nil || g.bloom.MayContain(key)
+}
+
+// learn 把写入的 key 加入布隆过滤器。
+func (g *Group) learn(key string) {
+	if g.bloom != nil {
+		g.bloom.Add(key)
+	}
+}
@@ -0,0 +1,41 @@
+package geecache
+
+// AdmissionPolicy 决定缓存已满时新条目能否替换掉将被淘汰的条目，tinylfu.Filter 是一个实现。
+// 实现需要并发安全：设置了 WithShards 时所有分片共用同一个准入策略。
+type AdmissionPolicy interface {
+	// Record 记录 key 的一次访问，主缓存的每次读取（命中或未命中）都会调用。
+	Record(key string)
+	// Admit 报告 candidate 能否替换掉下一个将被淘汰的 victim。
+	Admit(candidate, victim string) bool
+}
+
+// WithAdmissionPolicy 在主缓存前加上准入策略，例如 tinylfu.New(10 * 预计条目数量)。
+// 缓存未满或键已经在缓存中时总是写入；缓存已满时只有策略接纳的新条目才会写入并淘汰旧条目，
+// 被拒绝的值照常返回给调用方，只是不缓存。只访问一次的键因此不会把有价值的条目挤出缓存。
+// 只对能报告下一个被淘汰条目的淘汰策略生效，默认的 LRU 和 LFUPolicy 都支持。
+func WithAdmissionPolicy(p AdmissionPolicy) GroupOption {
+	return func(g *Group) {
+		g.mainCache.admission = p
+	}
+}
+
+// victimer 由能报告下一个将被淘汰的条目的底层存储实现。
+type victimer interface {
+	Victim() (key string, ok bool)
+}
+
+// admit 报告新条目能否写入缓存。调用方需要持有 c.mu。
+func (c *cache) admit(key string, value ByteView) bool {
+	if c.admission == nil || c.cacheBytes <= 0 || c.store.Contains(key) {
+		return true
+	}
+	if c.store.Bytes()+int64(len(key)+value.Len()) <= c.cacheBytes {
+		return true // 缓存还有空间，不需要淘汰任何条目
+	}
+	v, ok := c.store.(victimer)
+	if !ok {
+		return true
+	}
+	victim, ok := v.Victim()
+	return !ok || c.admission.Admit(key, victim)
+}
@@ -0,0 +1,437 @@
+package geecache
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"math/rand"
+	"time"
+
+	"testProject/cache/clock"
+	"testProject/cache/lru"
+)
+
+// 内存段的大小：maxBytes 被平均分成 arenaSegments 个段，每段不小于 minArenaSegment（但不超过 maxBytes）、
+// 不大于 maxArenaSegment。不限制大小时每段为 maxArenaSegment。
+const (
+	arenaSegments   = 16
+	minArenaSegment = 4 << 10
+	maxArenaSegment = 64 << 20
+)
+
+// 条目在内存段中的布局（小端序）：
+//
+//	写入时间 int64 | 过期时间 int64（UnixNano，0 表示永不过期）| len(key) uint32 | len(value) uint32 | 压缩算法 uint8 | key | value
+const arenaHeader = 25
+
+// ArenaPolicy 使用类似 bigcache 的存储引擎：条目的键和值依次追加写入少量大块的内存段，
+// 索引是开放寻址的散列表，只保存键的散列值和条目在内存段中的位置。整个缓存只有少数几个指针，
+// 缓存数千万个条目时 GC 也不需要逐个扫描，停顿时间与条目数量无关。
+//
+// 代价是淘汰按写入顺序进行：内存段用完时整段淘汰最早写入的条目，读取不会延长条目的寿命（近似 FIFO）；
+// 被覆盖和删除的条目在所在的段被淘汰之前仍然占用空间；每次读取都会复制一份值。
+// 设置了 WithShards 时每个分片各有一组内存段和索引。
+func ArenaPolicy(maxBytes int64, clk clock.Clock) EvictionPolicy {
+	return newArenaStore(maxBytes, clk)
+}
+
+// arenaSegment 是一块只追加写入的内存段。
+type arenaSegment struct {
+	id   uint32 // 段的编号，按创建顺序递增
+	buf  []byte
+	size int // 已写入的字节数
+}
+
+// arenaSlot 是索引中的一个槽位，loc 为 0 表示空槽位。
+type arenaSlot struct {
+	hash uint64
+	loc  uint64 // 高 32 位是段编号加 1，低 32 位是条目在段中的偏移量
+}
+
+// arenaStore 是把条目保存在大块内存段中的 EvictionPolicy，见 ArenaPolicy。
+// 与其他底层存储一样，除 Touch、Peek、Contains 和 Expiration 可以并发调用之外，它不是并发安全的。
+type arenaStore struct {
+	maxBytes     int64 // 所有内存段的大小之和的上限，0 表示不限制
+	segmentBytes int
+	segments     []*arenaSegment // 按编号排列，第一个最早写入，最后一个是当前写入的段
+	spare        []byte          // 最近被淘汰的段留下的缓冲区，创建新段时复用
+	arenaBytes   int64           // 所有内存段的大小之和
+	nextID       uint32
+
+	seed   maphash.Seed
+	slots  []arenaSlot // 开放寻址（线性探测）的索引，长度是 2 的幂
+	count  int         // 索引中的条目数量
+	nbytes int64       // 有效条目的键和值的长度之和
+
+	evictions int64
+	clock     clock.Clock
+	listeners []lru.EvictionListener
+}
+
+func newArenaStore(maxBytes int64, clk clock.Clock) *arenaStore {
+	segmentBytes := maxArenaSegment
+	if maxBytes > 0 {
+		segmentBytes = int(min(max(maxBytes/arenaSegments, minArenaSegment), maxArenaSegment, maxBytes))
+	}
+	return &arenaStore{
+		maxBytes:     maxBytes,
+		segmentBytes: segmentBytes,
+		seed:         maphash.MakeSeed(),
+		slots:        make([]arenaSlot, 16),
+		clock:        clk,
+	}
+}
+
+// arenaEntry 是从内存段中解析出的条目头部。
+type arenaEntry struct {
+	added, expires int64
+	key, value     []byte // 指向内存段，不能在段被淘汰之后继续使用
+	z              Compression
+}
+
+// size 返回条目在内存段中占用的字节数。
+func (e arenaEntry) size() int {
+	return arenaHeader + len(e.key) + len(e.value)
+}
+
+// expired 报告条目在 now 时是否已经过期。
+func (e arenaEntry) expired(now time.Time) bool {
+	return e.expires != 0 && now.UnixNano() >= e.expires
+}
+
+// view 返回条目的值的副本，内存段之后会被复用，不能直接引用。
+func (e arenaEntry) view() ByteView {
+	return ByteView{b: cloneBytes(e.value), z: e.z}
+}
+
+// decode 解析段 seg 中偏移量 off 处的条目。
+func (seg *arenaSegment) decode(off int) arenaEntry {
+	b := seg.buf[off:]
+	klen := int(binary.LittleEndian.Uint32(b[16:]))
+	vlen := int(binary.LittleEndian.Uint32(b[20:]))
+	return arenaEntry{
+		added:   int64(binary.LittleEndian.Uint64(b)),
+		expires: int64(binary.LittleEndian.Uint64(b[8:])),
+		key:     b[arenaHeader : arenaHeader+klen],
+		value:   b[arenaHeader+klen : arenaHeader+klen+vlen],
+		z:       Compression(b[24]),
+	}
+}
+
+// entry 返回索引位置 loc 处的条目。
+func (s *arenaStore) entry(loc uint64) arenaEntry {
+	id := uint32(loc>>32) - 1
+	seg := s.segments[id-s.segments[0].id] // 段按编号连续排列
+	return seg.decode(int(uint32(loc)))
+}
+
+// lookup 返回 key 在索引中的槽位，不存在时返回 -1。
+func (s *arenaStore) lookup(key string, h uint64) int {
+	mask := uint64(len(s.slots) - 1)
+	for i := h & mask; s.slots[i].loc != 0; i = (i + 1) & mask {
+		if s.slots[i].hash == h && string(s.entry(s.slots[i].loc).key) == key {
+			return int(i)
+		}
+	}
+	return -1
+}
+
+// find 返回 key 对应的未过期条目。
+func (s *arenaStore) find(key string) (arenaEntry, bool) {
+	i := s.lookup(key, maphash.String(s.seed, key))
+	if i < 0 {
+		return arenaEntry{}, false
+	}
+	e := s.entry(s.slots[i].loc)
+	if e.expired(s.clock.Now()) {
+		return arenaEntry{}, false
+	}
+	return e, true
+}
+
+// Get 返回 key 对应的值，已经过期的条目在这里被惰性删除。读取不影响淘汰顺序。
+func (s *arenaStore) Get(key string) (lru.Value, bool) {
+	i := s.lookup(key, maphash.String(s.seed, key))
+	if i < 0 {
+		return nil, false
+	}
+	e := s.entry(s.slots[i].loc)
+	if e.expired(s.clock.Now()) {
+		s.removeSlot(i, e)
+		return nil, false
+	}
+	return e.view(), true
+}
+
+// Touch 不做任何事：条目按写入顺序淘汰，读取不需要记录。实现它可以让缓存的读取不必延迟调用 Get。
+func (s *arenaStore) Touch(key string) {}
+
+// Peek 与 Get 相同，但不删除已经过期的条目。
+func (s *arenaStore) Peek(key string) (lru.Value, bool) {
+	if e, ok := s.find(key); ok {
+		return e.view(), true
+	}
+	return nil, false
+}
+
+// Contains 报告 key 是否在缓存中且尚未过期，不复制值。
+func (s *arenaStore) Contains(key string) bool {
+	_, ok := s.find(key)
+	return ok
+}
+
+// Expiration 返回 key 对应条目的过期时间，零值表示永不过期。
+func (s *arenaStore) Expiration(key string) (time.Time, bool) {
+	e, ok := s.find(key)
+	if !ok {
+		return time.Time{}, false
+	}
+	return unixTime(e.expires), true
+}
+
+// unixTime 把条目头部中的 UnixNano 时间还原为 time.Time，0 还原为零值。
+func unixTime(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+// AddWithExpire 把条目追加写入当前的内存段，并让索引指向新的位置，覆盖的旧条目成为无效数据。
+// 内存段的总大小超过上限时整段淘汰最早的内存段。
+func (s *arenaStore) AddWithExpire(key string, value lru.Value, expires time.Time) {
+	v := value.(ByteView)
+	b := v.bytes()
+	var exp int64
+	if !expires.IsZero() {
+		exp = expires.UnixNano()
+	}
+	seg := s.reserve(arenaHeader + len(key) + len(b))
+	off := seg.size
+	buf := seg.buf[off:]
+	binary.LittleEndian.PutUint64(buf, uint64(s.clock.Now().UnixNano()))
+	binary.LittleEndian.PutUint64(buf[8:], uint64(exp))
+	binary.LittleEndian.PutUint32(buf[16:], uint32(len(key)))
+	binary.LittleEndian.PutUint32(buf[20:], uint32(len(b)))
+	buf[24] = byte(v.z)
+	copy(buf[arenaHeader:], key)
+	copy(buf[arenaHeader+len(key):], b)
+	seg.size += arenaHeader + len(key) + len(b)
+	loc := uint64(seg.id+1)<<32 | uint64(off)
+
+	h := maphash.String(s.seed, key)
+	if i := s.lookup(key, h); i >= 0 {
+		s.nbytes -= int64(len(s.entry(s.slots[i].loc).value)) // 旧条目被覆盖，不会通知淘汰监听器
+		s.slots[i].loc = loc
+	} else {
+		s.insert(arenaSlot{hash: h, loc: loc})
+		s.nbytes += int64(len(key))
+	}
+	s.nbytes += int64(len(b))
+}
+
+// reserve 返回能够再写入 n 个字节的当前内存段，需要时创建新段并淘汰最早的段。
+// 比段的大小还大的条目独占一个刚好容纳它的段。
+func (s *arenaStore) reserve(n int) *arenaSegment {
+	if len(s.segments) > 0 {
+		if cur := s.segments[len(s.segments)-1]; cur.size+n <= len(cur.buf) {
+			return cur
+		}
+	}
+	size := max(n, s.segmentBytes)
+	for len(s.segments) > 0 && s.maxBytes != 0 && s.arenaBytes+int64(size) > s.maxBytes {
+		s.evictOldest()
+	}
+	var buf []byte
+	if size == s.segmentBytes && s.spare != nil {
+		buf, s.spare = s.spare, nil
+	} else {
+		buf = make([]byte, size)
+	}
+	seg := &arenaSegment{id: s.nextID, buf: buf}
+	s.nextID++
+	s.segments = append(s.segments, seg)
+	s.arenaBytes += int64(size)
+	return seg
+}
+
+// evictOldest 淘汰最早的内存段中所有仍然有效的条目，并回收这个段。
+func (s *arenaStore) evictOldest() {
+	seg := s.segments[0]
+	for off := 0; off < seg.size; {
+		e := seg.decode(off)
+		loc := uint64(seg.id+1)<<32 | uint64(off)
+		if i := s.slotOf(string(e.key), loc); i >= 0 {
+			s.removeSlot(i, e)
+			s.evictions++
+		}
+		off += e.size()
+	}
+	s.segments[0] = nil
+	s.segments = s.segments[1:]
+	s.arenaBytes -= int64(len(seg.buf))
+	if len(seg.buf) == s.segmentBytes {
+		s.spare = seg.buf // 只保留一个，淘汰之后通常马上就要创建新段
+	}
+}
+
+// slotOf 返回指向位置 loc 的槽位，条目已经被覆盖或删除时返回 -1。
+func (s *arenaStore) slotOf(key string, loc uint64) int {
+	mask := uint64(len(s.slots) - 1)
+	for i := maphash.String(s.seed, key) & mask; s.slots[i].loc != 0; i = (i + 1) & mask {
+		if s.slots[i].loc == loc {
+			return int(i)
+		}
+	}
+	return -1
+}
+
+// insert 把一个新槽位加入索引，装载因子超过 3/4 时先把索引扩大一倍。
+func (s *arenaStore) insert(slot arenaSlot) {
+	if (s.count+1)*4 > len(s.slots)*3 {
+		old := s.slots
+		s.slots = make([]arenaSlot, len(old)*2)
+		for _, o := range old {
+			if o.loc != 0 {
+				s.place(o)
+			}
+		}
+	}
+	s.place(slot)
+	s.count++
+}
+
+// place 把槽位放到从其散列值开始的第一个空位。
+func (s *arenaStore) place(slot arenaSlot) {
+	mask := uint64(len(s.slots) - 1)
+	i := slot.hash & mask
+	for s.slots[i].loc != 0 {
+		i = (i + 1) & mask
+	}
+	s.slots[i] = slot
+}
+
+// removeSlot 从索引中删除槽位 i 处的条目 e 并通知淘汰监听器。
+// 线性探测的索引不使用墓碑：把后面探测链上的槽位依次前移填补空位，查找时不会提前遇到空槽位。
+func (s *arenaStore) removeSlot(i int, e arenaEntry) {
+	s.nbytes -= int64(len(e.key) + len(e.value))
+	s.count--
+	mask := len(s.slots) - 1
+	for j := (i + 1) & mask; s.slots[j].loc != 0; j = (j + 1) & mask {
+		k := int(s.slots[j].hash) & mask // 槽位 j 的理想位置
+		// k 不在 (i, j] 之间（考虑回绕）时，槽位 j 可以前移到 i。
+		if (i <= j && (k <= i || k > j)) || (i > j && k <= i && k > j) {
+			s.slots[i] = s.slots[j]
+			i = j
+		}
+	}
+	s.slots[i] = arenaSlot{}
+	if len(s.listeners) > 0 {
+		key, value := string(e.key), e.view()
+		for _, fn := range s.listeners {
+			fn(key, value)
+		}
+	}
+}
+
+// Remove 删除 key 对应的条目，返回条目是否存在。条目占用的空间在所在的段被淘汰时回收。
+func (s *arenaStore) Remove(key string) bool {
+	i := s.lookup(key, maphash.String(s.seed, key))
+	if i < 0 {
+		return false
+	}
+	s.removeSlot(i, s.entry(s.slots[i].loc))
+	return true
+}
+
+// RemoveExpired 删除所有已经过期的条目，返回删除的数量。
+func (s *arenaStore) RemoveExpired() int {
+	now := s.clock.Now()
+	var expired []string
+	for _, slot := range s.slots {
+		if slot.loc == 0 {
+			continue
+		}
+		if e := s.entry(slot.loc); e.expired(now) {
+			expired = append(expired, string(e.key))
+		}
+	}
+	for _, key := range expired {
+		s.Remove(key) // 删除会移动槽位，按键重新查找
+	}
+	return len(expired)
+}
+
+// AddEvictionListener 注册一个淘汰监听器，条目被淘汰、过期删除或删除时按注册顺序调用，值是条目的副本。
+func (s *arenaStore) AddEvictionListener(fn lru.EvictionListener) {
+	s.listeners = append(s.listeners, fn)
+}
+
+// Evictions 返回因内存段被回收而淘汰的条目数量。
+func (s *arenaStore) Evictions() int64 {
+	return s.evictions
+}
+
+// Len 返回缓存的条目数量。
+func (s *arenaStore) Len() int {
+	return s.count
+}
+
+// Bytes 返回有效条目的键和值的长度之和，不包括条目头部和被覆盖、删除的条目占用的空间。
+func (s *arenaStore) Bytes() int64 {
+	return s.nbytes
+}
+
+// Resize 调整内存段总大小的上限，立即按写入顺序整段淘汰超出的部分。已经创建的段大小不变。
+func (s *arenaStore) Resize(maxBytes int64) {
+	s.maxBytes = maxBytes
+	for len(s.segments) > 0 && s.maxBytes != 0 && s.arenaBytes > s.maxBytes {
+		s.evictOldest()
+	}
+	s.spare = nil // 缩小之后不再需要
+}
+
+// Sample 随机返回最多 n 个未过期的条目，条目的值是副本。
+func (s *arenaStore) Sample(n int) []lru.EntryInfo {
+	if n <= 0 {
+		return nil
+	}
+	sample := make([]lru.EntryInfo, 0, n)
+	now := s.clock.Now()
+	i := 0
+	for _, slot := range s.slots {
+		if slot.loc == 0 {
+			continue
+		}
+		e := s.entry(slot.loc)
+		if e.expired(now) {
+			continue
+		}
+		if i < n {
+			sample = append(sample, s.info(e))
+		} else if j := rand.Intn(i + 1); j < n {
+			sample[j] = s.info(e)
+		}
+		i++
+	}
+	return sample
+}
+
+// info 把条目转换为 lru.EntryInfo。
+func (s *arenaStore) info(e arenaEntry) lru.EntryInfo {
+	return lru.EntryInfo{Key: string(e.key), Value: e.view(), Added: unixTime(e.added), Expires: unixTime(e.expires)}
+}
+
+// Scan 与 lru.Cache.Scan 相同：按 lru.KeyHash 的顺序分页遍历以 prefix 开头的键。
+func (s *arenaStore) Scan(cursor uint64, prefix string, count int) ([]string, uint64) {
+	now := s.clock.Now()
+	return lru.ScanKeys(cursor, prefix, count, func(yield func(string) bool) {
+		for _, slot := range s.slots {
+			if slot.loc == 0 {
+				continue
+			}
+			if e := s.entry(slot.loc); !e.expired(now) && !yield(string(e.key)) {
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,26 @@
+package geecache
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// WithAuthToken 设置集群共享的认证令牌：向对等节点发起的请求在 Authorization 请求头中携带该令牌，
+// 收到的请求令牌不匹配时返回 401。健康检查路径不要求认证，以便注册中心和负载均衡器探测。
+// 集群中的所有节点必须使用相同的令牌；令牌以明文传输，在不可信的网络上应同时启用 TLS。
+func WithAuthToken(token string) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.authToken = token
+	}
+}
+
+// authorized 报告请求是否携带了正确的认证令牌，没有设置令牌时所有请求都通过。
+func (p *HTTPPool) authorized(r *http.Request) bool {
+	if p.authToken == "" {
+		return true
+	}
+	got := r.Header.Get("Authorization")
+	want := "Bearer " + p.authToken
+	// 使用常数时间比较，避免通过响应时间逐字节猜测令牌。
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
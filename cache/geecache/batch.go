@@ -0,0 +1,247 @@
+package geecache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "testProject/cache/geecachepb"
+)
+
+const (
+	// batchPath 是批量读取请求的路径，相对于 basePath。
+	batchPath = "_batch"
+	// maxBatchKeys 是一次批量读取请求最多包含的键数量。
+	maxBatchKeys = 1000
+	// maxBatchBody 是批量读取请求体的最大字节数。
+	maxBatchBody = 4 << 20
+	// batchLoadConcurrency 是 GetMultiContext 中同时逐个加载的键数量上限。
+	batchLoadConcurrency = 8
+)
+
+// PeerResult 是从远程节点批量读取时单个键的结果。
+type PeerResult struct {
+	Value []byte
+	Err   error // 单个键失败的原因，数据源中不存在该键时为 ErrNotFound
+}
+
+// PeerMultiGetter 由能够在一次往返中读取多个键的 PeerGetter 实现。
+type PeerMultiGetter interface {
+	// GetMulti 读取 group 中的多个键，结果的顺序与 keys 相同。
+	// 单个键失败只体现在对应的 PeerResult 中，返回的错误表示整个请求失败。
+	GetMulti(ctx context.Context, group string, keys []string) ([]PeerResult, error)
+}
+
+// GetMulti 读取多个键，返回键到值的映射。见 GetMultiContext。
+func (g *Group) GetMulti(keys []string) (map[string]ByteView, error) {
+	return g.GetMultiContext(context.Background(), keys)
+}
+
+// GetMultiContext 读取多个键，返回键到值的映射，重复的键只读取一次。
+// 本地缓存没有命中的键按所有者分组，所有者实现了 PeerMultiGetter 时每个所有者只发起一次批量请求，
+// 多个所有者的请求并行进行；其他键（包括本节点拥有的键）按 GetContext 的方式加载，同时最多加载 batchLoadConcurrency 个。
+// 数据源中不存在的键不出现在结果中；其他键读取失败时仍然返回已经读到的值，同时返回第一个失败的错误。
+func (g *Group) GetMultiContext(ctx context.Context, keys []string) (map[string]ByteView, error) {
+	var (
+		mu       sync.Mutex
+		values   = make(map[string]ByteView, len(keys))
+		firstErr error
+	)
+	record := func(key string, v ByteView, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case err == nil:
+			values[key] = v
+		case errors.Is(err, ErrNotFound):
+		case firstErr == nil:
+			firstErr = fmt.Errorf("key %q: %w", key, err)
+		}
+	}
+
+	// 先查本地缓存，没有命中的键按所有者分组。
+	var single []string
+	byPeer := make(map[PeerMultiGetter][]string)
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if key == "" {
+			record(key, ByteView{}, fmt.Errorf("key is required"))
+			continue
+		}
+		if v, ok, err := g.lookup(ctx, key); ok {
+			record(key, v, err)
+			continue
+		}
+		if g.peers != nil {
+			if peer, ok := g.peers.PickPeer(key); ok {
+				if mg, ok := peer.(PeerMultiGetter); ok {
+					byPeer[mg] = append(byPeer[mg], key)
+					continue
+				}
+			}
+		}
+		single = append(single, key)
+	}
+
+	var wg sync.WaitGroup
+	for peer, keys := range byPeer {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.loadBatch(ctx, peer, keys, record)
+		}()
+	}
+	sem := make(chan struct{}, batchLoadConcurrency)
+	for _, key := range single {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			v, err := g.load(ctx, key)
+			record(key, v, err)
+		}()
+	}
+	wg.Wait()
+	return values, firstErr
+}
+
+// loadBatch 通过一次批量请求从 peer 读取 keys。整个请求失败或者单个键遇到 ErrNotFound 以外的错误时，
+// 该键改为按 GetContext 的方式加载，按缓存组的配置重试或者在本地加载。
+func (g *Group) loadBatch(ctx context.Context, peer PeerMultiGetter, keys []string, record func(string, ByteView, error)) {
+	results, err := peer.GetMulti(ctx, g.name, keys)
+	if err == nil && len(results) != len(keys) {
+		err = fmt.Errorf("peer returned %d results for %d keys", len(results), len(keys))
+	}
+	if err != nil {
+		g.logger.Errorf("[GeeCache] Failed to get batch from peer %v", err)
+		results = nil
+	}
+	for i, key := range keys {
+		if results == nil || (results[i].Err != nil && !errors.Is(results[i].Err, ErrNotFound)) {
+			v, err := g.load(ctx, key)
+			record(key, v, err)
+			continue
+		}
+		g.stats.loads.Add(1)
+		if err := results[i].Err; err != nil {
+			g.stats.loadErrors.Add(1)
+			g.cacheNotFound(key, err) // 所有者确认数据源中不存在 key
+			record(key, ByteView{}, err)
+			continue
+		}
+		g.stats.peerLoads.Add(1)
+		v := ByteView{b: results[i].Value}
+		g.populateHotCache(key, v)
+		g.predict(key)
+		record(key, v, nil)
+	}
+}
+
+// GetMulti 通过 POST 请求在一次往返中读取对端的多个键。
+func (h *httpGetter) GetMulti(ctx context.Context, group string, keys []string) ([]PeerResult, error) {
+	body, err := proto.Marshal(&pb.BatchRequest{Group: group, Keys: keys})
+	if err != nil {
+		return nil, err
+	}
+	req, err := h.newRequest(ctx, http.MethodPost, h.baseURL+batchPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", protobufContentType)
+	out := &pb.BatchResponse{}
+	if err := h.roundTrip(req, out); err != nil {
+		return nil, err
+	}
+	results := make([]PeerResult, len(out.Results))
+	for i, res := range out.Results {
+		switch res.Error {
+		case "":
+			results[i].Value = res.Value
+		case ErrNotFound.Error():
+			results[i].Err = ErrNotFound
+		default:
+			results[i].Err = errors.New(res.Error)
+		}
+	}
+	return results, nil
+}
+
+// serveBatch 处理批量读取请求，并行读取请求中的键（同时最多 batchLoadConcurrency 个），结果的顺序与请求中的键相同。
+// 请求方放弃之后还没有开始读取的键返回 ctx 的错误。
+func (p *HTTPPool) serveBatch(w http.ResponseWriter, r *http.Request) {
+	hops, ok := p.parseHops(w, r)
+	if !ok {
+		return
+	}
+	buf, err := readBuffer(http.MaxBytesReader(w, r.Body, maxBatchBody))
+	if err != nil {
+		http.Error(w, "reading request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	in := &pb.BatchRequest{}
+	err = proto.Unmarshal(buf.Bytes(), in)
+	putBuffer(buf) // 解码结果不引用缓冲区
+	if err != nil {
+		http.Error(w, "decoding request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(in.Keys) > maxBatchKeys {
+		http.Error(w, fmt.Sprintf("too many keys: %d > %d", len(in.Keys), maxBatchKeys), http.StatusRequestEntityTooLarge)
+		return
+	}
+	group := p.registry.Get(in.Group)
+	if group == nil {
+		http.Error(w, "no such group: "+in.Group, http.StatusNotFound)
+		return
+	}
+	ctx, cancel, err := requestContext(r, hops)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer cancel()
+
+	// 与 GetMultiContext 一样限制同时读取的键数量，一个请求不能让数据源同时承受上千个加载。
+	out := &pb.BatchResponse{Results: make([]*pb.Response, len(in.Keys))}
+	sem := make(chan struct{}, batchLoadConcurrency)
+	var wg sync.WaitGroup
+	for i, key := range in.Keys {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			out.Results[i] = &pb.Response{Error: ctx.Err().Error()} // 请求方已经放弃，不再开始新的读取
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			res := &pb.Response{}
+			view, err := group.get(ctx, key)
+			switch {
+			case errors.Is(err, ErrNotFound):
+				res.Error = ErrNotFound.Error()
+			case err != nil:
+				res.Error = err.Error()
+			default:
+				res.Value = view.bytes() // 编码响应时才复制
+				if expires, ok := group.mainCache.expiration(key); ok && !expires.IsZero() {
+					res.TtlMs = expires.Sub(group.clock.Now()).Milliseconds()
+				}
+			}
+			out.Results[i] = res
+		}()
+	}
+	wg.Wait()
+	p.respond(w, r, out)
+}
+
+var _ PeerMultiGetter = (*httpGetter)(nil)
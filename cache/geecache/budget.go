@@ -0,0 +1,167 @@
+package geecache
+
+import (
+	"sync"
+	"time"
+
+	"testProject/cache/clock"
+)
+
+// Budget 是进程级的内存预算，把总字节数按权重分配给加入预算的缓存组，
+// 并可以在运行时按各组的实际使用情况重新分配：收缩用不满的冷组，把空出来的内存分给已经用满的热组。
+type Budget struct {
+	mu      sync.Mutex
+	clock   clock.Clock     // 定时重新分配使用的时钟
+	total   int64           // 所有缓存组合计可用的最大内存
+	members []*budgetMember // 加入预算的缓存组，按加入顺序排列
+}
+
+// budgetMember 记录一个加入预算的缓存组及其权重。
+type budgetMember struct {
+	group  *Group
+	weight int
+}
+
+// NewBudget 创建一个总量为 totalBytes 的内存预算。
+func NewBudget(totalBytes int64) *Budget {
+	return &Budget{total: totalBytes, clock: clock.Real}
+}
+
+// SetClock 设置定时重新分配使用的时钟，需要在 StartRebalancing 之前调用。
+func (b *Budget) SetClock(c clock.Clock) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clock = c
+}
+
+// Add 把缓存组以 weight 的权重加入预算，并按权重重新分配所有缓存组的容量。
+// 缓存组已经在预算中时只更新其权重。weight 小于 1 时按 1 处理。
+func (b *Budget) Add(g *Group, weight int) {
+	if weight < 1 {
+		weight = 1
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if m := b.member(g); m != nil {
+		m.weight = weight
+	} else {
+		b.members = append(b.members, &budgetMember{group: g, weight: weight})
+	}
+	b.allocate()
+}
+
+// Remove 把缓存组移出预算，其余缓存组按权重重新分配容量。被移出的缓存组保留当前容量。
+func (b *Budget) Remove(g *Group) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, m := range b.members {
+		if m.group == g {
+			b.members = append(b.members[:i], b.members[i+1:]...)
+			break
+		}
+	}
+	b.allocate()
+}
+
+// SetTotal 调整预算总量，并按权重重新分配所有缓存组的容量。
+func (b *Budget) SetTotal(totalBytes int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.total = totalBytes
+	b.allocate()
+}
+
+// Rebalance 按各缓存组的实际使用情况重新分配容量。
+// 使用量的 1.25 倍仍低于其权重份额的缓存组是冷组，收缩到使用量的 1.25 倍（但不少于份额的 1/4）；
+// 其余缓存组是热组，按权重分得冷组让出的内存。没有热组时按权重平均分配。
+func (b *Budget) Rebalance() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	shares := b.shares()
+	allocs := make([]int64, len(b.members))
+	hot := make([]bool, len(b.members))
+	var freed int64
+	hotWeight := 0
+	for i, m := range b.members {
+		used := m.group.Bytes()
+		alloc := used + used/4 // 为冷组保留 25% 的增长空间
+		if min := shares[i] / 4; alloc < min {
+			alloc = min
+		}
+		if alloc >= shares[i] {
+			hot[i] = true
+			hotWeight += m.weight
+			continue
+		}
+		allocs[i] = alloc
+		freed += shares[i] - alloc
+	}
+	if hotWeight == 0 {
+		b.allocate() // 所有缓存组都用不满份额，不需要转移内存
+		return
+	}
+
+	for i, m := range b.members {
+		if hot[i] {
+			allocs[i] = shares[i] + freed*int64(m.weight)/int64(hotWeight)
+		}
+		m.group.mainCache.resize(allocs[i])
+	}
+}
+
+// StartRebalancing 启动一个后台 goroutine，每隔 interval 调用一次 Rebalance。
+// 返回的 stop 函数用于停止该 goroutine。
+func (b *Budget) StartRebalancing(interval time.Duration) (stop func()) {
+	b.mu.Lock()
+	ticker := b.clock.NewTicker(interval)
+	b.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C():
+				b.Rebalance()
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+	}
+}
+
+// member 返回缓存组在预算中的记录，不存在时返回 nil。调用方需要持有 b.mu。
+func (b *Budget) member(g *Group) *budgetMember {
+	for _, m := range b.members {
+		if m.group == g {
+			return m
+		}
+	}
+	return nil
+}
+
+// shares 返回每个缓存组按权重应得的份额。调用方需要持有 b.mu。
+func (b *Budget) shares() []int64 {
+	totalWeight := 0
+	for _, m := range b.members {
+		totalWeight += m.weight
+	}
+	shares := make([]int64, len(b.members))
+	for i, m := range b.members {
+		shares[i] = b.total * int64(m.weight) / int64(totalWeight)
+	}
+	return shares
+}
+
+// allocate 按权重把预算总量分配给所有缓存组。调用方需要持有 b.mu。
+func (b *Budget) allocate() {
+	for i, share := range b.shares() {
+		b.members[i].group.mainCache.resize(share)
+	}
+}
@@ -0,0 +1,58 @@
+package geecache
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// maxPooledBuffer 是放回缓冲池的缓冲区的最大容量，更大的缓冲区直接丢弃，
+// 避免偶尔传输一个很大的值之后缓冲池长期占用同样多的内存。
+const maxPooledBuffer = 1 << 20
+
+// bufPool 缓存节点之间传输数据时使用的缓冲区：读取请求体和响应体、编码响应。
+// 传输路径上的每次请求都复用缓冲区，而不是每次分配新的字节切片。
+var bufPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// getBuffer 从缓冲池中取出一个空的缓冲区，用完之后调用 putBuffer 放回。
+func getBuffer() *bytes.Buffer {
+	return bufPool.Get().(*bytes.Buffer)
+}
+
+// putBuffer 把缓冲区放回缓冲池。放回之后不能再使用缓冲区及其 Bytes 返回的切片。
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	bufPool.Put(buf)
+}
+
+// readBuffer 把 r 的全部内容读入一个从缓冲池中取出的缓冲区，调用方用完之后调用 putBuffer 放回。
+func readBuffer(r io.Reader) (*bytes.Buffer, error) {
+	buf := getBuffer()
+	if _, err := buf.ReadFrom(r); err != nil {
+		putBuffer(buf)
+		return nil, err
+	}
+	return buf, nil
+}
+
+// marshalBuffer 把 m 编码到一个从缓冲池中取出的缓冲区，调用方用完之后调用 putBuffer 放回。
+// 先计算编码后的大小并一次扩容到位，编码时不会再分配内存。
+func marshalBuffer(m proto.Message) (*bytes.Buffer, error) {
+	buf := getBuffer()
+	buf.Grow(proto.Size(m))
+	// Size 刚刚计算并缓存了各个消息的大小，编码时直接使用，不必再算一遍。
+	body, err := proto.MarshalOptions{UseCachedSize: true}.MarshalAppend(buf.AvailableBuffer(), m)
+	if err != nil {
+		putBuffer(buf)
+		return nil, err
+	}
+	buf.Write(body) // body 就是 buf 的可用空间，这里只是移动写入位置，不会复制到别处
+	return buf, nil
+}
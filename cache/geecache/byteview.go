@@ -1,23 +1,91 @@
 package geecache
 
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
 // ByteView 表示一个不可变的字节视图。
+// 底层数据是 []byte 或者 string 之一：数据源直接提供字符串时不需要再复制一份字节切片。
 type ByteView struct {
-	b []byte // 存储字节数据的切片
+	b []byte // 存储字节数据的切片，s 不为空时为 nil
+	s string // 存储字符串数据，b 不为 nil 时不使用
+	// z 不为 NoCompression 时 b 是压缩后的数据，只出现在本地缓存内部，见 WithCompression。
+	// 这样的视图必须先经过 Group.decompress 才能返回给调用方。
+	z Compression
 }
 
 // Len 返回视图的长度
 func (v ByteView) Len() int {
-	return len(v.b) // 返回字节切片的长度
+	if v.b != nil {
+		return len(v.b) // 返回字节切片的长度
+	}
+	return len(v.s)
 }
 
 // ByteSlice 返回数据的字节切片副本。
 func (v ByteView) ByteSlice() []byte {
-	return cloneBytes(v.b) // 调用 cloneBytes 函数，返回一个字节切片的深拷贝
+	if v.b != nil {
+		return cloneBytes(v.b) // 调用 cloneBytes 函数，返回一个字节切片的深拷贝
+	}
+	return []byte(v.s)
 }
 
 // String 返回数据作为字符串，如果需要则创建一个副本。
 func (v ByteView) String() string {
-	return string(v.b) // 将字节切片转换为字符串并返回
+	if v.b != nil {
+		return string(v.b) // 将字节切片转换为字符串并返回
+	}
+	return v.s // 字符串不可变，直接返回，不需要复制
+}
+
+// At 返回下标 i 处的字节，i 越界时 panic。
+func (v ByteView) At(i int) byte {
+	if v.b != nil {
+		return v.b[i]
+	}
+	return v.s[i]
+}
+
+// Slice 返回下标 [from, to) 之间的视图，与 v 共享底层数据，不会复制。
+func (v ByteView) Slice(from, to int) ByteView {
+	if v.b != nil {
+		return ByteView{b: v.b[from:to]}
+	}
+	return ByteView{s: v.s[from:to]}
+}
+
+// Equal 报告视图的内容是否与 b 相同。
+func (v ByteView) Equal(b []byte) bool {
+	if v.b != nil {
+		return bytes.Equal(v.b, b)
+	}
+	return v.s == string(b) // 编译器会优化掉比较中的转换，不会分配内存
+}
+
+// EqualString 报告视图的内容是否与 s 相同。
+func (v ByteView) EqualString(s string) bool {
+	if v.b != nil {
+		return string(v.b) == s
+	}
+	return v.s == s
+}
+
+// Reader 返回读取视图内容的 io.ReadSeeker，不会复制数据。
+func (v ByteView) Reader() io.ReadSeeker {
+	if v.b != nil {
+		return bytes.NewReader(v.b)
+	}
+	return strings.NewReader(v.s)
+}
+
+// bytes 返回视图的内容，供包内只读使用：字节切片不复制，字符串需要转换一次。
+func (v ByteView) bytes() []byte {
+	if v.b != nil {
+		return v.b
+	}
+	return []byte(v.s)
 }
 
 // cloneBytes 创建并返回字节切片的深拷贝。
@@ -1,41 +1,414 @@
 package geecache
 
 import (
+	"io"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"testProject/cache/clock"
 	"testProject/cache/lru"
 )
 
-// cache 结构体用于管理缓存，包含了互斥锁、LRU 缓存、以及缓存大小限制。
+// readBufferSize 是读锁下命中的键的缓冲区大小，见 cache.get。
+const readBufferSize = 64
+
+// cache 结构体用于管理缓存，包含了互斥锁、底层存储、以及缓存大小限制。
+// 读取只持有读锁，命中的键先记录在 hits 中，之后持有写锁时再批量更新底层存储中的淘汰顺序。
 type cache struct {
-	mu         sync.Mutex // 互斥锁，用于在并发操作中保护缓存数据
-	lru        *lru.Cache // LRU 缓存实例，用于实现缓存淘汰策略
-	cacheBytes int64      // 缓存的最大内存限制
+	mu         sync.RWMutex                                           // 读写锁，用于在并发操作中保护缓存数据
+	hits       [readBufferSize]string                                 // 读锁下命中、还没有更新淘汰顺序的键
+	nhits      atomic.Int64                                           // 已经占用的 hits 槽位数量，可能超过 readBufferSize
+	store      EvictionPolicy                                         // 底层存储，默认是 LRU 缓存，用于实现缓存淘汰策略
+	cacheBytes int64                                                  // 缓存的最大内存限制
+	newStore   func(cacheBytes int64, clk clock.Clock) EvictionPolicy // 创建底层存储的函数，为 nil 时使用 LRU 缓存
+	clock      clock.Clock                                            // 底层存储记录时间使用的时钟
+	overhead   int64                                                  // 默认的 LRU 存储中每个条目额外计入的字节数
+	admission  AdmissionPolicy                                        // 不为 nil 时缓存已满后由它决定是否接纳新条目
+	onRemoved  func(key string, value lru.Value, reason removal)      // 不为 nil 时在底层存储删除条目时调用
+	reason     removal                                                // 当前操作删除条目的原因，由 c.mu 保护
+	shards     []*cache                                               // 不为 nil 时条目按键的哈希值分布到各分片，见 WithShards
+}
+
+// EvictionPolicy 是 cache 底层的带淘汰策略的存储，由 cache 的读写锁保护，自身不需要并发安全；
+// 但 Peek、Contains、Expiration、Evictions、Len 和 Bytes 只持有读锁，可能被并发调用，不能修改存储。
+// lru.Cache 是默认实现，lfu.Cache 按访问频率淘汰，其他策略可以通过 WithEvictionPolicy 接入。
+type EvictionPolicy interface {
+	Get(key string) (value lru.Value, ok bool)
+	Peek(key string) (value lru.Value, ok bool)
+	Contains(key string) bool
+	AddWithExpire(key string, value lru.Value, expires time.Time)
+	Expiration(key string) (expires time.Time, ok bool)
+	RemoveExpired() int
+	Remove(key string) bool
+	Evictions() int64
+	Len() int
+	Bytes() int64
+	Resize(maxBytes int64)
+	Sample(n int) []lru.EntryInfo
+	Scan(cursor uint64, prefix string, count int) (keys []string, next uint64)
+}
+
+// add 方法用于向缓存中添加键值对，条目在 expires 之后过期，零值表示永不过期。
+func (c *cache) add(key string, value ByteView, expires time.Time) {
+	c.put(key, value, expires, false)
 }
 
-// add 方法用于向缓存中添加键值对。
-func (c *cache) add(key string, value ByteView) {
+// addIfAbsent 只在缓存中没有 key 时添加条目，不覆盖已有的值，返回是否添加。
+func (c *cache) addIfAbsent(key string, value ByteView, expires time.Time) bool {
+	return c.put(key, value, expires, true)
+}
+
+// put 是 add 和 addIfAbsent 的实现，ifAbsent 为 true 时在同一次加锁中检查 key 是否已经存在。
+func (c *cache) put(key string, value ByteView, expires time.Time, ifAbsent bool) bool {
+	if c.shards != nil {
+		return c.shard(key).put(key, value, expires, ifAbsent)
+	}
 	c.mu.Lock()         // 加锁以确保并发安全
 	defer c.mu.Unlock() // 函数返回前解锁
 
-	if c.lru == nil {
-		c.lru = lru.New(c.cacheBytes, nil) // 如果 LRU 缓存为空，创建一个新的
+	if c.store == nil {
+		c.store = c.createStore() // 如果底层存储为空，创建一个新的
 	}
+	if ifAbsent && c.store.Contains(key) {
+		return false
+	}
+	c.applyHits() // 淘汰之前先让淘汰顺序反映最近的读取
+	if !c.admit(key, value) {
+		return false // 准入策略拒绝了新条目，保留将被淘汰的条目
+	}
+	c.reason = removalEvicted
 
-	c.lru.Add(key, value) // 调用 LRU 缓存的 Add 方法，将键值对添加到缓存中
+	c.store.AddWithExpire(key, value, expires) // 调用底层存储的 AddWithExpire 方法，将键值对添加到缓存中
+	return true
+}
+
+// prepare 立即创建底层存储（分片时创建每个分片的），使存储的配置错误在创建缓存组时就暴露出来，
+// 而不是在第一次写入时。
+func (c *cache) prepare() {
+	for _, s := range c.shards {
+		s.prepare()
+	}
+	if c.shards != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store == nil {
+		c.store = c.createStore()
+	}
 }
 
 // get 方法用于从缓存中获取指定键的值。
+// 命中时只持有读锁：用 Peek 读取值，把键记入 hits，之后持有写锁的操作（或者填满 hits 的读取）
+// 再调用底层存储的 Get 更新淘汰顺序。因此淘汰顺序是近似的：hits 填满之后、清空之前的命中不会被记录。
+// 未命中时持有写锁再查找一次，惰性删除已经过期的条目。设置了准入策略时每次读取都需要写锁。
 func (c *cache) get(key string) (value ByteView, ok bool) {
+	if c.shards != nil {
+		return c.shard(key).get(key)
+	}
+	if c.admission == nil {
+		c.mu.RLock()
+		if c.store == nil {
+			c.mu.RUnlock()
+			return // 如果底层存储为空，直接返回
+		}
+		v, ok := c.store.Peek(key)
+		full := false
+		if t, touch := c.store.(toucher); ok && touch {
+			t.Touch(key) // 底层存储可以在读锁下直接记录访问，不需要延迟
+		} else if ok && c.nhits.Load() < readBufferSize {
+			// 每个读取占用不同的槽位，写锁下才会读取和清空 hits，因此读锁下写入槽位不会冲突。
+			if i := c.nhits.Add(1); i <= readBufferSize {
+				c.hits[i-1] = key
+				full = i == readBufferSize
+			}
+		}
+		c.mu.RUnlock()
+		if ok {
+			if full && c.mu.TryLock() {
+				c.applyHits() // 填满缓冲区的读取在能立即拿到写锁时顺便清空它
+				c.mu.Unlock()
+			}
+			return v.(ByteView), true
+		}
+	}
+
 	c.mu.Lock()         // 加锁以确保并发安全
 	defer c.mu.Unlock() // 函数返回前解锁
 
-	if c.lru == nil {
-		return // 如果 LRU 缓存为空，直接返回
+	if c.admission != nil {
+		c.admission.Record(key) // 命中和未命中都计入访问频率
+	}
+	if c.store == nil {
+		return // 如果底层存储为空，直接返回
 	}
 
-	if v, ok := c.lru.Get(key); ok {
-		return v.(ByteView), ok // 调用 LRU 缓存的 Get 方法，返回对应键的值和是否命中
+	c.applyHits()
+	c.reason = removalExpired // 读取时只会惰性删除过期的条目
+	if v, ok := c.store.Get(key); ok {
+		return v.(ByteView), ok // 调用底层存储的 Get 方法，返回对应键的值和是否命中
 	}
 
 	return // 如果未命中，直接返回
 }
+
+// applyHits 把 hits 中记录的命中应用到底层存储，更新条目的淘汰顺序。调用方需要持有写锁。
+func (c *cache) applyHits() {
+	n := min(c.nhits.Load(), readBufferSize)
+	c.reason = removalExpired // Get 只会惰性删除过期的条目
+	t, touch := c.store.(lockedToucher)
+	for i := range n {
+		if touch {
+			t.touchLocked(c.hits[i])
+		} else {
+			c.store.Get(c.hits[i])
+		}
+		c.hits[i] = ""
+	}
+	c.nhits.Store(0)
+}
+
+// peek 方法用于读取指定键的值，不影响条目的淘汰顺序。
+func (c *cache) peek(key string) (value ByteView, ok bool) {
+	if c.shards != nil {
+		return c.shard(key).peek(key)
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.store == nil {
+		return
+	}
+	if v, ok := c.store.Peek(key); ok {
+		return v.(ByteView), ok
+	}
+	return
+}
+
+// contains 报告缓存中是否有 key 对应的条目，不影响条目的淘汰顺序。
+func (c *cache) contains(key string) bool {
+	if c.shards != nil {
+		return c.shard(key).contains(key)
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.store != nil && c.store.Contains(key)
+}
+
+// createStore 按配置创建底层存储。调用方需要持有 c.mu。
+func (c *cache) createStore() EvictionPolicy {
+	clk := c.clock
+	if clk == nil {
+		clk = clock.Real
+	}
+	var s EvictionPolicy
+	if c.newStore != nil {
+		s = c.newStore(c.cacheBytes, clk)
+	} else {
+		s = lru.New(c.cacheBytes, nil, lru.WithClock(clk), lru.WithEntryOverhead(c.overhead))
+	}
+	if n, ok := s.(evictionNotifier); ok && c.onRemoved != nil {
+		n.AddEvictionListener(func(key string, value lru.Value) {
+			c.onRemoved(key, value, c.reason) // 监听器在持有 c.mu 的操作中被调用
+		})
+	}
+	return s
+}
+
+// removal 是底层存储删除条目的原因。
+type removal int
+
+const (
+	removalEvicted removal = iota // 超出容量被淘汰
+	removalExpired                // 已经过期
+	removalDeleted                // 被显式删除
+)
+
+// toucher 由能够在读锁下记录访问的底层存储实现，Touch 可以与 Peek 等只读方法并发调用，例如 sampled.Cache。
+type toucher interface {
+	Touch(key string)
+}
+
+// lockedToucher 由读取值代价较高的底层存储实现，applyHits 用 touchLocked 只更新淘汰顺序而不读取值，例如 diskStore。
+// 与 toucher 不同，touchLocked 只能在写锁下调用。
+type lockedToucher interface {
+	touchLocked(key string)
+}
+
+// evictionNotifier 由能够通知淘汰事件的底层存储实现，lru.Cache、lfu.Cache、arc.Cache 和 sampled.Cache 都支持。
+type evictionNotifier interface {
+	AddEvictionListener(fn lru.EvictionListener)
+}
+
+// expiration 返回 key 对应条目的过期时间，不影响条目的访问顺序。
+func (c *cache) expiration(key string) (time.Time, bool) {
+	if c.shards != nil {
+		return c.shard(key).expiration(key)
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.store == nil {
+		return time.Time{}, false
+	}
+	return c.store.Expiration(key)
+}
+
+// remove 从缓存中删除 key 对应的条目，返回条目是否存在。
+func (c *cache) remove(key string) bool {
+	if c.shards != nil {
+		return c.shard(key).remove(key)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store == nil {
+		return false
+	}
+	c.reason = removalDeleted
+	return c.store.Remove(key)
+}
+
+// removeExpired 删除缓存中所有已经过期的条目，返回删除的数量。
+func (c *cache) removeExpired() int {
+	if c.shards != nil {
+		n := 0
+		for _, s := range c.shards {
+			n += s.removeExpired()
+		}
+		return n
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store == nil {
+		return 0
+	}
+	c.reason = removalExpired
+	return c.store.RemoveExpired()
+}
+
+// evictions 返回缓存因超出容量而淘汰的条目数量。
+func (c *cache) evictions() int64 {
+	if c.shards != nil {
+		var n int64
+		for _, s := range c.shards {
+			n += s.evictions()
+		}
+		return n
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.store == nil {
+		return 0
+	}
+	return c.store.Evictions()
+}
+
+// len 返回缓存中的条目数量。
+func (c *cache) len() int {
+	if c.shards != nil {
+		n := 0
+		for _, s := range c.shards {
+			n += s.len()
+		}
+		return n
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.store == nil {
+		return 0
+	}
+	return c.store.Len()
+}
+
+// bytes 返回缓存当前已经使用的内存大小。
+func (c *cache) bytes() int64 {
+	if c.shards != nil {
+		var n int64
+		for _, s := range c.shards {
+			n += s.bytes()
+		}
+		return n
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.store == nil {
+		return 0
+	}
+	return c.store.Bytes()
+}
+
+// capacity 返回缓存的最大内存限制，0 表示不限制。
+func (c *cache) capacity() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cacheBytes
+}
+
+// resize 调整缓存的最大内存限制，超出新限制的条目会被立即淘汰。
+func (c *cache) resize(cacheBytes int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cacheBytes = cacheBytes
+	for _, s := range c.shards {
+		s.resize(shardBytes(cacheBytes, len(c.shards)))
+	}
+	if c.store != nil {
+		c.applyHits()
+		c.reason = removalEvicted
+		c.store.Resize(cacheBytes)
+	}
+}
+
+// sample 随机返回缓存中最多 n 个条目。
+func (c *cache) sample(n int) []lru.EntryInfo {
+	if c.shards != nil {
+		samples := make([][]lru.EntryInfo, len(c.shards))
+		sizes := make([]int, len(c.shards))
+		for i, s := range c.shards {
+			samples[i], sizes[i] = s.sample(n), s.len()
+		}
+		return lru.MergeSamples(n, samples, sizes)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store == nil {
+		return nil
+	}
+	return c.store.Sample(n)
+}
+
+// scan 按游标分页遍历缓存中的键，每次调用只在遍历当前页时持有锁。
+func (c *cache) scan(cursor uint64, prefix string, count int) ([]string, uint64) {
+	if c.shards != nil {
+		pages := make([][]string, len(c.shards))
+		nexts := make([]uint64, len(c.shards))
+		for i, s := range c.shards {
+			pages[i], nexts[i] = s.scan(cursor, prefix, count)
+		}
+		return lru.MergeScans(count, pages, nexts)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store == nil {
+		return nil, 0
+	}
+	return c.store.Scan(cursor, prefix, count)
+}
+
+// stats 返回缓存的容量相关统计：淘汰数量、已使用的内存和条目数量。
+func (c *cache) stats() CacheStats {
+	return CacheStats{Evictions: c.evictions(), Bytes: c.bytes(), Items: int64(c.len())}
+}
+
+// reset 丢弃缓存中的所有条目，释放底层存储。底层存储实现了 io.Closer 时（例如 WithDiskValues 的磁盘存储）
+// 先关闭它，释放文件、映射和临时目录。
+func (c *cache) reset() {
+	for _, s := range c.shards {
+		s.reset()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cl, ok := c.store.(io.Closer); ok {
+		cl.Close() // 失败时由底层存储自己记录日志
+	}
+	c.store = nil
+}
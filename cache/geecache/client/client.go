@@ -0,0 +1,103 @@
+// Package client 让不属于缓存集群的应用通过节点间相同的 HTTP 协议访问集群。
+// 客户端用与节点相同的一致性哈希找到键的所有者并直接向它发起请求，
+// 自身既不加入节点的哈希环，也不缓存任何数据。
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"testProject/cache/geecache"
+)
+
+// ErrNoPeers 表示客户端还没有设置任何节点。
+var ErrNoPeers = errors.New("geecache/client: no peers")
+
+// Client 是缓存集群的客户端，可以被多个 goroutine 并发使用。
+type Client struct {
+	pool *geecache.HTTPPool // 只用于选择节点和发起请求，本身不提供服务
+}
+
+// New 创建访问 peers（例如 "http://10.0.0.1:8001"）组成的集群的客户端。
+// opts 与节点创建 HTTPPool 时使用的选项相同：WithReplicas 和 WithHashFn 必须与节点一致，
+// 否则客户端选择的节点不是键的所有者；集群启用了认证或 TLS 时还需要 WithAuthToken 或 WithTLSConfig。
+func New(peers []string, opts ...geecache.HTTPPoolOption) *Client {
+	// self 为空，不会与任何节点相同，因此每个键都交给某个节点处理。
+	pool := geecache.NewHTTPPool("", append([]geecache.HTTPPoolOption{geecache.WithPoolLogger(geecache.NopLogger)}, opts...)...)
+	pool.Set(peers...)
+	return &Client{pool: pool}
+}
+
+// SetPeers 更新集群的节点列表，例如在服务发现报告节点变化时调用。
+func (c *Client) SetPeers(peers ...string) {
+	c.pool.Set(peers...)
+}
+
+// StartHealthCheck 每隔 interval 探测一次所有节点，所有者不健康时请求交给环上的下一个健康节点。
+// 返回的 stop 函数用于停止探测。
+func (c *Client) StartHealthCheck(interval time.Duration) (stop func()) {
+	return c.pool.StartHealthCheck(interval)
+}
+
+// owner 返回 key 的所有者。
+func (c *Client) owner(key string) (geecache.PeerGetter, error) {
+	peer, ok := c.pool.PickPeer(key)
+	if !ok {
+		return nil, ErrNoPeers
+	}
+	return peer, nil
+}
+
+// Get 从 key 的所有者读取缓存组 group 中的值，所有者没有缓存时由它从数据源加载。
+// 数据源中不存在 key 时返回 geecache.ErrNotFound。
+func (c *Client) Get(ctx context.Context, group, key string) ([]byte, error) {
+	peer, err := c.owner(key)
+	if err != nil {
+		return nil, err
+	}
+	return peer.Get(ctx, group, key)
+}
+
+// Stat 查询 key 的所有者上已缓存条目的元数据，不会触发加载，也不传输值。所有者没有缓存该条目时 ok 为 false。
+func (c *Client) Stat(ctx context.Context, group, key string) (meta geecache.EntryMeta, ok bool, err error) {
+	peer, err := c.owner(key)
+	if err != nil {
+		return geecache.EntryMeta{}, false, err
+	}
+	return peer.(geecache.PeerStater).Stat(ctx, group, key)
+}
+
+// Set 把 key 的新值写给它的所有者，所有者按照缓存组的配置写入缓存和数据源。
+// 返回的一致性令牌可以通过 geecache.ContextWithToken 传给之后的 Get，保证读到这次写入。
+func (c *Client) Set(ctx context.Context, group, key string, value []byte) (geecache.ConsistencyToken, error) {
+	peer, err := c.owner(key)
+	if err != nil {
+		return "", err
+	}
+	return peer.(geecache.PeerSetter).Set(ctx, group, key, value)
+}
+
+// Remove 删除所有节点缓存中 key 对应的条目，不影响数据源。
+// 部分节点失败时返回的错误中包含失败的节点数量，已经成功的节点不会回滚。
+func (c *Client) Remove(ctx context.Context, group, key string) error {
+	peers := c.pool.Peers()
+	if len(peers) == 0 {
+		return ErrNoPeers
+	}
+	var failed int
+	var firstErr error
+	for _, peer := range peers {
+		if err := peer.Remove(ctx, group, key); err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("remove %q: %d of %d peers failed: %v", key, failed, len(peers), firstErr)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"testProject/cache/geecache"
+)
+
+// newNode 启动一个提供 geecache 节点服务的测试服务器。
+func newNode() *httptest.Server {
+	srv := httptest.NewUnstartedServer(geecache.NewHTTPPool("http://node"))
+	srv.Config.Protocols = geecache.PeerProtocols()
+	srv.Start()
+	return srv
+}
+
+// 测试客户端通过节点协议读取、写入和删除条目
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	loads := 0
+	stored := map[string]string{"Tom": "630"}
+	g := geecache.NewGroup("client", 0, geecache.GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		if v, ok := stored[key]; ok {
+			return []byte(v), nil
+		}
+		return nil, geecache.ErrNotFound
+	}), geecache.WithWriteThrough(geecache.SetterFunc(func(key string, value []byte) error {
+		stored[key] = string(value)
+		return nil
+	})))
+
+	if _, err := New(nil).Get(ctx, "client", "Tom"); err != ErrNoPeers {
+		t.Fatalf("Get without peers: err = %v, want ErrNoPeers", err)
+	}
+
+	a, b := newNode(), newNode()
+	defer a.Close()
+	defer b.Close()
+	c := New([]string{a.URL, b.URL})
+
+	for i := 0; i < 2; i++ {
+		if v, err := c.Get(ctx, "client", "Tom"); err != nil || string(v) != "630" {
+			t.Fatalf("Get = %q, %v", v, err)
+		}
+	}
+	if loads != 1 {
+		t.Fatalf("loads = %d, want the owner to load Tom once", loads)
+	}
+	if _, err := c.Get(ctx, "client", "Bob"); !errors.Is(err, geecache.ErrNotFound) {
+		t.Fatalf("Get of a missing key: err = %v, want ErrNotFound", err)
+	}
+
+	if _, err := c.Set(ctx, "client", "Jack", []byte("589")); err != nil || stored["Jack"] != "589" {
+		t.Fatalf("Set = %v, stored %v", err, stored)
+	}
+	if meta, ok, err := c.Stat(ctx, "client", "Jack"); err != nil || !ok || meta.Size != 3 {
+		t.Fatalf("Stat = %+v, %v, %v", meta, ok, err)
+	}
+
+	if err := c.Remove(ctx, "client", "Jack"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Len() != 1 {
+		t.Fatalf("Len() = %d after Remove, want 1", g.Len())
+	}
+}
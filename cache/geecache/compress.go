@@ -0,0 +1,156 @@
+package geecache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/golang/snappy"
+)
+
+// Compression 是缓存值的压缩算法。
+type Compression uint8
+
+const (
+	// NoCompression 表示不压缩，这是默认行为。
+	NoCompression Compression = iota
+	// Snappy 压缩和解压都很快，压缩率一般，适合大多数场景。
+	Snappy
+	// Gzip 压缩率更高但更耗 CPU，适合较大且重复度高的值。
+	Gzip
+)
+
+// String 返回压缩算法在 Content-Encoding 中使用的名称。
+func (c Compression) String() string {
+	switch c {
+	case Snappy:
+		return "snappy"
+	case Gzip:
+		return "gzip"
+	default:
+		return "identity"
+	}
+}
+
+// parseCompression 按 Content-Encoding 中的名称返回压缩算法。
+func parseCompression(name string) (Compression, error) {
+	switch name {
+	case "", "identity":
+		return NoCompression, nil
+	case "snappy":
+		return Snappy, nil
+	case "gzip":
+		return Gzip, nil
+	default:
+		return NoCompression, fmt.Errorf("unsupported encoding %q", name)
+	}
+}
+
+// encode 压缩 b。
+func (c Compression) encode(b []byte) ([]byte, error) {
+	switch c {
+	case Snappy:
+		return snappy.Encode(nil, b), nil
+	case Gzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(b); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return b, nil
+	}
+}
+
+// decode 解压 encode 的结果。
+func (c Compression) decode(b []byte) ([]byte, error) {
+	switch c {
+	case Snappy:
+		return snappy.Decode(nil, b)
+	case Gzip:
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(r)
+	default:
+		return b, nil
+	}
+}
+
+// WithCompression 让缓存组把不小于 threshold 字节的值压缩之后再保存在本地缓存中，
+// 同样的 cacheBytes 可以容纳更多的条目，代价是每次命中都需要解压。
+// 压缩对调用方透明：读取到的始终是原始的值。压缩后没有变小的值按原样保存。
+func WithCompression(c Compression, threshold int) GroupOption {
+	return func(g *Group) {
+		g.compression = c
+		g.compressThreshold = threshold
+	}
+}
+
+// compress 按缓存组的配置压缩即将写入本地缓存的值。
+func (g *Group) compress(v ByteView) ByteView {
+	if g.compression == NoCompression || v.z != NoCompression || v.Len() < g.compressThreshold {
+		return v
+	}
+	b, err := g.compression.encode(v.bytes())
+	if err != nil || len(b) >= v.Len() {
+		return v // 压缩失败或者没有变小时按原样保存
+	}
+	return ByteView{b: b, z: g.compression}
+}
+
+// decompress 还原从本地缓存中读出的值，返回给调用方或者发送给其他节点之前必须调用。
+func (g *Group) decompress(v ByteView) (ByteView, error) {
+	if v.z == NoCompression {
+		return v, nil
+	}
+	b, err := v.z.decode(v.b)
+	if err != nil {
+		return ByteView{}, fmt.Errorf("decompressing cached value: %v", err)
+	}
+	return ByteView{b: b}, nil
+}
+
+// WithWireCompression 让节点在对端接受时压缩不小于 threshold 字节的响应体，
+// 通过 Accept-Encoding 和 Content-Encoding 协商。httpGetter 总是声明接受所有支持的压缩算法。
+func WithWireCompression(c Compression, threshold int) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.wireCompression = c
+		p.wireThreshold = threshold
+	}
+}
+
+// acceptEncoding 是 httpGetter 在请求中声明接受的压缩算法。
+const acceptEncoding = "snappy, gzip"
+
+// accepts 报告 Accept-Encoding 请求头 header 是否接受压缩算法 c。
+func accepts(header string, c Compression) bool {
+	for _, enc := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(enc), ";")
+		if name == c.String() && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeBody 在对端接受时按节点的配置压缩响应体，返回响应体以及使用的压缩算法。
+func (p *HTTPPool) encodeBody(r *http.Request, body []byte) ([]byte, Compression) {
+	c := p.wireCompression
+	if c == NoCompression || len(body) < p.wireThreshold || !accepts(r.Header.Get("Accept-Encoding"), c) {
+		return body, NoCompression
+	}
+	encoded, err := c.encode(body)
+	if err != nil || len(encoded) >= len(body) {
+		return body, NoCompression
+	}
+	return encoded, c
+}
@@ -0,0 +1,70 @@
+package geecache
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// tokenHeader 是节点间请求中携带一致性令牌的请求头。
+const tokenHeader = "X-Geecache-Token"
+
+// ConsistencyToken 是写入操作返回的一致性令牌，记录了签发它的缓存组实例（每次创建缓存组时随机生成的纪元）
+// 和写入发生时该实例的写入序号。之后的读取携带该令牌时，会绕过可能过期的非所有者副本直接从所有者节点读取，
+// 从而为同一个会话提供“读己之写”的一致性。空令牌表示不要求一致性。
+type ConsistencyToken string
+
+// tokenKey 是在 context 中保存一致性令牌的键。
+type tokenKey struct{}
+
+// ContextWithToken 返回一个携带一致性令牌的 context，传输层在服务端用它还原请求方携带的令牌。
+func ContextWithToken(ctx context.Context, token ConsistencyToken) context.Context {
+	if token == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, tokenKey{}, token)
+}
+
+// TokenFromContext 返回 context 中携带的一致性令牌，PeerGetter 用它把令牌转发给对端。
+func TokenFromContext(ctx context.Context) (ConsistencyToken, bool) {
+	token, ok := ctx.Value(tokenKey{}).(ConsistencyToken)
+	return token, ok
+}
+
+// GetConsistent 与 Get 相同，但保证能读到 token 所对应的写入之后的数据：
+// 本节点不是 key 的所有者时不使用本地缓存，直接从所有者节点读取；
+// 本节点是所有者但还没有见过该写入时，绕过缓存重新加载。
+// 令牌不是本节点上这个缓存组实例签发的（例如所有者重启过、节点集合变化过，或者令牌是伪造的）时，
+// 本节点无法判断是否见过该写入，按普通读取处理，避免这样的令牌让之后的每次读取都绕过缓存。
+func (g *Group) GetConsistent(key string, token ConsistencyToken) (ByteView, error) {
+	return g.get(ContextWithToken(context.Background(), token), key)
+}
+
+// recordWrite 记录一次写入并返回对应的一致性令牌，由写入类操作在写入完成后调用。
+func (g *Group) recordWrite() ConsistencyToken {
+	return g.token(g.writeSeq.Add(1))
+}
+
+// token 返回本缓存组实例签发的、写入序号为 seq 的一致性令牌，格式为“纪元.序号”。
+func (g *Group) token(seq uint64) ConsistencyToken {
+	return ConsistencyToken(strconv.FormatUint(g.epoch, 36) + "." + strconv.FormatUint(seq, 10))
+}
+
+// bypassCache 报告本次读取是否必须绕过本地缓存。
+func (g *Group) bypassCache(ctx context.Context, key string) bool {
+	token, ok := TokenFromContext(ctx)
+	if !ok {
+		return false
+	}
+	if g.peers != nil {
+		if _, remote := g.peers.PickPeer(key); remote {
+			return true // 非所有者节点上的缓存可能已经过期
+		}
+	}
+	epoch, seq, ok := strings.Cut(string(token), ".")
+	if !ok || epoch != strconv.FormatUint(g.epoch, 36) {
+		return false // 不是本实例签发的令牌，按普通读取处理
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	return err == nil && n > g.writeSeq.Load()
+}
@@ -0,0 +1,359 @@
+package geecache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"testProject/cache/clock"
+	"testProject/cache/lru"
+)
+
+// defaultSegmentBytes 是磁盘值存储中单个段文件的大小上限，写满后切换到新的段文件。
+const defaultSegmentBytes = 64 << 20
+
+// errMmapUnsupported 表示当前平台不支持 WithMappedValues。
+var errMmapUnsupported = errors.New("geecache: mmap is not supported on this platform")
+
+// WithDiskValues 让缓存组只在内存中保存键、值所在的位置和元数据，值本身追加写入 dir 下的段文件。
+// 此时 cacheBytes 限制的是磁盘上有效数据的大小，内存中每个条目只占用很小且固定的索引开销，
+// 单个节点可以缓存海量的小条目而不会给 GC 带来压力。
+// dir 只用作临时空间，缓存组会在其中创建独立的子目录。dir 不可用时 NewGroup panic。
+func WithDiskValues(dir string) GroupOption {
+	return func(g *Group) {
+		g.mainCache.newStore = g.diskPolicy(dir, false)
+	}
+}
+
+// WithMappedValues 与 WithDiskValues 相同，但段文件通过 mmap 映射到内存中读写，不经过 read/write 系统调用。
+// 值的缓存交给操作系统的页缓存：经常读取的值留在内存中，其余的由内核按需换出，
+// 缓存的数据可以远大于物理内存，而内存中始终只有键和索引。它与 WithDiskValues、WithEvictionPolicy 互相替换，
+// 以最后设置的为准。段文件的磁盘空间在映射之前预先分配，磁盘已满时切换段文件失败，而不是在写入映射时收到 SIGBUS。
+// 不支持 mmap 的平台上，以及 dir 不可用时 NewGroup panic。
+func WithMappedValues(dir string) GroupOption {
+	return func(g *Group) {
+		g.mainCache.newStore = g.diskPolicy(dir, true)
+	}
+}
+
+// diskPolicy 返回在 dir 下创建磁盘值存储的 PolicyFunc。
+// NewGroup 会立即创建底层存储，目录不可用时记录在 storeErr 中，由 NewGroup 在配置阶段报告；
+// 之后重新创建（例如 Flush 之后）失败时只记录日志并退回内存中的 LRU 存储，不会让正在处理请求的进程崩溃。
+func (g *Group) diskPolicy(dir string, mapped bool) PolicyFunc {
+	return func(cacheBytes int64, clk clock.Clock) EvictionPolicy {
+		var s *diskStore
+		err := errMmapUnsupported
+		if mmapSupported || !mapped {
+			s, err = newDiskStore(dir, cacheBytes, defaultSegmentBytes, clk)
+		}
+		if err != nil {
+			g.storeErr.CompareAndSwap(nil, &err)
+			g.logger.Errorf("[GeeCache] group %s: %v, caching values in memory instead", g.name, err)
+			return lru.New(cacheBytes, nil, lru.WithClock(clk))
+		}
+		s.logger = g.logger
+		s.mapped = mapped
+		return s
+	}
+}
+
+// diskRef 是值在段文件中的位置，由内存中的 LRU 索引持有。
+type diskRef struct {
+	seg  *segment    // 值所在的段文件
+	off  int64       // 值在段文件中的偏移量
+	n    int         // 值的字节数
+	dead bool        // 条目已被淘汰或覆盖
+	z    Compression // 值写入时的压缩算法，读出时原样还原
+}
+
+// Len 返回值的字节数，使 LRU 索引按值的大小计算占用。
+func (r *diskRef) Len() int {
+	return r.n
+}
+
+// segment 是一个只追加写入的段文件。
+type segment struct {
+	f    *os.File
+	data []byte     // 段文件映射到内存中的数据，没有映射时为 nil
+	size int64      // 已写入的字节数
+	live int64      // 仍然有效的值的字节数
+	refs []*diskRef // 写入该段文件的所有值，用于压缩时迁移仍然有效的值
+}
+
+// readAt 从段文件的偏移量 off 处读满 b。映射到内存中的段文件直接复制，段文件之后可能被解除映射，不能引用。
+func (seg *segment) readAt(b []byte, off int64) error {
+	if seg.data != nil {
+		copy(b, seg.data[off:])
+		return nil
+	}
+	_, err := seg.f.ReadAt(b, off)
+	return err
+}
+
+// writeAt 把 b 写入段文件的偏移量 off 处。
+func (seg *segment) writeAt(b []byte, off int64) error {
+	if seg.data != nil {
+		copy(seg.data[off:], b)
+		return nil
+	}
+	_, err := seg.f.WriteAt(b, off)
+	return err
+}
+
+// diskStore 是值存放在磁盘上的 EvictionPolicy：LRU 索引在内存中，值追加写入段文件。
+// 段文件中的值全部失效后文件会被删除；有效数据过少的旧段文件会被压缩，
+// 把仍然有效的值迁移到当前段文件中。
+type diskStore struct {
+	dir          string                // 存放段文件的目录
+	segmentBytes int64                 // 单个段文件的大小上限
+	index        *lru.Cache            // 内存中的 LRU 索引，键到 *diskRef
+	current      *segment              // 当前写入的段文件
+	segments     map[*segment]struct{} // 所有还没有删除的段文件，Close 时逐个释放
+	nextID       int                   // 下一个段文件的编号
+	logger       Logger                // 输出读写失败日志使用的 Logger
+	mapped       bool                  // 是否通过 mmap 读写段文件，见 WithMappedValues
+}
+
+// newDiskStore 在 dir 下创建一个新的子目录作为磁盘值存储。
+func newDiskStore(dir string, maxBytes int64, segmentBytes int64, clk clock.Clock) (*diskStore, error) {
+	sub, err := os.MkdirTemp(dir, "geecache-")
+	if err != nil {
+		return nil, fmt.Errorf("create disk store: %v", err)
+	}
+	s := &diskStore{dir: sub, segmentBytes: segmentBytes, segments: make(map[*segment]struct{}), logger: StdLogger}
+	s.index = lru.New(maxBytes, func(key string, value lru.Value) {
+		s.release(value.(*diskRef))
+	}, lru.WithClock(clk))
+	return s, nil
+}
+
+// Get 从 LRU 索引中找到值的位置，再从段文件中读出值。读取失败时视为未命中。
+func (s *diskStore) Get(key string) (lru.Value, bool) {
+	v, ok := s.index.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return s.read(v.(*diskRef))
+}
+
+// Peek 与 Get 相同，但不影响条目在 LRU 索引中的淘汰顺序。
+func (s *diskStore) Peek(key string) (lru.Value, bool) {
+	v, ok := s.index.Peek(key)
+	if !ok {
+		return nil, false
+	}
+	return s.read(v.(*diskRef))
+}
+
+// touchLocked 只在 LRU 索引中记录一次访问，不读取磁盘，由 cache.applyHits 在写锁下调用。
+func (s *diskStore) touchLocked(key string) {
+	s.index.Get(key)
+}
+
+// Contains 只检查 LRU 索引，不读取磁盘。
+func (s *diskStore) Contains(key string) bool {
+	return s.index.Contains(key)
+}
+
+// read 从段文件中读出 ref 指向的值。
+func (s *diskStore) read(ref *diskRef) (lru.Value, bool) {
+	b := make([]byte, ref.n)
+	if err := ref.seg.readAt(b, ref.off); err != nil {
+		s.logger.Errorf("[GeeCache] read disk value failed: %v", err)
+		return nil, false
+	}
+	return ByteView{b: b, z: ref.z}, true
+}
+
+// AddWithExpire 把值追加写入当前段文件，并在 LRU 索引中记录其位置和过期时间。写入失败时该条目不会被缓存。
+func (s *diskStore) AddWithExpire(key string, value lru.Value, expires time.Time) {
+	v := value.(ByteView)
+	ref, err := s.write(v.bytes())
+	if err != nil {
+		s.logger.Errorf("[GeeCache] write disk value failed: %v", err)
+		return
+	}
+	ref.z = v.z
+	if old, ok := s.index.Get(key); ok {
+		s.release(old.(*diskRef)) // 旧值被覆盖，不会触发淘汰回调
+	}
+	s.index.AddWithExpire(key, ref, expires)
+}
+
+// Expiration 返回 key 对应条目的过期时间。
+func (s *diskStore) Expiration(key string) (time.Time, bool) {
+	return s.index.Expiration(key)
+}
+
+// Evictions 返回 LRU 索引淘汰的条目数量。
+func (s *diskStore) Evictions() int64 {
+	return s.index.Evictions()
+}
+
+// Remove 删除 key 对应的条目，其磁盘空间通过淘汰回调释放。
+func (s *diskStore) Remove(key string) bool {
+	return s.index.Remove(key)
+}
+
+// RemoveExpired 删除所有已经过期的条目，其磁盘空间通过淘汰回调释放。
+func (s *diskStore) RemoveExpired() int {
+	return s.index.RemoveExpired()
+}
+
+// write 把 b 追加写入当前段文件，当前段文件写满时先切换到新的段文件。
+func (s *diskStore) write(b []byte) (*diskRef, error) {
+	if s.current == nil || s.current.size+int64(len(b)) > s.segmentBytes {
+		if err := s.rotate(max(s.segmentBytes, int64(len(b)))); err != nil {
+			return nil, err
+		}
+	}
+	seg := s.current
+	if err := seg.writeAt(b, seg.size); err != nil {
+		return nil, err
+	}
+	ref := &diskRef{seg: seg, off: seg.size, n: len(b)}
+	seg.size += int64(len(b))
+	seg.live += int64(len(b))
+	seg.refs = append(seg.refs, ref)
+	return ref, nil
+}
+
+// rotate 创建一个新的段文件作为当前段文件。通过 mmap 读写时先为段文件分配 size 字节的磁盘空间，再映射到内存中。
+func (s *diskStore) rotate(size int64) error {
+	f, err := os.Create(filepath.Join(s.dir, fmt.Sprintf("%08d.seg", s.nextID)))
+	if err != nil {
+		return err
+	}
+	s.nextID++
+	seg := &segment{f: f}
+	if s.mapped {
+		// 先为整个段文件分配磁盘空间：映射一个稀疏文件之后，磁盘写满时写入映射会收到 SIGBUS 而不是返回错误。
+		if err := reserveFile(f, size); err == nil {
+			seg.data, err = mmapFile(f, int(size))
+		}
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return err
+		}
+	}
+	old := s.current
+	s.current = seg
+	s.segments[seg] = struct{}{}
+	if old != nil && old.live == 0 {
+		s.removeSegment(old)
+	}
+	return nil
+}
+
+// writeZeros 把文件 f 的前 size 个字节写为零，使文件系统为它分配磁盘空间。
+func writeZeros(f *os.File, size int64) error {
+	zeros := make([]byte, 1<<20)
+	for off := int64(0); off < size; off += int64(len(zeros)) {
+		b := zeros
+		if size-off < int64(len(b)) {
+			b = b[:size-off]
+		}
+		if _, err := f.WriteAt(b, off); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// release 把值标记为失效。旧段文件中的值全部失效时删除该文件，
+// 有效数据不足四分之一时压缩该段文件。
+func (s *diskStore) release(ref *diskRef) {
+	if ref.dead {
+		return
+	}
+	ref.dead = true
+	seg := ref.seg
+	seg.live -= int64(ref.n)
+	if seg == s.current {
+		return
+	}
+	if seg.live == 0 {
+		s.removeSegment(seg)
+	} else if seg.live*4 < seg.size {
+		s.compact(seg)
+	}
+}
+
+// compact 把段文件中仍然有效的值迁移到当前段文件，然后删除该段文件。
+// LRU 索引持有的是同一个 *diskRef，原地更新位置即可，不会改变条目的访问顺序。
+func (s *diskStore) compact(seg *segment) {
+	for _, ref := range seg.refs {
+		if ref.dead {
+			continue
+		}
+		b := make([]byte, ref.n)
+		if err := seg.readAt(b, ref.off); err != nil {
+			s.logger.Errorf("[GeeCache] compact disk segment failed: %v", err)
+			return
+		}
+		moved, err := s.write(b)
+		if err != nil {
+			s.logger.Errorf("[GeeCache] compact disk segment failed: %v", err)
+			return
+		}
+		moved.seg.refs[len(moved.seg.refs)-1] = ref // 新段文件记录原来的 *diskRef
+		ref.seg, ref.off = moved.seg, moved.off
+	}
+	s.removeSegment(seg)
+}
+
+// removeSegment 关闭并删除段文件。
+func (s *diskStore) removeSegment(seg *segment) {
+	if seg.data != nil {
+		if err := munmap(seg.data); err != nil {
+			s.logger.Errorf("[GeeCache] unmap disk segment failed: %v", err)
+		}
+		seg.data = nil
+	}
+	seg.f.Close()
+	if err := os.Remove(seg.f.Name()); err != nil {
+		s.logger.Errorf("[GeeCache] remove disk segment failed: %v", err)
+	}
+	delete(s.segments, seg)
+}
+
+// Close 关闭并删除所有段文件和存储的子目录，之后存储不能再使用。缓存组被注销或 Flush 时调用。
+func (s *diskStore) Close() error {
+	for seg := range s.segments {
+		s.removeSegment(seg)
+	}
+	s.current = nil
+	err := os.RemoveAll(s.dir)
+	if err != nil {
+		s.logger.Errorf("[GeeCache] remove disk store failed: %v", err)
+	}
+	return err
+}
+
+// Len 返回缓存的条目数量。
+func (s *diskStore) Len() int {
+	return s.index.Len()
+}
+
+// Bytes 返回键和磁盘上有效值的大小之和。
+func (s *diskStore) Bytes() int64 {
+	return s.index.Bytes()
+}
+
+// Sample 随机返回最多 n 个条目，条目的值是其在磁盘上的位置，不会读取磁盘。
+func (s *diskStore) Sample(n int) []lru.EntryInfo {
+	return s.index.Sample(n)
+}
+
+// Scan 按游标分页遍历内存索引中的键。
+func (s *diskStore) Scan(cursor uint64, prefix string, count int) ([]string, uint64) {
+	return s.index.Scan(cursor, prefix, count)
+}
+
+// Resize 调整磁盘上有效数据的大小上限，超出部分按 LRU 淘汰。
+func (s *diskStore) Resize(maxBytes int64) {
+	s.index.Resize(maxBytes)
+}
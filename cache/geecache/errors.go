@@ -0,0 +1,150 @@
+package geecache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrorClass 是数据源返回的错误的分类，缓存组据此决定是否重试以及如何对待失败的加载。
+type ErrorClass int
+
+const (
+	// ErrorUnknown 表示错误没有被分类，缓存组按永久错误处理，不会重试。
+	ErrorUnknown ErrorClass = iota
+	// ErrorRetryable 表示暂时性的错误（超时、连接失败、后端过载等），稍后重试可能成功。
+	ErrorRetryable
+	// ErrorPermanent 表示重试也不会成功的错误（数据不存在、参数非法等）。
+	ErrorPermanent
+)
+
+// classifiedError 是带有分类的错误。
+type classifiedError struct {
+	err   error
+	class ErrorClass
+}
+
+func (e *classifiedError) Error() string { return e.err.Error() }
+func (e *classifiedError) Unwrap() error { return e.err }
+
+// Retryable 把 err 标记为可以重试的暂时性错误，err 为 nil 时返回 nil。
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{err: err, class: ErrorRetryable}
+}
+
+// Permanent 把 err 标记为重试也不会成功的永久性错误，err 为 nil 时返回 nil。
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{err: err, class: ErrorPermanent}
+}
+
+// Classify 返回 err 的分类。通过 Retryable 或 Permanent 包装的错误使用包装时的分类；
+// 实现了 Temporary() bool 方法的错误（例如 net.Error）按该方法的结果分类；其他错误为 ErrorUnknown。
+func Classify(err error) ErrorClass {
+	var ce *classifiedError
+	if errors.As(err, &ce) {
+		return ce.class
+	}
+	var te interface{ Temporary() bool }
+	if errors.As(err, &te) {
+		if te.Temporary() {
+			return ErrorRetryable
+		}
+		return ErrorPermanent
+	}
+	return ErrorUnknown
+}
+
+// IsRetryable 报告 err 是否是可以重试的暂时性错误。
+func IsRetryable(err error) bool {
+	return Classify(err) == ErrorRetryable
+}
+
+// definitive 报告加载失败的错误 err 是否说明数据源短期内不会有 key 的值：可以重试的错误不是，永久性错误是，
+// 未分类的错误只有 ErrNotFound 是。是否写入负缓存、刷新失败时是否继续提供缓存中的旧值都据此决定。
+func definitive(err error) bool {
+	switch Classify(err) {
+	case ErrorRetryable:
+		return false
+	case ErrorPermanent:
+		return true
+	}
+	return errors.Is(err, ErrNotFound)
+}
+
+// WithLoadRetries 设置数据源返回可以重试的错误时最多尝试加载的次数（包括第一次），
+// 每次重试之前等待 backoff，等待时间逐次翻倍。默认只尝试一次。
+// 永久性错误和未分类的错误不会重试；调用方放弃请求后也不再重试。
+func WithLoadRetries(attempts int, backoff time.Duration) GroupOption {
+	return func(g *Group) {
+		if attempts < 1 {
+			attempts = 1
+		}
+		g.loadAttempts = attempts
+		g.loadBackoff = backoff
+	}
+}
+
+// fetch 调用一次数据源并把数据封装为 ByteView。数据源实现了 StringGetter 时直接使用返回的字符串；
+// 否则复制返回的字节切片，使数据源之后修改它不会影响缓存。数据源实现了 ContextGetter 时传递 ctx。
+func (g *Group) fetch(ctx context.Context, key string) (ByteView, error) {
+	var (
+		bytes []byte
+		err   error
+	)
+	switch getter := g.getter.(type) {
+	case StringGetter:
+		s, err := getter.GetString(ctx, key)
+		if err != nil {
+			return ByteView{}, err
+		}
+		return ByteView{s: s}, nil
+	case ContextGetter:
+		bytes, err = getter.GetContext(ctx, key)
+	default:
+		bytes, err = g.getter.Get(key)
+	}
+	if err != nil {
+		return ByteView{}, err
+	}
+	return ByteView{b: cloneBytes(bytes)}, nil
+}
+
+// getWithRetry 从数据源获取 key 的数据，按缓存组的配置重试暂时性错误。
+func (g *Group) getWithRetry(ctx context.Context, key string) (ByteView, error) {
+	backoff := g.loadBackoff
+	for attempt := 1; ; attempt++ {
+		if err := g.acquireLoad(ctx); err != nil {
+			return ByteView{}, err
+		}
+		value, err := g.fetch(ctx, key)
+		g.releaseLoad()
+		if err == nil || attempt >= g.loadAttempts || !IsRetryable(err) {
+			return value, err
+		}
+		if !g.sleep(ctx, backoff) {
+			return ByteView{}, err
+		}
+		backoff *= 2
+	}
+}
+
+// sleep 按缓存组的时钟等待 d，ctx 先结束时返回 false。d <= 0 时不等待，只检查 ctx。
+func (g *Group) sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	ticker := g.clock.NewTicker(d)
+	defer ticker.Stop()
+	select {
+	case <-ticker.C():
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,129 @@
+package geecache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"testProject/cache/lru"
+)
+
+// EventType 是缓存组发布的事件类型。
+type EventType int
+
+const (
+	EventHit    EventType = iota + 1 // 本地缓存命中，负缓存命中时 Err 为 ErrNotFound
+	EventMiss                        // 本地缓存未命中，之后会加载
+	EventLoad                        // 一次加载结束（并发的同一个键只发布一次），Err 为加载失败的原因
+	EventEvict                       // 主缓存中的条目因超出容量被淘汰
+	EventExpire                      // 主缓存中的条目因过期被删除
+)
+
+// String 返回事件类型的名称。
+func (t EventType) String() string {
+	switch t {
+	case EventHit:
+		return "hit"
+	case EventMiss:
+		return "miss"
+	case EventLoad:
+		return "load"
+	case EventEvict:
+		return "evict"
+	case EventExpire:
+		return "expire"
+	}
+	return "unknown"
+}
+
+// Event 是缓存组发布给订阅者的事件。
+type Event struct {
+	Type     EventType
+	Group    string        // 缓存组的名称
+	Key      string        // 事件涉及的键
+	Value    ByteView      // 命中、加载成功、淘汰或过期的值，其他事件为零值
+	Err      error         // 负缓存命中或加载失败的原因
+	Duration time.Duration // 加载花费的时间，只有 EventLoad 设置
+}
+
+// eventBus 保存缓存组的事件订阅者。
+type eventBus struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[EventType]map[int]func(Event) // 每种事件的订阅者，按订阅编号索引
+	count  atomic.Int64                      // 订阅者总数，为 0 时发布事件不需要加锁
+}
+
+// Subscribe 让缓存组发布 t 类型的事件时调用 fn，返回的函数用于取消订阅，可以重复调用。
+// 可以用来实现自定义指标、审计日志或把失效通知转发到其他系统，而不需要修改本包。
+// fn 在产生事件的 goroutine 中同步调用，必须尽快返回；EventEvict 和 EventExpire 在持有缓存锁时调用，
+// 这时 fn 不能再访问同一个缓存组，否则会死锁。
+func (g *Group) Subscribe(t EventType, fn func(Event)) (unsubscribe func()) {
+	b := &g.events
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs == nil {
+		b.subs = make(map[EventType]map[int]func(Event))
+	}
+	if b.subs[t] == nil {
+		b.subs[t] = make(map[int]func(Event))
+	}
+	id := b.nextID
+	b.nextID++
+	b.subs[t][id] = fn
+	b.count.Add(1)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subs[t], id)
+			b.count.Add(-1)
+		})
+	}
+}
+
+// subscribed 报告是否有订阅者，用于在没有订阅者时跳过构造事件。
+func (g *Group) subscribed() bool {
+	return g.events.count.Load() > 0
+}
+
+// publish 把事件 e 发送给订阅了 e.Type 的所有订阅者。
+func (g *Group) publish(e Event) {
+	if !g.subscribed() {
+		return
+	}
+	e.Group = g.name
+	g.events.mu.RLock()
+	defer g.events.mu.RUnlock()
+	for _, fn := range g.events.subs[e.Type] {
+		fn(e)
+	}
+}
+
+// onRemoved 在主缓存删除条目时调用，通知 WithEvictionCallback 设置的回调并发布淘汰或过期事件。
+// 设置了 WithDiskValues 或 WithMappedValues 时值不在内存中，直接忽略。
+func (g *Group) onRemoved(key string, value lru.Value, reason removal) {
+	g.dropHandoff(key) // 条目已经不在本地，不会再被读到
+	if g.onEvicted == nil && !g.subscribed() {
+		return
+	}
+	v, ok := value.(ByteView)
+	if !ok {
+		return
+	}
+	v, err := g.decompress(v)
+	if err != nil {
+		return
+	}
+	if g.onEvicted != nil {
+		g.onEvicted(key, v)
+	}
+	switch reason {
+	case removalEvicted:
+		g.publish(Event{Type: EventEvict, Key: key, Value: v})
+	case removalExpired:
+		g.publish(Event{Type: EventExpire, Key: key, Value: v})
+	}
+}
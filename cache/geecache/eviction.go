@@ -0,0 +1,11 @@
+package geecache
+
+// WithEvictionCallback 让缓存组主缓存中的条目被淘汰、过期删除或通过 Remove 删除时调用 fn，
+// 可以用于统计指标或把数据异步写回数据源。fn 收到的值已经解压，可以安全地保留。
+// fn 在持有缓存锁时同步调用，必须尽快返回，并且不能再访问同一个缓存组，否则会死锁。
+// 设置了 WithDiskValues 或 WithMappedValues 时值不在内存中，不会调用 fn。
+func WithEvictionCallback(fn func(key string, value ByteView)) GroupOption {
+	return func(g *Group) {
+		g.onEvicted = fn
+	}
+}
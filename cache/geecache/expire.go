@@ -0,0 +1,86 @@
+package geecache
+
+import (
+	"math/rand"
+	"time"
+)
+
+// 后台清理过期条目的时间间隔的上下限，实际间隔取过期时间并限制在该范围内。
+const (
+	minSweepInterval = time.Second
+	maxSweepInterval = time.Minute
+)
+
+// WithExpiration 让缓存组中的条目在写入 d 之后过期，过期的条目视为未命中并重新加载，
+// 这样缓存的值不必等到被 LRU 淘汰才会更新。d <= 0 表示条目永不过期，这是默认行为。
+// 过期的条目在被访问时惰性删除，后台 goroutine 还会定期清理那些过期后再也没有被访问的条目。
+func WithExpiration(d time.Duration) GroupOption {
+	return func(g *Group) {
+		if d < 0 {
+			d = 0
+		}
+		g.expiration = d
+	}
+}
+
+// WithTTLJitter 让每个条目的有效期在 WithExpiration 设置的基础上随机浮动 ±fraction，
+// 例如 fraction 为 0.1 时有效期为 1 分钟的条目会在 54 到 66 秒之间过期。
+// 这样同一时间批量加载的条目不会同时过期，避免它们同时重新加载而压垮数据源（缓存雪崩）。
+// fraction 会被限制在 [0, 1) 之内，0 表示不浮动，这是默认行为。
+func WithTTLJitter(fraction float64) GroupOption {
+	return func(g *Group) {
+		if fraction < 0 {
+			fraction = 0
+		} else if fraction >= 1 {
+			fraction = 0.99
+		}
+		g.ttlJitter = fraction
+	}
+}
+
+// jitter 返回 [-1, 1) 之间的随机数，用于计算有效期的浮动，测试中可以替换。
+var jitter = func() float64 {
+	return rand.Float64()*2 - 1
+}
+
+// expiresAt 返回现在写入的条目的过期时间，零值表示永不过期。
+func (g *Group) expiresAt() time.Time {
+	if g.expiration <= 0 {
+		return time.Time{}
+	}
+	ttl := g.expiration
+	if g.ttlJitter > 0 {
+		ttl += time.Duration(float64(ttl) * g.ttlJitter * jitter())
+	}
+	return g.clock.Now().Add(ttl)
+}
+
+// startSweeper 在设置了过期时间或负缓存时启动后台清理过期条目的 goroutine，缓存组被销毁后退出。
+func (g *Group) startSweeper() {
+	interval := g.expiration
+	if interval <= 0 || (g.negativeTTL > 0 && g.negativeTTL < interval) {
+		interval = g.negativeTTL
+	}
+	if interval <= 0 {
+		return
+	}
+	if interval < minSweepInterval {
+		interval = minSweepInterval
+	} else if interval > maxSweepInterval {
+		interval = maxSweepInterval
+	}
+	ticker := g.clock.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C():
+				if n := g.mainCache.removeExpired() + g.hotCache.removeExpired() + g.negCache.removeExpired(); n > 0 {
+					g.logger.Infof("[GeeCache] group %s swept %d expired entries", g.name, n)
+				}
+			case <-g.done:
+				return
+			}
+		}
+	}()
+}
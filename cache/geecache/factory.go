@@ -0,0 +1,160 @@
+package geecache
+
+import (
+	"path"
+	"sync"
+	"time"
+
+	"testProject/cache/clock"
+)
+
+// GroupFactory 根据缓存组的名称创建缓存组，通常在内部调用 NewGroup(name, ...)。
+type GroupFactory func(name string) *Group
+
+// groupFactory 是一条注册的工厂规则。
+type groupFactory struct {
+	pattern string
+	factory GroupFactory
+}
+
+var (
+	factoryMu sync.Mutex     // 保护 factories，并串行化按需创建，避免同一个缓存组被创建两次
+	factories []groupFactory // 按注册顺序匹配的工厂规则
+)
+
+// RegisterGroupFactory 注册一个缓存组工厂：GetGroup 找不到名称匹配 pattern 的缓存组时，
+// 调用 factory 按需创建该缓存组。pattern 使用 path.Match 的语法，例如 "tenant-*"；
+// 多条规则都匹配时使用最先注册的规则。pattern 语法错误时 panic。
+// 按需创建的缓存组可以被 CollectIdleGroups 在长时间未被访问后销毁，之后再次访问时会重新创建。
+func RegisterGroupFactory(pattern string, factory GroupFactory) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		panic("geecache: bad group factory pattern " + pattern + ": " + err.Error())
+	}
+	if factory == nil {
+		panic("geecache: nil GroupFactory")
+	}
+	factoryMu.Lock()
+	defer factoryMu.Unlock()
+	factories = append(factories, groupFactory{pattern: pattern, factory: factory})
+}
+
+// createGroup 使用匹配 name 的工厂创建缓存组，没有匹配的工厂时返回 nil。
+func createGroup(name string) *Group {
+	factoryMu.Lock()
+	defer factoryMu.Unlock()
+
+	// 其他 goroutine 可能已经创建了该缓存组
+	r := DefaultRegistry
+	r.mu.RLock()
+	g := r.groups[name]
+	r.mu.RUnlock()
+	if g != nil {
+		return g
+	}
+
+	for _, f := range factories {
+		if ok, _ := path.Match(f.pattern, name); !ok {
+			continue
+		}
+		g = f.factory(name)
+		if g == nil {
+			return nil
+		}
+		g.lazy = true
+		r.mu.Lock()
+		r.groups[name] = g // 工厂可能没有以 name 注册该缓存组
+		r.mu.Unlock()
+		return g
+	}
+	return nil
+}
+
+// CollectIdleGroups 销毁超过 idle 时长未被访问的按需创建的缓存组，返回被销毁的缓存组名称。
+// 通过 NewGroup 直接创建的缓存组由调用方管理，不会被销毁。
+// 被销毁的缓存组从注册表中移除并释放缓存的数据，之后再次访问时由工厂重新创建。
+func CollectIdleGroups(idle time.Duration) []string {
+	r := DefaultRegistry
+	r.mu.Lock()
+	var collected []*Group
+	for name, g := range r.groups {
+		if g.lazy && g.idleFor() > idle {
+			delete(r.groups, name)
+			collected = append(collected, g)
+		}
+	}
+	r.mu.Unlock()
+
+	names := make([]string, len(collected))
+	for i, g := range collected {
+		g.destroy()
+		names[i] = g.name
+	}
+	return names
+}
+
+// IdleGCOption 用于定制 StartIdleGroupGC 的行为。
+type IdleGCOption func(*idleGC)
+
+// idleGC 是 StartIdleGroupGC 的配置。
+type idleGC struct {
+	clock clock.Clock
+}
+
+// WithIdleGCClock 设置 StartIdleGroupGC 计时使用的时钟，默认为 clock.Real。
+// 它应当与按需创建的缓存组（见 WithClock）使用同一个时钟，测试中可以使用 clock.Fake。
+func WithIdleGCClock(c clock.Clock) IdleGCOption {
+	return func(gc *idleGC) {
+		gc.clock = c
+	}
+}
+
+// StartIdleGroupGC 启动一个后台 goroutine，每隔 interval 调用一次 CollectIdleGroups(idle)。
+// 返回的 stop 函数用于停止该 goroutine。
+func StartIdleGroupGC(idle, interval time.Duration, opts ...IdleGCOption) (stop func()) {
+	gc := idleGC{clock: clock.Real}
+	for _, opt := range opts {
+		opt(&gc)
+	}
+	ticker := gc.clock.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C():
+				CollectIdleGroups(idle)
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+	}
+}
+
+// touch 记录缓存组最近一次被访问的时间。
+func (g *Group) touch() {
+	g.lastUsed.Store(g.clock.Now().UnixNano())
+}
+
+// idleFor 返回缓存组最近一次被访问至今的时长。
+func (g *Group) idleFor() time.Duration {
+	return g.clock.Now().Sub(time.Unix(0, g.lastUsed.Load()))
+}
+
+// stop 停止缓存组的后台任务。
+func (g *Group) stop() {
+	g.closeOnce.Do(func() { close(g.done) })
+}
+
+// destroy 停止缓存组的后台任务并释放缓存的数据。
+func (g *Group) destroy() {
+	g.stop()
+	g.mainCache.reset()
+	g.hotCache.reset()
+	g.negCache.reset()
+}
@@ -1,10 +1,17 @@
 package geecache
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"math/rand"
 	"sync"
+	"sync/atomic"
+	"testProject/cache/bloom"
+	"testProject/cache/clock"
+	"testProject/cache/codec"
 	"testProject/cache/singleflight"
+	"time"
 )
 
 // 回调函数 缓存未命中时从数据库中读取数据
@@ -20,6 +27,45 @@ func (f GetterFunc) Get(key string) ([]byte, error) {
 	return f(key)
 }
 
+// ContextGetter 是支持取消和超时的数据源。传给 NewGroup 的 Getter 同时实现了 ContextGetter 时，
+// 缓存组会改用 GetContext 加载数据，调用方的截止时间和取消信号都会传递给数据源。
+type ContextGetter interface {
+	GetContext(ctx context.Context, key string) ([]byte, error)
+}
+
+// ContextGetterFunc 用函数实现 ContextGetter，同时也实现了 Getter。
+type ContextGetterFunc func(ctx context.Context, key string) ([]byte, error)
+
+// GetContext implements ContextGetter interface function
+func (f ContextGetterFunc) GetContext(ctx context.Context, key string) ([]byte, error) {
+	return f(ctx, key)
+}
+
+// Get implements Getter interface function
+func (f ContextGetterFunc) Get(key string) ([]byte, error) {
+	return f(context.Background(), key)
+}
+
+// StringGetter 是以字符串提供数据的数据源。传给 NewGroup 的 Getter 同时实现了 StringGetter 时，
+// 缓存组会改用 GetString 加载数据，字符串直接作为 ByteView 的底层数据，省去一次分配和复制。
+type StringGetter interface {
+	GetString(ctx context.Context, key string) (string, error)
+}
+
+// StringGetterFunc 用函数实现 StringGetter，同时也实现了 Getter。
+type StringGetterFunc func(ctx context.Context, key string) (string, error)
+
+// GetString implements StringGetter interface function
+func (f StringGetterFunc) GetString(ctx context.Context, key string) (string, error) {
+	return f(ctx, key)
+}
+
+// Get implements Getter interface function
+func (f StringGetterFunc) Get(key string) ([]byte, error) {
+	s, err := f(context.Background(), key)
+	return []byte(s), err
+}
+
 // Group 结构表示一个缓存组，包括组名、Getter 接口实现和主缓存。
 // type Group struct {
 // 	name      string // 组的名称
@@ -27,38 +73,92 @@ func (f GetterFunc) Get(key string) ([]byte, error) {
 // 	mainCache cache  // 主缓存：并发缓存
 // }
 
-var (
-	mu     sync.RWMutex              // 用于保护 groups 映射的读写锁
-	groups = make(map[string]*Group) // 存储已创建的组的映射
-)
+// ErrFrozen 表示缓存组处于只读维护模式，拒绝写入。
+var ErrFrozen = errors.New("geecache: group is frozen")
 
 // NewGroup 创建一个新的 Group 实例。
 // 它接受组名、缓存大小限制（cacheBytes），以及实现 Getter 接口的数据获取器（getter）。
 // 如果 getter 为 nil，将会引发 panic。
 
-// GetGroup 返回之前使用 NewGroup 创建的具有指定名称的组，如果没有找到则返回 nil。
-func GetGroup(name string) *Group {
-	mu.RLock()        // 以只读模式加锁以防止数据竞争
-	g := groups[name] // 查找指定名称的组
-	mu.RUnlock()      // 解锁
-	return g          // 返回找到的组，或者 nil 如果未找到
-}
-
 // Get 方法用于从缓存中获取指定键的值。
 // 它接受一个键名作为参数，返回一个 ByteView 和可能的错误。
 func (g *Group) Get(key string) (ByteView, error) {
+	return g.get(context.Background(), key)
+}
+
+// GetContext 与 Get 相同，但 ctx 结束时立即返回 ctx 的错误。
+// ctx 会传递给远程节点的请求（截止时间通过请求头传给对端）以及实现了 ContextGetter 的数据源。
+// 同一个键的并发加载只执行一次，只有所有等待该加载的调用方都放弃时加载才会被取消。
+func (g *Group) GetContext(ctx context.Context, key string) (ByteView, error) {
+	return g.get(ctx, key)
+}
+
+// GetValue 获取 key 对应的值，并使用缓存组的编解码器解码到 v 中。
+func (g *Group) GetValue(key string, v interface{}) error {
+	view, err := g.Get(key)
+	if err != nil {
+		return err
+	}
+	return g.codec.Unmarshal(view.bytes(), v)
+}
+
+// Codec 返回缓存组使用的编解码器，数据源可以用它编码返回给缓存组的值。
+func (g *Group) Codec() codec.Codec {
+	return g.codec
+}
+
+// get 是 Get 的内部实现，ctx 携带调用方的截止时间以及节点间的转发跳数。
+func (g *Group) get(ctx context.Context, key string) (ByteView, error) {
 	if key == "" {
 		return ByteView{}, fmt.Errorf("key is required") // 如果键为空，返回错误
 	}
-
-	// 尝试从主缓存中获取值
-	if v, ok := g.mainCache.get(key); ok {
-		log.Println("[GeeCache] hit") // 命中缓存，记录日志
-		return v, nil
+	if v, ok, err := g.lookup(ctx, key); ok {
+		return v, err
 	}
 
 	// 如果没有命中，调用 load 方法来加载数据
-	return g.load(key)
+	v, err := g.load(ctx, key)
+	if err == nil {
+		g.predict(key)
+	}
+	return v, err
+}
+
+// lookup 在本地的主缓存、热点缓存和负缓存中查找 key，ok 为 false 表示没有命中、需要加载。
+// 负缓存命中时 ok 为 true，err 为 ErrNotFound。
+func (g *Group) lookup(ctx context.Context, key string) (v ByteView, ok bool, err error) {
+	g.touch()
+	g.stats.gets.Add(1)
+
+	// 尝试从主缓存和热点缓存中获取值，携带一致性令牌的读取可能需要绕过本地缓存
+	if !g.bypassCache(ctx, key) {
+		v, ok := g.mainCache.get(key)
+		if ok {
+			g.maybeRefresh(key)
+			g.maybeHandoff(key, v)
+		} else {
+			v, ok = g.hotCache.get(key)
+		}
+		if ok {
+			v, err := g.decompress(v)
+			if err != nil {
+				return ByteView{}, true, err
+			}
+			g.logger.Debugf("[GeeCache] hit") // 命中缓存，记录日志
+			g.stats.hits.Add(1)
+			g.publish(Event{Type: EventHit, Key: key, Value: v})
+			g.predict(key)
+			return v, true, nil
+		}
+		if err := g.negativeErr(key); err != nil {
+			g.stats.hits.Add(1)
+			g.publish(Event{Type: EventHit, Key: key, Err: err})
+			return ByteView{}, true, err // 不久之前数据源报告过 key 不存在或者返回过永久性错误
+		}
+	}
+	g.stats.misses.Add(1)
+	g.publish(Event{Type: EventMiss, Key: key})
+	return ByteView{}, false, nil
 }
 
 // load 方法用于加载指定键的数据。
@@ -70,20 +170,82 @@ func (g *Group) Get(key string) (ByteView, error) {
 // getLocally 方法用于从数据源获取指定键的数据。
 // 它接受一个键名作为参数，调用 Getter 接口的 Get 方法从数据源获取数据。
 // 如果获取成功，将数据封装为 ByteView，并调用 populateCache 方法将数据存入缓存。
-func (g *Group) getLocally(key string) (ByteView, error) {
-	bytes, err := g.getter.Get(key) // 从数据源获取数据
+// 数据源返回可以重试的错误时，按 WithLoadRetries 的配置重试。
+func (g *Group) getLocally(ctx context.Context, key string) (ByteView, error) {
+	value, err := g.getWithRetry(ctx, key) // 从数据源获取数据
 	if err != nil {
 		return ByteView{}, err // 如果获取失败，返回错误
 	}
-	value := ByteView{b: cloneBytes(bytes)} // 将数据封装为 ByteView
-	g.populateCache(key, value)             // 存入缓存
-	return value, nil                       // 返回数据视图
+	if err := g.checkEntrySize(key, value); err != nil {
+		return ByteView{}, err
+	}
+	g.populateCache(key, value) // 存入缓存
+	g.replicate(key, value)     // 推送给其他副本节点，见 WithReplication
+	return value, nil           // 返回数据视图
 }
 
 // populateCache 方法用于将指定键值对存入缓存。
 // 它接受一个键名和 ByteView 作为参数，将数据存入主缓存。
+// 缓存组被冻结时不会写入缓存。
 func (g *Group) populateCache(key string, value ByteView) {
-	g.mainCache.add(key, value) // 将数据存入主缓存
+	if g.frozen.Load() {
+		return // 冻结期间加载到的数据只返回给调用方，不写入缓存
+	}
+	if g.oversized(value) {
+		return // 超过单个条目大小上限的值只返回给调用方，见 WithMaxEntryBytes
+	}
+	g.mainCache.add(key, g.compress(value), g.expiresAt()) // 将数据存入主缓存
+}
+
+// populateCacheIfAbsent 与 populateCache 相同，但主缓存中已经有 key 时不覆盖，
+// 用于其他节点移交过来的条目：本节点上已有的值可能是之后写入或加载的，比移交的值更新。
+func (g *Group) populateCacheIfAbsent(key string, value ByteView) {
+	if g.frozen.Load() || g.oversized(value) {
+		return
+	}
+	g.mainCache.addIfAbsent(key, g.compress(value), g.expiresAt())
+}
+
+// Freeze 把缓存组切换到只读维护模式：已缓存的条目照常提供服务，
+// 写入操作返回 ErrFrozen，未命中时加载到的数据只返回给调用方而不写入缓存。
+// 适用于后端数据迁移或故障隔离期间，防止不一致的数据进入缓存。
+func (g *Group) Freeze() {
+	g.frozen.Store(true)
+}
+
+// Unfreeze 解除只读维护模式。
+func (g *Group) Unfreeze() {
+	g.frozen.Store(false)
+}
+
+// Frozen 报告缓存组是否处于只读维护模式。
+func (g *Group) Frozen() bool {
+	return g.frozen.Load()
+}
+
+// Len 返回缓存组中已缓存的条目数量。
+func (g *Group) Len() int {
+	return g.mainCache.len()
+}
+
+// Bytes 返回缓存组当前已经使用的内存大小（键和值的长度之和）。
+func (g *Group) Bytes() int64 {
+	return g.mainCache.bytes()
+}
+
+// Capacity 返回缓存组的最大内存限制，0 表示不限制。
+func (g *Group) Capacity() int64 {
+	return g.mainCache.capacity()
+}
+
+// SetCacheBytes 在运行时调整缓存组主缓存的最大内存限制，超出新限制的条目会被立即淘汰，0 表示不限制。
+// 运维可以借此应对内存压力而不必重启进程。热点缓存的容量不受影响；
+// 缓存组加入了 Budget 时，下一次重新分配会覆盖这里设置的值。
+func (g *Group) SetCacheBytes(n int64) {
+	if n < 0 {
+		n = 0
+	}
+	g.mainCache.resize(n)
 }
 
 // RegisterPeers 方法用于注册一个 PeerPicker，用于选择远程对等节点。
@@ -95,8 +257,8 @@ func (g *Group) RegisterPeers(peers PeerPicker) {
 }
 
 // getFromPeer 方法用于从远程对等节点获取数据。
-func (g *Group) getFromPeer(peer PeerGetter, key string) (ByteView, error) {
-	bytes, err := peer.Get(g.name, key)
+func (g *Group) getFromPeer(ctx context.Context, peer PeerGetter, key string) (ByteView, error) {
+	bytes, err := peer.Get(ctx, g.name, key)
 	if err != nil {
 		return ByteView{}, err
 	}
@@ -108,39 +270,281 @@ type Group struct {
 	name      string
 	getter    Getter
 	mainCache cache
+	hotCache  cache // 热点缓存：所有者是其他节点但在本节点被频繁读取的键
+	negCache  cache // 负缓存：数据源不久之前报告不存在的键，见 WithNegativeTTL
 	peers     PeerPicker
 	// 使用 singleflight.Group 以确保每个键只获取一次
-	loader *singleflight.Group
+	loader     *singleflight.Group
+	loaderOpts []singleflight.Option // 创建 loader 使用的选项，见 WithLoadShareWindow 和 WithMaxLoadWaiters
+	frozen     atomic.Bool           // 是否处于只读维护模式
+	codec      codec.Codec           // 值的编解码器，用于 GetValue
+	clock      clock.Clock           // 所有与时间相关的行为使用的时钟
+	logger     Logger                // 输出日志使用的 Logger
+
+	predictor       Predictor   // 预测接下来会被读取的键，为 nil 时不自动预取
+	prefetchOnce    sync.Once   // 第一次预取时启动后台 goroutine
+	prefetchQueue   chan string // 后台预取队列
+	prefetchWorkers int         // 同时执行预取的 goroutine 数量，见 WithPrefetchConcurrency
+
+	writeSeq atomic.Uint64 // 本节点上的写入序号，用于签发和校验一致性令牌
+	epoch    uint64        // 创建缓存组时随机生成，写入一致性令牌，区分重启之前签发的令牌
+
+	setLocks [setLockStripes]sync.Mutex // 按键分散的写入锁，见 setLocally
+
+	loadAttempts int             // 数据源返回暂时性错误时最多尝试加载的次数
+	loadBackoff  time.Duration   // 第一次重试之前的等待时间
+	loadSem      chan struct{}   // 限制同时访问数据源的加载数量，为 nil 时不限制
+	loadTimeout  time.Duration   // 等待一次加载的最长时间，0 表示不限制，见 WithLoadTimeout
+	peerRetry    PeerRetryPolicy // 从远程节点获取失败时的重试策略
+	peerFallback PeerFallback    // 从远程节点获取最终失败之后的行为
+	replication  int             // 每个键保存在多少个节点上，见 WithReplication
+	replicaQ     replicaQueue    // 按顺序发送给副本节点的推送
+	hedge        *hedger         // 向副本节点发出对冲请求的配置，见 WithHedgedRequests
+	expiration   time.Duration   // 条目写入之后的有效期，0 表示永不过期
+	ttlJitter    float64         // 有效期随机浮动的比例，见 WithTTLJitter
+	negativeTTL  time.Duration   // 不存在的结果在负缓存中保留的时间，0 表示不缓存
+	bloom        *bloom.Filter   // 访问数据源之前查询的布隆过滤器，为 nil 时不检查
+	setter       Setter          // Set 写穿透使用的数据源，为 nil 时只写入缓存
+	writeBehind  *writeBehind    // Set 异步写回数据源的队列，见 WithWriteBehind
+	refreshAhead float64         // 剩余有效期不超过该比例时提前刷新，见 WithRefreshAhead
+	refreshing   sync.Map        // 正在后台刷新的键
+	handoffs     sync.Map        // 标记为延迟移交的键，见 RebalanceLazy
+	stats        groupStats      // 运行计数，用于导出指标
+	shards       int             // 主缓存的分片数量，见 WithShards
+
+	storeErr atomic.Pointer[error] // 第一次创建底层存储失败的错误，见 WithDiskValues
+
+	compression       Compression // 本地缓存中的值使用的压缩算法，见 WithCompression
+	compressThreshold int         // 不小于该字节数的值才压缩
+
+	maxEntryBytes  int64          // 单个条目的大小上限，0 表示不限制，见 WithMaxEntryBytes
+	oversizePolicy OversizePolicy // 超过上限的值的处理方式
+
+	onEvicted func(key string, value ByteView) // 主缓存删除条目时调用，见 WithEvictionCallback
+	events    eventBus                         // 事件订阅者，见 Subscribe
+
+	snapshotPath     string        // 启动时恢复、定期写入的快照文件，为空时不使用快照
+	snapshotInterval time.Duration // 定期写入快照的时间间隔，0 表示只在启动时恢复
+
+	registry  *Registry     // 缓存组所在的注册表，见 WithRegistry
+	lazy      bool          // 是否由缓存组工厂按需创建，只有这样的缓存组会因空闲而被销毁
+	lastUsed  atomic.Int64  // 最近一次被访问的时间（UnixNano）
+	done      chan struct{} // 缓存组被销毁时关闭，用于停止后台任务
+	closeOnce sync.Once
 }
 
-// NewGroup 创建一个新的 Group 实例。
-func NewGroup(name string, cacheBytes int64, getter Getter) *Group {
-	// ...
+// GroupOption 用于在创建 Group 时定制其行为。
+type GroupOption func(*Group)
+
+// WithCodec 设置缓存组的值使用以 name 注册的编解码器（见 codec 包），默认为 JSON。
+// 这样值的编码方式是缓存组的一项配置，而不是分散在各处的序列化调用。
+func WithCodec(name string) GroupOption {
+	return func(g *Group) {
+		g.codec = codec.MustGet(name)
+	}
+}
+
+// WithClock 设置缓存组中所有与时间相关的行为使用的时钟，默认使用真实时间。
+// 测试中可以传入 clock.Fake，无需 sleep 就能确定性地测试与时间相关的逻辑。
+func WithClock(c clock.Clock) GroupOption {
+	return func(g *Group) {
+		g.clock = c
+		g.mainCache.clock = c
+		g.hotCache.clock = c
+		g.negCache.clock = c
+	}
+}
+
+// WithEntryOverhead 让主缓存和热点缓存的每个条目在键和值的长度之外额外计入 n 个字节，
+// 使 cacheBytes 更接近进程实际占用的内存，通常传入 lru.EntryOverhead。
+// 它只作用于默认的 LRU 存储，默认为 0。
+func WithEntryOverhead(n int64) GroupOption {
+	return func(g *Group) {
+		g.mainCache.overhead = n
+		g.hotCache.overhead = n
+	}
+}
+
+// WithMaxConcurrentLoads 限制缓存组同时访问数据源的加载数量最多为 n。
+// singleflight 只合并同一个键的并发加载，冷启动时大量不同的键同时未命中仍然会
+// 同时访问数据源；设置该上限后，超出的加载会排队等待，直到有加载完成或调用方放弃。
+// n <= 0 表示不限制，这是默认行为。
+func WithMaxConcurrentLoads(n int) GroupOption {
+	return func(g *Group) {
+		g.loadSem = nil
+		if n > 0 {
+			g.loadSem = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithLoadShareWindow 让一次成功的加载在结束之后继续共享 d 时长（例如 100ms），
+// 紧跟在加载结束之后到达的同一个键的未命中直接使用这次加载的结果，而不是再访问一次数据源，
+// 适合值可能不被缓存（例如冻结期间或超过 WithMaxEntryBytes）而读取又集中爆发的场景。
+// 本节点的 Set 和 Remove 会丢弃正在共享的结果，不会读到写入之前加载的值。共享期间按 WithClock 设置的时钟计时。默认不共享。
+func WithLoadShareWindow(d time.Duration) GroupOption {
+	return func(g *Group) {
+		g.loaderOpts = append(g.loaderOpts, singleflight.WithShareWindow(d))
+	}
+}
+
+// WithMaxLoadWaiters 限制同一个键同时等待一次加载的读取最多为 n 个，超出的读取立即失败，
+// 返回包装了 singleflight.ErrTooManyWaiters 的暂时性错误（HTTP 传输层返回 503）。
+// 数据源卡住时，这样可以避免热点键上堆积无限多的 goroutine。n <= 0 表示不限制，这是默认行为。
+func WithMaxLoadWaiters(n int) GroupOption {
+	return func(g *Group) {
+		g.loaderOpts = append(g.loaderOpts, singleflight.WithMaxWaiters(n))
+	}
+}
+
+// WithLoadTimeout 限制每次读取等待加载的最长时间为 d，超时的读取返回 context.DeadlineExceeded。
+// 该截止时间同样传给数据源（ContextGetter）和远程节点，所有等待方都超时之后加载被取消。
+// d <= 0 表示不限制，这是默认行为；调用方 ctx 的截止时间更早时以调用方为准。
+func WithLoadTimeout(d time.Duration) GroupOption {
+	return func(g *Group) {
+		g.loadTimeout = d
+	}
+}
+
+// acquireLoad 占用一个访问数据源的名额，ctx 结束之前没有空闲名额时返回 ctx 的错误。
+func (g *Group) acquireLoad(ctx context.Context) error {
+	if g.loadSem == nil {
+		return nil
+	}
+	select {
+	case g.loadSem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseLoad 释放 acquireLoad 占用的名额。
+func (g *Group) releaseLoad() {
+	if g.loadSem != nil {
+		<-g.loadSem
+	}
+}
+
+// NewGroup 创建一个新的 Group 实例，并以 name 注册到 DefaultRegistry（或者 WithRegistry 指定的注册表）中。
+// 注册表中已经存在同名的缓存组时 panic，需要替换时先调用 DeregisterGroup。
+func NewGroup(name string, cacheBytes int64, getter Getter, opts ...GroupOption) *Group {
+	if getter == nil {
+		panic("nil Getter") // 没有数据源的缓存组没有意义
+	}
 	g := &Group{
-		// ...
-		loader: &singleflight.Group{},
+		name:         name,
+		getter:       getter,
+		mainCache:    cache{cacheBytes: cacheBytes, clock: clock.Real},
+		hotCache:     cache{cacheBytes: defaultHotCacheBytes(cacheBytes), clock: clock.Real},
+		negCache:     cache{cacheBytes: negativeCacheBytes, clock: clock.Real},
+		codec:        codec.MustGet(codec.JSON),
+		clock:        clock.Real,
+		logger:       StdLogger,
+		loadAttempts: 1,
+		epoch:        rand.Uint64(),
+		done:         make(chan struct{}),
+		registry:     DefaultRegistry,
+	}
+	for _, opt := range opts {
+		opt(g)
 	}
+	g.loader = singleflight.New(append(g.loaderOpts, singleflight.WithClock(g.clock))...)
+	r := g.registry
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.groups[name] != nil {
+		panic("geecache: duplicate group " + name)
+	}
+	g.mainCache.onRemoved = g.onRemoved
+	if g.shards > 1 {
+		g.mainCache.split(g.shards)
+	}
+	g.mainCache.prepare()
+	if err := g.storeErr.Load(); err != nil {
+		panic(fmt.Sprintf("geecache: group %s: %v", name, *err)) // 与其他配置错误一样在创建时暴露
+	}
+	g.touch()
+	g.startSweeper()
+	g.startSnapshots()
+	g.startWriteBehind()
+	r.groups[name] = g
 	return g
 }
 
 // load 方法用于从缓存或远程节点加载数据。
-func (g *Group) load(key string) (value ByteView, err error) {
-	// 确保每个键只被获取一次（无论有多少并发调用）
-	viewi, err := g.loader.Do(key, func() (interface{}, error) {
+// 调用方放弃请求（ctx 被取消或超时）后不会再去数据源加载数据。
+func (g *Group) load(ctx context.Context, key string) (value ByteView, err error) {
+	// 确保每个键只被获取一次（无论有多少并发调用）。
+	// 携带一致性令牌的读取不能复用写入之前开始的加载，按令牌区分。
+	flight := key
+	if token, ok := TokenFromContext(ctx); ok {
+		flight = string(token) + "\x00" + key
+	}
+	if g.loadTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.loadTimeout)
+		defer cancel()
+	}
+	viewi, err := g.loader.DoContext(ctx, flight, func(ctx context.Context) (v interface{}, err error) {
+		g.stats.loads.Add(1)
+		if g.subscribed() {
+			start := g.clock.Now()
+			defer func() {
+				value, _ := v.(ByteView)
+				g.publish(Event{Type: EventLoad, Key: key, Value: value, Err: err, Duration: g.clock.Now().Sub(start)})
+			}()
+		}
 		if g.peers != nil {
 			if peer, ok := g.peers.PickPeer(key); ok {
-				if value, err = g.getFromPeer(peer, key); err == nil {
+				value, err := g.getFromPeerHedged(ctx, peer, key)
+				if err == nil {
+					g.stats.peerLoads.Add(1)
+					g.populateHotCache(key, value)
+					return value, nil
+				}
+				if errors.Is(err, ErrNotFound) {
+					// 所有者确认数据源中不存在 key，不需要在本地再加载一次。
+					g.stats.loadErrors.Add(1)
+					g.cacheNotFound(key, err)
+					return ByteView{}, err
+				}
+				g.stats.peerErrors.Add(1)
+				g.logger.Errorf("[GeeCache] Failed to get from peer %v", err)
+				if value, ok := g.getFromReplicas(ctx, peer, key); ok {
+					g.stats.peerLoads.Add(1)
+					g.populateHotCache(key, value)
 					return value, nil
 				}
-				log.Println("[GeeCache] Failed to get from peer", err)
+				if g.peerFallback == FallbackError {
+					g.stats.loadErrors.Add(1)
+					return ByteView{}, err
+				}
 			}
 		}
 
-		return g.getLocally(key)
+		if err := ctx.Err(); err != nil {
+			g.stats.loadErrors.Add(1)
+			return ByteView{}, err // 调用方已经放弃，不再访问数据源
+		}
+		if !g.admit(key) {
+			return ByteView{}, ErrNotFound // 布隆过滤器确定数据源中没有 key
+		}
+		value, err := g.getLocally(ctx, key)
+		if err != nil {
+			g.stats.loadErrors.Add(1)
+			g.cacheNotFound(key, err)
+		} else {
+			g.stats.localLoads.Add(1)
+		}
+		return value, err
 	})
 
 	if err == nil {
 		return viewi.(ByteView), nil
 	}
+	if errors.Is(err, singleflight.ErrTooManyWaiters) {
+		err = Retryable(fmt.Errorf("loading %q: %w", key, err)) // 稍后重试可能成功
+	}
 	return
 }
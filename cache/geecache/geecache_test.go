@@ -1,10 +1,30 @@
 package geecache
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"testProject/cache/bloom"
+	"testProject/cache/clock"
+	pb "testProject/cache/geecachepb"
+	"testProject/cache/singleflight"
+	"testProject/cache/tinylfu"
 )
 
 func TestGetter(t *testing.T) {
@@ -45,8 +65,8 @@ func TestGet(t *testing.T) {
 				loadCounts[key] += 1
 				return []byte(v), nil
 			}
-			// 如果未找到值，返回错误信息。
-			return nil, fmt.Errorf("%s not exist", key)
+			// 如果未找到值，返回包装了 ErrNotFound 的错误。
+			return nil, fmt.Errorf("%s: %w", key, ErrNotFound)
 		}))
 
 	// 遍历模拟数据库中的键值对，尝试从 GeeCache 组（gee）中获取值。
@@ -60,8 +80,1551 @@ func TestGet(t *testing.T) {
 		} // cache hit
 	}
 
-	// 测试获取一个未知键时，预期会返回空值并报告错误。
-	if view, err := gee.Get("unknown"); err == nil {
-		t.Fatalf("the value of unknow should be empty, but %s got", view)
+	// 测试获取一个未知键时，预期会返回空值并报告 ErrNotFound。
+	if view, err := gee.Get("unknown"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("the value of unknow should be empty with ErrNotFound, but %s, %v got", view, err)
+	}
+}
+
+// 测试缓存组的条目数量、已用内存和容量
+func TestGroupUtilization(t *testing.T) {
+	gee := NewGroup("utilization", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(db[key]), nil
+	}))
+	if gee.Len() != 0 || gee.Bytes() != 0 || gee.Capacity() != 2<<10 {
+		t.Fatalf("empty group: Len=%d Bytes=%d Capacity=%d", gee.Len(), gee.Bytes(), gee.Capacity())
+	}
+	gee.Get("Tom")
+	if gee.Len() != 1 || gee.Bytes() != int64(len("Tom")+len(db["Tom"])) {
+		t.Fatalf("after one load: Len=%d Bytes=%d", gee.Len(), gee.Bytes())
+	}
+}
+
+// 测试运行时缩小缓存容量会立即淘汰超出的条目
+func TestSetCacheBytes(t *testing.T) {
+	gee := NewGroup("setcachebytes", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(db[key]), nil
+	}))
+	for _, key := range []string{"Jack", "Sam", "Tom"} {
+		gee.Get(key)
+	}
+	limit := int64(len("Tom") + len(db["Tom"]))
+	gee.SetCacheBytes(limit)
+	if gee.Capacity() != limit || gee.Bytes() > limit || gee.Len() != 1 {
+		t.Fatalf("after SetCacheBytes: Capacity=%d Bytes=%d Len=%d", gee.Capacity(), gee.Bytes(), gee.Len())
+	}
+}
+
+// 测试内存预算按权重分配，并在重新分配时把冷组的内存让给热组
+func TestBudget(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		return make([]byte, 100), nil
+	})
+	hot := NewGroup("budget-hot", 0, getter)
+	cold := NewGroup("budget-cold", 0, getter)
+
+	b := NewBudget(1000)
+	b.Add(hot, 1)
+	b.Add(cold, 1)
+	if hot.Capacity() != 500 || cold.Capacity() != 500 {
+		t.Fatalf("capacities = %d, %d; want 500, 500", hot.Capacity(), cold.Capacity())
+	}
+
+	for i := 0; i < 10; i++ {
+		hot.Get(fmt.Sprintf("key%d", i))
+	}
+	b.Rebalance()
+	if cold.Capacity() != 125 || hot.Capacity() != 875 {
+		t.Fatalf("capacities after rebalance = %d, %d; want 875, 125", hot.Capacity(), cold.Capacity())
+	}
+
+	b.Remove(cold)
+	if hot.Capacity() != 1000 {
+		t.Fatalf("capacity after removing cold group = %d, want 1000", hot.Capacity())
+	}
+}
+
+// 测试一个租户超出配额时只会淘汰自己的条目
+func TestTenantQuota(t *testing.T) {
+	gee := NewGroup("tenants", 0, GetterFunc(func(key string) ([]byte, error) {
+		return make([]byte, 10), nil
+	}), WithTenants(TenantPrefix(":"), TenantQuota{MaxEntries: 2}, map[string]TenantQuota{
+		"vip": {MaxEntries: 10},
+	}))
+
+	gee.Get("quiet:1")
+	for i := 0; i < 5; i++ {
+		gee.Get(fmt.Sprintf("noisy:%d", i))
+		gee.Get(fmt.Sprintf("vip:%d", i))
+	}
+	if _, entries := gee.TenantUsage("noisy"); entries != 2 {
+		t.Fatalf("noisy tenant has %d entries, want 2", entries)
+	}
+	if _, entries := gee.TenantUsage("vip"); entries != 5 {
+		t.Fatalf("vip tenant has %d entries, want 5", entries)
+	}
+	if _, ok := gee.mainCache.get("quiet:1"); !ok {
+		t.Fatal("quiet tenant was evicted by the noisy tenant")
+	}
+}
+
+// 测试值存放在磁盘上时能正确读回，并且失效的段文件会被回收
+func TestDiskValues(t *testing.T) {
+	dir := t.TempDir()
+	gee := NewGroup("disk", 0, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(db[key]), nil
+	}), WithDiskValues(dir))
+	for k, v := range db {
+		if view, err := gee.Get(k); err != nil || view.String() != v {
+			t.Fatalf("Get(%q) = %q, %v; want %q", k, view, err, v)
+		}
+		if view, ok := gee.mainCache.get(k); !ok || view.String() != v {
+			t.Fatalf("cached %q = %q, %v; want %q", k, view, ok, v)
+		}
+	}
+	// 注销缓存组时关闭段文件并删除它的子目录
+	DeregisterGroup("disk")
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Fatalf("%d entries left in %s after DeregisterGroup, want none", len(files), dir)
+	}
+
+	s, err := newDiskStore(dir, 100, 32, clock.Real)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		s.AddWithExpire(fmt.Sprintf("k%02d", i), ByteView{b: []byte(fmt.Sprintf("value%02d", i))}, time.Time{})
+	}
+	for i := 90; i < 100; i++ {
+		if v, ok := s.Get(fmt.Sprintf("k%02d", i)); !ok || v.(ByteView).String() != fmt.Sprintf("value%02d", i) {
+			t.Fatalf("Get(k%02d) = %v, %v", i, v, ok)
+		}
+	}
+	files, _ := os.ReadDir(s.dir)
+	if len(files) > 6 {
+		t.Fatalf("%d segment files left for 100 bytes of live data, dead segments are not reclaimed", len(files))
+	}
+
+	// 目录不可用时创建缓存组就失败，而不是在处理请求时第一次写入才失败
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "create disk store") {
+			t.Fatalf("NewGroup with a missing dir: recovered %v, want a disk store error", r)
+		}
+	}()
+	NewGroup("disk-missing", 0, GetterFunc(func(key string) ([]byte, error) {
+		return nil, nil
+	}), WithRegistry(NewRegistry()), WithDiskValues(filepath.Join(dir, "missing")))
+}
+
+// 测试通过 mmap 读写段文件时值能正确读回，比段文件还大的值独占一个段文件
+func TestMappedValues(t *testing.T) {
+	dir := t.TempDir()
+	gee := NewGroup("mapped", 0, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(db[key]), nil
+	}), WithMappedValues(dir))
+	for k, v := range db {
+		if view, err := gee.Get(k); err != nil || view.String() != v {
+			t.Fatalf("Get(%q) = %q, %v; want %q", k, view, err, v)
+		}
+	}
+
+	s, err := newDiskStore(dir, 200, 32, clock.Real)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mapped = true
+	for i := 0; i < 100; i++ {
+		s.AddWithExpire(fmt.Sprintf("k%02d", i), ByteView{b: []byte(fmt.Sprintf("value%02d", i))}, time.Time{})
+	}
+	large := strings.Repeat("x", 100)
+	s.AddWithExpire("large", ByteView{s: large}, time.Time{})
+	if v, ok := s.Get("large"); !ok || v.(ByteView).String() != large {
+		t.Fatalf("Get(large) = %v, %v", v, ok)
+	}
+	if v, ok := s.Get("k99"); !ok || v.(ByteView).String() != "value99" {
+		t.Fatalf("Get(k99) = %v, %v", v, ok)
+	}
+	if files, _ := os.ReadDir(s.dir); len(files) > 12 {
+		t.Fatalf("%d segment files left, dead segments are not unmapped and removed", len(files))
+	}
+}
+
+// 测试随机采样返回已缓存的键及其大小
+func TestGroupSample(t *testing.T) {
+	gee := NewGroup("sample", 0, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(db[key]), nil
+	}))
+	for k := range db {
+		gee.Get(k)
+	}
+	sample := gee.Sample(2)
+	if len(sample) != 2 {
+		t.Fatalf("Sample(2) returned %d keys", len(sample))
+	}
+	for _, info := range sample {
+		if info.Size != len(db[info.Key]) || info.Age < 0 {
+			t.Fatalf("unexpected key info %+v", info)
+		}
+	}
+}
+
+// 测试注入的时钟决定条目的存在时间，无需 sleep
+func TestGroupClock(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	gee := NewGroup("clock", 0, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(db[key]), nil
+	}), WithClock(clk))
+	gee.Get("Tom")
+	clk.Advance(time.Minute)
+	gee.Get("Jack")
+	clk.Advance(time.Minute)
+
+	ages := make(map[string]time.Duration)
+	for _, info := range gee.Sample(10) {
+		ages[info.Key] = info.Age
+	}
+	if ages["Tom"] != 2*time.Minute || ages["Jack"] != time.Minute {
+		t.Fatalf("ages = %v, want Tom=2m Jack=1m", ages)
+	}
+}
+
+// 测试读取一个键后在后台预取预测器给出的键
+func TestPrefetch(t *testing.T) {
+	loaded := make(chan string, 10)
+	gee := NewGroup("prefetch", 0, GetterFunc(func(key string) ([]byte, error) {
+		loaded <- key
+		return []byte(key), nil
+	}), WithPredictor(func(key string) []string {
+		var page int
+		if _, err := fmt.Sscanf(key, "page:%d", &page); err != nil {
+			return nil
+		}
+		return []string{fmt.Sprintf("page:%d", page+1)}
+	}))
+
+	gee.Get("page:3")
+	for _, want := range []string{"page:3", "page:4"} {
+		select {
+		case got := <-loaded:
+			if got != want {
+				t.Fatalf("loaded %q, want %q", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%q was not loaded", want)
+		}
+	}
+	// page:4 的加载完成后才会写入缓存，等待它出现
+	for i := 0; i < 100 && gee.Len() < 2; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if _, ok := gee.mainCache.get("page:4"); !ok {
+		t.Fatal("page:4 should have been prefetched into the cache")
+	}
+}
+
+// tokenPeer 是总是选中自身的 PeerPicker，记录收到的一致性令牌
+type tokenPeer struct{ tokens []ConsistencyToken }
+
+func (p *tokenPeer) PickPeer(key string) (PeerGetter, bool) { return p, true }
+
+func (p *tokenPeer) Remove(ctx context.Context, group string, key string) error { return nil }
+
+func (p *tokenPeer) Get(ctx context.Context, group string, key string) ([]byte, error) {
+	token, _ := TokenFromContext(ctx)
+	p.tokens = append(p.tokens, token)
+	return []byte("fresh"), nil
+}
+
+// slowPeer 是在 release 关闭之后才返回 value 的 PeerGetter（release 为 nil 时立即返回），ctx 先结束时返回 ctx 的错误。
+// Peek 在 cached 为 true 时立即返回 value，否则返回 ErrNotCached
+type slowPeer struct {
+	release chan struct{}
+	value   string
+	cached  bool
+	gets    atomic.Int32
+}
+
+func (p *slowPeer) Remove(ctx context.Context, group string, key string) error { return nil }
+
+func (p *slowPeer) Get(ctx context.Context, group string, key string) ([]byte, error) {
+	p.gets.Add(1)
+	select {
+	case <-p.release:
+		return []byte(p.value), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *slowPeer) Peek(ctx context.Context, group string, key string) ([]byte, error) {
+	if !p.cached {
+		return nil, ErrNotCached
+	}
+	return []byte(p.value), nil
+}
+
+// replicaPicker 是把 peers 依次作为副本节点的 PeerPicker，第一个是所有者
+type replicaPicker []PeerGetter
+
+func (p replicaPicker) PickPeer(key string) (PeerGetter, bool) { return p[0], true }
+
+func (p replicaPicker) PickPeers(key string, n int) []PeerGetter { return p[:min(n, len(p))] }
+
+// recordingPeer 是记录收到的推送和删除的 PeerGetter，第一次推送开始时关闭 started，并一直阻塞到 release 关闭
+type recordingPeer struct {
+	mu      sync.Mutex
+	ops     []string
+	pushed  bool
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p *recordingPeer) Get(ctx context.Context, group string, key string) ([]byte, error) {
+	return nil, ErrNotCached
+}
+
+func (p *recordingPeer) Push(ctx context.Context, group string, key string, value []byte) error {
+	p.mu.Lock()
+	first := !p.pushed
+	p.pushed = true
+	p.mu.Unlock()
+	if first {
+		close(p.started)
+		<-p.release
+	}
+	p.record("push " + string(value))
+	return nil
+}
+
+func (p *recordingPeer) Remove(ctx context.Context, group string, key string) error {
+	p.record("remove")
+	return nil
+}
+
+func (p *recordingPeer) record(op string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ops = append(p.ops, op)
+}
+
+// 测试推送给副本节点的写入和删除按顺序到达，排队的旧值被之后的写入替换，不会在删除之后写回副本
+func TestReplicationOrder(t *testing.T) {
+	peer := &recordingPeer{started: make(chan struct{}), release: make(chan struct{})}
+	g := NewGroup("replication-order", 0, GetterFunc(func(key string) ([]byte, error) {
+		return []byte("v"), nil
+	}), WithReplication(2))
+	g.RegisterPeers(replicaPicker{peer})
+
+	for i, v := range []string{"v1", "v2", "v3"} {
+		if _, err := g.SetLocal("k", []byte(v)); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			<-peer.started
+		}
+	}
+	if _, err := g.Remove("k"); err != nil { // v1 还在发送，v3 还在排队
+		t.Fatal(err)
+	}
+	close(peer.release)
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		g.replicaQ.mu.Lock()
+		running := g.replicaQ.running
+		g.replicaQ.mu.Unlock()
+		if !running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("replica queue did not drain")
+		}
+	}
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+	if want := []string{"remove", "push v1", "remove"}; !reflect.DeepEqual(peer.ops, want) {
+		t.Fatalf("replica received %q, want %q", peer.ops, want)
+	}
+}
+
+// 测试所有者超过对冲延迟还没有返回时采用副本节点的结果，以及对冲延迟按耗时的百分位数计算
+func TestHedgedRequests(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	owner := &slowPeer{release: make(chan struct{}), value: "owner"}
+	backup := &slowPeer{value: "backup", cached: true}
+	g := NewGroup("hedged", 0, GetterFunc(func(key string) ([]byte, error) {
+		return nil, errors.New("should not load locally")
+	}), WithClock(clk), WithReplication(2), WithHedgedRequests(0.9, 10*time.Millisecond))
+	g.RegisterPeers(replicaPicker{owner, backup})
+
+	// get 在后台读取 key，并不断推进时钟直到读取返回，对冲延迟的计时器在读取开始之后才创建。
+	get := func(key string) (ByteView, error) {
+		type result struct {
+			v   ByteView
+			err error
+		}
+		done := make(chan result, 1)
+		go func() {
+			v, err := g.Get(key)
+			done <- result{v, err}
+		}()
+		for {
+			select {
+			case r := <-done:
+				return r.v, r.err
+			case <-time.After(time.Millisecond):
+				clk.Advance(10 * time.Millisecond)
+			}
+		}
+	}
+	if v, err := get("k"); err != nil || v.String() != "backup" {
+		t.Fatalf("Get = %q, %v; want the hedged result", v, err)
+	}
+	if hedges := g.Stats().Hedges; hedges != 1 {
+		t.Fatalf("Hedges = %d, want 1", hedges)
+	}
+
+	// 副本没有缓存 key 时对冲失败，继续等待所有者，副本不会被要求加载
+	backup.cached = false
+	go func() {
+		for g.Stats().Hedges < 2 {
+			time.Sleep(time.Millisecond)
+		}
+		close(owner.release)
+	}()
+	if v, err := get("k2"); err != nil || v.String() != "owner" {
+		t.Fatalf("Get with a cold replica = %q, %v; want the owner's result", v, err)
+	}
+	if gets := backup.gets.Load(); gets != 0 {
+		t.Fatalf("the replica received %d full gets, want hedges to only peek", gets)
+	}
+
+	h := newHedger(0.5, time.Millisecond)
+	for i := 1; i <= hedgeRecompute; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	if got := time.Duration(h.delay.Load()); got != 8*time.Millisecond {
+		t.Fatalf("delay = %v, want the median 8ms", got)
+	}
+}
+
+// 测试携带一致性令牌的读取绕过可能过期的本地缓存
+func TestGetConsistent(t *testing.T) {
+	value := "old"
+	gee := NewGroup("consistent", 0, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(value), nil
+	}))
+	gee.Get("k")
+	value = "new"
+
+	// 所有者已经见过的写入不需要绕过缓存
+	token := gee.recordWrite()
+	if v, _ := gee.GetConsistent("k", token); v.String() != "old" {
+		t.Fatalf("GetConsistent with a seen token = %q, want cached value", v.String())
+	}
+	// 不是本实例签发的令牌（重启之前签发的或者伪造的）按普通读取处理
+	for _, forged := range []ConsistencyToken{"100", "x.100", NewGroup("consistent-restarted", 0, GetterFunc(func(key string) ([]byte, error) { return nil, nil })).token(100)} {
+		if v, _ := gee.GetConsistent("k", forged); v.String() != "old" {
+			t.Fatalf("GetConsistent with foreign token %q = %q, want cached value", forged, v.String())
+		}
+	}
+	// 所有者没有见过的写入需要重新加载
+	if v, _ := gee.GetConsistent("k", gee.token(100)); v.String() != "new" {
+		t.Fatalf("GetConsistent with an unseen token = %q, want reloaded value", v.String())
+	}
+
+	// 非所有者节点直接从所有者读取，并转发令牌
+	peer := &tokenPeer{}
+	gee.RegisterPeers(peer)
+	if v, _ := gee.GetConsistent("k", token); v.String() != "fresh" {
+		t.Fatalf("GetConsistent on a non-owner = %q, want value from the owner", v.String())
+	}
+	if !reflect.DeepEqual(peer.tokens, []ConsistencyToken{token}) {
+		t.Fatalf("owner received tokens %v, want [%s]", peer.tokens, token)
+	}
+}
+
+// 测试只有可以重试的错误才会重试
+func TestErrorClassification(t *testing.T) {
+	if Classify(Retryable(errors.New("busy"))) != ErrorRetryable ||
+		Classify(fmt.Errorf("wrapped: %w", Permanent(errors.New("gone")))) != ErrorPermanent ||
+		Classify(errors.New("plain")) != ErrorUnknown {
+		t.Fatal("unexpected error classification")
+	}
+
+	calls := make(map[string]int)
+	gee := NewGroup("classify", 0, GetterFunc(func(key string) ([]byte, error) {
+		calls[key]++
+		if key == "flaky" && calls[key] < 3 {
+			return nil, Retryable(errors.New("backend busy"))
+		}
+		if key == "missing" {
+			return nil, Permanent(errors.New("not found"))
+		}
+		return []byte(key), nil
+	}), WithLoadRetries(3, 0))
+
+	if v, err := gee.Get("flaky"); err != nil || v.String() != "flaky" {
+		t.Fatalf("Get(flaky) = %q, %v; want success after retries", v.String(), err)
+	}
+	if _, err := gee.Get("missing"); Classify(err) != ErrorPermanent {
+		t.Fatalf("Get(missing) err = %v, want a permanent error", err)
+	}
+	if calls["flaky"] != 3 || calls["missing"] != 1 {
+		t.Fatalf("calls = %v, want flaky=3 missing=1", calls)
+	}
+
+	// 永久性错误写入负缓存，可以重试的错误即使包装了 ErrNotFound 也不写入
+	var fail error
+	calls = make(map[string]int)
+	neg := NewGroup("classify-negative", 0, GetterFunc(func(key string) ([]byte, error) {
+		calls[key]++
+		if fail != nil {
+			return nil, fail
+		}
+		return []byte(key), nil
+	}), WithNegativeTTL(time.Minute))
+	fail = Permanent(errors.New("gone"))
+	for i := 0; i < 2; i++ {
+		if _, err := neg.Get("gone"); Classify(err) != ErrorPermanent || err.Error() != "gone" {
+			t.Fatalf("Get(gone) err = %v, want the permanent error", err)
+		}
+	}
+	fail = Retryable(ErrNotFound)
+	for i := 0; i < 2; i++ {
+		neg.Get("busy")
+	}
+	if calls["gone"] != 1 || calls["busy"] != 2 {
+		t.Fatalf("calls = %v, want gone=1 busy=2", calls)
+	}
+
+	// 刷新遇到可以重试的错误时保留旧值，遇到永久性错误时删除旧值
+	fail = nil
+	neg.Get("k")
+	fail = Retryable(errors.New("backend busy"))
+	if _, err := neg.Refresh("k"); err == nil || !neg.mainCache.contains("k") {
+		t.Fatalf("Refresh with a retryable error: err %v, cached %v; want the old value kept", err, neg.mainCache.contains("k"))
+	}
+	fail = Permanent(errors.New("gone"))
+	if _, err := neg.Refresh("k"); err == nil || neg.mainCache.contains("k") {
+		t.Fatalf("Refresh with a permanent error: err %v, cached %v; want the old value dropped", err, neg.mainCache.contains("k"))
+	}
+}
+
+// 测试同时访问数据源的加载数量不超过上限
+func TestMaxConcurrentLoads(t *testing.T) {
+	var running, peak int32
+	gee := NewGroup("concurrent-loads", 0, GetterFunc(func(key string) ([]byte, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return []byte(key), nil
+	}), WithMaxConcurrentLoads(2))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			gee.Get(fmt.Sprintf("key%d", i))
+		}(i)
+	}
+	wg.Wait()
+	if peak > 2 {
+		t.Fatalf("peak concurrent loads = %d, want at most 2", peak)
+	}
+	if gee.Len() != 10 {
+		t.Fatalf("Len() = %d, want all 10 keys loaded", gee.Len())
+	}
+}
+
+// 测试共享时间内的未命中复用上一次加载的结果，删除之后重新加载
+func TestLoadShareWindow(t *testing.T) {
+	loads := 0
+	g := NewGroup("share-window", 0, GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte(key), nil
+	}), WithLoadShareWindow(time.Minute))
+	g.Freeze() // 冻结期间加载的值不写入缓存，每次读取都会未命中
+	g.Get("k")
+	g.Get("k")
+	if loads != 1 {
+		t.Fatalf("loads = %d, want misses within the window to share one load", loads)
+	}
+	g.Unfreeze()
+	g.Remove("k")
+	g.Freeze()
+	g.Get("k")
+	if loads != 2 {
+		t.Fatalf("loads = %d, want Remove to discard the shared result", loads)
+	}
+}
+
+// 测试数据源卡住时读取按 WithLoadTimeout 超时，超过等待上限的读取立即以暂时性错误失败
+func TestLoadTimeoutAndMaxWaiters(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{}, 1)
+	g := NewGroup("stuck", 0, GetterFunc(func(key string) ([]byte, error) {
+		started <- struct{}{}
+		<-release
+		return []byte(key), nil
+	}), WithLoadTimeout(20*time.Millisecond), WithMaxLoadWaiters(1))
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := g.Get("k")
+		errs <- err
+	}()
+	<-started
+	if _, err := g.Get("k"); !errors.Is(err, singleflight.ErrTooManyWaiters) || !IsRetryable(err) {
+		t.Fatalf("second Get: err = %v, want a retryable ErrTooManyWaiters", err)
+	}
+	if s := g.Stats(); s.RejectedLoads != 1 || s.LoadsInFlight != 1 {
+		t.Fatalf("Stats = %+v, want 1 rejected and 1 in-flight load", s)
+	}
+	if err := <-errs; !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("first Get: err = %v, want DeadlineExceeded", err)
+	}
+}
+
+// 测试按需创建缓存组，并销毁长时间空闲的缓存组
+func TestGroupFactory(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	created := 0
+	RegisterGroupFactory("lazy-*", func(name string) *Group {
+		created++
+		return NewGroup(name, 0, GetterFunc(func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}), WithClock(clk))
+	})
+
+	if GetGroup("eager-1") != nil {
+		t.Fatal("GetGroup should return nil for names without a factory")
+	}
+	a := GetGroup("lazy-a")
+	if a == nil || GetGroup("lazy-a") != a || created != 1 {
+		t.Fatalf("factory should create lazy-a once, created %d", created)
+	}
+	b := GetGroup("lazy-b")
+	a.Get("k")
+
+	clk.Advance(time.Minute)
+	b.Get("k")
+	if got := CollectIdleGroups(30 * time.Second); !reflect.DeepEqual(got, []string{"lazy-a"}) {
+		t.Fatalf("CollectIdleGroups = %v, want [lazy-a]", got)
+	}
+	if GetGroup("lazy-a") == a || created != 3 {
+		t.Fatal("an idle group should be recreated by the factory on next use")
+	}
+
+	// 后台回收按注入的时钟计时
+	stop := StartIdleGroupGC(30*time.Second, 10*time.Second, WithIdleGCClock(clk))
+	defer stop()
+	for i := 0; slices.ContainsFunc(ListGroups(), func(name string) bool { return strings.HasPrefix(name, "lazy-") }); i++ {
+		if i == 1000 {
+			t.Fatalf("idle groups %v were not collected", ListGroups())
+		}
+		clk.Advance(10 * time.Second)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// 测试缓存组名称冲突时 panic，注销之后可以重新创建
+func TestGroupRegistry(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil })
+	g := NewGroup("registry", 0, getter)
+	g.Get("k")
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("NewGroup with a duplicate name should panic")
+			}
+		}()
+		NewGroup("registry", 0, getter)
+	}()
+	if GetGroup("registry") != g {
+		t.Fatal("the duplicate group should not replace the registered one")
+	}
+
+	names := ListGroups()
+	if i := sort.SearchStrings(names, "registry"); i == len(names) || names[i] != "registry" {
+		t.Fatalf("ListGroups() = %v, want registry included", names)
+	}
+	if !DeregisterGroup("registry") || DeregisterGroup("registry") {
+		t.Fatal("DeregisterGroup should remove registry exactly once")
+	}
+	if GetGroup("registry") != nil || g.Len() != 0 {
+		t.Fatal("a deregistered group should be unreachable and emptied")
+	}
+	select {
+	case <-g.done:
+	default:
+		t.Fatal("a deregistered group should stop its background tasks")
+	}
+	if NewGroup("registry", 0, getter) == g {
+		t.Fatal("the name should be reusable after DeregisterGroup")
+	}
+}
+
+// 测试条目过期后重新加载，剩余有效期随时间减少
+func TestExpiration(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	loads := 0
+	gee := NewGroup("expiration", 0, GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte(key), nil
+	}), WithClock(clk), WithExpiration(time.Minute))
+
+	gee.Get("k")
+	clk.Advance(20 * time.Second)
+	if ttl, err := gee.TTL("k"); err != nil || ttl != 40*time.Second {
+		t.Fatalf("TTL = %v, %v; want 40s", ttl, err)
+	}
+	gee.Get("k")
+	if loads != 1 {
+		t.Fatalf("loads = %d before expiration, want 1", loads)
+	}
+
+	clk.Advance(time.Minute)
+	if _, err := gee.TTL("k"); err != ErrNotCached {
+		t.Fatalf("TTL after expiration: err = %v, want ErrNotCached", err)
+	}
+	gee.Get("k")
+	if loads != 2 {
+		t.Fatalf("loads = %d after expiration, want 2", loads)
+	}
+}
+
+// 测试 GetContext 把截止时间传递给数据源
+func TestGetContext(t *testing.T) {
+	gee := NewGroup("context", 0, ContextGetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		if key == "slow" {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return []byte(key), nil
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := gee.GetContext(ctx, "slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetContext(slow) err = %v, want context.DeadlineExceeded", err)
+	}
+	if v, err := gee.GetContext(context.Background(), "fast"); err != nil || v.String() != "fast" {
+		t.Fatalf("GetContext(fast) = %q, %v", v.String(), err)
+	}
+}
+
+// 测试查询条目的元数据和剩余有效期不会触发加载
+func TestGroupMetadata(t *testing.T) {
+	gee := NewGroup("metadata", 0, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(db[key]), nil
+	}))
+	if _, err := gee.TTL("Tom"); err != ErrNotCached {
+		t.Fatalf("TTL before load: err = %v, want ErrNotCached", err)
+	}
+	if gee.Len() != 0 {
+		t.Fatal("Metadata should not load the key")
+	}
+
+	gee.Get("Tom")
+	meta, err := gee.Metadata("Tom")
+	if err != nil || meta.Size != len(db["Tom"]) || meta.Version == "" {
+		t.Fatalf("Metadata = %+v, %v", meta, err)
+	}
+	if ttl, err := gee.TTL("Tom"); err != nil || ttl != 0 {
+		t.Fatalf("TTL = %v, %v; want 0, nil for entries without expiration", ttl, err)
+	}
+}
+
+// 测试冻结期间已缓存的条目照常命中，新加载的数据不写入缓存
+func TestFreeze(t *testing.T) {
+	gee := NewGroup("freeze", 0, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(db[key]), nil
+	}))
+	gee.Get("Tom")
+	gee.Freeze()
+	if view, err := gee.Get("Tom"); err != nil || view.String() != db["Tom"] {
+		t.Fatalf("Get(Tom) while frozen = %q, %v", view, err)
+	}
+	if view, err := gee.Get("Jack"); err != nil || view.String() != db["Jack"] {
+		t.Fatalf("Get(Jack) while frozen = %q, %v", view, err)
+	}
+	if gee.Len() != 1 {
+		t.Fatalf("frozen group cached %d entries, want 1", gee.Len())
+	}
+	gee.Unfreeze()
+	gee.Get("Jack")
+	if gee.Len() != 2 || gee.Frozen() {
+		t.Fatalf("unfrozen group cached %d entries, want 2", gee.Len())
+	}
+}
+
+// 测试缓存组按配置的编解码器解码值
+func TestGetValue(t *testing.T) {
+	type user struct{ Name string }
+	var gee *Group
+	gee = NewGroup("codec", 0, GetterFunc(func(key string) ([]byte, error) {
+		return gee.Codec().Marshal(user{Name: key})
+	}), WithCodec("gob"))
+
+	var u user
+	if err := gee.GetValue("Tom", &u); err != nil || u.Name != "Tom" {
+		t.Fatalf("GetValue = %+v, %v", u, err)
+	}
+}
+
+// 测试分片的缓存组与不分片时行为一致
+func TestShards(t *testing.T) {
+	g := NewGroup("shards", 0, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}), WithShards(4))
+	if len(g.mainCache.shards) != 4 {
+		t.Fatalf("got %d shards, want 4", len(g.mainCache.shards))
+	}
+	for i := 0; i < 100; i++ {
+		g.Get(fmt.Sprintf("key%d", i))
+	}
+	if g.Len() != 100 {
+		t.Fatalf("Len = %d, want 100", g.Len())
+	}
+	var all []string
+	for cursor := uint64(0); ; {
+		var keys []string
+		keys, cursor = g.Scan(cursor, "", 7)
+		all = append(all, keys...)
+		if cursor == 0 {
+			break
+		}
+	}
+	if len(all) != 100 {
+		t.Fatalf("Scan returned %d keys, want 100", len(all))
+	}
+	if _, err := g.Remove("key1"); err != nil || g.mainCache.contains("key1") {
+		t.Fatalf("Remove on a sharded group: %v", err)
+	}
+}
+
+// 对比不同分片数量下的并发读取性能
+func BenchmarkGetParallel(b *testing.B) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+	for _, n := range []int{1, 16} {
+		b.Run(fmt.Sprintf("shards=%d", n), func(b *testing.B) {
+			g := NewGroup("bench", 0, GetterFunc(func(key string) ([]byte, error) {
+				return []byte(key), nil
+			}), WithShards(n), WithRegistry(NewRegistry())) // 每次运行都重新创建，不能注册到全局的注册表
+
+			for _, key := range keys {
+				g.Get(key)
+			}
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					g.Get(keys[i%len(keys)])
+					i++
+				}
+			})
+		})
+	}
+}
+
+// 测试只持有读锁的命中在之后的写入之前更新淘汰顺序
+func TestCacheDeferredRecency(t *testing.T) {
+	c := &cache{cacheBytes: 4} // 只能容纳两个 1 字节键、1 字节值的条目
+	c.add("a", ByteView{b: []byte("1")}, time.Time{})
+	c.add("b", ByteView{b: []byte("2")}, time.Time{})
+	if _, ok := c.get("a"); !ok {
+		t.Fatal("a should be cached")
+	}
+	c.add("c", ByteView{b: []byte("3")}, time.Time{})
+	if !c.contains("a") || c.contains("b") {
+		t.Fatal("the read of a should be applied before evicting, so b is evicted instead")
+	}
+}
+
+// 对比只读和读多写少时主缓存的并发读取性能
+func BenchmarkCacheGetParallel(b *testing.B) {
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+	for _, writes := range []int{0, 10} { // 每 100 次操作中写入的次数
+		b.Run(fmt.Sprintf("writes=%d%%", writes), func(b *testing.B) {
+			c := &cache{}
+			for _, key := range keys {
+				c.add(key, ByteView{b: []byte(key)}, time.Time{})
+			}
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					key := keys[i%len(keys)]
+					if i%100 < writes {
+						c.add(key, ByteView{b: []byte(key)}, time.Time{})
+					} else {
+						c.get(key)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+// 测试 Stats 返回的读取、命中和加载计数
+func TestStats(t *testing.T) {
+	g := NewGroup("stats", 0, GetterFunc(func(key string) ([]byte, error) {
+		if key == "bad" {
+			return nil, fmt.Errorf("no such key")
+		}
+		return []byte(key), nil
+	}))
+	g.Get("Tom")
+	g.Get("Tom")
+	g.Get("bad")
+
+	want := CacheStats{Gets: 3, Hits: 1, Loads: 2, LoadErrors: 1, LocalLoads: 1, Bytes: 6, Items: 1}
+	if got := g.Stats(); got != want {
+		t.Fatalf("Stats = %+v, want %+v", got, want)
+	}
+}
+
+// recordLogger 记录 Debugf 输出的日志
+type recordLogger struct {
+	mu    sync.Mutex
+	debug []string
+}
+
+func (l *recordLogger) Debugf(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.debug = append(l.debug, fmt.Sprintf(format, v...))
+}
+func (l *recordLogger) Infof(format string, v ...interface{})  {}
+func (l *recordLogger) Errorf(format string, v ...interface{}) {}
+
+// 测试缓存组的日志输出到 WithLogger 设置的 Logger
+func TestWithLogger(t *testing.T) {
+	l := &recordLogger{}
+	g := NewGroup("logger", 0, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}), WithLogger(l))
+	g.Get("Tom")
+	g.Get("Tom")
+	if !reflect.DeepEqual(l.debug, []string{"[GeeCache] hit"}) {
+		t.Fatalf("logged %q, want one hit", l.debug)
+	}
+}
+
+// 测试数据源报告不存在的 key 在负缓存有效期内不会再次访问数据源
+func TestNegativeCache(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	var loads int
+	stored := map[string]string{}
+	g := NewGroup("negative", 0, GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		if v, ok := stored[key]; ok {
+			return []byte(v), nil
+		}
+		return nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+	}), WithNegativeTTL(time.Second), WithClock(fake))
+
+	for i := 0; i < 3; i++ {
+		if _, err := g.Get("ghost"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Get = %v, want ErrNotFound", err)
+		}
+	}
+	if loads != 1 {
+		t.Fatalf("getter called %d times, want 1", loads)
+	}
+	fake.Advance(2 * time.Second)
+	if _, err := g.Get("ghost"); !errors.Is(err, ErrNotFound) || loads != 2 {
+		t.Fatalf("after the TTL: err %v, loads %d; want ErrNotFound, 2", err, loads)
+	}
+
+	// Set 立即清除负缓存
+	if _, err := g.Set("ghost", []byte("boo")); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := g.Get("ghost"); err != nil || v.String() != "boo" {
+		t.Fatalf("Get after Set = %q, %v", v.String(), err)
+	}
+}
+
+// 测试布隆过滤器中不存在的键不会访问数据源
+func TestBloomFilter(t *testing.T) {
+	f := bloom.New(100, 0.01)
+	f.Add("Tom")
+	var loads int
+	g := NewGroup("bloom", 0, GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte(key), nil
+	}), WithBloomFilter(f))
+
+	if v, err := g.Get("Tom"); err != nil || v.String() != "Tom" {
+		t.Fatalf("Get(Tom) = %q, %v", v.String(), err)
+	}
+	if _, err := g.Get("ghost"); !errors.Is(err, ErrNotFound) || loads != 1 {
+		t.Fatalf("Get(ghost): err %v, loads %d; want ErrNotFound, 1", err, loads)
+	}
+	g.Set("ghost", []byte("boo"))
+	if !f.MayContain("ghost") {
+		t.Fatal("Set should add the key to the filter")
+	}
+}
+
+// 测试有效期按比例随机浮动
+func TestTTLJitter(t *testing.T) {
+	defer func(f func() float64) { jitter = f }(jitter)
+	fake := clock.NewFake(time.Unix(0, 0))
+	g := NewGroup("jitter", 0, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}), WithExpiration(time.Minute), WithTTLJitter(0.1), WithClock(fake))
+
+	for r, want := range map[float64]time.Duration{-1: 54 * time.Second, 0: time.Minute, 0.5: 63 * time.Second} {
+		jitter = func() float64 { return r }
+		if got := g.expiresAt().Sub(fake.Now()); got != want {
+			t.Errorf("jitter %v: TTL = %v, want %v", r, got, want)
+		}
+	}
+}
+
+// 测试 ByteView 的只读访问方法不会暴露或复制底层数据
+func TestByteView(t *testing.T) {
+	v := ByteView{b: []byte("geecache")}
+	if v.At(3) != 'c' || !v.Equal([]byte("geecache")) || v.Equal([]byte("gee")) {
+		t.Fatal("At or Equal returned a wrong result")
+	}
+	if s := v.Slice(3, 8); s.String() != "cache" || s.Len() != 5 {
+		t.Fatalf("Slice(3, 8) = %q", s.String())
+	}
+	r := v.Reader()
+	r.Seek(3, io.SeekStart)
+	if b, err := io.ReadAll(r); err != nil || string(b) != "cache" {
+		t.Fatalf("Reader after Seek = %q, %v", b, err)
+	}
+}
+
+// 测试以字符串提供数据的数据源直接作为 ByteView 的底层数据
+func TestStringGetter(t *testing.T) {
+	g := NewGroup("strings", 2<<10, StringGetterFunc(func(ctx context.Context, key string) (string, error) {
+		return key + "!", nil
+	}))
+	v, err := g.Get("Tom")
+	if err != nil || v.String() != "Tom!" || !v.EqualString("Tom!") || !v.Equal([]byte("Tom!")) {
+		t.Fatalf("Get = %q, %v", v.String(), err)
+	}
+	if v.b != nil || v.s != "Tom!" {
+		t.Fatal("a string getter should produce a string-backed view")
+	}
+	if v.At(3) != '!' || v.Slice(0, 3).String() != "Tom" || string(v.ByteSlice()) != "Tom!" {
+		t.Fatal("string-backed view returned a wrong result")
+	}
+}
+
+// 测试 GetInto 把值写入各种 Sink
+func TestGetInto(t *testing.T) {
+	want := &pb.Response{Value: []byte("v"), TtlMs: 7}
+	encoded, _ := proto.Marshal(want)
+	g := NewGroup("sinks", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		if key == "proto" {
+			return encoded, nil
+		}
+		return []byte(key), nil
+	}))
+
+	var s string
+	if err := g.GetInto("Tom", StringSink(&s)); err != nil || s != "Tom" {
+		t.Fatalf("StringSink = %q, %v", s, err)
+	}
+	var b []byte
+	if err := g.GetInto("Tom", ByteSliceSink(&b)); err != nil || string(b) != "Tom" {
+		t.Fatalf("ByteSliceSink = %q, %v", b, err)
+	}
+	b[0] = 'X' // 修改得到的副本不影响缓存
+	if v, _ := g.Get("Tom"); v.String() != "Tom" {
+		t.Fatalf("cached value changed to %q", v.String())
+	}
+	got := &pb.Response{}
+	if err := g.GetInto("proto", ProtoSink(got)); err != nil || !proto.Equal(got, want) {
+		t.Fatalf("ProtoSink = %v, %v", got, err)
+	}
+}
+
+// 测试超过单个条目大小上限的值按策略直接返回或被拒绝
+func TestMaxEntryBytes(t *testing.T) {
+	loads := 0
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte(key), nil
+	})
+
+	g := NewGroup("maxentry-pass", 2<<10, getter, WithMaxEntryBytes(4, OversizePassThrough))
+	for i := 0; i < 2; i++ {
+		if v, err := g.Get("oversized"); err != nil || v.String() != "oversized" {
+			t.Fatalf("Get = %q, %v", v.String(), err)
+		}
+	}
+	if loads != 2 {
+		t.Fatalf("oversized value was cached: %d loads", loads)
+	}
+	g.Get("Tom")
+	g.Get("Tom")
+	if loads != 3 {
+		t.Fatalf("small value was not cached: %d loads", loads)
+	}
+	if _, err := g.Set("Tom", []byte("oversized")); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := g.Get("Tom"); v.String() != "Tom" || loads != 4 {
+		t.Fatalf("stale value %q served after an oversized Set", v.String())
+	}
+
+	r := NewGroup("maxentry-reject", 2<<10, getter, WithMaxEntryBytes(4, OversizeReject))
+	if _, err := r.Get("oversized"); !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("Get err = %v, want ErrValueTooLarge", err)
+	}
+	if _, err := r.Set("Tom", []byte("oversized")); !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("Set err = %v, want ErrValueTooLarge", err)
+	}
+}
+
+// 测试 LFU 淘汰策略下一批只访问一次的键不会把热点键挤出缓存
+func TestLFUPolicy(t *testing.T) {
+	loads := make(map[string]int)
+	g := NewGroup("lfu", 8, GetterFunc(func(key string) ([]byte, error) {
+		loads[key]++
+		return []byte("v"), nil
+	}), WithEvictionPolicy(LFUPolicy))
+
+	for i := 0; i < 3; i++ {
+		g.Get("hot")
+	}
+	for _, key := range []string{"a", "b", "c", "d"} {
+		g.Get(key)
+	}
+	g.Get("hot")
+	if loads["hot"] != 1 {
+		t.Fatalf("hot key loaded %d times, want it to stay cached", loads["hot"])
+	}
+}
+
+// 测试采样近似 LRU 策略在读锁下记录的命中会影响之后的淘汰
+func TestSampledLRUPolicy(t *testing.T) {
+	loads := make(map[string]int)
+	g := NewGroup("sampled", 6, GetterFunc(func(key string) ([]byte, error) {
+		loads[key]++
+		return []byte("v"), nil
+	}), WithEvictionPolicy(SampledLRUPolicy(8)))
+
+	g.Get("a")
+	g.Get("b")
+	g.Get("c")
+	g.Get("a") // 命中只更新访问序号
+	g.Get("d") // 淘汰最久未访问的 b
+	g.Get("a")
+	if loads["a"] != 1 || g.mainCache.contains("b") {
+		t.Fatalf("loads = %v, want b evicted and a kept", loads)
+	}
+}
+
+// 测试内存段存储引擎的索引在大量写入、覆盖和删除之后与 map 保持一致
+func TestArenaStore(t *testing.T) {
+	s := newArenaStore(0, clock.Real)
+	want := make(map[string]string)
+	for i := 0; i < 5000; i++ {
+		key := fmt.Sprintf("k%d", i%700)
+		switch i % 5 {
+		case 0, 1, 2:
+			value := strings.Repeat("v", i%37)
+			s.AddWithExpire(key, ByteView{s: value}, time.Time{})
+			want[key] = value
+		default:
+			if _, ok := want[key]; s.Remove(key) != ok {
+				t.Fatalf("Remove(%q) disagrees with the model", key)
+			}
+			delete(want, key)
+		}
+	}
+	var nbytes int64
+	for key, value := range want {
+		if v, ok := s.Peek(key); !ok || v.(ByteView).String() != value {
+			t.Fatalf("Peek(%q) = %v, %v; want %q", key, v, ok, value)
+		}
+		nbytes += int64(len(key) + len(value))
+	}
+	if s.Len() != len(want) || s.Bytes() != nbytes {
+		t.Fatalf("Len, Bytes = %d, %d; want %d, %d", s.Len(), s.Bytes(), len(want), nbytes)
+	}
+	if s.Contains("missing") {
+		t.Fatal("missing key should not be found")
+	}
+}
+
+// 测试内存段用完时按写入顺序整段淘汰，过期的条目视为不存在
+func TestArenaPolicy(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	var evicted []string
+	g := NewGroup("arena", 4<<10, GetterFunc(func(key string) ([]byte, error) {
+		return bytes.Repeat([]byte("v"), 200), nil
+	}), WithEvictionPolicy(ArenaPolicy), WithClock(clk), WithEvictionCallback(func(key string, value ByteView) {
+		evicted = append(evicted, key)
+	}))
+
+	for i := 0; i < 20; i++ {
+		if v, err := g.Get(fmt.Sprintf("key%02d", i)); err != nil || v.Len() != 200 {
+			t.Fatalf("Get = %d bytes, %v", v.Len(), err)
+		}
+	}
+	if len(evicted) == 0 || evicted[0] != "key00" {
+		t.Fatalf("evicted = %v, want the earliest writes evicted first", evicted)
+	}
+	if !g.mainCache.contains("key19") || g.mainCache.bytes() > 4<<10 {
+		t.Fatalf("latest key should be cached within the limit, bytes = %d", g.mainCache.bytes())
+	}
+
+	g.mainCache.add("ttl", ByteView{s: "x"}, clk.Now().Add(time.Second))
+	if exp, ok := g.mainCache.expiration("ttl"); !ok || !exp.Equal(clk.Now().Add(time.Second)) {
+		t.Fatalf("expiration = %v, %v", exp, ok)
+	}
+	clk.Advance(time.Second)
+	if g.mainCache.contains("ttl") {
+		t.Fatal("expired entry should not be found")
+	}
+}
+
+// 测试 TinyLFU 准入策略拒绝只访问一次的键，LRU 中的热点键不会被挤出缓存
+func TestAdmissionPolicy(t *testing.T) {
+	loads := make(map[string]int)
+	g := NewGroup("tinylfu", 8, GetterFunc(func(key string) ([]byte, error) {
+		loads[key]++
+		return []byte("v"), nil
+	}), WithAdmissionPolicy(tinylfu.New(1000)))
+
+	for i := 0; i < 3; i++ {
+		g.Get("hot")
+	}
+	for _, key := range []string{"a", "b", "c", "d"} {
+		if v, err := g.Get(key); err != nil || v.String() != "v" {
+			t.Fatalf("Get(%q) = %q, %v", key, v.String(), err)
+		}
+	}
+	g.Get("hot")
+	if loads["hot"] != 1 {
+		t.Fatalf("hot key loaded %d times, want it to stay cached", loads["hot"])
+	}
+	if g.Len() != 3 {
+		t.Fatalf("Len = %d, want the rejected keys left uncached", g.Len())
+	}
+}
+
+// 测试主缓存淘汰或删除条目时调用缓存组的淘汰回调，压缩过的值在回调中已经解压
+func TestEvictionCallback(t *testing.T) {
+	evicted := make(map[string]string)
+	big := strings.Repeat("v", 100)
+	g := NewGroup("evictcb", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(big), nil
+	}), WithCompression(Gzip, 0), WithEvictionCallback(func(key string, value ByteView) {
+		evicted[key] = value.String()
+	}))
+	for _, key := range []string{"k1", "k2", "k3"} {
+		g.Get(key)
+	}
+	if _, err := g.Remove("k3"); err != nil {
+		t.Fatal(err)
+	}
+	if len(evicted) != 1 || evicted["k3"] != big {
+		t.Fatalf("after Remove: evicted = %v", evicted)
+	}
+	g.SetCacheBytes(1) // 淘汰剩下的所有条目
+	if len(evicted) != 3 || evicted["k1"] != big || evicted["k2"] != big {
+		t.Fatalf("after SetCacheBytes: evicted %d entries", len(evicted))
+	}
+}
+
+// 测试订阅缓存组事件，取消订阅之后不再收到事件
+func TestSubscribe(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	big := strings.Repeat("v", 100)
+	g := NewGroup("events", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		if key == "missing" {
+			return nil, ErrNotFound
+		}
+		return []byte(big), nil
+	}), WithClock(clk), WithExpiration(time.Minute), WithNegativeTTL(time.Minute))
+
+	var got []string
+	record := func(e Event) {
+		s := e.Type.String() + ":" + e.Key
+		if e.Err != nil {
+			s += ":err"
+		}
+		got = append(got, s)
+	}
+	var unsubs []func()
+	for _, typ := range []EventType{EventHit, EventMiss, EventLoad, EventEvict, EventExpire} {
+		unsubs = append(unsubs, g.Subscribe(typ, record))
+	}
+
+	g.Get("k1")      // miss、load
+	g.Get("k1")      // hit
+	g.Get("missing") // miss、加载失败
+	g.Get("missing") // 负缓存命中
+	clk.Advance(2 * time.Minute)
+	g.Get("k1")        // 过期、miss、load
+	g.SetCacheBytes(1) // 淘汰 k1
+	want := []string{
+		"miss:k1", "load:k1", "hit:k1",
+		"miss:missing", "load:missing:err", "hit:missing:err",
+		"expire:k1", "miss:k1", "load:k1",
+		"evict:k1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+
+	for _, unsub := range unsubs {
+		unsub()
+		unsub() // 重复取消订阅没有影响
+	}
+	g.Get("k2")
+	if len(got) != len(want) {
+		t.Fatalf("received %v after unsubscribe", got[len(want):])
+	}
+}
+
+// 测试快照的保存和恢复：保留过期时间，跳过已经过期的条目，损坏的快照不写入任何条目
+func TestSnapshot(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		return []byte("value-" + key), nil
+	})
+	src := NewGroup("snapshot-src", 0, getter, WithClock(clk), WithExpiration(time.Minute), WithCompression(Gzip, 0))
+	src.Get("a")
+	clk.Advance(30 * time.Second)
+	src.Get("b")
+	var buf bytes.Buffer
+	if err := src.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	clk.Advance(45 * time.Second) // a 已经过期，b 还剩 15 秒
+	dst := NewGroup("snapshot-dst", 0, getter, WithClock(clk))
+	data := buf.Bytes()
+	corrupt := append([]byte(nil), data...)
+	corrupt[len(corrupt)-10] ^= 0xff
+	if err := dst.Restore(bytes.NewReader(corrupt)); !errors.Is(err, ErrBadSnapshot) || dst.Len() != 0 {
+		t.Fatalf("Restore of a corrupt snapshot = %v, Len() = %d", err, dst.Len())
+	}
+	if err := dst.Restore(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if dst.Len() != 1 {
+		t.Fatalf("Len() = %d, want only b restored", dst.Len())
+	}
+	if ttl, err := dst.TTL("b"); err != nil || ttl != 15*time.Second {
+		t.Fatalf("TTL(b) = %v, %v, want 15s", ttl, err)
+	}
+	if v, ok := dst.mainCache.peek("b"); !ok || v.String() != "value-b" {
+		t.Fatalf("restored b = %q", v.String())
+	}
+
+	// 定期写入快照，重启时从快照恢复
+	path := filepath.Join(t.TempDir(), "cache.snap")
+	g := NewGroup("snapshot-file", 0, getter, WithClock(clk), WithSnapshotFile(path, time.Minute))
+	g.Get("c")
+	if err := g.snapshotFile(path); err != nil {
+		t.Fatal(err)
+	}
+	DeregisterGroup("snapshot-file")
+	g = NewGroup("snapshot-file", 0, getter, WithClock(clk), WithSnapshotFile(path, time.Minute))
+	if !g.mainCache.contains("c") {
+		t.Fatal("a new group should restore c from the snapshot file")
+	}
+}
+
+// 测试异步写回：写入立即可见，队列满时拒绝写入，同一批中的同一个键只写回一次，失败后重试
+func TestWriteBehind(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	stored := make(map[string]string)
+	started, release := make(chan struct{}), make(chan struct{})
+	failed := false
+	setter := SetterFunc(func(key string, value []byte) error {
+		if key == "a" {
+			close(started)
+			<-release // 让第一批写回阻塞，之后的写入留在队列中
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if key == "r" && !failed {
+			failed = true
+			return fmt.Errorf("backend unavailable")
+		}
+		calls = append(calls, key)
+		stored[key] = string(value)
+		return nil
+	})
+	g := NewGroup("writebehind", 0, GetterFunc(func(key string) ([]byte, error) {
+		return nil, ErrNotFound
+	}), WithWriteBehind(setter, 2, 10), WithLogger(NopLogger))
+
+	if _, err := g.Set("a", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	g.Set("b", []byte("1"))
+	g.Set("b", []byte("2"))
+	if _, err := g.Set("c", []byte("1")); err != ErrWriteQueueFull || g.mainCache.contains("c") {
+		t.Fatalf("Set with a full queue: err = %v", err)
+	}
+	if v, err := g.Get("b"); err != nil || v.String() != "2" {
+		t.Fatalf("Get(b) = %q, %v before the write is persisted", v.String(), err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	close(release)
+	if err := g.WaitWrites(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(calls, []string{"a", "b"}) || stored["b"] != "2" {
+		t.Fatalf("calls = %v, stored = %v", calls, stored)
+	}
+
+	g.Set("r", []byte("1"))
+	if err := g.WaitWrites(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if stored["r"] != "1" {
+		t.Fatal("a failed write should be retried")
+	}
+
+	// 并发写入同一个键时，写回数据源的顺序与写入缓存的顺序一致
+	ordered := NewGroup("writebehind-order", 0, GetterFunc(func(key string) ([]byte, error) {
+		return nil, ErrNotFound
+	}), WithWriteBehind(setter, 1000, 10), WithLogger(NopLogger))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				ordered.Set("k", []byte(fmt.Sprint(i, "-", j)))
+			}
+		}()
+	}
+	wg.Wait()
+	if err := ordered.WaitWrites(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := ordered.Get("k"); v.String() != stored["k"] {
+		t.Fatalf("cached %q, written back %q", v.String(), stored["k"])
+	}
+}
+
+// 测试临近过期的条目在被读取时提前刷新，以及 KeepFresh 定期刷新指定的键
+func TestRefreshAhead(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	var loads atomic.Int64
+	g := NewGroup("refresh", 0, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(fmt.Sprint(loads.Add(1))), nil
+	}), WithClock(clk), WithExpiration(time.Minute), WithRefreshAhead(0.2))
+	waitLoads := func(want int64) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); loads.Load() < want; {
+			if time.Now().After(deadline) {
+				t.Fatalf("loads = %d, want %d", loads.Load(), want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	g.Get("k")
+	clk.Advance(30 * time.Second)
+	g.Get("k") // 剩余 30 秒，不刷新
+	clk.Advance(20 * time.Second)
+	if v, _ := g.Get("k"); v.String() != "1" { // 剩余 10 秒，返回旧值并在后台刷新
+		t.Fatalf("Get(k) = %q, want the cached value", v.String())
+	}
+	waitLoads(2)
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		if ttl, _ := g.TTL("k"); ttl == time.Minute {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("TTL(k) = %v after refresh, want 1m", ttl)
+		}
+	}
+
+	stop := g.KeepFresh(10*time.Second, "hot")
+	defer stop()
+	clk.Advance(10 * time.Second)
+	waitLoads(3)
+	if loads.Load() != 3 {
+		t.Fatalf("loads = %d, want one refresh of hot", loads.Load())
+	}
+}
+
+// 测试 PrefetchWait 并发加载所有键，Refresh 用数据源的新值替换缓存，数据源中已经删除的键从缓存中删除，冻结时返回 ErrFrozen
+func TestRefresh(t *testing.T) {
+	var mu sync.Mutex
+	source := map[string]string{"a": "1", "b": "2", "c": "3"}
+	var inflight, peak atomic.Int64
+	g := NewGroup("refresh-explicit", 0, GetterFunc(func(key string) ([]byte, error) {
+		if n := inflight.Add(1); n > peak.Load() {
+			peak.Store(n)
+		}
+		defer inflight.Add(-1)
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		v, ok := source[key]
+		if !ok {
+			return nil, ErrNotFound
+		}
+		return []byte(v), nil
+	}), WithPrefetchConcurrency(3))
+
+	if err := g.PrefetchWait(context.Background(), "a", "b", "c"); err != nil {
+		t.Fatalf("PrefetchWait: %v", err)
+	}
+	if g.Len() != 3 {
+		t.Fatalf("Len() = %d after PrefetchWait, want 3", g.Len())
+	}
+	if peak.Load() < 2 {
+		t.Fatalf("peak concurrent loads = %d, want keys loaded concurrently", peak.Load())
+	}
+
+	mu.Lock()
+	source["a"] = "10"
+	delete(source, "b")
+	mu.Unlock()
+	if v, _ := g.Get("a"); v.String() != "1" {
+		t.Fatalf("Get(a) = %q before Refresh, want the cached value", v.String())
+	}
+	if v, err := g.Refresh("a"); err != nil || v.String() != "10" {
+		t.Fatalf("Refresh(a) = %q, %v, want 10", v.String(), err)
+	}
+	if v, _ := g.Get("a"); v.String() != "10" {
+		t.Fatalf("Get(a) = %q after Refresh, want 10", v.String())
+	}
+	if _, err := g.Refresh("b"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Refresh(b) error = %v, want ErrNotFound", err)
+	}
+	if g.mainCache.contains("b") {
+		t.Fatal("b should be removed after the source reported it missing")
+	}
+
+	g.Freeze()
+	if _, err := g.Refresh("c"); !errors.Is(err, ErrFrozen) {
+		t.Fatalf("Refresh on frozen group error = %v, want ErrFrozen", err)
 	}
 }
@@ -0,0 +1,91 @@
+package geecache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// handoffScanCount 是移交条目时每次遍历的键数量。
+const handoffScanCount = 256
+
+// maxPushBytes 是对端接受的单个推送条目的最大字节数。
+const maxPushBytes = 64 << 20
+
+// Handoff 在本节点计划下线之前，把本节点缓存的所有条目推送给它们的新所有者，
+// 新所有者按去掉本节点之后的节点列表计算。这样有计划的缩容不会在其他节点上造成大量未命中。
+// 新所有者已经缓存了 key 时保留它自己的值，不会被移交的旧值覆盖。
+// 单个条目推送失败不会中止移交，Handoff 会继续推送剩余的条目并在最后返回失败的汇总；
+// ctx 结束时立即停止。Handoff 不会修改本节点的节点列表，调用方应在移交完成之后再下线本节点。
+func (p *HTTPPool) Handoff(ctx context.Context) error {
+	p.mu.Lock()
+	var others []string
+	for _, peer := range p.peerList() {
+		if peer != p.self {
+			others = append(others, peer)
+		}
+	}
+	getters := make(map[string]*httpGetter, len(others))
+	for _, peer := range others {
+		getters[peer] = p.httpGetters[peer]
+	}
+	p.mu.Unlock()
+	if len(others) == 0 {
+		return nil // 没有其他节点可以接收条目
+	}
+
+	ring := p.newRing()
+	p.addToRing(ring, others)
+
+	var pushed, failed int
+	var firstErr error
+	for _, g := range p.registry.list() {
+		var cursor uint64
+		for {
+			keys, next := g.Scan(cursor, "", handoffScanCount)
+			for _, key := range keys {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				value, ok := g.mainCache.peek(key)
+				if !ok {
+					continue // 遍历期间已经被淘汰
+				}
+				value, err := g.decompress(value)
+				if err != nil {
+					continue
+				}
+				owner := getters[ring.Get(key)]
+				if err := owner.offer(ctx, g.name, key, value.ByteSlice()); err != nil {
+					failed++
+					if firstErr == nil {
+						firstErr = err
+					}
+					continue
+				}
+				pushed++
+			}
+			if next == 0 {
+				break
+			}
+			cursor = next
+		}
+	}
+	p.Log("handoff pushed %d entries, %d failed", pushed, failed)
+	if failed > 0 {
+		return fmt.Errorf("handoff: %d of %d entries failed: %v", failed, pushed+failed, firstErr)
+	}
+	return nil
+}
+
+// Push 把条目通过带有移交标记的 PUT 请求写入对端的缓存，对端不会把它写入数据源。
+func (h *httpGetter) Push(ctx context.Context, group string, key string, value []byte) error {
+	_, err := h.put(ctx, group, key, value, http.Header{handoffHeader: {"1"}})
+	return err
+}
+
+// offer 与 Push 相同，但对端已经缓存了 key 时保留对端的值，用于把条目移交给新所有者。
+func (h *httpGetter) offer(ctx context.Context, group string, key string, value []byte) error {
+	_, err := h.put(ctx, group, key, value, http.Header{handoffHeader: {"1"}, ifAbsentHeader: {"1"}})
+	return err
+}
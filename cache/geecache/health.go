@@ -0,0 +1,106 @@
+package geecache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// healthPath 是节点的健康检查路径，位于 basePath 之下。
+const healthPath = "_health"
+
+// CheckHealth 探测所有其他节点的健康检查路径，更新它们的健康状态，返回当前不健康的节点。
+// PickPeer 会跳过不健康的节点，把它们的键交给环上的下一个健康节点，下一个节点是本节点时在本地加载。
+func (p *HTTPPool) CheckHealth(ctx context.Context) (unhealthy []string) {
+	p.mu.Lock()
+	peers := p.peerList()
+	getters := make(map[string]*httpGetter, len(p.httpGetters))
+	for peer, g := range p.httpGetters {
+		getters[peer] = g
+	}
+	p.mu.Unlock()
+
+	for _, peer := range peers {
+		if peer == p.self {
+			continue
+		}
+		g := getters[peer]
+		err := g.checkHealth(ctx)
+		if wasUnhealthy := g.unhealthy.Swap(err != nil); wasUnhealthy != (err != nil) {
+			if err != nil {
+				p.logger.Errorf("[Server %s] peer %s is unhealthy: %v", p.self, peer, err)
+			} else {
+				p.logger.Infof("[Server %s] peer %s is healthy again", p.self, peer)
+			}
+		}
+		if err != nil {
+			unhealthy = append(unhealthy, peer)
+		}
+	}
+	return unhealthy
+}
+
+// StartHealthCheck 启动一个后台 goroutine，每隔 interval 调用一次 CheckHealth，每次探测的超时时间为 interval。
+// 返回的 stop 函数用于停止该 goroutine。
+func (p *HTTPPool) StartHealthCheck(interval time.Duration) (stop func()) {
+	ticker := p.clock.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C():
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				p.CheckHealth(ctx)
+				cancel()
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+	}
+}
+
+// failover 在 key 的所有者不健康时沿着哈希环选择下一个健康的节点，下一个节点是本节点时返回 false。
+// 调用方需要持有 p.mu。
+func (p *HTTPPool) failover(key string) (PeerGetter, bool) {
+	for _, peer := range p.peers.GetN(key, len(p.httpGetters)) {
+		if peer == p.self {
+			return nil, false
+		}
+		if g := p.httpGetters[peer]; !g.unhealthy.Load() {
+			return g, true
+		}
+	}
+	return nil, false // 所有节点都不健康，在本地加载
+}
+
+// serveHealth 响应其他节点的健康检查。
+func (p *HTTPPool) serveHealth(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok"))
+}
+
+// checkHealth 请求对端的健康检查路径，对端无法访问或没有返回 200 时返回错误。
+func (h *httpGetter) checkHealth(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.baseURL+healthPath, nil)
+	if err != nil {
+		return err
+	}
+	res, err := h.do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned: %v", res.Status)
+	}
+	return nil
+}
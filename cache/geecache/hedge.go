@@ -0,0 +1,143 @@
+package geecache
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// hedgeWindow 是计算对冲延迟时保留的最近的从远程节点获取成功的耗时数量，
+// 每记录 hedgeRecompute 个新的耗时重新计算一次对冲延迟。
+const (
+	hedgeWindow    = 256
+	hedgeRecompute = 16
+)
+
+// WithHedgedRequests 让从所有者获取的请求在超过对冲延迟还没有返回时，向下一个副本节点再发出一个只读取缓存的请求，
+// 采用先成功返回的结果，另一个请求随即取消。副本没有缓存 key 时对冲请求直接失败，继续等待所有者，
+// 对冲不会让副本转发给所有者或者访问数据源，不会在所有者变慢时额外增加负载。对冲延迟是最近从远程节点获取成功的耗时的第 percentile 个百分位数
+// （例如 0.95），不小于 minDelay；还没有足够的耗时记录时使用 minDelay。
+// 这样偶尔变慢的节点（GC 停顿、网络抖动）不会拖慢尾延迟，代价是大约 1-percentile 的请求会多发一次。
+// 副本节点由 WithReplication 决定，没有其他实现了 PeerPeeker 的副本节点时不对冲。
+func WithHedgedRequests(percentile float64, minDelay time.Duration) GroupOption {
+	return func(g *Group) {
+		g.hedge = newHedger(percentile, minDelay)
+	}
+}
+
+// hedger 记录从远程节点获取的耗时并计算对冲延迟。
+type hedger struct {
+	percentile float64
+	minDelay   time.Duration
+	delay      atomic.Int64 // 当前的对冲延迟（纳秒）
+
+	mu      sync.Mutex
+	samples [hedgeWindow]time.Duration // 最近的耗时，循环写入
+	n       int                        // 已经记录的耗时总数
+}
+
+func newHedger(percentile float64, minDelay time.Duration) *hedger {
+	h := &hedger{percentile: min(max(percentile, 0), 1), minDelay: minDelay}
+	h.delay.Store(int64(minDelay))
+	return h
+}
+
+// observe 记录一次从远程节点获取成功的耗时。
+func (h *hedger) observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.samples[h.n%hedgeWindow] = d
+	h.n++
+	if h.n%hedgeRecompute != 0 {
+		return
+	}
+	sorted := slices.Clone(h.samples[:min(h.n, hedgeWindow)])
+	slices.Sort(sorted)
+	i := max(int(math.Ceil(h.percentile*float64(len(sorted))))-1, 0) // 最近秩法
+	h.delay.Store(int64(max(sorted[i], h.minDelay)))
+}
+
+// hedgeResult 是对冲的两个请求之一的结果。
+type hedgeResult struct {
+	value ByteView
+	err   error
+}
+
+// getFromPeerHedged 从所有者 owner 获取 key，设置了 WithHedgedRequests 时超过对冲延迟向下一个副本节点发出对冲请求。
+// 所有者确认 key 不存在时直接返回 ErrNotFound；两个请求都失败时返回所有者的错误。
+func (g *Group) getFromPeerHedged(ctx context.Context, owner PeerGetter, key string) (ByteView, error) {
+	h := g.hedge
+	if h == nil {
+		return g.getFromPeerWithRetry(ctx, owner, key)
+	}
+	var backup PeerGetter
+	for _, peer := range g.replicas(key) {
+		if _, ok := peer.(PeerPeeker); ok && peer != owner {
+			backup = peer
+			break
+		}
+	}
+	start := g.clock.Now()
+	if backup == nil {
+		value, err := g.getFromPeerWithRetry(ctx, owner, key)
+		if err == nil {
+			h.observe(g.clock.Now().Sub(start))
+		}
+		return value, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // 取消还没有返回的请求
+	primary := make(chan hedgeResult, 1)
+	go func() {
+		value, err := g.getFromPeerWithRetry(ctx, owner, key)
+		primary <- hedgeResult{value, err}
+	}()
+
+	ticker := g.clock.NewTicker(time.Duration(h.delay.Load()))
+	defer ticker.Stop()
+	select {
+	case r := <-primary:
+		if r.err == nil {
+			h.observe(g.clock.Now().Sub(start))
+		}
+		return r.value, r.err
+	case <-ticker.C():
+	case <-ctx.Done():
+		return ByteView{}, ctx.Err()
+	}
+
+	g.stats.hedges.Add(1)
+	hedged := make(chan hedgeResult, 1)
+	go func() {
+		value, err := g.peekFromPeer(ctx, backup, key) // 只读取副本已经缓存的值
+		hedged <- hedgeResult{value, err}
+	}()
+	var ownerErr error
+	for pending := 2; pending > 0; pending-- {
+		var r hedgeResult
+		select {
+		case r = <-primary:
+			primary = nil
+			if r.err != nil {
+				if errors.Is(r.err, ErrNotFound) {
+					return ByteView{}, r.err // 所有者的结论是权威的
+				}
+				ownerErr = r.err
+				continue
+			}
+		case r = <-hedged:
+			hedged = nil
+			if r.err != nil {
+				continue
+			}
+		}
+		h.observe(g.clock.Now().Sub(start))
+		return r.value, nil
+	}
+	return ByteView{}, ownerErr
+}
@@ -0,0 +1,53 @@
+package geecache
+
+import "math/rand"
+
+// hotCacheRatio 是热点缓存默认占 cacheBytes 的比例的倒数：热点缓存的容量为 cacheBytes 的 1/8。
+const hotCacheRatio = 8
+
+// promoteToHot 决定一次从远程节点获取的值是否写入热点缓存。
+// 与 groupcache 相同，只有约 1/10 的远程读取会写入，经常被读取的键才会大概率留在热点缓存中。
+var promoteToHot = func() bool {
+	return rand.Intn(10) == 0
+}
+
+// defaultHotCacheBytes 返回容量为 cacheBytes 的缓存组默认的热点缓存容量。
+// cacheBytes 太小以至于分不出热点缓存时关闭热点缓存，而不是让它变成不限容量。
+func defaultHotCacheBytes(cacheBytes int64) int64 {
+	if cacheBytes > 0 && cacheBytes/hotCacheRatio == 0 {
+		return -1
+	}
+	return cacheBytes / hotCacheRatio
+}
+
+// WithHotCacheBytes 设置热点缓存的容量，默认为 cacheBytes 的 1/8。
+// 热点缓存保存所有者是其他节点、但在本节点被频繁读取的键，避免热点键的每次读取都访问所有者节点。
+// n < 0 表示关闭热点缓存，0 表示不限制容量。
+func WithHotCacheBytes(n int64) GroupOption {
+	return func(g *Group) {
+		g.hotCache.cacheBytes = n
+	}
+}
+
+// hotCacheEnabled 报告缓存组是否启用了热点缓存。
+func (g *Group) hotCacheEnabled() bool {
+	return g.hotCache.cacheBytes >= 0
+}
+
+// populateHotCache 按概率把从远程节点获取的值写入热点缓存。缓存组被冻结时不会写入。
+func (g *Group) populateHotCache(key string, value ByteView) {
+	if !g.hotCacheEnabled() || g.frozen.Load() || g.oversized(value) || !promoteToHot() {
+		return
+	}
+	g.hotCache.add(key, g.compress(value), g.expiresAt())
+}
+
+// HotLen 返回热点缓存中的条目数量。
+func (g *Group) HotLen() int {
+	return g.hotCache.len()
+}
+
+// HotBytes 返回热点缓存当前已经使用的内存大小。
+func (g *Group) HotBytes() int64 {
+	return g.hotCache.bytes()
+}
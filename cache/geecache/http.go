@@ -1,10 +1,20 @@
 package geecache
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"testProject/cache/clock"
+	consistenthashgo "testProject/cache/consistenthash.go"
+	pb "testProject/cache/geecachepb"
 )
 
 // const defaultBasePath = "/_geecache/"
@@ -16,60 +26,373 @@ import (
 // 	basePath string //作为节点间通讯地址的前缀，默认是 /_geecache/
 // }
 
+// HTTPPoolOption 用于在创建 HTTPPool 时定制其配置。
+type HTTPPoolOption func(*HTTPPool)
+
+// WithReplicas 设置一致性哈希中每个真实节点对应的虚拟节点数量。
+// 小集群需要更多虚拟节点才能分布均匀，大集群则可以减少虚拟节点以降低 Set 的开销。
+func WithReplicas(n int) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		if n > 0 {
+			p.replicas = n
+		}
+	}
+}
+
+// WithHashFn 设置一致性哈希使用的散列函数，默认使用 CRC32，也可以使用分布更均匀的 consistenthashgo.XXHash。
+func WithHashFn(fn consistenthashgo.Hash) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.hashFn = fn
+	}
+}
+
+// WithStrategy 设置把键映射到节点的策略，默认为 consistenthashgo.RingStrategy（带虚拟节点的哈希环），
+// 也可以使用 RendezvousStrategy、JumpStrategy 或 MaglevStrategy，各自的取舍见 consistenthashgo 包。
+// 集群中的所有节点必须使用相同的策略。
+func WithStrategy(fn consistenthashgo.StrategyFunc) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.strategy = fn
+	}
+}
+
+// WithPeerWeights 设置节点在一致性哈希中的权重，键是节点的地址（与 Set 的参数相同），没有列出的节点权重为 1。
+// 权重为 w 的节点分到的键的数量大致与权重成正比，例如在哈希环中有 w 倍的虚拟节点，见 consistenthashgo.Strategy。
+// 集群中的所有节点必须使用相同的权重，否则同一个键在不同节点上会路由到不同的所有者。
+func WithPeerWeights(weights map[string]int) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.weights = make(map[string]int, len(weights))
+		for peer, w := range weights {
+			p.weights[peer] = w
+		}
+	}
+}
+
+// WithBasePath 设置节点间通信使用的路径前缀，默认为 "/_geecache/"，必须以 "/" 开头和结尾。
+// 集群中的所有节点以及访问集群的客户端必须使用相同的前缀。
+func WithBasePath(path string) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		if strings.HasPrefix(path, "/") && strings.HasSuffix(path, "/") {
+			p.basePath = path
+		}
+	}
+}
+
+// WithPoolRegistry 让 HTTPPool 在 r 中查找请求的缓存组，默认为 DefaultRegistry。
+// Handoff 也只移交 r 中的缓存组。
+func WithPoolRegistry(r *Registry) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.registry = r
+	}
+}
+
+// WithMaxHops 设置节点间请求允许的最大转发跳数。
+func WithMaxHops(n int) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		if n > 0 {
+			p.maxHops = n
+		}
+	}
+}
+
+// WithHTTPClient 设置向对等节点发起请求使用的 HTTP 客户端，所有节点共享同一个客户端及其连接池。
+// 默认使用针对节点间通信调优的客户端：带有连接、握手和响应头超时，开启 keep-alive 并保留较多的空闲连接。
+func WithHTTPClient(c *http.Client) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.client = c
+	}
+}
+
 // NewHTTPPool 创建并初始化一个 HTTPPool 实例。
-func NewHTTPPool(self string) *HTTPPool {
-	return &HTTPPool{
+func NewHTTPPool(self string, opts ...HTTPPoolOption) *HTTPPool {
+	p := &HTTPPool{
 		self:     self,
 		basePath: defaultBasePath,
+		maxHops:  defaultMaxHops,
+		replicas: defaultReplicas,
+		clock:    clock.Real,
+		logger:   StdLogger,
+		registry: DefaultRegistry,
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.tlsConfig != nil && p.client == nil {
+		p.client = &http.Client{Transport: newPeerTransport(p.tlsConfig), Timeout: defaultClientTimeout}
+	}
+	return p
 }
 
 // Log 用于记录带有服务器名称的日志信息。
 // 它接受一个格式字符串和可选的参数，并使用服务器名称格式化日志消息。
 func (p *HTTPPool) Log(format string, v ...interface{}) {
-	log.Printf("[Server %s] %s", p.self, fmt.Sprintf(format, v...))
+	p.logger.Debugf("[Server %s] %s", p.self, fmt.Sprintf(format, v...))
+}
+
+// parsePath 从转义后的原始路径中解析出组名和键。
+// 必须使用转义后的路径：解码后的 r.URL.Path 无法区分键中的 "/" 和路径分隔符。
+func (p *HTTPPool) parsePath(u *url.URL) (group, key string, err error) {
+	escaped := u.EscapedPath()
+	if !strings.HasPrefix(escaped, p.basePath) {
+		return "", "", fmt.Errorf("unexpected path %q", escaped)
+	}
+	parts := strings.Split(escaped[len(p.basePath):], "/")
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("want <group>/<key>, got %q", escaped[len(p.basePath):])
+	}
+	if group, err = url.PathUnescape(parts[0]); err != nil {
+		return "", "", err
+	}
+	if key, err = url.PathUnescape(parts[1]); err != nil {
+		return "", "", err
+	}
+	return group, key, nil
 }
 
-// ServeHTTP 处理所有的 HTTP 请求。
-// 它接受一个 HTTP 响应写入器（w）和 HTTP 请求（r）作为参数。
+// escapeSegment 把组名或键转义为一个路径段，任意字节（包括 "/"、"%" 和非 UTF-8 字节）都能原样还原。
+// "." 和 ".." 会被路由器当作相对路径清理掉，因此其中的点也被转义。
+func escapeSegment(s string) string {
+	if s == "." || s == ".." {
+		return strings.Repeat("%2E", len(s))
+	}
+	return url.PathEscape(s)
+}
+
+// ServeHTTP 处理所有的 HTTP 请求，按路径和方法分发：
+//
+//	GET    <basePath>_health        健康检查
+//	GET    <basePath>_ring          节点列表视图
+//	POST   <basePath>_batch         批量读取多个键
+//	GET    <basePath><group>/<key>  读取条目
+//	HEAD   <basePath><group>/<key>  查询条目的元数据
+//	PUT    <basePath><group>/<key>  写入条目
+//	DELETE <basePath><group>/<key>  删除本地条目
+//
+// 未知的路径返回 404，不支持的方法返回 405。请求来自网络，任何输入都不会引起 panic。
 func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// 检查请求路径是否以指定的基本路径（basePath）开头。
+	// 不在基本路径（basePath）下的请求不属于 HTTPPool。
 	if !strings.HasPrefix(r.URL.Path, p.basePath) {
-		panic("HTTPPool serving unexpected path: " + r.URL.Path)
+		http.NotFound(w, r)
+		return
 	}
 	// 记录日志，包括 HTTP 方法和请求路径。
 	p.Log("%s %s", r.Method, r.URL.Path)
 
+	// 其他节点的健康检查。
+	if r.URL.Path == p.basePath+healthPath {
+		if allowMethods(w, r, http.MethodGet) {
+			p.serveHealth(w)
+		}
+		return
+	}
+	// 除健康检查以外的请求都需要认证。
+	if !p.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	// 其他节点查询本节点的节点列表视图，用于发现节点间路由不一致。
+	if r.URL.Path == p.basePath+ringPath {
+		if allowMethods(w, r, http.MethodGet) {
+			p.serveRing(w)
+		}
+		return
+	}
+	// 其他节点在一次请求中读取多个键。
+	if r.URL.Path == p.basePath+batchPath {
+		if allowMethods(w, r, http.MethodPost) {
+			p.serveBatch(w, r)
+		}
+		return
+	}
+
 	// 从请求路径中提取组名（groupName）和键（key）。
-	// 请求路径格式为 /<basepath>/<groupname>/<key>。
-	parts := strings.SplitN(r.URL.Path[len(p.basePath):], "/", 2)
-	if len(parts) != 2 {
-		// 如果路径不符合预期格式，返回 "bad request" 错误。
-		http.Error(w, "bad request", http.StatusBadRequest)
+	// 请求路径格式为 /<basepath>/<groupname>/<key>，组名和键都经过 escapeSegment 转义。
+	groupName, key, err := p.parsePath(r.URL)
+	if err != nil {
+		// 路径不是 <group>/<key> 的形式，不对应任何资源。
+		http.Error(w, "not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+	if !allowMethods(w, r, http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete) {
 		return
 	}
 
-	groupName := parts[0]
-	key := parts[1]
+	hops, ok := p.parseHops(w, r)
+	if !ok {
+		return
+	}
 
 	// 根据组名获取对应的缓存组（group）。
-	group := GetGroup(groupName)
+	group := p.registry.Get(groupName)
 	if group == nil {
 		// 如果找不到对应的组，返回 "no such group" 错误。
 		http.Error(w, "no such group: "+groupName, http.StatusNotFound)
 		return
 	}
 
+	switch r.Method {
+	case http.MethodHead:
+		// HEAD 请求只返回本节点已缓存条目的元数据，不触发加载，也不返回值。
+		p.serveMeta(w, group, key)
+	case http.MethodPut:
+		// PUT 请求是转发给所有者的写入，或者其他节点推送过来的条目（例如节点下线前的移交）。
+		p.servePut(w, r, group, key)
+	case http.MethodDelete:
+		// DELETE 请求是其他节点广播的失效，只删除本地缓存中的条目。
+		p.serveDelete(w, group, key)
+	default:
+		p.serveGet(w, r, group, key, hops)
+	}
+}
+
+// parseHops 读取请求已经过的转发跳数。请求头格式错误，或者跳数超过上限（说明节点间存在路由环路）时
+// 返回错误响应，ok 为 false。
+func (p *HTTPPool) parseHops(w http.ResponseWriter, r *http.Request) (hops int, ok bool) {
+	if h := r.Header.Get(hopsHeader); h != "" {
+		n, err := strconv.Atoi(h)
+		if err != nil || n < 0 {
+			http.Error(w, "bad hops header", http.StatusBadRequest)
+			return 0, false
+		}
+		hops = n
+	}
+	if hops > p.maxHops {
+		http.Error(w, "hop limit exceeded", http.StatusLoopDetected)
+		return 0, false
+	}
+	return hops, true
+}
+
+// requestContext 根据请求头构造处理请求使用的 context，携带转发跳数、一致性令牌以及请求方剩余的超时时间。
+// 在本节点上同样应用请求方的截止时间，避免请求方已经放弃之后本节点仍然继续加载数据。
+func requestContext(r *http.Request, hops int) (context.Context, context.CancelFunc, error) {
+	ctx := ContextWithHops(r.Context(), hops)
+	ctx = ContextWithToken(ctx, ConsistencyToken(r.Header.Get(tokenHeader)))
+	if d := r.Header.Get(deadlineHeader); d != "" {
+		ms, err := strconv.ParseInt(d, 10, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("bad deadline header")
+		}
+		ctx, cancel := context.WithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+		return ctx, cancel, nil
+	}
+	return ctx, func() {}, nil
+}
+
+// allowMethods 报告请求的方法是否在 methods 之中，不在时返回 405 并在 Allow 响应头中列出允许的方法。
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+// serveGet 读取 key 对应的条目，必要时触发加载。
+// 数据源报告 key 不存在时返回 404 并设置 notFoundHeader，与路径或缓存组不存在的 404 区分开。
+func (p *HTTPPool) serveGet(w http.ResponseWriter, r *http.Request, group *Group, key string, hops int) {
+	ctx, cancel, err := requestContext(r, hops)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer cancel()
+
 	// 使用组的 Get 方法获取指定键（key）的数据视图（view）。
-	view, err := group.Get(key)
+	// 副本读取只读取本地缓存，没有命中时直接返回，不会再转发或加载。
+	var view ByteView
+	if r.Header.Get(peekHeader) != "" {
+		view, err = group.GetLocal(key)
+	} else {
+		view, err = group.get(ctx, key)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(w, err))
+		return
+	}
+
+	// 响应体是 protobuf 编码的 Response，附带条目的剩余有效期。
+	// 编码时会把值复制到响应体中，这里直接引用视图的数据，不需要先复制一份。
+	res := &pb.Response{Value: view.bytes()}
+	if expires, ok := group.mainCache.expiration(key); ok && !expires.IsZero() {
+		res.TtlMs = expires.Sub(group.clock.Now()).Milliseconds()
+	}
+	p.respond(w, r, res)
+}
+
+// errorStatus 返回加载失败时的响应状态码：key 不存在返回 404，截止时间已过返回 504，
+// 暂时性错误返回 503 让请求方知道可以重试，其他错误返回 500。
+func errorStatus(w http.ResponseWriter, err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		w.Header().Set(notFoundHeader, "1")
+		return http.StatusNotFound
+	case errors.Is(err, ErrNotCached):
+		w.Header().Set(notCachedHeader, "1")
+		return http.StatusNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout // 请求方已不再等待结果
+	case IsRetryable(err):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeResponse 把 protobuf 编码的响应写入 w，并在响应体之后发送校验和 trailer。
+func writeResponse(w http.ResponseWriter, res proto.Message) {
+	buf, err := marshalBuffer(res)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer putBuffer(buf)
+	writeBody(w, buf.Bytes())
+}
+
+// respond 与 writeResponse 相同，但在请求方接受时按节点的配置压缩响应体，见 WithWireCompression。
+func (p *HTTPPool) respond(w http.ResponseWriter, r *http.Request, res proto.Message) {
+	buf, err := marshalBuffer(res)
 	if err != nil {
-		// 如果获取失败，返回内部服务器错误并包含错误信息。
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	defer putBuffer(buf)
+	body, c := p.encodeBody(r, buf.Bytes())
+	if c != NoCompression {
+		w.Header().Set("Content-Encoding", c.String())
+	}
+	writeBody(w, body)
+}
+
+// writeBody 写入 protobuf 编码的响应体，并在响应体之后发送其校验和 trailer。
+// 响应体经过压缩时校验和针对压缩后的数据，调用方先校验再解压。
+func writeBody(w http.ResponseWriter, body []byte) {
+	// 设置响应头的内容类型为 protobuf。
+	w.Header().Set("Content-Type", protobufContentType)
+	// 声明校验和 trailer，在响应体写完之后再发送，调用方据此校验数据完整性。
+	w.Header().Set("Trailer", checksumTrailer)
+	w.Write(body)
+	w.Header().Set(checksumTrailer, checksum(body))
+}
 
-	// 设置响应头的内容类型为 "application/octet-stream"。
+// serveMeta 以响应头的形式返回 key 对应条目的元数据：
+// Content-Length 为值的大小，ETag 为值的版本，X-Geecache-Ttl 为剩余有效期（毫秒）。
+// 条目不在本节点缓存中时返回 404。
+func (p *HTTPPool) serveMeta(w http.ResponseWriter, group *Group, key string) {
+	meta, ok := group.meta(key)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
 	w.Header().Set("Content-Type", "application/octet-stream")
-	// 将数据视图（view）的字节切片写入响应。
-	w.Write(view.ByteSlice())
+	w.Header().Set("Content-Length", strconv.Itoa(meta.Size))
+	w.Header().Set("ETag", strconv.Quote(meta.Version))
+	if meta.TTL > 0 {
+		w.Header().Set(ttlHeader, strconv.FormatInt(meta.TTL.Milliseconds(), 10))
+	}
+	w.WriteHeader(http.StatusOK)
 }
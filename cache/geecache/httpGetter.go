@@ -1,85 +1,429 @@
 package geecache
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
-	"io/ioutil"
+	"hash/crc32"
+	"io"
+	"net"
 	"net/http"
-	"net/url"
+	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/golang/groupcache/consistenthash"
+	"google.golang.org/protobuf/proto"
+
+	"testProject/cache/clock"
+	consistenthashgo "testProject/cache/consistenthash.go"
+	pb "testProject/cache/geecachepb"
 )
 
 // httpGetter 结构体表示一个 HTTP 请求获取器，用于向远程 HTTP 服务器发起 GET 请求。
 type httpGetter struct {
-	baseURL string // baseURL 存储远程服务器的基本 URL 地址
+	baseURL   string       // baseURL 存储远程服务器的基本 URL 地址
+	maxHops   int          // 允许的最大转发跳数，超过后拒绝继续转发
+	unhealthy atomic.Bool  // 最近一次健康检查是否失败，见 HTTPPool.CheckHealth
+	client    *http.Client // 发起请求使用的 HTTP 客户端，为 nil 时使用 defaultClient
+	authToken string       // 请求中携带的集群认证令牌，为空时不携带，见 WithAuthToken
+}
+
+// do 使用 httpGetter 配置的 HTTP 客户端发起请求，并附带集群认证令牌。
+func (h *httpGetter) do(req *http.Request) (*http.Response, error) {
+	if h.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+h.authToken)
+	}
+	if h.client != nil {
+		return h.client.Do(req)
+	}
+	return defaultClient.Do(req)
 }
 
 // Get 方法用于从远程服务器获取指定 group 和 key 对应的数据。
-func (h *httpGetter) Get(group string, key string) ([]byte, error) {
-	// 构建完整的请求 URL，将 group 和 key 编码为 URL 安全格式。
-	u := fmt.Sprintf(
-		"%v%v/%v",
-		h.baseURL,
-		url.QueryEscape(group),
-		url.QueryEscape(key),
-	)
+// 请求头中会携带本次请求的转发跳数以及 ctx 剩余的超时时间，
+// 转发跳数超过 maxHops 时直接返回错误，不再向远程节点发起请求。
+func (h *httpGetter) Get(ctx context.Context, group string, key string) ([]byte, error) {
+	req, err := h.newRequest(ctx, http.MethodGet, h.url(group, key), nil)
+	if err != nil {
+		return nil, err
+	}
+	// 响应体是 protobuf 编码的 Response。
+	out := &pb.Response{}
+	if err := h.roundTrip(req, out); err != nil {
+		return nil, err
+	}
+	return out.Value, nil
+}
+
+// Peek 方法只读取远程节点已经缓存的 key，远程节点没有缓存时返回 ErrNotCached，不会触发加载。
+func (h *httpGetter) Peek(ctx context.Context, group string, key string) ([]byte, error) {
+	req, err := h.newRequest(ctx, http.MethodGet, h.url(group, key), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(peekHeader, "1")
+	out := &pb.Response{}
+	if err := h.roundTrip(req, out); err != nil {
+		return nil, err
+	}
+	return out.Value, nil
+}
+
+// newRequest 创建转发给对端的读取请求，请求头中携带转发跳数、一致性令牌以及 ctx 剩余的超时时间。
+// 转发跳数超过 maxHops 或者 ctx 已经超时时直接返回错误。
+func (h *httpGetter) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	hops := HopsFromContext(ctx) + 1 // 本次转发计入跳数
+	if hops > h.maxHops {
+		return nil, fmt.Errorf("hop limit %d exceeded", h.maxHops)
+	}
 
-	// 发起 HTTP GET 请求。
-	res, err := http.Get(u)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
+	req.Header.Set(hopsHeader, strconv.Itoa(hops)) // 携带转发跳数，供对端判断是否继续转发
+	// 显式声明接受的压缩算法，Transport 不会再自动添加 gzip 并透明解压，响应体由 roundTrip 解压。
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	if token, ok := TokenFromContext(ctx); ok {
+		req.Header.Set(tokenHeader, string(token)) // 让所有者节点判断是否需要绕过缓存
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		// 传递剩余时间而不是绝对时间点，避免节点间时钟不一致带来的误差。
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, context.DeadlineExceeded
+		}
+		req.Header.Set(deadlineHeader, strconv.FormatInt(remaining.Milliseconds(), 10))
+	}
+	return req, nil
+}
+
+// roundTrip 发起请求，校验响应状态和校验和之后把 protobuf 编码的响应体解码到 out 中。
+func (h *httpGetter) roundTrip(req *http.Request, out proto.Message) error {
+	// 发起 HTTP 请求，复用节点间共享的连接。
+	res, err := h.do(req)
+	if err != nil {
+		return err
+	}
 	defer res.Body.Close()
 
 	// 检查响应状态码，如果不是 200 OK，则返回错误。
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("server returned: %v", res.Status)
+		if res.StatusCode == http.StatusNotFound && res.Header.Get(notFoundHeader) != "" {
+			return ErrNotFound // 所有者的数据源中不存在 key
+		}
+		if res.StatusCode == http.StatusNotFound && res.Header.Get(notCachedHeader) != "" {
+			return ErrNotCached // 只读取缓存的请求没有命中
+		}
+		err := fmt.Errorf("server returned: %v", res.Status)
+		if res.StatusCode == http.StatusServiceUnavailable {
+			return Retryable(err) // 对端遇到了暂时性错误
+		}
+		return err
 	}
 
-	// 读取响应体的内容。
-	bytes, err := ioutil.ReadAll(res.Body)
+	// 把响应体读入从缓冲池中取出的缓冲区。proto.Unmarshal 会复制 bytes 类型的字段，
+	// 解码之后的 out 不引用缓冲区，返回之前就可以把缓冲区放回缓冲池。
+	buf, err := readBuffer(res.Body)
 	if err != nil {
-		return nil, fmt.Errorf("reading response body: %v", err)
+		return fmt.Errorf("reading response body: %v", err)
 	}
+	defer putBuffer(buf)
+	bytes := buf.Bytes()
 
-	return bytes, nil
+	// 对端声明了校验和 trailer 时，校验通过后才返回数据，
+	// 防止被截断或损坏的传输结果污染本地缓存。
+	if _, ok := res.Trailer[checksumTrailer]; ok {
+		if sum := res.Trailer.Get(checksumTrailer); sum != checksum(bytes) {
+			return fmt.Errorf("checksum mismatch: got %q, want %q", checksum(bytes), sum)
+		}
+	}
+
+	// 对端压缩了响应体时先解压。
+	c, err := parseCompression(res.Header.Get("Content-Encoding"))
+	if err != nil {
+		return err
+	}
+	if bytes, err = c.decode(bytes); err != nil {
+		return fmt.Errorf("decompressing response body: %v", err)
+	}
+
+	if err := proto.Unmarshal(bytes, out); err != nil {
+		return fmt.Errorf("decoding response body: %v", err)
+	}
+	return nil
+}
+
+// Stat 方法通过 HEAD 请求查询远程节点上 group 和 key 对应条目的元数据，不传输条目的值。
+// 远程节点没有缓存该条目时 ok 为 false。
+func (h *httpGetter) Stat(ctx context.Context, group string, key string) (meta EntryMeta, ok bool, err error) {
+	req, err := h.newRequest(ctx, http.MethodHead, h.url(group, key), nil)
+	if err != nil {
+		return EntryMeta{}, false, err
+	}
+	res, err := h.do(req)
+	if err != nil {
+		return EntryMeta{}, false, err
+	}
+	res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return EntryMeta{}, false, nil
+	default:
+		return EntryMeta{}, false, fmt.Errorf("server returned: %v", res.Status)
+	}
+
+	meta.Size = int(res.ContentLength)
+	if meta.Version, err = strconv.Unquote(res.Header.Get("ETag")); err != nil {
+		return EntryMeta{}, false, fmt.Errorf("bad ETag %q", res.Header.Get("ETag"))
+	}
+	if ttl := res.Header.Get(ttlHeader); ttl != "" {
+		ms, err := strconv.ParseInt(ttl, 10, 64)
+		if err != nil {
+			return EntryMeta{}, false, fmt.Errorf("bad TTL header %q", ttl)
+		}
+		meta.TTL = time.Duration(ms) * time.Millisecond
+	}
+	return meta, true, nil
+}
+
+// url 构建 group 和 key 对应的完整请求 URL，将 group 和 key 编码为 URL 安全格式。
+func (h *httpGetter) url(group string, key string) string {
+	return fmt.Sprintf(
+		"%v%v/%v",
+		h.baseURL,
+		escapeSegment(group),
+		escapeSegment(key),
+	)
 }
 
 // httpGetter 类型实现了 PeerGetter 接口，这意味着它可以作为 PeerGetter 接口的实现。
 // 这是通过将 (*httpGetter)(nil) 赋值给 _ PeerGetter 来实现的，表示 httpGetter 满足 PeerGetter 接口的要求。
 var _ PeerGetter = (*httpGetter)(nil)
+var _ PeerStater = (*httpGetter)(nil)
+var _ PeerPeeker = (*httpGetter)(nil)
 
 // defaultBasePath 定义了 HTTP 池的默认基本路径。
 const (
 	defaultBasePath = "/_geecache/"
 	defaultReplicas = 50
+	// defaultMaxHops 是节点间请求默认允许的最大转发跳数：
+	// 请求从发起节点到达 key 的拥有者计为 1 跳，拥有者不再继续转发。
+	defaultMaxHops = 1
+	// hopsHeader 是节点间请求中携带转发跳数的请求头。
+	hopsHeader = "X-Geecache-Hops"
+	// deadlineHeader 是节点间请求中携带调用方剩余超时时间（毫秒）的请求头。
+	deadlineHeader = "X-Geecache-Deadline"
+	// checksumTrailer 是响应体之后携带数据校验和的 trailer。
+	checksumTrailer = "X-Geecache-Checksum"
+	// protobufContentType 是节点间 protobuf 消息体的内容类型
+	protobufContentType = "application/x-protobuf"
+	// ttlHeader 是 HEAD 响应中携带条目剩余有效期（毫秒）的响应头。
+	ttlHeader = "X-Geecache-Ttl"
+	// notFoundHeader 标记 404 响应是因为数据源中不存在 key，而不是路径或缓存组不存在。
+	notFoundHeader = "X-Geecache-Not-Found"
+	// peekHeader 标记读取请求只读取对端已经缓存的值，不触发加载，见 PeerPeeker。
+	peekHeader = "X-Geecache-Peek"
+	// notCachedHeader 标记 404 响应是因为只读取缓存的请求没有命中。
+	notCachedHeader = "X-Geecache-Not-Cached"
 )
 
+// defaultTransport 是节点之间通信使用的默认 Transport。
+// 对等节点之间互相信任，直接以先验知识方式使用明文 HTTP/2（h2c），
+// 所有请求复用少量长连接，并调大空闲连接上限，避免高负载下频繁建立和断开连接。
+var defaultTransport = newPeerTransport(nil)
+
+// defaultClientTimeout 是默认 HTTP 客户端单个请求的总超时时间，
+// 调用方通过 ctx 设置了更短的截止时间时以 ctx 为准。
+const defaultClientTimeout = 30 * time.Second
+
+// defaultClient 是 httpGetter 默认使用的 HTTP 客户端，所有对等节点共享。
+var defaultClient = &http.Client{Transport: defaultTransport, Timeout: defaultClientTimeout}
+
+// newPeerTransport 创建一个针对节点间通信调优的 Transport。
+// tlsConfig 为 nil 时使用 h2c，否则通过 TLS 通信并按 ALPN 协商 HTTP/2。
+func newPeerTransport(tlsConfig *tls.Config) *http.Transport {
+	var protocols http.Protocols
+	if tlsConfig == nil {
+		protocols.SetUnencryptedHTTP2(true) // 只启用 h2c，http:// 地址直接以 HTTP/2 通信
+	} else {
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+		tlsConfig = tlsConfig.Clone()
+	}
+	return &http.Transport{
+		TLSClientConfig: tlsConfig,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,  // 建立连接的超时时间
+			KeepAlive: 30 * time.Second, // TCP keep-alive 探测间隔
+		}).DialContext,
+		Protocols:           &protocols,
+		MaxIdleConns:        256,              // 所有节点合计的最大空闲连接数
+		MaxIdleConnsPerHost: 32,               // 每个节点保留的最大空闲连接数
+		IdleConnTimeout:     90 * time.Second, // 空闲连接的保留时间
+		TLSHandshakeTimeout: 5 * time.Second,  // TLS 握手的超时时间
+		HTTP2: &http.HTTP2Config{
+			SendPingTimeout: 30 * time.Second, // 连接空闲时发送 PING 检测对端是否存活
+			PingTimeout:     10 * time.Second, // PING 无响应时关闭连接
+		},
+	}
+}
+
+// PeerProtocols 返回节点服务端应启用的协议：HTTP/1 以及明文 HTTP/2（h2c）。
+// 对等节点之间默认使用 h2c 通信，因此承载 HTTPPool 的 http.Server 需要设置
+// Protocols 为该值，同时仍然兼容 curl 等 HTTP/1 客户端。
+func PeerProtocols() *http.Protocols {
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return &protocols
+}
+
+// checksum 返回 b 的 CRC32 校验和的十六进制表示，用于校验节点间传输的数据是否完整。
+func checksum(b []byte) string {
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE(b))
+}
+
 // HTTPPool 结构体实现了 PeerPicker 接口，用于管理一组 HTTP 对等节点的池。
 type HTTPPool struct {
 	// self 表示当前节点的基本 URL 地址，例如 "https://example.net:8000"。
 	self        string
 	basePath    string
-	mu          sync.Mutex             // 互斥锁，用于保护 peers 和 httpGetters。
-	peers       *consistenthash.Map    // 一致性哈希算法的映射，用于管理对等节点。
-	httpGetters map[string]*httpGetter // 存储 HTTP 请求获取器的映射，按键值 "http://10.0.0.2:8008" 存储。
+	maxHops     int                           // 节点间请求允许的最大转发跳数
+	replicas    int                           // 每个真实节点对应的虚拟节点数量
+	hashFn      consistenthashgo.Hash         // 一致性哈希使用的散列函数，为 nil 时使用 CRC32
+	strategy    consistenthashgo.StrategyFunc // 把键映射到节点的策略，为 nil 时使用哈希环，见 WithStrategy
+	weights     map[string]int                // 节点的权重，没有列出的节点权重为 1，见 WithPeerWeights
+	mu          sync.Mutex                    // 互斥锁，用于保护 peers 和 httpGetters。
+	peers       consistenthashgo.Strategy     // 一致性哈希算法的映射，用于管理对等节点。
+	httpGetters map[string]*httpGetter        // 存储 HTTP 请求获取器的映射，按键值 "http://10.0.0.2:8008" 存储。
+	clock       clock.Clock                   // 后台检查使用的时钟
+	logger      Logger                        // 输出日志使用的 Logger
+	client      *http.Client                  // 所有 httpGetter 共享的 HTTP 客户端，为 nil 时使用 defaultClient
+	tlsConfig   *tls.Config                   // 节点之间使用 HTTPS 时的 TLS 配置，见 WithTLSConfig
+	authToken   string                        // 集群共享的认证令牌，见 WithAuthToken
+	registry    *Registry                     // 处理请求时查找缓存组的注册表，见 WithPoolRegistry
+	// wireCompression 和 wireThreshold 决定响应体的压缩，见 WithWireCompression。
+	wireCompression Compression
+	wireThreshold   int
+	// onTopologyChange 是节点集合变化时依次调用的回调，由 OnTopologyChange 注册。
+	onTopologyChange []func(added, removed []string)
+	// rebalance 是节点集合变化之后迁移条目的方式，rebalanceRun 是最近一次迁移，见 WithRebalance。
+	rebalance    RebalanceMode
+	rebalanceRun *rebalanceRun
+}
+
+// OnTopologyChange 注册一个在节点集合发生变化时调用的回调，added 和 removed 分别是新加入和已经离开的节点。
+// 回调在节点集合更新完成之后、不持有锁的情况下同步调用，可以用于记录日志、上报指标或触发预热；
+// 节点集合没有变化时不会调用。可以多次调用注册多个回调，按注册顺序依次调用。
+func (p *HTTPPool) OnTopologyChange(fn func(added, removed []string)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onTopologyChange = append(p.onTopologyChange, fn)
 }
 
 // Set 方法用于更新池的对等节点列表。
+// 新的节点列表会与当前列表做差异比较：仍然存在的节点保留原有的 httpGetter（以及其中的连接和状态），
+// 只为新加入的节点创建 httpGetter，并移除已经离开的节点。
+// 设置了 WithRebalance 时，节点集合变化之后在后台把不再由本节点负责的条目迁移给新所有者。
 func (p *HTTPPool) Set(peers ...string) {
 	p.mu.Lock()
-	defer p.mu.Unlock()
+	var prev consistenthashgo.Strategy
+	if p.rebalance != RebalanceOff && p.peers != nil {
+		prev = p.snapshotRing() // setPeers 会原地修改 p.peers
+	}
+	added, removed := p.setPeers(peers)
+	if prev != nil && (len(added) > 0 || len(removed) > 0) {
+		p.startRebalance(prev)
+	}
+	callbacks := p.onTopologyChange
+	p.mu.Unlock()
 
-	// 创建一个新的一致性哈希映射，设置副本数为默认值，并将传入的节点添加到映射中。
-	p.peers = consistenthash.New(defaultReplicas, nil)
-	p.peers.Add(peers...)
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+	for _, fn := range callbacks {
+		fn(added, removed)
+	}
+}
 
-	// 初始化 HTTP 请求获取器映射，为每个节点创建一个对应的 HTTP 客户端。
-	p.httpGetters = make(map[string]*httpGetter, len(peers))
+// setPeers 把节点集合更新为 peers，返回新加入和已经离开的节点。调用方需要持有 p.mu。
+func (p *HTTPPool) setPeers(peers []string) (added, removed []string) {
+	added, removed = p.diffPeers(peers)
+	if p.httpGetters == nil {
+		p.httpGetters = make(map[string]*httpGetter, len(added))
+	}
+	for _, peer := range removed {
+		delete(p.httpGetters, peer)
+	}
+	for _, peer := range added {
+		p.httpGetters[peer] = &httpGetter{baseURL: peer + p.basePath, maxHops: p.maxHops, client: p.client, authToken: p.authToken}
+	}
+
+	// 只在哈希环中增删发生变化的节点，不需要重建整个哈希环。
+	if p.peers == nil {
+		p.peers = p.newRing()
+	}
+	for _, peer := range removed {
+		p.peers.Remove(peer)
+	}
+	p.addToRing(p.peers, added)
+	return added, removed
+}
+
+// newRing 按节点配置的策略创建一个空的节点映射。
+func (p *HTTPPool) newRing() consistenthashgo.Strategy {
+	if p.strategy != nil {
+		return p.strategy(p.replicas, p.hashFn)
+	}
+	return consistenthashgo.New(p.replicas, p.hashFn)
+}
+
+// addToRing 按节点的权重把 peers 加入节点映射 ring。
+func (p *HTTPPool) addToRing(ring consistenthashgo.Strategy, peers []string) {
+	if len(p.weights) == 0 {
+		ring.Add(peers...)
+		return
+	}
+	for _, peer := range peers {
+		ring.AddWeighted(peer, p.weights[peer])
+	}
+}
+
+// diffPeers 比较新的节点列表与当前的节点列表，返回新加入和已经离开的节点，重复的节点只计一次。
+// 调用方需要持有 p.mu。
+func (p *HTTPPool) diffPeers(peers []string) (added, removed []string) {
+	next := make(map[string]bool, len(peers))
 	for _, peer := range peers {
-		p.httpGetters[peer] = &httpGetter{baseURL: peer + p.basePath}
+		if next[peer] {
+			continue
+		}
+		next[peer] = true
+		if _, ok := p.httpGetters[peer]; !ok {
+			added = append(added, peer)
+		}
+	}
+	for peer := range p.httpGetters {
+		if !next[peer] {
+			removed = append(removed, peer)
+		}
+	}
+	sort.Strings(removed)
+	return added, removed
+}
+
+// peerList 返回当前所有节点的有序列表。调用方需要持有 p.mu。
+func (p *HTTPPool) peerList() []string {
+	peers := make([]string, 0, len(p.httpGetters))
+	for peer := range p.httpGetters {
+		peers = append(peers, peer)
 	}
+	sort.Strings(peers)
+	return peers
 }
 
 // PickPeer 方法根据给定的键选择一个对等节点。
@@ -89,14 +433,36 @@ func (p *HTTPPool) PickPeer(key string) (PeerGetter, bool) {
 
 	// 使用一致性哈希算法根据键获取对等节点。
 	if peer := p.peers.Get(key); peer != "" && peer != p.self {
+		g := p.httpGetters[peer]
+		if g.unhealthy.Load() {
+			// 所有者不健康时交给环上的下一个健康节点，或者在本地加载。
+			return p.failover(key)
+		}
 		p.Log("Pick peer %s", peer)
 		// 如果找到了合适的对等节点，则返回对应的 HTTP 客户端。
-		return p.httpGetters[peer], true
+		return g, true
 	}
 
 	// 如果没有找到合适的对等节点，返回 nil 和 false。
 	return nil, false
 }
 
+// PickPeers 实现 PeerReplicaPicker，返回 key 的前 n 个副本节点中除本节点之外的节点，不考虑节点的健康状态。
+func (p *HTTPPool) PickPeers(key string, n int) []PeerGetter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.peers == nil {
+		return nil
+	}
+	var peers []PeerGetter
+	for _, peer := range p.peers.GetN(key, n) {
+		if peer != p.self {
+			peers = append(peers, p.httpGetters[peer])
+		}
+	}
+	return peers
+}
+
 // HTTPPool 类型实现了 PeerPicker 接口，这表示它可以用作 PeerPicker 接口的实现。
 var _ PeerPicker = (*HTTPPool)(nil)
+var _ PeerReplicaPicker = (*HTTPPool)(nil)
@@ -0,0 +1,1224 @@
+package geecache
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	consistenthashgo "testProject/cache/consistenthash.go"
+	pb "testProject/cache/geecachepb"
+)
+
+// newTestGroup 创建一个以 key 本身作为值的缓存组，用于 HTTP 相关测试。
+func newTestGroup(name string) *Group {
+	return NewGroup(name, 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}))
+}
+
+// newPeerServer 启动一个与对等节点一样支持 h2c 的测试服务器。
+func newPeerServer(handler http.Handler) *httptest.Server {
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Config.Protocols = PeerProtocols()
+	srv.Start()
+	return srv
+}
+
+// 测试超过最大跳数的节点间请求会被拒绝
+func TestServeHTTPHopLimit(t *testing.T) {
+	newTestGroup("hops")
+	pool := NewHTTPPool("http://self")
+
+	for hops, want := range map[int]int{
+		0:                  http.StatusOK,
+		defaultMaxHops:     http.StatusOK,
+		defaultMaxHops + 1: http.StatusLoopDetected,
+	} {
+		req := httptest.NewRequest(http.MethodGet, defaultBasePath+"hops/Tom", nil)
+		req.Header.Set(hopsHeader, strconv.Itoa(hops))
+		rec := httptest.NewRecorder()
+		pool.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("hops=%d: status = %d, want %d", hops, rec.Code, want)
+		}
+	}
+}
+
+// 测试 httpGetter 在跳数超限时不会再向远程节点发起请求
+func TestHTTPGetterHopLimit(t *testing.T) {
+	var requests int
+	srv := newPeerServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		writeResponse(w, &pb.Response{Value: []byte(r.Header.Get(hopsHeader))})
+	}))
+	defer srv.Close()
+
+	h := &httpGetter{baseURL: srv.URL + defaultBasePath, maxHops: 2}
+	if b, err := h.Get(ContextWithHops(context.Background(), 1), "g", "k"); err != nil || string(b) != "2" {
+		t.Fatalf("Get after 1 hop = %q, %v; want \"2\", nil", b, err)
+	}
+	if _, err := h.Get(ContextWithHops(context.Background(), 2), "g", "k"); err == nil {
+		t.Fatal("Get after 2 hops should fail when maxHops is 2")
+	}
+	if requests != 1 {
+		t.Fatalf("server received %d requests, want 1", requests)
+	}
+}
+
+// 测试 HTTPPool 的虚拟节点数量和散列函数可以通过选项配置
+func TestHTTPPoolOptions(t *testing.T) {
+	var hashed int
+	pool := NewHTTPPool("http://self", WithReplicas(3), WithHashFn(func(data []byte) uint32 {
+		hashed++
+		return 0
+	}))
+	pool.Set("http://a", "http://b")
+	if hashed != 6 {
+		t.Fatalf("hash function called %d times for 2 peers, want 2*3", hashed)
+	}
+	if _, ok := pool.PickPeer("Tom"); !ok {
+		t.Fatal("PickPeer should choose a remote peer")
+	}
+
+	maglev := NewHTTPPool("http://self", WithStrategy(consistenthashgo.MaglevStrategy))
+	maglev.Set("http://self", "http://a")
+	if _, ok := maglev.peers.(*consistenthashgo.Maglev); !ok {
+		t.Fatalf("peers = %T, want the maglev strategy", maglev.peers)
+	}
+	if peers := maglev.PickPeers("Tom", 2); len(peers) != 1 || peers[0] != maglev.httpGetters["http://a"] {
+		t.Fatalf("PickPeers = %v, want only the remote peer", peers)
+	}
+}
+
+// 测试调用方的剩余超时时间会通过请求头传递，并在对端生效
+func TestDeadlinePropagation(t *testing.T) {
+	srv := newPeerServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeResponse(w, &pb.Response{Value: []byte(r.Header.Get(deadlineHeader))})
+	}))
+	defer srv.Close()
+
+	h := &httpGetter{baseURL: srv.URL + defaultBasePath, maxHops: defaultMaxHops}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	b, err := h.Get(ctx, "g", "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms, err := strconv.Atoi(string(b)); err != nil || ms <= 0 || ms > int(time.Minute/time.Millisecond) {
+		t.Fatalf("deadline header = %q, want remaining milliseconds", b)
+	}
+
+	// 对端收到已经耗尽的截止时间后不应再加载数据
+	var loads int
+	NewGroup("deadline", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte(key), nil
+	}))
+	req := httptest.NewRequest(http.MethodGet, defaultBasePath+"deadline/Tom", nil)
+	req.Header.Set(deadlineHeader, "0")
+	rec := httptest.NewRecorder()
+	NewHTTPPool("http://self").ServeHTTP(rec, req)
+	if rec.Code != http.StatusGatewayTimeout || loads != 0 {
+		t.Fatalf("status = %d, loads = %d; want %d, 0", rec.Code, loads, http.StatusGatewayTimeout)
+	}
+}
+
+// 测试响应体的校验和 trailer 会被校验，损坏的数据不会被返回
+func TestChecksumTrailer(t *testing.T) {
+	newTestGroup("checksum")
+	pool := NewHTTPPool("http://self")
+	srv := newPeerServer(pool)
+	defer srv.Close()
+
+	h := &httpGetter{baseURL: srv.URL + defaultBasePath, maxHops: defaultMaxHops}
+	if b, err := h.Get(context.Background(), "checksum", "Tom"); err != nil || string(b) != "Tom" {
+		t.Fatalf("Get = %q, %v; want \"Tom\", nil", b, err)
+	}
+
+	corrupt := newPeerServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := proto.Marshal(&pb.Response{Value: []byte("Tom")})
+		w.Header().Set("Trailer", checksumTrailer)
+		w.Write(body)
+		w.Header().Set(checksumTrailer, checksum([]byte("Jack")))
+	}))
+	defer corrupt.Close()
+
+	h = &httpGetter{baseURL: corrupt.URL + defaultBasePath, maxHops: defaultMaxHops}
+	if _, err := h.Get(context.Background(), "checksum", "Tom"); err == nil {
+		t.Fatal("Get should fail when the checksum trailer does not match")
+	}
+}
+
+// 测试节点间请求默认通过 h2c 发送
+func TestPeerTransportH2C(t *testing.T) {
+	srv := newPeerServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeResponse(w, &pb.Response{Value: []byte(r.Proto)})
+	}))
+	defer srv.Close()
+
+	h := &httpGetter{baseURL: srv.URL + defaultBasePath, maxHops: defaultMaxHops}
+	if b, err := h.Get(context.Background(), "g", "k"); err != nil || string(b) != "HTTP/2.0" {
+		t.Fatalf("Get = %q, %v; want \"HTTP/2.0\", nil", b, err)
+	}
+}
+
+// roundTripFunc 把函数适配为 http.RoundTripper。
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// 测试 WithHTTPClient 设置的客户端被所有节点共享
+func TestWithHTTPClient(t *testing.T) {
+	srv := newPeerServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeResponse(w, &pb.Response{Value: []byte("v")})
+	}))
+	defer srv.Close()
+
+	var requests int
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requests++
+		r.URL.Host = strings.TrimPrefix(srv.URL, "http://") // 所有节点都转发到同一个测试服务器
+		return defaultTransport.RoundTrip(r)
+	})}
+	pool := NewHTTPPool("http://self", WithHTTPClient(client))
+	pool.Set("http://a", "http://b")
+	for _, peer := range []string{"http://a", "http://b"} {
+		if b, err := pool.httpGetters[peer].Get(context.Background(), "g", "k"); err != nil || string(b) != "v" {
+			t.Fatalf("Get from %s = %q, %v", peer, b, err)
+		}
+	}
+	if requests != 2 {
+		t.Fatalf("custom client handled %d requests, want 2", requests)
+	}
+}
+
+// 测试 Set 只增删发生变化的节点，保留仍然存在的节点的 httpGetter
+func TestHTTPPoolSetIncremental(t *testing.T) {
+	pool := NewHTTPPool("http://self")
+	pool.Set("http://a", "http://b")
+	b := pool.httpGetters["http://b"]
+
+	pool.Set("http://b", "http://c", "http://c")
+	if len(pool.httpGetters) != 2 || pool.httpGetters["http://a"] != nil || pool.httpGetters["http://c"] == nil {
+		t.Fatalf("peers after Set = %v, want b and c", pool.peerList())
+	}
+	if pool.httpGetters["http://b"] != b {
+		t.Fatal("Set should keep the existing httpGetter of a peer that is still present")
+	}
+	for _, key := range []string{"Tom", "Jack", "Sam"} {
+		if peer, ok := pool.PickPeer(key); ok && peer != pool.httpGetters["http://b"] && peer != pool.httpGetters["http://c"] {
+			t.Fatalf("PickPeer(%q) returned a removed peer", key)
+		}
+	}
+}
+
+// 测试节点集合变化时调用回调，集合不变时不调用
+func TestHTTPPoolOnTopologyChange(t *testing.T) {
+	pool := NewHTTPPool("http://self")
+	var events [][2][]string
+	pool.OnTopologyChange(func(added, removed []string) {
+		events = append(events, [2][]string{added, removed})
+	})
+
+	pool.Set("http://a", "http://b")
+	pool.Set("http://b", "http://a")
+	pool.Set("http://b", "http://c")
+	want := [][2][]string{
+		{{"http://a", "http://b"}, nil},
+		{{"http://c"}, {"http://a"}},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+}
+
+// 测试节点下线前把缓存的条目推送给新的所有者
+func TestHTTPPoolHandoff(t *testing.T) {
+	g := newTestGroup("handoff")
+	for _, key := range []string{"Tom", "Jack", "Sam"} {
+		g.Get(key)
+	}
+
+	var mu sync.Mutex
+	received := make(map[string]string)
+	srv := newPeerServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		in := &pb.Request{}
+		if r.Method != http.MethodPut || r.Header.Get(checksumTrailer) != checksum(body) ||
+			r.Header.Get(handoffHeader) == "" || proto.Unmarshal(body, in) != nil {
+			http.Error(w, "bad push", http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		received[r.URL.Path] = string(in.Value)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	pool := NewHTTPPool("http://self")
+	pool.Set("http://self", srv.URL)
+	if err := pool.Handoff(context.Background()); err != nil {
+		t.Fatalf("Handoff() = %v", err)
+	}
+	for _, key := range []string{"Tom", "Jack", "Sam"} {
+		if got := received[defaultBasePath+"handoff/"+key]; got != key {
+			t.Fatalf("new owner received %q for %s, want %q", got, key, key)
+		}
+	}
+}
+
+// 测试其他节点推送的条目写入缓存，校验和不匹配时拒绝
+func TestServePush(t *testing.T) {
+	g := newTestGroup("push")
+	srv := newPeerServer(NewHTTPPool("http://self"))
+	defer srv.Close()
+
+	h := &httpGetter{baseURL: srv.URL + defaultBasePath, maxHops: defaultMaxHops}
+	if err := h.Push(context.Background(), "push", "k", []byte("pushed")); err != nil {
+		t.Fatalf("Push() = %v", err)
+	}
+	if v, ok := g.mainCache.get("k"); !ok || v.String() != "pushed" {
+		t.Fatalf("cache after push = %q, %v; want pushed", v.String(), ok)
+	}
+
+	req, _ := http.NewRequest(http.MethodPut, h.url("push", "bad"), strings.NewReader("x"))
+	req.Header.Set(checksumTrailer, "00000000")
+	res, err := defaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("push with bad checksum: status %d, want 400", res.StatusCode)
+	}
+}
+
+// 测试节点间比较节点列表视图，发现路由不一致的节点
+func TestVerifyRing(t *testing.T) {
+	remote := NewHTTPPool("http://remote")
+	srv := newPeerServer(remote)
+	defer srv.Close()
+
+	local := NewHTTPPool("http://local")
+	local.Set("http://local", srv.URL)
+	remote.Set("http://local", srv.URL)
+	if divergent, err := local.VerifyRing(context.Background()); err != nil || len(divergent) != 0 {
+		t.Fatalf("VerifyRing with the same peers = %v, %v; want no divergence", divergent, err)
+	}
+
+	remote.Set("http://local", srv.URL, "http://other")
+	if divergent, err := local.VerifyRing(context.Background()); err != nil || !reflect.DeepEqual(divergent, []string{srv.URL}) {
+		t.Fatalf("VerifyRing with different peers = %v, %v; want [%s]", divergent, err, srv.URL)
+	}
+}
+
+// 测试写入在所有者上执行：先写穿透到数据源，再写入缓存并签发一致性令牌
+func TestGroupSet(t *testing.T) {
+	stored := make(map[string]string)
+	g := NewGroup("set", 0, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(stored[key]), nil
+	}), WithWriteThrough(SetterFunc(func(key string, value []byte) error {
+		if key == "bad" {
+			return fmt.Errorf("backend rejected %s", key)
+		}
+		stored[key] = string(value)
+		return nil
+	})))
+
+	// 本节点是所有者
+	token, err := g.Set("k1", []byte("v1"))
+	if err != nil || token == "" || stored["k1"] != "v1" {
+		t.Fatalf("local Set = %q, %v; stored %v", token, err, stored)
+	}
+	if _, err := g.Set("bad", []byte("x")); err == nil || g.Len() != 1 {
+		t.Fatalf("Set should fail without caching when write-through fails, err %v", err)
+	}
+
+	// 所有者是远程节点，写入通过 PUT 转发
+	srv := newPeerServer(NewHTTPPool("http://owner"))
+	defer srv.Close()
+	pool := NewHTTPPool("http://self")
+	pool.Set(srv.URL)
+	g.RegisterPeers(pool)
+	token, err = g.Set("k2", []byte("v2"))
+	if err != nil || token == "" || stored["k2"] != "v2" {
+		t.Fatalf("remote Set = %q, %v; stored %v", token, err, stored)
+	}
+	if v, ok := g.mainCache.get("k2"); !ok || v.String() != "v2" {
+		t.Fatal("owner should cache the written value")
+	}
+
+	g.Freeze()
+	defer g.Unfreeze()
+	if _, err := g.Set("k3", []byte("v3")); err != ErrFrozen {
+		t.Fatalf("Set on a frozen group: err = %v, want ErrFrozen", err)
+	}
+}
+
+// 测试删除在本地执行并广播给所有其他节点
+func TestGroupRemove(t *testing.T) {
+	g := newTestGroup("remove")
+	g.Get("Tom")
+
+	var mu sync.Mutex
+	var deleted []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		deleted = append(deleted, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		w.Header().Set(tokenHeader, "7")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	a, b := newPeerServer(handler), newPeerServer(handler)
+	defer a.Close()
+	defer b.Close()
+	pool := NewHTTPPool("http://self")
+	pool.Set("http://self", a.URL, b.URL)
+	g.RegisterPeers(pool)
+
+	token, err := g.Remove("Tom")
+	if err != nil || token == "" {
+		t.Fatalf("Remove = %q, %v", token, err)
+	}
+	if _, ok := g.mainCache.get("Tom"); ok {
+		t.Fatal("Remove should delete the local entry")
+	}
+	want := "DELETE " + defaultBasePath + "remove/Tom"
+	if len(deleted) != 2 || deleted[0] != want || deleted[1] != want {
+		t.Fatalf("peers received %v, want two %q", deleted, want)
+	}
+}
+
+// 测试按缓存组导出 Prometheus 指标
+func TestMetricsHandler(t *testing.T) {
+	g := NewGroup("metrics", 0, GetterFunc(func(key string) ([]byte, error) {
+		if key == "bad" {
+			return nil, fmt.Errorf("no such key")
+		}
+		return []byte(key), nil
+	}))
+	g.Get("Tom")
+	g.Get("Tom")
+	g.Get("bad")
+
+	rec := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE geecache_hits_total counter",
+		`geecache_hits_total{group="metrics"} 1`,
+		`geecache_misses_total{group="metrics"} 2`,
+		`geecache_loads_total{group="metrics"} 2`,
+		`geecache_load_errors_total{group="metrics"} 1`,
+		`geecache_entries{group="metrics"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Fatalf("metrics output is missing %q:\n%s", line, body)
+		}
+	}
+}
+
+// 测试管理接口：认证、统计、列出键、查看条目、清空缓存和调整日志级别
+func TestAdminHandler(t *testing.T) {
+	g := NewGroup("admin", 0, GetterFunc(func(key string) ([]byte, error) {
+		return []byte("value-" + key), nil
+	}))
+	g.Get("Tom")
+	g.Get("Jack")
+	h := AdminHandler(WithAdminToken("secret"))
+	do := func(method, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, defaultAdminPath+target, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, defaultAdminPath+"stats", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("request without token: status %d, want 401", rec.Code)
+	}
+
+	var stats map[string]CacheStats
+	if err := json.NewDecoder(do(http.MethodGet, "stats").Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if s := stats["admin"]; s.Gets != 2 || s.Items != 2 {
+		t.Fatalf("stats[admin] = %+v", s)
+	}
+
+	var keys adminKeys
+	if err := json.NewDecoder(do(http.MethodGet, "keys?group=admin&prefix=T").Body).Decode(&keys); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keys.Keys, []string{"Tom"}) || keys.Next != 0 {
+		t.Fatalf("keys = %+v", keys)
+	}
+
+	var entry adminEntry
+	if err := json.NewDecoder(do(http.MethodGet, "entry?group=admin&key=Tom").Body).Decode(&entry); err != nil {
+		t.Fatal(err)
+	}
+	if string(entry.Value) != "value-Tom" || entry.Size != len("value-Tom") {
+		t.Fatalf("entry = %+v", entry)
+	}
+	if rec := do(http.MethodGet, "entry?group=admin&key=Bob"); rec.Code != http.StatusNotFound {
+		t.Fatalf("entry of an uncached key: status %d, want 404", rec.Code)
+	}
+
+	if rec := do(http.MethodGet, "flush?group=admin"); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET flush: status %d, want 405", rec.Code)
+	}
+	if rec := do(http.MethodPost, "flush?group=admin"); rec.Code != http.StatusNoContent {
+		t.Fatalf("flush: status %d, want 204", rec.Code)
+	}
+	if g.Len() != 0 {
+		t.Fatalf("Len() = %d after flush", g.Len())
+	}
+
+	if rec := do(http.MethodPost, "freeze?group=admin"); rec.Code != http.StatusNoContent || !g.Frozen() {
+		t.Fatalf("freeze: status %d, Frozen() = %v", rec.Code, g.Frozen())
+	}
+	if rec := do(http.MethodPost, "flush?group=admin"); rec.Code != http.StatusConflict {
+		t.Fatalf("flush of a frozen group: status %d, want 409", rec.Code)
+	}
+	if rec := do(http.MethodPost, "unfreeze?group=admin"); rec.Code != http.StatusNoContent || g.Frozen() {
+		t.Fatalf("unfreeze: status %d, Frozen() = %v", rec.Code, g.Frozen())
+	}
+
+	defer SetLogLevel(GetLogLevel())
+	if rec := do(http.MethodPost, "loglevel?level=error"); rec.Body.String() != "error" || GetLogLevel() != LevelError {
+		t.Fatalf("loglevel = %q", rec.Body.String())
+	}
+	if rec := do(http.MethodPost, "loglevel?level=verbose"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown log level: status %d, want 400", rec.Code)
+	}
+
+	// WithAdmin 挂载到 WithAdminBasePath 指定的路径
+	s := NewServer("127.0.0.1:0", NewHTTPPool("http://self", WithPoolRegistry(NewRegistry())), WithAdmin(AdminHandler(WithAdminBasePath("/ops/"))))
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ops/loglevel", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("admin API under a custom base path: status %d, want 200", rec.Code)
+	}
+}
+
+// 测试绑定到不同注册表的节点可以使用相同的缓存组名称，互不影响
+func TestRegistry(t *testing.T) {
+	var nodes [2]*HTTPPool
+	for i := range nodes {
+		r := NewRegistry()
+		value := []byte(fmt.Sprint("node", i))
+		NewGroup("registry-scores", 0, GetterFunc(func(key string) ([]byte, error) {
+			return value, nil
+		}), WithRegistry(r))
+		nodes[i] = NewHTTPPool("http://self", WithPoolRegistry(r))
+		if got := r.List(); !reflect.DeepEqual(got, []string{"registry-scores"}) {
+			t.Fatalf("List() = %v", got)
+		}
+	}
+	if GetGroup("registry-scores") != nil {
+		t.Fatal("groups in other registries should not be visible in DefaultRegistry")
+	}
+	for i, pool := range nodes {
+		rec := httptest.NewRecorder()
+		pool.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, defaultBasePath+"registry-scores/k", nil))
+		if want := fmt.Sprint("node", i); !strings.Contains(rec.Body.String(), want) {
+			t.Fatalf("node %d served %q, want %q", i, rec.Body.String(), want)
+		}
+	}
+}
+
+// 测试任意字节的组名和键都能在节点间原样传递
+func TestKeyEscaping(t *testing.T) {
+	keys := []string{"a/b", "100%", "héllo", "a b+c", "..", ".", "?x=1#y", "\xff\x00/\n"}
+	for _, name := range []string{"escape", "escape/group %"} {
+		newTestGroup(name)
+	}
+	srv := newPeerServer(NewHTTPPool("http://self"))
+	defer srv.Close()
+
+	h := &httpGetter{baseURL: srv.URL + defaultBasePath, maxHops: defaultMaxHops}
+	for _, group := range []string{"escape", "escape/group %"} {
+		for _, key := range keys {
+			got, err := h.Get(context.Background(), group, key)
+			if err != nil || string(got) != key {
+				t.Fatalf("Get(%q, %q) = %q, %v; want the key itself", group, key, got, err)
+			}
+		}
+	}
+}
+
+// 测试 HEAD 请求只返回已缓存条目的元数据，不会触发加载
+func TestHTTPGetterStat(t *testing.T) {
+	g := newTestGroup("stat")
+	srv := newPeerServer(NewHTTPPool("http://self"))
+	defer srv.Close()
+
+	h := &httpGetter{baseURL: srv.URL + defaultBasePath, maxHops: defaultMaxHops}
+	if _, ok, err := h.Stat(context.Background(), "stat", "Tom"); err != nil || ok {
+		t.Fatalf("Stat before load = %v, %v; want false, nil", ok, err)
+	}
+	if _, ok := g.mainCache.get("Tom"); ok {
+		t.Fatal("Stat should not load the key")
+	}
+
+	g.Get("Tom")
+	meta, ok, err := h.Stat(context.Background(), "stat", "Tom")
+	if err != nil || !ok {
+		t.Fatalf("Stat after load = %v, %v; want true, nil", ok, err)
+	}
+	if meta.Size != len("Tom") || meta.Version != checksum([]byte("Tom")) {
+		t.Fatalf("Stat = %+v, want size 3 and version %s", meta, checksum([]byte("Tom")))
+	}
+	// Stat 和 Get 一样受转发跳数限制
+	if _, _, err := h.Stat(ContextWithHops(context.Background(), defaultMaxHops), "stat", "Tom"); err == nil {
+		t.Fatal("Stat should fail once the hop limit is reached")
+	}
+}
+
+// 测试从远程节点获取的热点键会缓存在本节点的热点缓存中
+func TestHotCache(t *testing.T) {
+	defer func(f func() bool) { promoteToHot = f }(promoteToHot)
+	promoteToHot = func() bool { return true }
+
+	var fetches int
+	var mu sync.Mutex
+	srv := newPeerServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		mu.Lock()
+		fetches++
+		mu.Unlock()
+		writeResponse(w, &pb.Response{Value: []byte("remote")})
+	}))
+	defer srv.Close()
+	g := newTestGroup("hot")
+	pool := NewHTTPPool("http://self")
+	pool.Set(srv.URL)
+	g.RegisterPeers(pool)
+
+	for i := 0; i < 3; i++ {
+		if v, err := g.Get("Tom"); err != nil || v.String() != "remote" {
+			t.Fatalf("Get = %q, %v", v.String(), err)
+		}
+	}
+	if fetches != 1 || g.HotLen() != 1 || g.Len() != 0 {
+		t.Fatalf("fetches = %d, hot entries = %d, main entries = %d; want 1, 1, 0", fetches, g.HotLen(), g.Len())
+	}
+	if _, err := g.Remove("Tom"); err != nil || g.HotLen() != 0 {
+		t.Fatalf("Remove should drop the hot copy: err %v, hot entries %d", err, g.HotLen())
+	}
+}
+
+// 测试不健康的所有者被跳过，键交给环上的下一个健康节点
+func TestHealthFailover(t *testing.T) {
+	healthy := newPeerServer(NewHTTPPool("http://healthy"))
+	defer healthy.Close()
+	dead := newPeerServer(http.NotFoundHandler())
+	dead.Close() // 关闭后无法连接
+
+	pool := NewHTTPPool("http://self")
+	pool.Set(healthy.URL, dead.URL)
+	var deadKey string
+	for i := 0; deadKey == ""; i++ {
+		key := strconv.Itoa(i)
+		if peer, _ := pool.PickPeer(key); peer == pool.httpGetters[dead.URL] {
+			deadKey = key
+		}
+	}
+
+	unhealthy := pool.CheckHealth(context.Background())
+	if !reflect.DeepEqual(unhealthy, []string{dead.URL}) {
+		t.Fatalf("CheckHealth = %v, want [%s]", unhealthy, dead.URL)
+	}
+	if peer, ok := pool.PickPeer(deadKey); !ok || peer != pool.httpGetters[healthy.URL] {
+		t.Fatalf("PickPeer(%q) should fail over to the healthy peer", deadKey)
+	}
+}
+
+// 测试所有者加载的值被推送给副本节点，所有者下线之后从副本读取而不是访问数据源
+func TestReplication(t *testing.T) {
+	var (
+		pools   [3]*HTTPPool
+		groups  [3]*Group
+		servers [3]*httptest.Server
+		loads   [3]atomic.Int32
+		urls    []string
+	)
+	for i := range servers {
+		servers[i] = newPeerServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pools[i].ServeHTTP(w, r)
+		}))
+		defer servers[i].Close()
+		urls = append(urls, servers[i].URL)
+	}
+	for i := range pools {
+		r := NewRegistry()
+		groups[i] = NewGroup("replicated", 0, GetterFunc(func(key string) ([]byte, error) {
+			loads[i].Add(1)
+			return []byte("v"), nil
+		}), WithRegistry(r), WithReplication(2))
+		pools[i] = NewHTTPPool(urls[i], WithPoolRegistry(r))
+		pools[i].Set(urls...)
+		groups[i].RegisterPeers(pools[i])
+	}
+	// 找一个所有者是节点 0、第二个副本是节点 1 的键，从节点 2 读取。
+	var key string
+	for i := 0; key == ""; i++ {
+		if nodes := pools[2].peers.GetN(strconv.Itoa(i), 2); nodes[0] == urls[0] && nodes[1] == urls[1] {
+			key = strconv.Itoa(i)
+		}
+	}
+	if peers := pools[2].PickPeers(key, 2); len(peers) != 2 || peers[0] != pools[2].httpGetters[urls[0]] {
+		t.Fatalf("PickPeers(%q, 2) = %v, want the owner first", key, peers)
+	}
+
+	if _, err := groups[0].Get(key); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); !groups[1].mainCache.contains(key); {
+		if time.Now().After(deadline) {
+			t.Fatal("the value was not replicated to the second replica")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 副本读取只读取缓存，没有缓存的键不会让副本加载
+	if _, err := pools[2].httpGetters[urls[1]].Peek(context.Background(), "replicated", "uncached"); !errors.Is(err, ErrNotCached) {
+		t.Fatalf("Peek of an uncached key: err = %v, want ErrNotCached", err)
+	}
+
+	servers[0].Close() // 所有者下线
+	if v, err := groups[2].Get(key); err != nil || v.String() != "v" {
+		t.Fatalf("Get after the owner went down = %q, %v", v, err)
+	}
+	if got := [3]int32{loads[0].Load(), loads[1].Load(), loads[2].Load()}; got != [3]int32{1, 0, 0} {
+		t.Fatalf("loads per node = %v, want only the owner to load", got)
+	}
+}
+
+// 测试节点加入之后把不再由本节点负责的条目迁移给新所有者
+func TestRebalance(t *testing.T) {
+	for _, mode := range []RebalanceMode{RebalancePush, RebalanceLazy} {
+		var (
+			pools   [2]*HTTPPool
+			groups  [2]*Group
+			servers [2]*httptest.Server
+			urls    []string
+		)
+		for i := range servers {
+			servers[i] = newPeerServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				pools[i].ServeHTTP(w, r)
+			}))
+			defer servers[i].Close()
+			urls = append(urls, servers[i].URL)
+		}
+		for i := range pools {
+			r := NewRegistry()
+			groups[i] = NewGroup("rebalanced", 0, GetterFunc(func(key string) ([]byte, error) {
+				return []byte("v" + key), nil
+			}), WithRegistry(r))
+			pools[i] = NewHTTPPool(urls[i], WithPoolRegistry(r), WithRebalance(mode))
+			groups[i].RegisterPeers(pools[i])
+		}
+		// 节点 0 单独运行时负责所有的键。
+		pools[0].Set(urls[0])
+		for i := 0; i < 100; i++ {
+			if _, err := groups[0].Get(strconv.Itoa(i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if p := pools[0].RebalanceProgress(); p != (RebalanceProgress{}) {
+			t.Fatalf("progress before any change = %+v", p)
+		}
+
+		pools[1].Set(urls...)
+		pools[0].Set(urls...)
+		var moved []string
+		for i := 0; i < 100; i++ {
+			if key := strconv.Itoa(i); pools[0].peers.Get(key) == urls[1] {
+				moved = append(moved, key)
+			}
+		}
+		for deadline := time.Now().Add(5 * time.Second); pools[0].RebalanceProgress().Running; {
+			if time.Now().After(deadline) {
+				t.Fatal("rebalance did not finish")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		p := pools[0].RebalanceProgress()
+		if p.Scanned != 100 || p.Moved != int64(len(moved)) || len(moved) == 0 {
+			t.Fatalf("%v: progress = %+v, want %d of 100 moved", mode, p, len(moved))
+		}
+		want := int64(len(moved))
+		if mode == RebalanceLazy {
+			if p.Pending != int64(len(moved)) || groups[1].Len() != 0 {
+				t.Fatalf("lazy: progress = %+v, %d entries on the new owner before any read", p, groups[1].Len())
+			}
+			// 本地删除的条目取消标记，新所有者之后写入的值不会被延迟推送的旧值覆盖
+			groups[0].mainCache.remove(moved[0])
+			if p := pools[0].RebalanceProgress(); p.Pending != want-1 {
+				t.Fatalf("lazy: pending = %d after removing a marked entry, want %d", p.Pending, want-1)
+			}
+			if _, err := groups[1].SetLocal(moved[1], []byte("new")); err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range moved[1:] {
+				groups[0].Get(key) // 读到标记的条目时才推送
+			}
+			want--
+		}
+		for deadline := time.Now().Add(5 * time.Second); pools[0].RebalanceProgress().Pushed != want; {
+			if time.Now().After(deadline) {
+				t.Fatalf("%v: progress = %+v, want all moved entries pushed", mode, pools[0].RebalanceProgress())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		for _, key := range moved[int64(len(moved))-want:] {
+			value := "v" + key
+			if mode == RebalanceLazy && key == moved[1] {
+				value = "new"
+			}
+			if v, ok := groups[1].mainCache.peek(key); !ok || v.String() != value {
+				t.Fatalf("%v: new owner has %q = %q, %v; want %q", mode, key, v, ok, value)
+			}
+		}
+		if s := groups[0].Stats(); s.RebalancePushed != want || s.PendingHandoffs != 0 {
+			t.Fatalf("%v: stats = %+v", mode, s)
+		}
+	}
+}
+
+// 测试从远程节点获取失败时按策略重试，以及不回退到本地加载的配置
+func TestPeerRetries(t *testing.T) {
+	var mu sync.Mutex
+	var calls int
+	srv := newPeerServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+		if n%2 == 1 {
+			http.Error(w, "busy", http.StatusServiceUnavailable) // 奇数次请求失败
+			return
+		}
+		writeResponse(w, &pb.Response{Value: []byte("remote")})
+	}))
+	defer srv.Close()
+
+	var local int
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		local++
+		return []byte("local"), nil
+	})
+	g := NewGroup("peer-retry", 0, getter, WithPeerRetries(PeerRetryPolicy{Attempts: 2}))
+	pool := NewHTTPPool("http://self")
+	pool.Set(srv.URL)
+	g.RegisterPeers(pool)
+	if v, err := g.Get("Tom"); err != nil || v.String() != "remote" || local != 0 {
+		t.Fatalf("Get = %q, %v with %d local loads; want the retried remote value", v.String(), err, local)
+	}
+
+	g = NewGroup("peer-fallback", 0, getter, WithPeerFallback(FallbackError))
+	g.RegisterPeers(pool)
+	if _, err := g.Get("Tom"); err == nil || local != 0 {
+		t.Fatalf("Get: err %v with %d local loads; want an error without loading locally", err, local)
+	}
+}
+
+// writeTestCert 在 dir 中生成一张自签名证书及其私钥，可以同时作为 CA、服务端证书和客户端证书。
+func writeTestCert(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "geecache"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certFile, keyFile
+}
+
+// 测试节点之间通过双向 TLS 通信，没有出示客户端证书的请求会被拒绝
+func TestMutualTLS(t *testing.T) {
+	newTestGroup("tls")
+	certFile, keyFile := writeTestCert(t, t.TempDir())
+	cfg, err := LoadMutualTLSConfig(certFile, keyFile, certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer := "https://" + lis.Addr().String()
+	srv := NewHTTPPoolTLS(peer, cfg).HTTPServer("")
+	go srv.ServeTLS(lis, "", "")
+	defer srv.Close()
+
+	pool := NewHTTPPoolTLS("https://self", cfg)
+	pool.Set(peer)
+	if b, err := pool.httpGetters[peer].Get(context.Background(), "tls", "Tom"); err != nil || string(b) != "Tom" {
+		t.Fatalf("Get over mTLS = %q, %v", b, err)
+	}
+
+	// 只信任服务端证书、不出示客户端证书
+	noCert := NewHTTPPoolTLS("https://self", &tls.Config{RootCAs: cfg.RootCAs})
+	noCert.Set(peer)
+	if _, err := noCert.httpGetters[peer].Get(context.Background(), "tls", "Tom"); err == nil {
+		t.Fatal("Get without a client certificate should fail")
+	}
+}
+
+// 测试设置了认证令牌的节点拒绝没有携带正确令牌的请求，健康检查不要求认证
+func TestAuthToken(t *testing.T) {
+	newTestGroup("auth")
+	srv := newPeerServer(NewHTTPPool("http://self", WithAuthToken("secret")))
+	defer srv.Close()
+
+	pool := NewHTTPPool("http://self", WithAuthToken("secret"))
+	pool.Set(srv.URL)
+	if b, err := pool.httpGetters[srv.URL].Get(context.Background(), "auth", "Tom"); err != nil || string(b) != "Tom" {
+		t.Fatalf("Get with token = %q, %v", b, err)
+	}
+
+	for _, token := range []string{"", "wrong"} {
+		h := &httpGetter{baseURL: srv.URL + defaultBasePath, maxHops: defaultMaxHops, authToken: token}
+		if _, err := h.Get(context.Background(), "auth", "Tom"); err == nil || !strings.Contains(err.Error(), "401") {
+			t.Fatalf("Get with token %q: err = %v, want 401", token, err)
+		}
+		if err := h.checkHealth(context.Background()); err != nil {
+			t.Fatalf("health check with token %q: %v", token, err)
+		}
+	}
+}
+
+// 测试 Server 只把 basePath 下的请求交给 HTTPPool，并在关闭时等待正在处理的请求完成
+func TestServerShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	NewGroup("server", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		close(started)
+		<-release
+		return []byte(key), nil
+	}))
+	s := NewServer("127.0.0.1:0", NewHTTPPool("http://self"), WithWriteTimeout(5*time.Second))
+	shuttingDown := make(chan struct{})
+	s.server.RegisterOnShutdown(func() { close(shuttingDown) })
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	base := "http://" + s.Addr().String()
+
+	res, err := http.Get(base + "/other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("unexpected path: status %d, want 404", res.StatusCode)
+	}
+
+	// 关闭时正在处理的请求仍然能够完成
+	h := &httpGetter{baseURL: base + defaultBasePath, maxHops: defaultMaxHops}
+	got := make(chan error, 1)
+	go func() {
+		b, err := h.Get(context.Background(), "server", "Tom")
+		if err == nil && string(b) != "Tom" {
+			err = fmt.Errorf("got %q", b)
+		}
+		got <- err
+	}()
+	<-started
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- s.Shutdown(context.Background()) }()
+	<-shuttingDown // Shutdown 已经关闭监听，开始等待正在处理的请求
+	close(release)
+	if err := <-got; err != nil {
+		t.Fatalf("in-flight Get: %v", err)
+	}
+	if err := <-shutdown; err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if _, err := http.Get(base + defaultBasePath + "server/Tom"); err == nil {
+		t.Fatal("Server should not accept requests after Shutdown")
+	}
+}
+
+// 测试 ServeHTTP 对未知路径返回 404、对不支持的方法返回 405，数据源中不存在的 key 返回 404 并还原为 ErrNotFound
+func TestServeHTTPRouting(t *testing.T) {
+	NewGroup("routing", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+	}))
+	pool := NewHTTPPool("http://self")
+	for _, tc := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/elsewhere", http.StatusNotFound},
+		{http.MethodGet, defaultBasePath + "a/b/c", http.StatusNotFound},
+		{http.MethodGet, defaultBasePath + "nosuch/k", http.StatusNotFound},
+		{http.MethodPost, defaultBasePath + "routing/k", http.StatusMethodNotAllowed},
+		{http.MethodPost, defaultBasePath + healthPath, http.StatusMethodNotAllowed},
+		{http.MethodGet, defaultBasePath + "routing/ghost", http.StatusNotFound},
+	} {
+		rec := httptest.NewRecorder()
+		pool.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if rec.Code != tc.want {
+			t.Errorf("%s %s: status %d, want %d", tc.method, tc.path, rec.Code, tc.want)
+		}
+	}
+
+	srv := newPeerServer(pool)
+	defer srv.Close()
+	h := &httpGetter{baseURL: srv.URL + defaultBasePath, maxHops: defaultMaxHops}
+	if _, err := h.Get(context.Background(), "routing", "ghost"); err != ErrNotFound {
+		t.Fatalf("Get(ghost) = %v, want ErrNotFound", err)
+	}
+	if _, err := h.Get(context.Background(), "nosuch", "ghost"); err == nil || err == ErrNotFound {
+		t.Fatalf("Get on an unknown group = %v, want a non-ErrNotFound error", err)
+	}
+}
+
+// 测试 GetMulti 为每个所有者只发起一次批量请求，合并重复的键，不存在的键不出现在结果中，
+// 批量结果中失败的键改为单独加载
+func TestGetMulti(t *testing.T) {
+	var batches, gets int
+	var mu sync.Mutex
+	srv := newPeerServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method != http.MethodPost {
+			gets++
+			http.Error(w, "unavailable", http.StatusInternalServerError) // 单独加载时回退到本地
+			return
+		}
+		batches++
+		body, _ := io.ReadAll(r.Body)
+		in := &pb.BatchRequest{}
+		proto.Unmarshal(body, in)
+		out := &pb.BatchResponse{}
+		for _, key := range in.Keys {
+			switch key {
+			case "ghost":
+				out.Results = append(out.Results, &pb.Response{Error: ErrNotFound.Error()})
+			case "bad":
+				out.Results = append(out.Results, &pb.Response{Error: "boom"})
+			default:
+				out.Results = append(out.Results, &pb.Response{Value: []byte(key + "!")})
+			}
+		}
+		writeResponse(w, out)
+	}))
+	defer srv.Close()
+	g := newTestGroup("multi")
+	pool := NewHTTPPool("http://self")
+	pool.Set(srv.URL)
+	g.RegisterPeers(pool)
+
+	values, err := g.GetMulti([]string{"a", "b", "a", "ghost", "bad"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a": "a!", "b": "b!", "bad": "bad"}
+	if len(values) != len(want) {
+		t.Fatalf("GetMulti = %v, want %v", values, want)
+	}
+	for k, v := range want {
+		if values[k].String() != v {
+			t.Errorf("values[%q] = %q, want %q", k, values[k].String(), v)
+		}
+	}
+	if batches != 1 || gets != 1 {
+		t.Fatalf("batches = %d, single gets = %d; want 1, 1", batches, gets)
+	}
+
+	// 不能批量读取的键并行加载：三个加载都开始之后才会返回
+	var started atomic.Int32
+	all := make(chan struct{})
+	local := NewGroup("multi-local", 0, GetterFunc(func(key string) ([]byte, error) {
+		if started.Add(1) == 3 {
+			close(all)
+		}
+		select {
+		case <-all:
+		case <-time.After(time.Second):
+		}
+		return []byte(key), nil
+	}))
+	if values, err := local.GetMulti([]string{"x", "y", "z"}); err != nil || len(values) != 3 {
+		t.Fatalf("GetMulti = %v, %v", values, err)
+	}
+	select {
+	case <-all:
+	default:
+		t.Fatal("keys without a batch getter were loaded one at a time")
+	}
+}
+
+// 测试节点按顺序返回批量读取中每个键的结果
+func TestServeBatch(t *testing.T) {
+	NewGroup("batchsrv", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		if key == "ghost" {
+			return nil, ErrNotFound
+		}
+		return []byte(key), nil
+	}))
+	srv := newPeerServer(NewHTTPPool("http://self"))
+	defer srv.Close()
+	h := &httpGetter{baseURL: srv.URL + defaultBasePath, maxHops: defaultMaxHops}
+	results, err := h.GetMulti(context.Background(), "batchsrv", []string{"Tom", "ghost", "Sam"})
+	if err != nil || len(results) != 3 {
+		t.Fatalf("GetMulti = %v, %v", results, err)
+	}
+	if string(results[0].Value) != "Tom" || results[1].Err != ErrNotFound || string(results[2].Value) != "Sam" {
+		t.Fatalf("GetMulti results = %+v", results)
+	}
+	if _, err := h.GetMulti(context.Background(), "nosuch", []string{"Tom"}); err == nil {
+		t.Fatal("GetMulti on an unknown group should fail")
+	}
+
+	// 一个批量请求同时读取的键数量不超过 batchLoadConcurrency
+	var running, peak atomic.Int32
+	NewGroup("batchsrv-bounded", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		n := running.Add(1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(time.Millisecond)
+		running.Add(-1)
+		return []byte(key), nil
+	}))
+	keys := make([]string, 3*batchLoadConcurrency)
+	for i := range keys {
+		keys[i] = fmt.Sprint("k", i)
+	}
+	if results, err := h.GetMulti(context.Background(), "batchsrv-bounded", keys); err != nil || len(results) != len(keys) {
+		t.Fatalf("GetMulti = %d results, %v", len(results), err)
+	}
+	if p := peak.Load(); p > batchLoadConcurrency {
+		t.Fatalf("peak concurrent loads = %d, want at most %d", p, batchLoadConcurrency)
+	}
+}
+
+// 测试本地缓存中的值被压缩保存而读取到原始的值，以及节点之间按协商压缩响应体
+func TestCompression(t *testing.T) {
+	big := strings.Repeat("geecache ", 100)
+	g := NewGroup("compressed", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		if key == "small" {
+			return []byte(key), nil
+		}
+		return []byte(big), nil
+	}), WithCompression(Snappy, 64))
+
+	for i := 0; i < 2; i++ { // 第二次读取命中压缩保存的条目
+		if v, err := g.Get("big"); err != nil || v.String() != big {
+			t.Fatalf("Get(big) = %d bytes, %v", v.Len(), err)
+		}
+	}
+	if g.Bytes() >= int64(len(big)) {
+		t.Fatalf("cache holds %d bytes for a %d byte value, want it compressed", g.Bytes(), len(big))
+	}
+	if meta, err := g.Metadata("big"); err != nil || meta.Size != len(big) {
+		t.Fatalf("Metadata = %+v, %v; want the uncompressed size", meta, err)
+	}
+	if v, _ := g.Get("small"); v.String() != "small" {
+		t.Fatalf("Get(small) = %q", v.String())
+	}
+
+	var encoding string
+	pool := NewHTTPPool("http://self", WithWireCompression(Gzip, 64))
+	srv := newPeerServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pool.ServeHTTP(w, r)
+		encoding = w.Header().Get("Content-Encoding")
+	}))
+	defer srv.Close()
+	h := &httpGetter{baseURL: srv.URL + defaultBasePath, maxHops: defaultMaxHops}
+	if b, err := h.Get(context.Background(), "compressed", "big"); err != nil || string(b) != big {
+		t.Fatalf("Get over the wire = %d bytes, %v", len(b), err)
+	}
+	if encoding != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", encoding)
+	}
+}
+
+// 测量节点间读取一个条目的每次分配：对端编码响应，请求方读取并解码响应
+func BenchmarkPeerGet(b *testing.B) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	value := bytes.Repeat([]byte("x"), 16<<10)
+	r := NewRegistry()
+	NewGroup("wire", 0, GetterFunc(func(key string) ([]byte, error) {
+		return value, nil
+	}), WithRegistry(r))
+	pool := NewHTTPPool("http://self", WithPoolRegistry(r))
+
+	b.Run("serve", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			rec := httptest.NewRecorder()
+			pool.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, defaultBasePath+"wire/k", nil))
+		}
+	})
+	b.Run("roundtrip", func(b *testing.B) {
+		srv := newPeerServer(pool)
+		defer srv.Close()
+		h := &httpGetter{baseURL: srv.URL + defaultBasePath, maxHops: defaultMaxHops}
+		b.ReportAllocs()
+		b.SetBytes(int64(len(value)))
+		for i := 0; i < b.N; i++ {
+			if _, err := h.Get(context.Background(), "wire", "k"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
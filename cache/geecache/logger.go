@@ -0,0 +1,100 @@
+package geecache
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+)
+
+// Logger 是缓存组和 HTTPPool 输出日志使用的接口，可以接入 zap、logrus 等日志库。
+// Debugf 用于每次请求都会出现的日志（例如缓存命中），Infof 用于正常运行中的事件，Errorf 用于错误。
+type Logger interface {
+	Debugf(format string, v ...interface{})
+	Infof(format string, v ...interface{})
+	Errorf(format string, v ...interface{})
+}
+
+// StdLogger 是默认的 Logger，所有级别的日志都通过标准库的 log 包输出。
+var StdLogger Logger = stdLogger{}
+
+// NopLogger 丢弃所有日志，例如用于关闭每次命中都会输出的 "[GeeCache] hit"。
+var NopLogger Logger = nopLogger{}
+
+// LogLevel 是 StdLogger 输出日志的最低级别。
+type LogLevel int32
+
+const (
+	LevelDebug LogLevel = iota // 输出所有日志，这是默认级别
+	LevelInfo                  // 不输出 Debugf 的日志
+	LevelError                 // 只输出 Errorf 的日志
+)
+
+// String 返回日志级别的名称，与 ParseLogLevel 接受的名称相同。
+func (l LogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("LogLevel(%d)", int32(l))
+}
+
+// ParseLogLevel 解析日志级别的名称："debug"、"info" 或 "error"。
+func ParseLogLevel(s string) (LogLevel, error) {
+	for _, l := range []LogLevel{LevelDebug, LevelInfo, LevelError} {
+		if s == l.String() {
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q", s)
+}
+
+// logLevel 是 StdLogger 当前的日志级别。
+var logLevel atomic.Int32
+
+// SetLogLevel 设置 StdLogger 输出日志的最低级别，可以在运行中随时调整，例如排查问题时临时打开 debug 日志。
+// 它不影响通过 WithLogger 或 WithPoolLogger 设置的其他 Logger。
+func SetLogLevel(l LogLevel) {
+	logLevel.Store(int32(l))
+}
+
+// GetLogLevel 返回 StdLogger 当前的日志级别。
+func GetLogLevel() LogLevel {
+	return LogLevel(logLevel.Load())
+}
+
+type stdLogger struct{}
+
+func (stdLogger) Debugf(format string, v ...interface{}) { stdOutput(LevelDebug, format, v...) }
+func (stdLogger) Infof(format string, v ...interface{})  { stdOutput(LevelInfo, format, v...) }
+func (stdLogger) Errorf(format string, v ...interface{}) { stdOutput(LevelError, format, v...) }
+
+// stdOutput 在 level 不低于当前日志级别时通过 log 包输出日志，调用位置是 stdLogger 的调用方。
+func stdOutput(level LogLevel, format string, v ...interface{}) {
+	if level >= GetLogLevel() {
+		log.Output(3, fmt.Sprintf(format, v...))
+	}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debugf(format string, v ...interface{}) {}
+func (nopLogger) Infof(format string, v ...interface{})  {}
+func (nopLogger) Errorf(format string, v ...interface{}) {}
+
+// WithLogger 设置缓存组输出日志使用的 Logger，默认为 StdLogger。
+func WithLogger(l Logger) GroupOption {
+	return func(g *Group) {
+		g.logger = l
+	}
+}
+
+// WithPoolLogger 设置 HTTPPool 输出日志使用的 Logger，默认为 StdLogger。
+func WithPoolLogger(l Logger) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.logger = l
+	}
+}
@@ -0,0 +1,41 @@
+package geecache
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrValueTooLarge 表示值超过了 WithMaxEntryBytes 设置的单个条目大小上限，且超限策略为 OversizeReject。
+var ErrValueTooLarge = errors.New("geecache: value exceeds the maximum entry size")
+
+// OversizePolicy 决定超过单个条目大小上限的值如何处理。
+type OversizePolicy int
+
+const (
+	// OversizePassThrough 照常把值返回给调用方（或写入数据源），但不写入缓存。
+	OversizePassThrough OversizePolicy = iota
+	// OversizeReject 拒绝超限的值，读取和写入都返回 ErrValueTooLarge。
+	OversizeReject
+)
+
+// WithMaxEntryBytes 设置单个条目的大小上限（按原始的值计算，不考虑压缩），n <= 0 表示不限制，这是默认行为。
+// 没有上限时，一个巨大的值会把缓存中的其他条目全部淘汰；设置上限后超限的值按 policy 处理。
+func WithMaxEntryBytes(n int64, policy OversizePolicy) GroupOption {
+	return func(g *Group) {
+		g.maxEntryBytes = n
+		g.oversizePolicy = policy
+	}
+}
+
+// oversized 报告 value 是否超过了单个条目的大小上限。
+func (g *Group) oversized(value ByteView) bool {
+	return g.maxEntryBytes > 0 && int64(value.Len()) > g.maxEntryBytes
+}
+
+// checkEntrySize 在超限策略为 OversizeReject 且 value 超限时返回错误。
+func (g *Group) checkEntrySize(key string, value ByteView) error {
+	if g.oversizePolicy == OversizeReject && g.oversized(value) {
+		return fmt.Errorf("%w: %q is %d bytes, limit %d", ErrValueTooLarge, key, value.Len(), g.maxEntryBytes)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package geecache
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotCached 表示 key 不在本节点的缓存中。
+var ErrNotCached = errors.New("geecache: key is not cached")
+
+// EntryMeta 描述一个缓存条目的元数据，不包含条目的值本身。
+type EntryMeta struct {
+	Size    int           // 值的字节数
+	Version string        // 值的版本，取值为内容的校验和，内容不变则版本不变
+	TTL     time.Duration // 条目的剩余有效期，0 表示条目不会过期
+}
+
+// KeyInfo 描述缓存中的一个键，用于了解缓存中实际存放了哪些数据。
+type KeyInfo struct {
+	Key  string
+	Size int           // 值的字节数
+	Age  time.Duration // 条目写入缓存至今的时间
+}
+
+// Sample 随机返回缓存组中最多 n 个已缓存的键及其大小和存在时间，不影响条目的访问顺序。
+// 采样需要遍历全部条目，耗时与条目数量成正比，适合运维排查而不是在请求路径上调用。
+func (g *Group) Sample(n int) []KeyInfo {
+	now := g.clock.Now()
+	entries := g.mainCache.sample(n)
+	infos := make([]KeyInfo, len(entries))
+	for i, e := range entries {
+		infos[i] = KeyInfo{Key: e.Key, Size: e.Value.Len(), Age: now.Sub(e.Added)}
+	}
+	return infos
+}
+
+// Scan 按游标分页遍历缓存组中以 matchPrefix 开头的键，每页最多返回 count 个键。
+// 第一次调用时 cursor 传 0，之后传入上一次返回的 next，next 为 0 表示遍历结束。
+// 与 Redis 的 SCAN 类似，遍历期间一直存在的键一定会被返回；
+// 每一页只在遍历时短暂持有锁，不会在整个遍历过程中阻塞读写，适合审计和有针对性的清理。
+func (g *Group) Scan(cursor uint64, matchPrefix string, count int) (keys []string, next uint64) {
+	return g.mainCache.scan(cursor, matchPrefix, count)
+}
+
+// GetLocal 只读取本节点主缓存中 key 对应的值，不会触发加载，也不会转发给其他节点，
+// key 不在缓存中时返回 ErrNotCached。它供传输层处理其他节点的副本读取，应用代码应当使用 Get。
+func (g *Group) GetLocal(key string) (ByteView, error) {
+	v, ok := g.mainCache.get(key)
+	if !ok {
+		return ByteView{}, ErrNotCached
+	}
+	return g.decompress(v)
+}
+
+// TTL 返回本节点缓存中 key 对应条目的剩余有效期，0 表示条目不会过期。
+// 它不会触发加载，key 不在缓存中时返回 ErrNotCached。
+func (g *Group) TTL(key string) (time.Duration, error) {
+	meta, err := g.Metadata(key)
+	if err != nil {
+		return 0, err
+	}
+	return meta.TTL, nil
+}
+
+// Metadata 返回本节点缓存中 key 对应条目的元数据（大小、版本和剩余有效期），
+// 应用和运维工具可以借此检查条目而无需读取或修改它。
+// 它不会触发加载，key 不在缓存中时返回 ErrNotCached。
+func (g *Group) Metadata(key string) (EntryMeta, error) {
+	meta, ok := g.meta(key)
+	if !ok {
+		return EntryMeta{}, ErrNotCached
+	}
+	return meta, nil
+}
+
+// meta 返回本地缓存中 key 对应条目的元数据，不会触发加载。
+// 条目不在本地缓存中时 ok 为 false。
+func (g *Group) meta(key string) (meta EntryMeta, ok bool) {
+	_, meta, ok = g.entry(key)
+	return meta, ok
+}
+
+// entry 返回本地缓存中 key 对应的解压后的值及其元数据，不会触发加载。
+// 条目不在本地缓存中时 ok 为 false。
+func (g *Group) entry(key string) (v ByteView, meta EntryMeta, ok bool) {
+	v, ok = g.mainCache.peek(key) // 检查元数据不算一次访问，不影响淘汰顺序
+	if !ok {
+		return ByteView{}, EntryMeta{}, false
+	}
+	v, err := g.decompress(v) // 元数据描述的是原始的值
+	if err != nil {
+		return ByteView{}, EntryMeta{}, false
+	}
+	meta = EntryMeta{Size: v.Len(), Version: checksum(v.bytes())}
+	if expires, ok := g.mainCache.expiration(key); ok && !expires.IsZero() {
+		meta.TTL = expires.Sub(g.clock.Now())
+	}
+	return v, meta, true
+}
@@ -0,0 +1,128 @@
+package geecache
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// groupStats 是缓存组的运行计数，所有字段都可以并发更新。
+type groupStats struct {
+	gets       atomic.Int64 // 读取次数，包括命中和未命中
+	hits       atomic.Int64 // 本地缓存命中次数
+	misses     atomic.Int64 // 本地缓存未命中次数
+	loads      atomic.Int64 // 实际执行的加载次数（并发的同一个键只计一次）
+	loadErrors atomic.Int64 // 失败的加载次数
+	peerLoads  atomic.Int64 // 从远程节点成功获取的次数
+	peerErrors atomic.Int64 // 从远程节点获取失败的次数
+	localLoads atomic.Int64 // 从数据源成功加载的次数
+	hedges     atomic.Int64 // 向副本节点发出的对冲请求次数
+
+	rebalancePushed atomic.Int64 // 节点集合变化之后推送给新所有者的条目数量
+	rebalanceFailed atomic.Int64 // 节点集合变化之后推送失败的条目数量
+	pendingHandoffs atomic.Int64 // 标记为延迟移交、还没有推送的条目数量
+}
+
+// CacheStats 是缓存组的运行统计，由 Group.Stats 返回。计数类字段从缓存组创建时开始累计。
+type CacheStats struct {
+	Gets       int64 `json:"gets"`        // 读取次数，包括命中和未命中
+	Hits       int64 `json:"hits"`        // 本地缓存（主缓存或热点缓存）命中次数
+	Loads      int64 `json:"loads"`       // 未命中后实际执行的加载次数，并发的同一个键只计一次
+	LoadErrors int64 `json:"load_errors"` // 失败的加载次数
+	PeerLoads  int64 `json:"peer_loads"`  // 从远程节点成功获取的次数
+	PeerErrors int64 `json:"peer_errors"` // 从远程节点获取失败的次数
+	LocalLoads int64 `json:"local_loads"` // 从数据源成功加载的次数
+	Hedges     int64 `json:"hedges"`      // 向副本节点发出的对冲请求次数，见 WithHedgedRequests
+	// RebalancePushed、RebalanceFailed 和 PendingHandoffs 是节点集合变化之后迁移条目的进度，见 WithRebalance。
+	RebalancePushed int64 `json:"rebalance_pushed"`
+	RebalanceFailed int64 `json:"rebalance_failed"`
+	PendingHandoffs int64 `json:"pending_handoffs"`
+	// SharedLoads 是未命中之后共享了同一个键的其他加载而没有自己加载的次数，
+	// SharedLoads / (Loads + SharedLoads) 即 singleflight 为数据源节省的加载比例。
+	SharedLoads   int64 `json:"shared_loads"`
+	RejectedLoads int64 `json:"rejected_loads"`  // 超过 WithMaxLoadWaiters 上限而直接失败的读取次数
+	LoadsInFlight int64 `json:"loads_in_flight"` // 当前正在进行的加载数量
+	Evictions     int64 `json:"evictions"`       // 主缓存因超出容量而淘汰的条目数量
+	Bytes         int64 `json:"bytes"`           // 主缓存当前已经使用的内存
+	Items         int64 `json:"items"`           // 主缓存中的条目数量
+}
+
+// Stats 返回缓存组当前的运行统计。各字段分别读取，并发读写时彼此之间不保证是同一时刻的快照。
+func (g *Group) Stats() CacheStats {
+	s := g.mainCache.stats()
+	s.Gets = g.stats.gets.Load()
+	s.Hits = g.stats.hits.Load()
+	s.Loads = g.stats.loads.Load()
+	s.LoadErrors = g.stats.loadErrors.Load()
+	s.PeerLoads = g.stats.peerLoads.Load()
+	s.PeerErrors = g.stats.peerErrors.Load()
+	s.LocalLoads = g.stats.localLoads.Load()
+	s.Hedges = g.stats.hedges.Load()
+	s.RebalancePushed = g.stats.rebalancePushed.Load()
+	s.RebalanceFailed = g.stats.rebalanceFailed.Load()
+	s.PendingHandoffs = g.stats.pendingHandoffs.Load()
+	ls := g.loader.Stats()
+	s.SharedLoads = ls.Shared
+	s.RejectedLoads = ls.Rejected
+	s.LoadsInFlight = int64(ls.InFlight)
+	return s
+}
+
+// metric 描述一个导出的指标。
+type metric struct {
+	name  string
+	help  string
+	kind  string // counter 或 gauge
+	value func(g *Group) int64
+}
+
+// metrics 是每个缓存组导出的指标，都带有 group 标签。
+var metrics = []metric{
+	{"geecache_gets_total", "Number of gets, including hits and misses.", "counter", func(g *Group) int64 { return g.stats.gets.Load() }},
+	{"geecache_hits_total", "Number of gets served from the local cache.", "counter", func(g *Group) int64 { return g.stats.hits.Load() }},
+	{"geecache_misses_total", "Number of gets not served from the local cache.", "counter", func(g *Group) int64 { return g.stats.misses.Load() }},
+	{"geecache_evictions_total", "Number of entries evicted because the cache was full.", "counter", func(g *Group) int64 { return g.mainCache.evictions() }},
+	{"geecache_loads_total", "Number of loads executed after singleflight deduplication.", "counter", func(g *Group) int64 { return g.stats.loads.Load() }},
+	{"geecache_load_errors_total", "Number of loads that returned an error.", "counter", func(g *Group) int64 { return g.stats.loadErrors.Load() }},
+	{"geecache_peer_fetches_total", "Number of values fetched from peers.", "counter", func(g *Group) int64 { return g.stats.peerLoads.Load() }},
+	{"geecache_peer_errors_total", "Number of failed fetches from peers.", "counter", func(g *Group) int64 { return g.stats.peerErrors.Load() }},
+	{"geecache_hedged_requests_total", "Number of hedge requests sent to replica peers.", "counter", func(g *Group) int64 { return g.stats.hedges.Load() }},
+	{"geecache_rebalance_pushed_total", "Number of entries pushed to their new owners after the peer set changed.", "counter", func(g *Group) int64 { return g.stats.rebalancePushed.Load() }},
+	{"geecache_rebalance_failed_total", "Number of entries that failed to be pushed to their new owners after the peer set changed.", "counter", func(g *Group) int64 { return g.stats.rebalanceFailed.Load() }},
+	{"geecache_rebalance_pending", "Number of entries marked for lazy handoff and not yet pushed.", "gauge", func(g *Group) int64 { return g.stats.pendingHandoffs.Load() }},
+	{"geecache_shared_loads_total", "Number of misses that shared another caller's load instead of loading.", "counter", func(g *Group) int64 { return g.loader.Stats().Shared }},
+	{"geecache_rejected_loads_total", "Number of gets rejected because too many callers were waiting for the same load.", "counter", func(g *Group) int64 { return g.loader.Stats().Rejected }},
+	{"geecache_loads_in_flight", "Number of loads currently in progress.", "gauge", func(g *Group) int64 { return int64(g.loader.Stats().InFlight) }},
+	{"geecache_local_loads_total", "Number of values loaded from the data source.", "counter", func(g *Group) int64 { return g.stats.localLoads.Load() }},
+	{"geecache_bytes", "Bytes used by cached keys and values.", "gauge", func(g *Group) int64 { return g.Bytes() }},
+	{"geecache_entries", "Number of cached entries.", "gauge", func(g *Group) int64 { return int64(g.Len()) }},
+	{"geecache_hot_bytes", "Bytes used by the hot cache of keys owned by other peers.", "gauge", func(g *Group) int64 { return g.HotBytes() }},
+	{"geecache_capacity_bytes", "Maximum bytes of the cache, 0 means unlimited.", "gauge", func(g *Group) int64 { return g.Capacity() }},
+}
+
+// MetricsHandler 返回以 Prometheus 文本格式导出所有缓存组指标的 http.Handler，
+// 可以与 HTTPPool 挂载在同一个服务上，例如 mux.Handle("/metrics", geecache.MetricsHandler())。
+// 每个指标都带有 group 标签，便于在同一个面板中比较不同的缓存组。
+// 只导出 DefaultRegistry 中的缓存组，其他注册表使用 Registry.MetricsHandler。
+func MetricsHandler() http.Handler {
+	return DefaultRegistry.MetricsHandler()
+}
+
+// writeMetrics 以 Prometheus 文本格式写出 groups 的指标。
+func writeMetrics(w io.Writer, groups []*Group) {
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for _, g := range groups {
+			fmt.Fprintf(w, "%s{group=\"%s\"} %d\n", m.name, escapeLabel(g.name), m.value(g))
+		}
+	}
+}
+
+// labelEscaper 按 Prometheus 文本格式转义标签值。
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
@@ -0,0 +1,21 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package geecache
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmapSupported 报告当前平台是否支持 WithMappedValues。
+const mmapSupported = true
+
+// mmapFile 把文件 f 的前 size 个字节以共享、可读写的方式映射到内存中，写入的数据由内核写回文件。
+func mmapFile(f *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+// munmap 解除 mmapFile 建立的映射，之后不能再访问 data。
+func munmap(data []byte) error {
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,56 @@
+package geecache
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound 表示数据源中不存在 key。数据源返回 ErrNotFound（或包装了它的错误）时，
+// 设置了 WithNegativeTTL 的缓存组会在一段时间内记住这个结果，不再为同一个 key 访问数据源。
+var ErrNotFound = errors.New("geecache: key not found")
+
+// negativeCacheBytes 是负缓存的容量，负缓存的条目只占用键的长度。
+const negativeCacheBytes = 1 << 20
+
+// WithNegativeTTL 让缓存组在数据源返回 ErrNotFound 之后的 d 时间内直接对同一个 key 返回 ErrNotFound，
+// 防止大量请求不存在的 key 时每次都穿透到数据源。d 应当较短，使新写入数据源的 key 能及时被读到；
+// 通过 Set 或 Remove 修改 key 时会立即清除它的负缓存。d <= 0 表示不缓存不存在的结果，这是默认行为。
+// 数据源返回的永久性错误（见 Permanent）同样会被记住，之后返回同样错误信息的永久性错误；
+// 可以重试的错误（见 Retryable）即使包装了 ErrNotFound 也不会被记住。
+func WithNegativeTTL(d time.Duration) GroupOption {
+	return func(g *Group) {
+		if d < 0 {
+			d = 0
+		}
+		g.negativeTTL = d
+	}
+}
+
+// negativeErr 返回负缓存中记录的 key 的错误，即不久之前数据源报告过 key 不存在或者返回过永久性错误；
+// key 不在负缓存中时返回 nil。
+func (g *Group) negativeErr(key string) error {
+	if g.negativeTTL <= 0 {
+		return nil
+	}
+	v, ok := g.negCache.peek(key)
+	if !ok {
+		return nil
+	}
+	if v.Len() == 0 {
+		return ErrNotFound
+	}
+	return Permanent(errors.New(v.String()))
+}
+
+// cacheNotFound 在数据源报告 key 不存在或者返回永久性错误时把 key 加入负缓存，见 definitive。
+// ErrNotFound 只记录 key，其他错误同时记录错误信息。缓存组被冻结时不会写入。
+func (g *Group) cacheNotFound(key string, err error) {
+	if g.negativeTTL <= 0 || g.frozen.Load() || !definitive(err) {
+		return
+	}
+	var v ByteView
+	if !errors.Is(err, ErrNotFound) {
+		v = ByteView{s: err.Error()}
+	}
+	g.negCache.add(key, v, g.clock.Now().Add(g.negativeTTL))
+}
@@ -0,0 +1,62 @@
+package geecache
+
+import (
+	"context"
+	"time"
+)
+
+// PeerRetryPolicy 描述从远程节点获取失败时的重试策略。
+type PeerRetryPolicy struct {
+	Attempts   int                  // 最多尝试的次数（包括第一次），<= 1 表示不重试
+	Backoff    time.Duration        // 第一次重试之前的等待时间，之后逐次翻倍
+	MaxBackoff time.Duration        // 等待时间的上限，0 表示不限制
+	RetryOn    func(err error) bool // 判断错误是否值得重试，为 nil 时使用 IsRetryable（对端返回 503 等）
+}
+
+// PeerFallback 决定从远程节点获取最终失败之后缓存组的行为。
+type PeerFallback int
+
+const (
+	// FallbackLocal 在本节点从数据源加载，这是默认行为。
+	// 所有者节点故障时数据仍然可以读到，但会增加数据源的负载，并且该值不会写入所有者的缓存。
+	FallbackLocal PeerFallback = iota
+	// FallbackError 直接把错误返回给调用方，适用于数据源无法承受所有节点同时访问的场景。
+	FallbackError
+)
+
+// WithPeerRetries 设置从远程节点获取失败时的重试策略，默认不重试。
+func WithPeerRetries(policy PeerRetryPolicy) GroupOption {
+	return func(g *Group) {
+		g.peerRetry = policy
+	}
+}
+
+// WithPeerFallback 设置从远程节点获取最终失败之后的行为，默认为 FallbackLocal。
+func WithPeerFallback(f PeerFallback) GroupOption {
+	return func(g *Group) {
+		g.peerFallback = f
+	}
+}
+
+// getFromPeerWithRetry 从远程节点获取 key，按缓存组的重试策略重试。
+func (g *Group) getFromPeerWithRetry(ctx context.Context, peer PeerGetter, key string) (ByteView, error) {
+	policy := g.peerRetry
+	retryOn := policy.RetryOn
+	if retryOn == nil {
+		retryOn = IsRetryable
+	}
+	backoff := policy.Backoff
+	for attempt := 1; ; attempt++ {
+		value, err := g.getFromPeer(ctx, peer, key)
+		if err == nil || attempt >= policy.Attempts || !retryOn(err) {
+			return value, err
+		}
+		if !g.sleep(ctx, backoff) {
+			return value, err
+		}
+		backoff *= 2
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package geecache
 
+import "context"
+
 //根据传入的 key 选择相应节点 PeerGetter
 type PeerPicker interface {
 	PickPeer(key string) (peer PeerGetter, ok bool)
@@ -7,5 +9,59 @@ type PeerPicker interface {
 
 //从对应 group 查找缓存值
 type PeerGetter interface {
-	Get(group string, key string) ([]byte, error)
+	Get(ctx context.Context, group string, key string) ([]byte, error)
+	// Remove 删除对端缓存中 key 对应的条目，用于在整个集群中失效一个键。
+	Remove(ctx context.Context, group string, key string) error
+}
+
+// PeerReplicaPicker 由能够为 key 选择多个副本节点的 PeerPicker 实现，用于 WithReplication。
+type PeerReplicaPicker interface {
+	// PickPeers 返回 key 在环上从所有者开始顺时针遇到的前 n 个不同节点中除本节点之外的节点，按环上的顺序排列，
+	// 第一个通常就是 PickPeer 返回的所有者。本节点在前 n 个节点之中时结果少于 n 个。
+	PickPeers(key string, n int) []PeerGetter
+}
+
+// PeerPeeker 由能够只读取对端已经缓存的值的 PeerGetter 实现，用于从副本节点读取。
+// 对端没有缓存 key 时返回 ErrNotCached，不会触发加载，也不会再转发给其他节点。
+type PeerPeeker interface {
+	Peek(ctx context.Context, group string, key string) ([]byte, error)
+}
+
+// PeerLister 由能够列出所有远程节点的 PeerPicker 实现，用于向所有节点广播失效等操作。
+type PeerLister interface {
+	// Peers 返回除本节点之外的所有节点。
+	Peers() []PeerGetter
+}
+
+// PeerStater 由能够只查询条目元数据的 PeerGetter 实现。
+// 它只检查对端已经缓存的条目，不会触发加载，也不会传输条目的值，
+// 适合用于存在性检查、重新验证以及预取决策。
+type PeerStater interface {
+	Stat(ctx context.Context, group string, key string) (meta EntryMeta, ok bool, err error)
+}
+
+// PeerPusher 由能够把条目直接写入对端缓存的 PeerGetter 实现。
+// 推送的条目只写入对端的缓存，不经过对端的数据源。
+type PeerPusher interface {
+	Push(ctx context.Context, group string, key string, value []byte) error
+}
+
+// PeerSetter 由能够把写入转发给对端的 PeerGetter 实现，对端作为 key 的所有者执行写入。
+type PeerSetter interface {
+	Set(ctx context.Context, group string, key string, value []byte) (ConsistencyToken, error)
+}
+
+// hopsKey 是在 context 中保存节点间转发跳数的键。
+type hopsKey struct{}
+
+// ContextWithHops 返回一个携带转发跳数的 context，hops 表示请求到达本节点之前已经经过的转发次数。
+// 传输层在服务端收到请求时调用它，PeerGetter 在转发时通过 HopsFromContext 读取并加一。
+func ContextWithHops(ctx context.Context, hops int) context.Context {
+	return context.WithValue(ctx, hopsKey{}, hops)
+}
+
+// HopsFromContext 返回 context 中记录的转发跳数，本节点发起的请求为 0。
+func HopsFromContext(ctx context.Context) int {
+	hops, _ := ctx.Value(hopsKey{}).(int)
+	return hops
 }
@@ -0,0 +1,55 @@
+package geecache
+
+import (
+	"testProject/cache/arc"
+	"testProject/cache/clock"
+	"testProject/cache/lfu"
+	"testProject/cache/lru"
+	"testProject/cache/sampled"
+)
+
+// PolicyFunc 创建缓存组主缓存的底层存储，maxBytes 是它允许使用的最大内存，
+// clk 是记录条目写入时间和判断过期使用的时钟。设置了 WithShards 时每个分片各调用一次。
+type PolicyFunc func(maxBytes int64, clk clock.Clock) EvictionPolicy
+
+// LRUPolicy 按最近最少访问淘汰，这是默认的淘汰策略。
+func LRUPolicy(maxBytes int64, clk clock.Clock) EvictionPolicy {
+	return lru.New(maxBytes, nil, lru.WithClock(clk))
+}
+
+// SLRUPolicy 返回分段 LRU 淘汰策略，protectedRatio 是保护段占用 cacheBytes 的比例，见 lru.WithSegments。
+// 大量只访问一次的扫描请求不会把反复访问的条目挤出缓存。
+func SLRUPolicy(protectedRatio float64) PolicyFunc {
+	return func(maxBytes int64, clk clock.Clock) EvictionPolicy {
+		return lru.New(maxBytes, nil, lru.WithClock(clk), lru.WithSegments(protectedRatio))
+	}
+}
+
+// LFUPolicy 按访问频率淘汰，访问次数相同时淘汰最久未访问的条目。
+// 访问频率差异很大时，一批只访问一次的冷数据不会把热数据挤出缓存。
+func LFUPolicy(maxBytes int64, clk clock.Clock) EvictionPolicy {
+	return lfu.New(maxBytes, nil, lfu.WithClock(clk))
+}
+
+// ARCPolicy 使用自适应替换缓存（ARC），根据访问模式自动平衡最近访问和访问频率，不需要手工选择分段比例。
+func ARCPolicy(maxBytes int64, clk clock.Clock) EvictionPolicy {
+	return arc.New(maxBytes, nil, arc.WithClock(clk))
+}
+
+// SampledLRUPolicy 返回按采样近似 LRU 淘汰的策略：需要淘汰时随机抽取 samples 个条目（<= 0 时为 5），
+// 淘汰其中最久未访问的一个，见 sampled.Cache。它不维护访问顺序链表，命中只在读锁下更新条目上的原子计数，
+// 适合条目数量很大、读取并发很高的缓存，代价是淘汰的不一定是全局最久未访问的条目。
+func SampledLRUPolicy(samples int) PolicyFunc {
+	return func(maxBytes int64, clk clock.Clock) EvictionPolicy {
+		return sampled.New(maxBytes, nil, sampled.WithClock(clk), sampled.WithSamples(samples))
+	}
+}
+
+// WithEvictionPolicy 设置缓存组主缓存使用的淘汰策略，例如 LFUPolicy、ARCPolicy 或 SLRUPolicy(0.8)，默认使用 LRU。
+// ArenaPolicy 同时替换了存储引擎，适合条目数量极大、需要控制 GC 停顿的缓存。
+// 它与 WithTenants、WithDiskValues、WithMappedValues 互相替换，以最后设置的为准；WithEntryOverhead 只作用于默认策略。
+func WithEvictionPolicy(newPolicy PolicyFunc) GroupOption {
+	return func(g *Group) {
+		g.mainCache.newStore = newPolicy
+	}
+}
@@ -0,0 +1,127 @@
+package geecache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// prefetchQueueSize 是后台预取队列的长度，队列已满时新的预取请求会被丢弃。
+const prefetchQueueSize = 256
+
+// Predictor 根据刚刚被读取的 key 预测接下来可能被读取的键，
+// 例如读取 "page:3" 之后预测会读取 "page:4"。返回的键会在后台预取。
+type Predictor func(key string) []string
+
+// WithPredictor 为缓存组设置预测器，每次成功读取一个键后，预测器返回的键会被加入后台预取队列，
+// 用于提高顺序访问等可预测访问模式下的命中率。
+func WithPredictor(p Predictor) GroupOption {
+	return func(g *Group) {
+		g.predictor = p
+	}
+}
+
+// WithPrefetchConcurrency 设置同时执行预取的 goroutine 数量，默认为 1，即串行预取。
+// 启动时或流量高峰之前需要预热大量键时可以调大，代价是数据源同时承受更多的加载。
+func WithPrefetchConcurrency(n int) GroupOption {
+	return func(g *Group) {
+		if n > 0 {
+			g.prefetchWorkers = n
+		}
+	}
+}
+
+// Prefetch 把 keys 加入后台预取队列，由后台 goroutine 加载到缓存中，调用方不会被阻塞。
+// 预取的优先级低于正常读取：默认只有一个后台 goroutine 串行加载（见 WithPrefetchConcurrency），
+// 队列已满时多余的键直接丢弃，已经缓存的键会被跳过。缓存组被冻结时不进行预取。
+func (g *Group) Prefetch(keys ...string) {
+	if len(keys) == 0 || g.frozen.Load() {
+		return
+	}
+	g.prefetchOnce.Do(func() {
+		g.prefetchQueue = make(chan string, prefetchQueueSize)
+		for i := 0; i < max(g.prefetchWorkers, 1); i++ {
+			go g.prefetchLoop()
+		}
+	})
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		select {
+		case g.prefetchQueue <- key:
+		default:
+			return // 队列已满，放弃剩余的预取请求
+		}
+	}
+}
+
+// PrefetchWait 把 keys 加载到缓存中并等待全部完成，同时执行的加载数量由 WithPrefetchConcurrency 决定。
+// 与 Prefetch 不同，它不会丢弃任何键，适合在启动时预热缓存之后再开始接收流量。
+// 已经缓存的键会被跳过；ctx 结束时不再开始新的加载。部分键加载失败时返回的错误中包含失败的数量和第一个错误。
+func (g *Group) PrefetchWait(ctx context.Context, keys ...string) error {
+	if g.frozen.Load() {
+		return ErrFrozen
+	}
+	sem := make(chan struct{}, max(g.prefetchWorkers, 1))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed int
+	var firstErr error
+	for _, key := range keys {
+		if key == "" || g.mainCache.contains(key) {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			if _, err := g.load(ctx, key); err != nil {
+				mu.Lock()
+				failed++
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if failed > 0 {
+		return fmt.Errorf("prefetch: %d of %d keys failed: %v", failed, len(keys), firstErr)
+	}
+	return nil
+}
+
+// prefetchLoop 处理预取队列中的键，缓存组被销毁后退出。
+func (g *Group) prefetchLoop() {
+	for {
+		var key string
+		select {
+		case key = <-g.prefetchQueue:
+		case <-g.done:
+			return
+		}
+		if g.frozen.Load() {
+			continue
+		}
+		if g.mainCache.contains(key) {
+			continue // 已经缓存，不需要预取
+		}
+		if _, err := g.load(context.Background(), key); err != nil {
+			g.logger.Errorf("[GeeCache] prefetch failed: %v", err)
+		}
+	}
+}
+
+// predict 在成功读取 key 之后把预测器给出的键加入预取队列。
+func (g *Group) predict(key string) {
+	if g.predictor != nil {
+		g.Prefetch(g.predictor(key)...)
+	}
+}
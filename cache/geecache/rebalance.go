@@ -0,0 +1,237 @@
+package geecache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	consistenthashgo "testProject/cache/consistenthash.go"
+)
+
+// rebalanceScanCount 是迁移时每次遍历的键数量。
+const rebalanceScanCount = 256
+
+// rebalancePushTimeout 是迁移时推送单个条目的超时时间。
+const rebalancePushTimeout = 5 * time.Second
+
+// RebalanceMode 决定节点集合变化之后，本节点如何处理所有者从本节点变为其他节点的条目，见 WithRebalance。
+type RebalanceMode int
+
+const (
+	// RebalanceOff 不迁移条目，新所有者在第一次读取时从数据源重新加载（默认）。
+	RebalanceOff RebalanceMode = iota
+	// RebalancePush 在节点集合变化之后立即在后台把条目推送给新所有者。
+	RebalancePush
+	// RebalanceLazy 只标记条目，本节点下一次在主缓存中读到它时再推送给新所有者，
+	// 只迁移仍然有人读取的条目，避免节点集合变化时的推送风暴。
+	RebalanceLazy
+)
+
+// WithRebalance 设置节点集合变化（Set）之后的迁移方式。
+// 节点集合变化时，一部分键的所有者会从本节点变为其他节点，新所有者上没有这些条目，命中率会骤降并把读取压到数据源上。
+// 启用迁移之后，Set 比较变化前后每个本地条目的所有者，把不再由本节点负责的条目按 mode 推送给新所有者，
+// 推送的条目只写入新所有者的缓存，不经过数据源，新所有者已经缓存了 key 时保留它自己（更新）的值。
+// 本地的条目不会删除，按各自的 TTL 过期或被淘汰；延迟移交的条目在本地被删除、淘汰或过期时随之取消。
+// 迁移的进度由 RebalanceProgress 返回，每个缓存组的推送计数也作为指标导出。
+func WithRebalance(mode RebalanceMode) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.rebalance = mode
+	}
+}
+
+// RebalanceProgress 是最近一次迁移的进度，由 HTTPPool.RebalanceProgress 返回。
+type RebalanceProgress struct {
+	Running bool      `json:"running"` // 是否仍在遍历本地条目
+	Started time.Time `json:"started"` // 迁移开始的时间，即 Set 改变节点集合的时间
+	Scanned int64     `json:"scanned"` // 已经检查的本地条目数量
+	Moved   int64     `json:"moved"`   // 所有者从本节点变为其他节点的条目数量
+	Pushed  int64     `json:"pushed"`  // 已经推送给新所有者的条目数量
+	Failed  int64     `json:"failed"`  // 推送失败的条目数量
+	Pending int64     `json:"pending"` // RebalanceLazy 下已经标记、还没有推送的条目数量
+}
+
+// rebalanceRun 是一次迁移的状态。遍历结束之后，RebalanceLazy 标记的条目仍然会更新它的计数。
+type rebalanceRun struct {
+	started time.Time
+	cancel  context.CancelFunc
+	done    chan struct{} // 遍历结束时关闭
+	scanned atomic.Int64
+	moved   atomic.Int64
+	pushed  atomic.Int64
+	failed  atomic.Int64
+	pending atomic.Int64
+}
+
+// progress 返回迁移当前的进度。
+func (run *rebalanceRun) progress() RebalanceProgress {
+	running := true
+	select {
+	case <-run.done:
+		running = false
+	default:
+	}
+	return RebalanceProgress{
+		Running: running,
+		Started: run.started,
+		Scanned: run.scanned.Load(),
+		Moved:   run.moved.Load(),
+		Pushed:  run.pushed.Load(),
+		Failed:  run.failed.Load(),
+		Pending: run.pending.Load(),
+	}
+}
+
+// RebalanceProgress 返回最近一次迁移的进度，还没有迁移过时返回零值。
+func (p *HTTPPool) RebalanceProgress() RebalanceProgress {
+	p.mu.Lock()
+	run := p.rebalanceRun
+	p.mu.Unlock()
+	if run == nil {
+		return RebalanceProgress{}
+	}
+	return run.progress()
+}
+
+// snapshotRing 返回当前节点映射的一份副本，之后的 Set 不会修改它。调用方需要持有 p.mu。
+func (p *HTTPPool) snapshotRing() consistenthashgo.Strategy {
+	ring := p.newRing()
+	p.addToRing(ring, p.peerList())
+	return ring
+}
+
+// startRebalance 取消正在进行的迁移，按节点集合变化之前的映射 prev 开始一次新的迁移。调用方需要持有 p.mu。
+func (p *HTTPPool) startRebalance(prev consistenthashgo.Strategy) {
+	if p.rebalanceRun != nil {
+		p.rebalanceRun.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &rebalanceRun{started: p.clock.Now(), cancel: cancel, done: make(chan struct{})}
+	p.rebalanceRun = run
+
+	next := p.snapshotRing()
+	getters := make(map[string]*httpGetter, len(p.httpGetters))
+	for peer, getter := range p.httpGetters {
+		getters[peer] = getter
+	}
+	go p.runRebalance(ctx, run, prev, next, getters)
+}
+
+// runRebalance 遍历所有缓存组的本地条目，把所有者由 prev 中的本节点变为 next 中其他节点的条目
+// 推送给新所有者（RebalancePush）或者标记为延迟移交（RebalanceLazy）。
+func (p *HTTPPool) runRebalance(ctx context.Context, run *rebalanceRun, prev, next consistenthashgo.Strategy, getters map[string]*httpGetter) {
+	defer close(run.done)
+	groups := p.registry.list()
+	for _, g := range groups {
+		g.clearHandoffs() // 之前标记的条目的新所有者可能又变了
+	}
+	for _, g := range groups {
+		var cursor uint64
+		for {
+			keys, nextCursor := g.Scan(cursor, "", rebalanceScanCount)
+			for _, key := range keys {
+				if ctx.Err() != nil {
+					return // 节点集合又变化了，由新的迁移接手
+				}
+				run.scanned.Add(1)
+				if prev.Get(key) != p.self {
+					continue
+				}
+				name := next.Get(key)
+				owner, ok := getters[name]
+				if !ok || name == p.self {
+					continue
+				}
+				run.moved.Add(1)
+				if p.rebalance == RebalanceLazy {
+					g.markHandoff(key, owner, run)
+					continue
+				}
+				value, ok := g.mainCache.peek(key)
+				if !ok {
+					continue // 遍历期间已经被淘汰
+				}
+				g.pushHandoff(ctx, key, value, owner, run)
+			}
+			if nextCursor == 0 {
+				break
+			}
+			cursor = nextCursor
+		}
+	}
+	p.Log("rebalance scanned %d entries, %d moved, %d pushed, %d failed",
+		run.scanned.Load(), run.moved.Load(), run.pushed.Load(), run.failed.Load())
+}
+
+// pendingHandoff 是一个标记为延迟移交的条目的新所有者。
+type pendingHandoff struct {
+	owner *httpGetter
+	run   *rebalanceRun
+}
+
+// markHandoff 把 key 标记为延迟移交，本节点下一次在主缓存中读到它时推送给 owner。
+func (g *Group) markHandoff(key string, owner *httpGetter, run *rebalanceRun) {
+	if old, loaded := g.handoffs.Swap(key, &pendingHandoff{owner: owner, run: run}); loaded {
+		old.(*pendingHandoff).run.pending.Add(-1)
+	} else {
+		g.stats.pendingHandoffs.Add(1)
+	}
+	run.pending.Add(1)
+}
+
+// takeHandoff 取出并删除 key 的延迟移交标记。
+func (g *Group) takeHandoff(key string) (*pendingHandoff, bool) {
+	v, ok := g.handoffs.LoadAndDelete(key)
+	if !ok {
+		return nil, false
+	}
+	h := v.(*pendingHandoff)
+	g.stats.pendingHandoffs.Add(-1)
+	h.run.pending.Add(-1)
+	return h, true
+}
+
+// dropHandoff 在本地的条目被删除、淘汰或过期时取消它的延迟移交标记。
+func (g *Group) dropHandoff(key string) {
+	if g.stats.pendingHandoffs.Load() > 0 {
+		g.takeHandoff(key)
+	}
+}
+
+// clearHandoffs 删除所有延迟移交标记。
+func (g *Group) clearHandoffs() {
+	g.handoffs.Range(func(key, _ any) bool {
+		g.takeHandoff(key.(string))
+		return true
+	})
+}
+
+// maybeHandoff 在主缓存命中 key 之后检查它是否标记为延迟移交，是则在后台推送给新所有者。
+// value 是主缓存中的原始值（可能是压缩的）。
+func (g *Group) maybeHandoff(key string, value ByteView) {
+	if g.stats.pendingHandoffs.Load() == 0 {
+		return // 没有标记的条目，读取路径上只多一次原子读取
+	}
+	h, ok := g.takeHandoff(key)
+	if !ok {
+		return
+	}
+	go g.pushHandoff(context.Background(), key, value, h.owner, h.run)
+}
+
+// pushHandoff 把主缓存中的值 value 推送给 key 的新所有者 owner，并更新迁移的计数。
+func (g *Group) pushHandoff(ctx context.Context, key string, value ByteView, owner *httpGetter, run *rebalanceRun) {
+	value, err := g.decompress(value)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(ctx, rebalancePushTimeout)
+		err = owner.offer(ctx, g.name, key, value.ByteSlice())
+		cancel()
+	}
+	if err != nil {
+		run.failed.Add(1)
+		g.stats.rebalanceFailed.Add(1)
+		g.logger.Errorf("[GeeCache] group %s failed to hand off %q: %v", g.name, key, err)
+		return
+	}
+	run.pushed.Add(1)
+	g.stats.rebalancePushed.Add(1)
+}
@@ -0,0 +1,129 @@
+package geecache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WithRefreshAhead 让主缓存中剩余有效期不超过 WithExpiration 的 fraction 比例的条目在被读取时于后台重新加载，
+// 例如有效期为 1 分钟、fraction 为 0.2 时，写入 48 秒之后被读取的条目会提前刷新。
+// 本次读取照常返回缓存中的值，刷新与同一个键的其他加载共用一次 singleflight，经常被读取的键因此不会过期未命中。
+// 刷新遇到可以重试的错误时继续提供缓存中的旧值，之后的读取会再次触发刷新；遇到永久性错误或 ErrNotFound 时删除旧值。
+// fraction 会被限制在 (0, 1) 之内，没有设置 WithExpiration 时不起作用。
+func WithRefreshAhead(fraction float64) GroupOption {
+	return func(g *Group) {
+		if fraction <= 0 {
+			fraction = 0
+		} else if fraction >= 1 {
+			fraction = 0.99
+		}
+		g.refreshAhead = fraction
+	}
+}
+
+// maybeRefresh 在主缓存命中 key 之后调用，剩余有效期不超过 refreshAhead 比例时在后台刷新 key。
+func (g *Group) maybeRefresh(key string) {
+	if g.refreshAhead <= 0 || g.expiration <= 0 || g.frozen.Load() {
+		return
+	}
+	expires, ok := g.mainCache.expiration(key)
+	if !ok || expires.IsZero() {
+		return
+	}
+	if expires.Sub(g.clock.Now()) > time.Duration(float64(g.expiration)*g.refreshAhead) {
+		return
+	}
+	g.refresh(key)
+}
+
+// refresh 在后台重新加载 key，同一个键同时最多只有一个刷新。
+func (g *Group) refresh(key string) {
+	if _, busy := g.refreshing.LoadOrStore(key, struct{}{}); busy {
+		return
+	}
+	go func() {
+		defer g.refreshing.Delete(key)
+		if _, err := g.load(context.Background(), key); err != nil {
+			g.logger.Errorf("[GeeCache] refresh of %q failed: %v", key, err)
+			if definitive(err) {
+				g.mainCache.remove(key) // 数据源已经没有可用的值，不再提供旧值
+			}
+		}
+	}()
+}
+
+// KeepFresh 每隔 interval 在后台重新加载一次 keys，不论它们是否被读取，适合少量必须始终命中的关键键。
+// 返回的 stop 函数用于停止刷新，缓存组被销毁时也会停止。缓存组被冻结期间跳过刷新。
+func (g *Group) KeepFresh(interval time.Duration, keys ...string) (stop func()) {
+	ticker := g.clock.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C():
+				if g.frozen.Load() {
+					continue
+				}
+				for _, key := range keys {
+					g.refresh(key)
+				}
+			case <-done:
+				return
+			case <-g.done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// Refresh 强制从数据源重新加载 key 并替换缓存中的值，见 RefreshContext。
+func (g *Group) Refresh(key string) (ByteView, error) {
+	return g.RefreshContext(context.Background(), key)
+}
+
+// RefreshContext 绕过缓存从数据源重新加载 key，用新值替换本节点主缓存中的条目并返回它，
+// 适合在已知数据源发生变化、又不想等条目过期时调用。数据源报告 key 不存在或者返回永久性错误时删除缓存中的条目；
+// 可以重试的错误和未分类的错误保留缓存中原来的值。同一个键的并发刷新共用一次加载，但不会复用刷新之前已经开始的普通加载。
+// key 的所有者是其他节点时只丢弃本节点热点缓存和负缓存中的条目，再从所有者读取，所有者缓存的值不会被重新加载。
+// 缓存组被冻结时返回 ErrFrozen。
+func (g *Group) RefreshContext(ctx context.Context, key string) (ByteView, error) {
+	if key == "" {
+		return ByteView{}, fmt.Errorf("key is required")
+	}
+	if g.frozen.Load() {
+		return ByteView{}, ErrFrozen
+	}
+	if g.peers != nil {
+		if _, remote := g.peers.PickPeer(key); remote {
+			g.hotCache.remove(key)
+			g.negCache.remove(key)
+			return g.load(ctx, key)
+		}
+	}
+	viewi, err := g.loader.DoContext(ctx, "\x00refresh\x00"+key, func(ctx context.Context) (interface{}, error) {
+		g.stats.loads.Add(1)
+		value, err := g.getLocally(ctx, key)
+		if err != nil {
+			g.stats.loadErrors.Add(1)
+			if definitive(err) {
+				g.mainCache.remove(key)
+				g.cacheNotFound(key, err)
+			}
+			return ByteView{}, err
+		}
+		g.stats.localLoads.Add(1)
+		g.negCache.remove(key)
+		return value, nil
+	})
+	if err != nil {
+		return ByteView{}, err
+	}
+	return viewi.(ByteView), nil
+}
@@ -0,0 +1,105 @@
+package geecache
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Registry 是按名称管理缓存组的注册表。NewGroup 默认把缓存组注册到全局的 DefaultRegistry，
+// 设置 WithRegistry 后注册到指定的注册表；HTTPPool、管理接口和指标接口也可以绑定到指定的注册表
+// （见 WithPoolRegistry、WithAdminRegistry 和 Registry.MetricsHandler），
+// 这样同一个进程中的多套互相独立的缓存（例如测试中的多个节点）可以使用相同的缓存组名称而不会冲突。
+type Registry struct {
+	mu     sync.RWMutex      // 保护 groups
+	groups map[string]*Group // 已注册的缓存组，按名称索引
+}
+
+// NewRegistry 创建一个空的注册表。
+func NewRegistry() *Registry {
+	return &Registry{groups: make(map[string]*Group)}
+}
+
+// DefaultRegistry 是包级函数（NewGroup、GetGroup 等）使用的全局注册表。
+// 缓存组工厂（见 RegisterGroupFactory）只为 DefaultRegistry 按需创建缓存组。
+var DefaultRegistry = NewRegistry()
+
+// WithRegistry 把缓存组注册到 r 而不是 DefaultRegistry。
+func WithRegistry(r *Registry) GroupOption {
+	return func(g *Group) {
+		g.registry = r
+	}
+}
+
+// Get 返回注册表中名为 name 的缓存组，没有找到时返回 nil。
+// 对于 DefaultRegistry，有名称匹配的缓存组工厂时按需创建缓存组。
+func (r *Registry) Get(name string) *Group {
+	r.mu.RLock()
+	g := r.groups[name]
+	r.mu.RUnlock()
+	if g == nil && r == DefaultRegistry {
+		g = createGroup(name)
+	}
+	return g
+}
+
+// Deregister 把名为 name 的缓存组从注册表中移除，停止它的后台任务并释放缓存的数据，
+// 之后可以用同一个名称重新创建缓存组。没有该缓存组时返回 false。
+// 设置了 WithWriteBehind 时，队列中剩余的写入会在后台各写回一次。
+func (r *Registry) Deregister(name string) bool {
+	r.mu.Lock()
+	g := r.groups[name]
+	delete(r.groups, name)
+	r.mu.Unlock()
+	if g == nil {
+		return false
+	}
+	g.destroy()
+	return true
+}
+
+// List 返回注册表中所有缓存组的名称，按名称排序。
+func (r *Registry) List() []string {
+	list := r.list()
+	names := make([]string, len(list))
+	for i, g := range list {
+		names[i] = g.name
+	}
+	return names
+}
+
+// list 返回注册表中所有的缓存组，按名称排序。
+func (r *Registry) list() []*Group {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := make([]*Group, 0, len(r.groups))
+	for _, g := range r.groups {
+		list = append(list, g)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	return list
+}
+
+// MetricsHandler 返回以 Prometheus 文本格式导出注册表中所有缓存组指标的 http.Handler，见 MetricsHandler。
+func (r *Registry) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMetrics(w, r.list())
+	})
+}
+
+// GetGroup 返回之前使用 NewGroup 创建的具有指定名称的组。
+// 没有找到时，如果有名称匹配的缓存组工厂（见 RegisterGroupFactory）则按需创建，否则返回 nil。
+func GetGroup(name string) *Group {
+	return DefaultRegistry.Get(name)
+}
+
+// DeregisterGroup 把名为 name 的缓存组从全局注册表中移除，见 Registry.Deregister。
+func DeregisterGroup(name string) bool {
+	return DefaultRegistry.Deregister(name)
+}
+
+// ListGroups 返回全局注册表中所有缓存组的名称，按名称排序。
+func ListGroups() []string {
+	return DefaultRegistry.List()
+}
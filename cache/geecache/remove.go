@@ -0,0 +1,146 @@
+package geecache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// tokenRemover 由删除时能够返回所有者签发的一致性令牌的 PeerGetter 实现。
+type tokenRemover interface {
+	removeWithToken(ctx context.Context, group string, key string) (ConsistencyToken, error)
+}
+
+// Remove 在整个集群中失效 key：删除本地缓存中的条目，并向所有其他节点广播删除请求，
+// 清除它们可能持有的过期副本。返回 key 的所有者签发的一致性令牌，可以传给 GetConsistent。
+// 部分节点删除失败时仍会尝试其余节点，并返回失败的汇总。缓存组被冻结时返回 ErrFrozen。
+func (g *Group) Remove(key string) (ConsistencyToken, error) {
+	return g.RemoveContext(context.Background(), key)
+}
+
+// RemoveContext 与 Remove 相同，ctx 用于取消广播给其他节点的请求。
+func (g *Group) RemoveContext(ctx context.Context, key string) (ConsistencyToken, error) {
+	if key == "" {
+		return "", fmt.Errorf("key is required")
+	}
+	if g.frozen.Load() {
+		return "", ErrFrozen
+	}
+	token := g.removeLocally(key)
+	if g.peers == nil {
+		return token, nil
+	}
+	g.unreplicate(key, false) // 排在之前的推送不能在广播之后再把旧值写回副本
+
+	owner, remote := g.peers.PickPeer(key)
+	var peers []PeerGetter
+	if lister, ok := g.peers.(PeerLister); ok {
+		peers = lister.Peers()
+	} else if remote {
+		peers = []PeerGetter{owner} // 无法列出所有节点时至少失效所有者上的条目
+	}
+
+	var failed int
+	var firstErr error
+	for _, peer := range peers {
+		var err error
+		if tr, ok := peer.(tokenRemover); ok && remote && peer == owner {
+			token, err = tr.removeWithToken(ctx, g.name, key)
+		} else {
+			err = peer.Remove(ctx, g.name, key)
+		}
+		if err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if failed > 0 {
+		return token, fmt.Errorf("remove %q: %d of %d peers failed: %v", key, failed, len(peers), firstErr)
+	}
+	return token, nil
+}
+
+// RemoveLocal 只删除本节点缓存中的条目，不向其他节点广播，返回本节点签发的一致性令牌。
+// 它供传输层处理其他节点广播的删除请求，应用代码应当使用 Remove。缓存组被冻结时返回 ErrFrozen。
+func (g *Group) RemoveLocal(key string) (ConsistencyToken, error) {
+	if g.frozen.Load() {
+		return "", ErrFrozen
+	}
+	return g.removeLocally(key), nil
+}
+
+// Flush 丢弃本节点主缓存、热点缓存和负缓存中的所有条目，不向其他节点广播，返回本节点签发的一致性令牌。
+// 主缓存的淘汰计数随之从 0 重新开始。缓存组被冻结时返回 ErrFrozen。
+func (g *Group) Flush() (ConsistencyToken, error) {
+	if g.frozen.Load() {
+		return "", ErrFrozen
+	}
+	g.mainCache.reset()
+	g.hotCache.reset()
+	g.negCache.reset()
+	g.clearHandoffs()
+	return g.recordWrite(), nil
+}
+
+// removeLocally 删除本地缓存中的条目并签发一致性令牌。
+func (g *Group) removeLocally(key string) ConsistencyToken {
+	g.loader.Forget(key) // 删除之前加载的结果不应再共享给之后的读取
+	g.mainCache.remove(key)
+	g.hotCache.remove(key)
+	g.negCache.remove(key)
+	return g.recordWrite()
+}
+
+// Remove 通过 DELETE 请求删除对端缓存中的条目。
+func (h *httpGetter) Remove(ctx context.Context, group string, key string) error {
+	_, err := h.removeWithToken(ctx, group, key)
+	return err
+}
+
+// removeWithToken 通过 DELETE 请求删除对端缓存中的条目，返回对端签发的一致性令牌。
+func (h *httpGetter) removeWithToken(ctx context.Context, group string, key string) (ConsistencyToken, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, h.url(group, key), nil)
+	if err != nil {
+		return "", err
+	}
+	res, err := h.do(req)
+	if err != nil {
+		return "", err
+	}
+	res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusNoContent:
+		return ConsistencyToken(res.Header.Get(tokenHeader)), nil
+	case http.StatusConflict:
+		return "", ErrFrozen
+	default:
+		return "", fmt.Errorf("server returned: %v", res.Status)
+	}
+}
+
+// serveDelete 处理其他节点广播的删除请求，只删除本地缓存中的条目，不再继续广播。
+func (p *HTTPPool) serveDelete(w http.ResponseWriter, group *Group, key string) {
+	if group.Frozen() {
+		http.Error(w, ErrFrozen.Error(), http.StatusConflict)
+		return
+	}
+	w.Header().Set(tokenHeader, string(group.removeLocally(key)))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Peers 返回除本节点之外的所有节点，按地址排序。
+func (p *HTTPPool) Peers() []PeerGetter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var peers []PeerGetter
+	for _, peer := range p.peerList() {
+		if peer != p.self {
+			peers = append(peers, p.httpGetters[peer])
+		}
+	}
+	return peers
+}
+
+var _ PeerLister = (*HTTPPool)(nil)
@@ -0,0 +1,164 @@
+package geecache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// replicateTimeout 是把一个值推送给所有副本节点的超时时间。
+const replicateTimeout = 5 * time.Second
+
+// WithReplication 让每个键保存在环上从所有者开始的 n 个不同节点上，而不是只保存在所有者上：
+// 本节点从数据源加载或写入一个键之后，把值异步推送给其他副本节点（对端实现 PeerPusher 时），
+// 从所有者获取失败时依次尝试其他副本节点已经缓存的值（对端实现 PeerPeeker 时），之后才按 WithPeerFallback 的配置处理。
+// 副本节点只返回自己缓存中的值，不会再转发给所有者或者访问数据源，所有者下线时读取不会在副本之间互相等待。
+// 这样一个节点重启或下线时，它负责的键仍然可以从副本读到，不会全部变成未命中打到数据源。
+// 节点选择器需要实现 PeerReplicaPicker，否则只有所有者保存一份。n <= 1 时不复制。
+// 副本是尽力而为的：推送失败只记录日志，副本中的值按各自节点的 TTL 过期。
+func WithReplication(n int) GroupOption {
+	return func(g *Group) {
+		g.replication = n
+	}
+}
+
+// replicas 返回 key 除本节点之外的副本节点，没有设置 WithReplication 时返回 nil。
+func (g *Group) replicas(key string) []PeerGetter {
+	if g.replication <= 1 || g.peers == nil {
+		return nil
+	}
+	rp, ok := g.peers.(PeerReplicaPicker)
+	if !ok {
+		return nil
+	}
+	return rp.PickPeers(key, g.replication)
+}
+
+// replicaOp 是排队等待发送给副本节点的一次写入或删除。
+type replicaOp struct {
+	value  []byte // 写入的值，删除时为 nil
+	remove bool
+}
+
+// replicaQueue 按顺序把写入和删除发送给副本节点。所有推送由同一个 goroutine 依次发送，
+// 同一个键的上一次推送完成之后才会发送下一次，还在排队的推送被同一个键之后的写入或删除直接替换，
+// 因此副本最终保存的总是最后一次写入的值，不会被先发出、后到达的旧值覆盖。
+type replicaQueue struct {
+	mu      sync.Mutex
+	pending map[string]replicaOp // 排队的推送，每个键只保留最后一次
+	order   []string             // pending 中的键，按第一次入队的顺序排列
+	sending string               // 正在发送的键
+	running bool                 // 是否有 goroutine 在发送
+}
+
+// replicate 把本节点刚刚加载或写入的值异步推送给 key 的其他副本节点。
+func (g *Group) replicate(key string, value ByteView) {
+	if len(g.replicas(key)) == 0 {
+		return
+	}
+	g.enqueueReplica(key, replicaOp{value: value.ByteSlice()}) // 所有推送共用一份副本
+}
+
+// unreplicate 在 key 还有推送在排队或者正在发送时，在它之后再排一次删除。
+// 删除本身已经广播给了所有节点，这里只是保证之前的推送到达副本之后不会留下旧值。
+// force 为 true 时总是排队，用于不广播删除的场景（例如新的值超过大小上限而不缓存）。
+func (g *Group) unreplicate(key string, force bool) {
+	if len(g.replicas(key)) == 0 {
+		return
+	}
+	q := &g.replicaQ
+	q.mu.Lock()
+	_, queued := q.pending[key]
+	busy := queued || q.sending == key
+	q.mu.Unlock()
+	if busy || force {
+		g.enqueueReplica(key, replicaOp{remove: true})
+	}
+}
+
+// enqueueReplica 把 key 的推送加入队列，必要时启动发送的 goroutine。
+func (g *Group) enqueueReplica(key string, op replicaOp) {
+	q := &g.replicaQ
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending == nil {
+		q.pending = make(map[string]replicaOp)
+	}
+	if _, ok := q.pending[key]; !ok {
+		q.order = append(q.order, key)
+	}
+	q.pending[key] = op
+	if !q.running {
+		q.running = true
+		go g.sendReplicas()
+	}
+}
+
+// sendReplicas 依次发送队列中的推送，队列为空时退出。
+func (g *Group) sendReplicas() {
+	q := &g.replicaQ
+	for {
+		q.mu.Lock()
+		q.sending = ""
+		if len(q.order) == 0 {
+			q.running = false
+			q.mu.Unlock()
+			return
+		}
+		key := q.order[0]
+		q.order = q.order[1:]
+		op := q.pending[key]
+		delete(q.pending, key)
+		q.sending = key
+		q.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), replicateTimeout)
+		for _, peer := range g.replicas(key) {
+			var err error
+			if op.remove {
+				err = peer.Remove(ctx, g.name, key)
+			} else if pusher, ok := peer.(PeerPusher); ok {
+				err = pusher.Push(ctx, g.name, key, op.value)
+			}
+			if err != nil {
+				g.logger.Errorf("[GeeCache] group %s failed to replicate %q: %v", g.name, key, err)
+			}
+		}
+		cancel()
+	}
+}
+
+// getFromReplicas 在从所有者 owner 获取失败之后依次尝试 key 的其他副本节点已经缓存的值，返回第一个命中的结果。
+func (g *Group) getFromReplicas(ctx context.Context, owner PeerGetter, key string) (ByteView, bool) {
+	for _, peer := range g.replicas(key) {
+		if peer == owner {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		value, err := g.peekFromPeer(ctx, peer, key)
+		if err == nil {
+			return value, true
+		}
+		if errors.Is(err, ErrNotCached) {
+			continue // 副本还没有收到推送，或者已经淘汰
+		}
+		g.logger.Errorf("[GeeCache] Failed to get from replica %v", err)
+	}
+	return ByteView{}, false
+}
+
+// peekFromPeer 只读取副本节点 peer 已经缓存的 key。peer 不支持只读取缓存时返回 ErrNotCached。
+func (g *Group) peekFromPeer(ctx context.Context, peer PeerGetter, key string) (ByteView, error) {
+	peeker, ok := peer.(PeerPeeker)
+	if !ok {
+		return ByteView{}, ErrNotCached
+	}
+	bytes, err := peeker.Peek(ctx, g.name, key)
+	if err != nil {
+		return ByteView{}, err
+	}
+	return ByteView{b: bytes}, nil
+}
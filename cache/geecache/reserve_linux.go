@@ -0,0 +1,16 @@
+package geecache
+
+import (
+	"os"
+	"syscall"
+)
+
+// reserveFile 为文件 f 的前 size 个字节分配磁盘空间，磁盘空间不足时返回错误。
+// 文件系统不支持 fallocate 时退回写入零。
+func reserveFile(f *os.File, size int64) error {
+	err := syscall.Fallocate(int(f.Fd()), 0, 0, size)
+	if err == syscall.EOPNOTSUPP || err == syscall.ENOSYS {
+		return writeZeros(f, size)
+	}
+	return err
+}
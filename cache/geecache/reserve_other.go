@@ -0,0 +1,10 @@
+//go:build !linux
+
+package geecache
+
+import "os"
+
+// reserveFile 为文件 f 的前 size 个字节分配磁盘空间，磁盘空间不足时返回错误。
+func reserveFile(f *os.File, size int64) error {
+	return writeZeros(f, size)
+}
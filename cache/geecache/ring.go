@@ -0,0 +1,146 @@
+package geecache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"testProject/cache/clock"
+)
+
+// ringPath 是节点返回自己的节点列表视图的路径，位于 basePath 之下。
+const ringPath = "_ring"
+
+// ringProbes 是计算节点列表视图指纹时用于探测路由结果的键数量。
+const ringProbes = 64
+
+// RingView 是一个节点对节点列表的视图。
+type RingView struct {
+	Self  string   `json:"self"`
+	Peers []string `json:"peers"` // 有序的节点列表
+	Hash  string   `json:"hash"`  // 节点列表、虚拟节点数量以及探测键的路由结果的指纹
+}
+
+// WithPoolClock 设置 HTTPPool 的后台检查使用的时钟，默认使用真实时间。
+func WithPoolClock(c clock.Clock) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.clock = c
+	}
+}
+
+// RingView 返回本节点当前的节点列表视图。
+// 指纹同时包含一组探测键的路由结果，因此散列函数不同而节点列表相同的配置也会被识别为不一致。
+func (p *HTTPPool) RingView() RingView {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	view := RingView{Self: p.self, Peers: p.peerList()}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d|%s|", p.replicas, strings.Join(view.Peers, ","))
+	if p.peers != nil {
+		for i := 0; i < ringProbes; i++ {
+			b.WriteString(p.peers.Get("probe-" + strconv.Itoa(i)))
+			b.WriteByte(',')
+		}
+	}
+	view.Hash = checksum([]byte(b.String()))
+	return view
+}
+
+// VerifyRing 向所有其他节点查询它们的节点列表视图，返回视图与本节点不一致的节点。
+// 不一致说明不同节点会把同一个键路由到不同的所有者（例如配置分裂），需要人工介入。
+// 无法访问的节点不计入不一致，其错误通过 err 返回。
+func (p *HTTPPool) VerifyRing(ctx context.Context) (divergent []string, err error) {
+	local := p.RingView()
+	p.mu.Lock()
+	getters := make(map[string]*httpGetter, len(p.httpGetters))
+	for peer, g := range p.httpGetters {
+		getters[peer] = g
+	}
+	p.mu.Unlock()
+
+	var firstErr error
+	for _, peer := range local.Peers {
+		if peer == p.self {
+			continue
+		}
+		remote, err := getters[peer].ringView(ctx)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("ring view of %s: %v", peer, err)
+			}
+			continue
+		}
+		if remote.Hash != local.Hash {
+			divergent = append(divergent, peer)
+		}
+	}
+	return divergent, firstErr
+}
+
+// StartRingCheck 启动一个后台 goroutine，每隔 interval 调用一次 VerifyRing，
+// 发现不一致的节点时记录日志并调用 onDivergence（可以为 nil）。
+// 返回的 stop 函数用于停止该 goroutine。
+func (p *HTTPPool) StartRingCheck(interval time.Duration, onDivergence func(peers []string)) (stop func()) {
+	ticker := p.clock.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C():
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				divergent, err := p.VerifyRing(ctx)
+				cancel()
+				if err != nil {
+					p.Log("ring check: %v", err)
+				}
+				if len(divergent) > 0 {
+					p.Log("ring view diverges from peers %v", divergent)
+					if onDivergence != nil {
+						onDivergence(divergent)
+					}
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+	}
+}
+
+// serveRing 以 JSON 返回本节点的节点列表视图。
+func (p *HTTPPool) serveRing(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p.RingView())
+}
+
+// ringView 查询对端的节点列表视图。
+func (h *httpGetter) ringView(ctx context.Context) (RingView, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.baseURL+ringPath, nil)
+	if err != nil {
+		return RingView{}, err
+	}
+	res, err := h.do(req)
+	if err != nil {
+		return RingView{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return RingView{}, fmt.Errorf("server returned: %v", res.Status)
+	}
+	var view RingView
+	if err := json.NewDecoder(res.Body).Decode(&view); err != nil {
+		return RingView{}, fmt.Errorf("decoding ring view: %v", err)
+	}
+	return view, nil
+}
@@ -0,0 +1,146 @@
+package geecache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 节点服务端默认的超时时间。WriteTimeout 需要覆盖一次完整的加载，因此比读超时长。
+const (
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultReadTimeout       = 30 * time.Second
+	defaultWriteTimeout      = 60 * time.Second
+	defaultIdleTimeout       = 2 * time.Minute
+)
+
+// Server 在一个地址上提供 HTTPPool 的节点服务，封装了配置好超时时间的 http.Server，
+// 并支持优雅关闭：Shutdown 停止接受新请求，等待正在处理的请求完成。
+type Server struct {
+	pool   *HTTPPool
+	server *http.Server
+	done   chan error // Start 启动的服务协程结束时收到 Serve 的返回值
+
+	mu  sync.Mutex // 保护 lis，ListenAndServe 设置它时 Addr 可能正在其他协程中读取
+	lis net.Listener
+}
+
+// ServerOption 用于在创建 Server 时定制其配置。
+type ServerOption func(*http.Server)
+
+// WithReadTimeout 设置读取整个请求（包括请求体）的超时时间，默认为 30 秒。
+func WithReadTimeout(d time.Duration) ServerOption {
+	return func(s *http.Server) { s.ReadTimeout = d }
+}
+
+// WithWriteTimeout 设置从读完请求头到写完响应的超时时间，默认为 60 秒，应大于数据源加载一个键的耗时。
+func WithWriteTimeout(d time.Duration) ServerOption {
+	return func(s *http.Server) { s.WriteTimeout = d }
+}
+
+// WithIdleTimeout 设置 keep-alive 连接空闲多久之后关闭，默认为 2 分钟。
+func WithIdleTimeout(d time.Duration) ServerOption {
+	return func(s *http.Server) { s.IdleTimeout = d }
+}
+
+// NewServer 创建在 addr（例如 ":8001"）上提供 pool 服务的 Server。
+// 只有 pool 的 basePath 下的请求交给 pool 处理，其他路径返回 404。
+// pool 启用了 TLS 时 Server 通过 HTTPS 提供服务。
+func NewServer(addr string, pool *HTTPPool, opts ...ServerOption) *Server {
+	server := pool.HTTPServer(addr)
+	mux := http.NewServeMux()
+	mux.Handle(pool.basePath, pool)
+	server.Handler = mux
+	server.ReadHeaderTimeout = defaultReadHeaderTimeout
+	server.ReadTimeout = defaultReadTimeout
+	server.WriteTimeout = defaultWriteTimeout
+	server.IdleTimeout = defaultIdleTimeout
+	for _, opt := range opts {
+		opt(server)
+	}
+	return &Server{pool: pool, server: server}
+}
+
+// Start 开始监听并在后台提供服务，监听失败时直接返回错误。
+func (s *Server) Start() error {
+	lis, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	s.setListener(lis)
+	s.done = make(chan error, 1)
+	go func() {
+		err := s.serve(lis)
+		if err != nil {
+			s.pool.logger.Errorf("[GeeCache] server on %s stopped: %v", lis.Addr(), err)
+		}
+		s.done <- err
+	}()
+	return nil
+}
+
+// ListenAndServe 开始监听并提供服务，一直阻塞到服务结束。通过 Shutdown 正常关闭时返回 nil。
+func (s *Server) ListenAndServe() error {
+	lis, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	s.setListener(lis)
+	return s.serve(lis)
+}
+
+func (s *Server) setListener(lis net.Listener) {
+	s.mu.Lock()
+	s.lis = lis
+	s.mu.Unlock()
+}
+
+// serve 在 lis 上提供服务，正常关闭时返回 nil。
+func (s *Server) serve(lis net.Listener) error {
+	var err error
+	if s.server.TLSConfig != nil {
+		err = s.server.ServeTLS(lis, "", "")
+	} else {
+		err = s.server.Serve(lis)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Addr 返回 Server 实际监听的地址，在 Start 或 ListenAndServe 开始监听之前返回 nil。
+// 以 ":0" 创建 Server 时可以通过它获取系统分配的端口。
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	lis := s.lis
+	s.mu.Unlock()
+	if lis == nil {
+		return nil
+	}
+	return lis.Addr()
+}
+
+// Shutdown 优雅地关闭 Server：停止接受新连接，等待正在处理的请求完成后返回。
+// ctx 先结束时不再等待，返回 ctx 的错误，剩余的连接由调用方决定是否通过 Close 强制关闭。
+func (s *Server) Shutdown(ctx context.Context) error {
+	if err := s.server.Shutdown(ctx); err != nil {
+		return err
+	}
+	if s.done != nil {
+		select {
+		case <-s.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Close 立即关闭 Server 及其所有连接，不等待正在处理的请求。
+func (s *Server) Close() error {
+	return s.server.Close()
+}
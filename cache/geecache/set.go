@@ -0,0 +1,230 @@
+package geecache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "testProject/cache/geecachepb"
+)
+
+// handoffHeader 标记 PUT 请求是节点间移交的条目，只写入缓存，不写入数据源。
+const handoffHeader = "X-Geecache-Handoff"
+
+// ifAbsentHeader 标记移交的条目只在对端没有缓存 key 时写入，不覆盖对端已有的更新的值。
+const ifAbsentHeader = "X-Geecache-If-Absent"
+
+// Setter 把值写入缓存背后的数据源，用于 Group.Set 的写穿透或异步写回，见 WithWriteThrough 和 WithWriteBehind。
+type Setter interface {
+	Set(key string, value []byte) error
+}
+
+// A SetterFunc implements Setter with a function.
+type SetterFunc func(key string, value []byte) error
+
+// Set implements Setter interface function
+func (f SetterFunc) Set(key string, value []byte) error {
+	return f(key, value)
+}
+
+// WithWriteThrough 让 Group.Set 在写入缓存之前先通过 s 把值写入数据源，
+// 写入数据源失败时不会写入缓存。默认只写入缓存。它与 WithWriteBehind 互相替换，以最后设置的为准。
+func WithWriteThrough(s Setter) GroupOption {
+	return func(g *Group) {
+		g.setter = s
+		g.writeBehind = nil
+	}
+}
+
+// Set 把 key 的值设置为 value。本节点是 key 的所有者时直接写入本地缓存，
+// 否则通过 PUT 请求把写入转发给所有者节点。设置了 WithWriteThrough 时所有者会先写入数据源，
+// 设置了 WithWriteBehind 时所有者写入缓存之后由后台异步写回数据源。
+// 返回的一致性令牌可以传给 GetConsistent，以便之后的读取一定能看到这次写入。
+// 缓存组被冻结时返回 ErrFrozen。
+func (g *Group) Set(key string, value []byte) (ConsistencyToken, error) {
+	return g.SetContext(context.Background(), key, value)
+}
+
+// SetContext 与 Set 相同，ctx 用于取消转发给所有者节点的请求。
+func (g *Group) SetContext(ctx context.Context, key string, value []byte) (ConsistencyToken, error) {
+	if key == "" {
+		return "", fmt.Errorf("key is required")
+	}
+	if g.frozen.Load() {
+		return "", ErrFrozen
+	}
+	if g.peers != nil {
+		if peer, ok := g.peers.PickPeer(key); ok {
+			setter, ok := peer.(PeerSetter)
+			if !ok {
+				return "", fmt.Errorf("peer for key %q does not support Set", key)
+			}
+			token, err := setter.Set(ctx, g.name, key, value)
+			if err == nil {
+				g.hotCache.remove(key) // 本节点热点缓存中的副本已经过期
+				g.negCache.remove(key)
+			}
+			return token, err
+		}
+	}
+	return g.setLocally(key, value)
+}
+
+// SetLocal 把本节点当作 key 的所有者执行写入，不再转发给其他节点，返回本节点签发的一致性令牌。
+// 它供传输层处理其他节点转发来的写入，应用代码应当使用 Set。
+func (g *Group) SetLocal(key string, value []byte) (ConsistencyToken, error) {
+	return g.setLocally(key, value)
+}
+
+// setLockStripes 是 setLocally 使用的键锁的数量，同一个键的写入总是使用同一把锁。
+const setLockStripes = 64
+
+// setLock 返回 key 的写入使用的锁。
+func (g *Group) setLock(key string) *sync.Mutex {
+	return &g.setLocks[keyHash(key)%setLockStripes]
+}
+
+// setLocally 在本节点（key 的所有者）上执行写入：先写穿透到数据源，再写入缓存，最后签发一致性令牌。
+// 同一个键的写入持有同一把锁依次执行，写回数据源（或者写回队列）的顺序与写入缓存的顺序一致，
+// 并发的写入不会让数据源和缓存最终保存不同的值。
+func (g *Group) setLocally(key string, value []byte) (ConsistencyToken, error) {
+	if g.frozen.Load() {
+		return "", ErrFrozen
+	}
+	view := ByteView{b: value}
+	if err := g.checkEntrySize(key, view); err != nil {
+		return "", err
+	}
+	mu := g.setLock(key)
+	mu.Lock()
+	defer mu.Unlock()
+	if g.setter != nil {
+		if err := g.setter.Set(key, value); err != nil {
+			return "", err
+		}
+	}
+	if g.writeBehind != nil {
+		if err := g.writeBehind.enqueue(key, cloneBytes(value)); err != nil {
+			return "", err
+		}
+	}
+	g.learn(key)
+	if g.oversized(view) {
+		g.mainCache.remove(key) // 新的值不缓存，旧的值已经过期
+	} else {
+		g.mainCache.add(key, g.compress(ByteView{b: cloneBytes(value)}), g.expiresAt())
+	}
+	g.negCache.remove(key)
+	g.loader.Forget(key) // 写入之前加载的结果不应再共享给之后的读取
+	if g.oversized(view) {
+		g.unreplicate(key, true) // 副本节点上的旧值已经过期，新的值也不缓存
+	} else {
+		g.replicate(key, view) // 副本节点上的旧值已经过期
+	}
+	return g.recordWrite(), nil
+}
+
+// Set 通过 PUT 请求把写入转发给对端，返回对端签发的一致性令牌。
+func (h *httpGetter) Set(ctx context.Context, group string, key string, value []byte) (ConsistencyToken, error) {
+	res, err := h.put(ctx, group, key, value, nil)
+	if err != nil {
+		return "", err
+	}
+	return ConsistencyToken(res.Header.Get(tokenHeader)), nil
+}
+
+// put 发送 PUT 请求并检查响应状态，header 中的请求头会被加入请求。
+// 请求体是 protobuf 编码的 Request。
+func (h *httpGetter) put(ctx context.Context, group string, key string, value []byte, header http.Header) (*http.Response, error) {
+	body, err := proto.Marshal(&pb.Request{Group: group, Key: key, Value: value})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, h.url(group, key), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", protobufContentType)
+	req.Header.Set(checksumTrailer, checksum(body)) // 对端校验通过后才写入
+	res, err := h.do(req)
+	if err != nil {
+		return nil, err
+	}
+	res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusNoContent:
+		return res, nil
+	case http.StatusConflict:
+		return nil, ErrFrozen
+	default:
+		return nil, fmt.Errorf("server returned: %v", res.Status)
+	}
+}
+
+// servePut 处理 PUT 请求。移交的条目只写入缓存，其他写入在本节点上执行 Set 的写入流程。
+func (p *HTTPPool) servePut(w http.ResponseWriter, r *http.Request, group *Group, key string) {
+	buf, err := readBuffer(io.LimitReader(r.Body, maxPushBytes+1))
+	if err != nil {
+		http.Error(w, "reading request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer putBuffer(buf) // 解码结果不引用缓冲区
+	body := buf.Bytes()
+	if len(body) > maxPushBytes {
+		http.Error(w, "entry too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if sum := r.Header.Get(checksumTrailer); sum != "" && sum != checksum(body) {
+		http.Error(w, "checksum mismatch", http.StatusBadRequest)
+		return
+	}
+	in := &pb.Request{}
+	if err := proto.Unmarshal(body, in); err != nil {
+		http.Error(w, "decoding request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if in.Group != group.name || in.Key != key {
+		http.Error(w, "request body does not match the path", http.StatusBadRequest)
+		return
+	}
+	if group.Frozen() {
+		http.Error(w, ErrFrozen.Error(), http.StatusConflict)
+		return
+	}
+	if r.Header.Get(handoffHeader) != "" {
+		if r.Header.Get(ifAbsentHeader) != "" {
+			group.populateCacheIfAbsent(key, ByteView{b: in.Value})
+		} else {
+			group.populateCache(key, ByteView{b: in.Value}) // 副本推送的是所有者最新的值
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	token, err := group.setLocally(key, in.Value)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case err == ErrFrozen:
+			status = http.StatusConflict
+		case errors.Is(err, ErrValueTooLarge):
+			status = http.StatusRequestEntityTooLarge
+		case err == ErrWriteQueueFull:
+			status = http.StatusServiceUnavailable // 写回队列空出来之后可以重试
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set(tokenHeader, string(token))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var _ PeerSetter = (*httpGetter)(nil)
@@ -0,0 +1,55 @@
+package geecache
+
+// WithShards 把缓存组的主缓存划分为 n 个分片，条目按键的哈希值分布到各分片，
+// 每个分片有自己的锁和底层存储，并发读写不同分片的键时不会互相等待。
+// cacheBytes 平均分配给各分片，每个分片独立按淘汰策略淘汰；设置了 WithTenants 时租户配额同样按分片生效。
+// n <= 1 表示不分片，这是默认行为。
+func WithShards(n int) GroupOption {
+	return func(g *Group) {
+		g.shards = n
+	}
+}
+
+// split 把缓存划分为 n 个分片，分片继承缓存的时钟和底层存储的创建方式。
+// 只能在缓存开始使用之前调用。
+func (c *cache) split(n int) {
+	c.shards = make([]*cache, n)
+	for i := range c.shards {
+		c.shards[i] = &cache{
+			cacheBytes: shardBytes(c.cacheBytes, n),
+			newStore:   c.newStore,
+			clock:      c.clock,
+			overhead:   c.overhead,
+			admission:  c.admission,
+			onRemoved:  c.onRemoved,
+		}
+	}
+}
+
+// shard 返回 key 所在的分片。
+func (c *cache) shard(key string) *cache {
+	return c.shards[keyHash(key)%uint64(len(c.shards))]
+}
+
+// keyHash 返回 key 的哈希值，用于把键分散到分片或者锁上。
+func keyHash(key string) uint64 {
+	// 内联的 64 位 FNV-1a，避免每次读写都分配 hash.Hash。
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
+// shardBytes 返回把 cacheBytes 平均分给 n 个分片后每个分片的容量。
+// 容量有限时每个分片至少为 1，避免变成不限制。
+func shardBytes(cacheBytes int64, n int) int64 {
+	if cacheBytes <= 0 {
+		return cacheBytes
+	}
+	if per := cacheBytes / int64(n); per > 0 {
+		return per
+	}
+	return 1
+}
@@ -0,0 +1,88 @@
+package geecache
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Sink 接收 GetInto 读取到的值。缓存中的值可能以字节切片或字符串保存，
+// GetInto 调用与之对应的方法，使 Sink 可以直接使用缓存中的数据，不经过中间的副本。
+type Sink interface {
+	// SetString 把值设置为 s。
+	SetString(s string) error
+	// SetBytes 把值设置为 b。b 是缓存中的数据，实现只能读取，需要保留时必须复制。
+	SetBytes(b []byte) error
+}
+
+// GetInto 读取 key 的值并写入 dest。见 GetIntoContext。
+func (g *Group) GetInto(key string, dest Sink) error {
+	return g.GetIntoContext(context.Background(), key, dest)
+}
+
+// GetIntoContext 与 GetContext 相同，但把读取到的值直接写入 dest，
+// 需要字符串或 protobuf 消息的调用方不需要先通过 ByteSlice 复制一份。
+func (g *Group) GetIntoContext(ctx context.Context, key string, dest Sink) error {
+	v, err := g.get(ctx, key)
+	if err != nil {
+		return err
+	}
+	return setSinkView(dest, v)
+}
+
+// setSinkView 按视图的底层数据调用 dest 对应的方法。
+func setSinkView(dest Sink, v ByteView) error {
+	if v.b != nil {
+		return dest.SetBytes(v.b)
+	}
+	return dest.SetString(v.s)
+}
+
+// StringSink 返回把值写入 *sp 的 Sink。以字符串保存的值不需要复制。
+func StringSink(sp *string) Sink {
+	return stringSink{sp}
+}
+
+type stringSink struct{ sp *string }
+
+func (s stringSink) SetString(v string) error {
+	*s.sp = v
+	return nil
+}
+
+func (s stringSink) SetBytes(b []byte) error {
+	*s.sp = string(b)
+	return nil
+}
+
+// ByteSliceSink 返回把值的副本写入 *dst 的 Sink，调用方可以随意修改得到的字节切片。
+func ByteSliceSink(dst *[]byte) Sink {
+	return byteSliceSink{dst}
+}
+
+type byteSliceSink struct{ dst *[]byte }
+
+func (s byteSliceSink) SetString(v string) error {
+	*s.dst = []byte(v)
+	return nil
+}
+
+func (s byteSliceSink) SetBytes(b []byte) error {
+	*s.dst = cloneBytes(b) // 缓存中的数据不可变，必须复制
+	return nil
+}
+
+// ProtoSink 返回把值作为 protobuf 编码的消息解码到 m 中的 Sink，直接从缓存中的数据解码，不经过中间的副本。
+func ProtoSink(m proto.Message) Sink {
+	return protoSink{m}
+}
+
+type protoSink struct{ m proto.Message }
+
+func (s protoSink) SetString(v string) error {
+	return proto.Unmarshal([]byte(v), s.m)
+}
+
+func (s protoSink) SetBytes(b []byte) error {
+	return proto.Unmarshal(b, s.m)
+}
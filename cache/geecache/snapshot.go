@@ -0,0 +1,246 @@
+package geecache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 快照文件的格式（整数都是大端序或 varint）：
+//
+//	magic "GEESNAP" | version uint16
+//	每个条目：1 | uvarint len(key) | key | uvarint len(value) | value | varint 过期时间（UnixNano，0 表示永不过期）
+//	0 | crc32 uint32（从 magic 开始到结束标记的所有字节）
+//
+// 值以解压后的形式保存，恢复时按当前缓存组的配置重新压缩。
+const (
+	snapshotMagic   = "GEESNAP"
+	snapshotVersion = 1
+)
+
+// snapshotScanCount 是生成快照时每次遍历的键数量。
+const snapshotScanCount = 256
+
+// ErrBadSnapshot 表示快照的格式无法识别、版本不受支持或者内容已经损坏。
+var ErrBadSnapshot = errors.New("geecache: bad snapshot")
+
+// Snapshot 把本节点主缓存中的条目（键、值和过期时间）写入 w，之后可以通过 Restore 恢复。
+// 遍历期间不会阻塞读写，快照不是某一时刻的精确副本：遍历期间写入或淘汰的条目可能包含也可能不包含在内。
+func (g *Group) Snapshot(w io.Writer) error {
+	crc := crc32.NewIEEE()
+	bw := bufio.NewWriter(io.MultiWriter(w, crc))
+	bw.WriteString(snapshotMagic)
+	binary.Write(bw, binary.BigEndian, uint16(snapshotVersion))
+
+	var buf [binary.MaxVarintLen64]byte
+	var cursor uint64
+	for {
+		keys, next := g.Scan(cursor, "", snapshotScanCount)
+		for _, key := range keys {
+			value, ok := g.mainCache.peek(key)
+			if !ok {
+				continue // 遍历期间已经被淘汰
+			}
+			value, err := g.decompress(value)
+			if err != nil {
+				continue
+			}
+			var expires int64
+			if t, ok := g.mainCache.expiration(key); ok && !t.IsZero() {
+				expires = t.UnixNano()
+			}
+			bw.WriteByte(1)
+			bw.Write(buf[:binary.PutUvarint(buf[:], uint64(len(key)))])
+			bw.WriteString(key)
+			bw.Write(buf[:binary.PutUvarint(buf[:], uint64(value.Len()))])
+			bw.Write(value.bytes())
+			bw.Write(buf[:binary.PutVarint(buf[:], expires)])
+		}
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+	bw.WriteByte(0)
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.BigEndian, crc.Sum32())
+}
+
+// Restore 把 Snapshot 写出的条目读入本节点的主缓存，条目保留快照中的过期时间，不经过数据源。
+// 已经过期的条目和超过 WithMaxEntryBytes 上限的条目被跳过，缓存中已有的同名条目被快照中的值覆盖。
+// 整个快照校验通过之后才写入缓存，快照损坏时返回 ErrBadSnapshot 并且不写入任何条目。缓存组被冻结时返回 ErrFrozen。
+func (g *Group) Restore(r io.Reader) error {
+	if g.frozen.Load() {
+		return ErrFrozen
+	}
+	sr := &snapshotReader{r: bufio.NewReader(r), crc: crc32.NewIEEE()}
+
+	header := make([]byte, len(snapshotMagic)+2)
+	if _, err := io.ReadFull(sr, header); err != nil || string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return fmt.Errorf("%w: missing header", ErrBadSnapshot)
+	}
+	if v := binary.BigEndian.Uint16(header[len(snapshotMagic):]); v != snapshotVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrBadSnapshot, v)
+	}
+
+	type entry struct {
+		key     string
+		value   ByteView
+		expires time.Time
+	}
+	var entries []entry
+	now := g.clock.Now()
+	for {
+		flag, err := sr.ReadByte()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrBadSnapshot, err)
+		}
+		if flag == 0 {
+			break
+		}
+		key, err := sr.readBytes()
+		if err != nil {
+			return err
+		}
+		value, err := sr.readBytes()
+		if err != nil {
+			return err
+		}
+		ns, err := binary.ReadVarint(sr)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrBadSnapshot, err)
+		}
+		var expires time.Time
+		if ns != 0 {
+			if expires = time.Unix(0, ns); !expires.After(now) {
+				continue // 快照保存之后已经过期
+			}
+		}
+		entries = append(entries, entry{string(key), ByteView{b: value}, expires})
+	}
+
+	sum := sr.crc.Sum32() // 校验和本身不计入校验和
+	var want uint32
+	if err := binary.Read(sr.r, binary.BigEndian, &want); err != nil {
+		return fmt.Errorf("%w: missing checksum", ErrBadSnapshot)
+	}
+	if want != sum {
+		return fmt.Errorf("%w: checksum mismatch", ErrBadSnapshot)
+	}
+	for _, e := range entries {
+		if !g.oversized(e.value) {
+			g.learn(e.key)
+			g.mainCache.add(e.key, g.compress(e.value), e.expires)
+		}
+	}
+	return nil
+}
+
+// maxSnapshotField 是快照中单个键或值的最大长度，防止损坏的长度字段导致分配过大的内存。
+const maxSnapshotField = 1 << 30
+
+// snapshotReader 读取快照，同时计算已经读取的字节的校验和。
+type snapshotReader struct {
+	r   *bufio.Reader
+	crc hash.Hash32
+}
+
+func (sr *snapshotReader) Read(p []byte) (int, error) {
+	n, err := sr.r.Read(p)
+	sr.crc.Write(p[:n])
+	return n, err
+}
+
+func (sr *snapshotReader) ReadByte() (byte, error) {
+	b, err := sr.r.ReadByte()
+	if err == nil {
+		sr.crc.Write([]byte{b})
+	}
+	return b, err
+}
+
+// readBytes 读取一个以 uvarint 长度为前缀的字段。
+func (sr *snapshotReader) readBytes() ([]byte, error) {
+	n, err := binary.ReadUvarint(sr)
+	if err != nil || n > maxSnapshotField {
+		return nil, fmt.Errorf("%w: bad field length", ErrBadSnapshot)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(sr, b); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadSnapshot, err)
+	}
+	return b, nil
+}
+
+// WithSnapshotFile 让缓存组在创建时从 path 恢复快照，之后每隔 interval 把快照写入 path，
+// 这样节点重启之后不必从完全空的缓存开始。path 不存在时从空缓存开始；快照先写入临时文件再重命名，
+// 写到一半的快照不会覆盖之前完整的快照。缓存组被销毁时停止定期写入，不会再写最后一次快照。
+func WithSnapshotFile(path string, interval time.Duration) GroupOption {
+	return func(g *Group) {
+		g.snapshotPath = path
+		g.snapshotInterval = interval
+	}
+}
+
+// startSnapshots 在设置了 WithSnapshotFile 时恢复快照并启动定期写入快照的 goroutine，缓存组被销毁后退出。
+func (g *Group) startSnapshots() {
+	if g.snapshotPath == "" {
+		return
+	}
+	if err := g.restoreFile(g.snapshotPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		g.logger.Errorf("[GeeCache] group %s failed to restore snapshot %s: %v", g.name, g.snapshotPath, err)
+	}
+	if g.snapshotInterval <= 0 {
+		return
+	}
+	ticker := g.clock.NewTicker(g.snapshotInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C():
+				if err := g.snapshotFile(g.snapshotPath); err != nil {
+					g.logger.Errorf("[GeeCache] group %s failed to write snapshot %s: %v", g.name, g.snapshotPath, err)
+				}
+			case <-g.done:
+				return
+			}
+		}
+	}()
+}
+
+// restoreFile 从文件 path 恢复快照。
+func (g *Group) restoreFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return g.Restore(f)
+}
+
+// snapshotFile 把快照写入同一目录下的临时文件，写完之后重命名为 path。
+func (g *Group) snapshotFile(path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // 重命名成功之后删除不存在的文件，没有影响
+	if err := g.Snapshot(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
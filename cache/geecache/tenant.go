@@ -0,0 +1,257 @@
+package geecache
+
+import (
+	"strings"
+	"time"
+
+	"testProject/cache/clock"
+	"testProject/cache/lru"
+)
+
+// TenantFunc 返回 key 所属的租户，返回空字符串表示该 key 不属于任何租户。
+type TenantFunc func(key string) string
+
+// TenantPrefix 返回按 key 的前缀划分租户的 TenantFunc：
+// 例如 sep 为 ":" 时，"acme:user:1" 属于租户 "acme"，不包含 sep 的 key 不属于任何租户。
+func TenantPrefix(sep string) TenantFunc {
+	return func(key string) string {
+		if i := strings.Index(key, sep); i > 0 {
+			return key[:i]
+		}
+		return ""
+	}
+}
+
+// TenantQuota 是单个租户在缓存组中可以占用的上限，字段为 0 表示不限制。
+type TenantQuota struct {
+	MaxBytes   int64 // 租户的条目最多占用的内存
+	MaxEntries int   // 租户最多拥有的条目数量
+}
+
+// WithTenants 让缓存组按 tenantOf 把条目归属到租户，每个租户的配额为 quota，
+// overrides 中可以为个别租户单独设置配额。
+// 每个租户的条目单独按 LRU 淘汰：租户超出配额时只淘汰该租户自己的条目；
+// 整个缓存组超出 cacheBytes 时，从占用内存最多的租户中淘汰，
+// 因此一个流量很大的租户不会把其他租户的数据挤出缓存。
+func WithTenants(tenantOf TenantFunc, quota TenantQuota, overrides map[string]TenantQuota) GroupOption {
+	return func(g *Group) {
+		g.mainCache.newStore = func(cacheBytes int64, clk clock.Clock) EvictionPolicy {
+			return newTenantStore(cacheBytes, clk, tenantOf, quota, overrides)
+		}
+	}
+}
+
+// TenantUsage 返回租户当前占用的内存和条目数量。缓存组未按租户划分时返回 0。
+func (g *Group) TenantUsage(tenant string) (bytes int64, entries int) {
+	if g.mainCache.shards == nil {
+		return g.mainCache.tenantUsage(tenant)
+	}
+	for _, s := range g.mainCache.shards {
+		b, n := s.tenantUsage(tenant)
+		bytes, entries = bytes+b, entries+n
+	}
+	return bytes, entries
+}
+
+// tenantUsage 返回租户在缓存 c 中占用的内存和条目数量。
+func (c *cache) tenantUsage(tenant string) (bytes int64, entries int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.store.(*tenantStore)
+	if !ok {
+		return 0, 0
+	}
+	if tc, ok := t.caches[tenant]; ok {
+		return tc.Bytes(), tc.Len()
+	}
+	return 0, 0
+}
+
+// tenantStore 是按租户划分的 EvictionPolicy，每个租户拥有一个独立的 LRU 缓存。
+type tenantStore struct {
+	maxBytes     int64                  // 所有租户合计的最大内存，0 表示不限制
+	nbytes       int64                  // 所有租户合计已经使用的内存
+	clock        clock.Clock            // 各租户的 LRU 缓存使用的时钟
+	tenantOf     TenantFunc             // 计算 key 所属的租户
+	defaultQuota TenantQuota            // 没有单独设置配额的租户使用的配额
+	quotas       map[string]TenantQuota // 单独设置的租户配额
+	caches       map[string]*lru.Cache  // 每个租户的 LRU 缓存，不属于任何租户的 key 使用 "" 租户
+	listeners    []lru.EvictionListener // 注册到每个租户的 LRU 缓存上的淘汰监听器
+}
+
+// newTenantStore 创建一个按租户划分的存储。
+func newTenantStore(maxBytes int64, clk clock.Clock, tenantOf TenantFunc, quota TenantQuota, quotas map[string]TenantQuota) *tenantStore {
+	return &tenantStore{
+		maxBytes:     maxBytes,
+		clock:        clk,
+		tenantOf:     tenantOf,
+		defaultQuota: quota,
+		quotas:       quotas,
+		caches:       make(map[string]*lru.Cache),
+	}
+}
+
+// quota 返回租户的配额。不属于任何租户的 key 不受配额限制。
+func (t *tenantStore) quota(tenant string) TenantQuota {
+	if q, ok := t.quotas[tenant]; ok {
+		return q
+	}
+	if tenant == "" {
+		return TenantQuota{}
+	}
+	return t.defaultQuota
+}
+
+// Get 从 key 所属租户的缓存中查找值，已经过期的条目被删除时同步更新合计的内存占用。
+func (t *tenantStore) Get(key string) (lru.Value, bool) {
+	if tc, ok := t.caches[t.tenantOf(key)]; ok {
+		before := tc.Bytes()
+		v, ok := tc.Get(key)
+		t.nbytes -= before - tc.Bytes()
+		return v, ok
+	}
+	return nil, false
+}
+
+// Peek 从所属租户的缓存中读取 key，不影响条目的淘汰顺序。
+func (t *tenantStore) Peek(key string) (lru.Value, bool) {
+	if tc, ok := t.caches[t.tenantOf(key)]; ok {
+		return tc.Peek(key)
+	}
+	return nil, false
+}
+
+// Contains 报告所属租户的缓存中是否有 key。
+func (t *tenantStore) Contains(key string) bool {
+	tc, ok := t.caches[t.tenantOf(key)]
+	return ok && tc.Contains(key)
+}
+
+// Expiration 返回 key 对应条目的过期时间。
+func (t *tenantStore) Expiration(key string) (time.Time, bool) {
+	if tc, ok := t.caches[t.tenantOf(key)]; ok {
+		return tc.Expiration(key)
+	}
+	return time.Time{}, false
+}
+
+// Evictions 返回所有租户淘汰的条目数量之和。
+func (t *tenantStore) Evictions() int64 {
+	var n int64
+	for _, tc := range t.caches {
+		n += tc.Evictions()
+	}
+	return n
+}
+
+// Remove 从所属租户的缓存中删除 key 对应的条目。
+func (t *tenantStore) Remove(key string) bool {
+	tc, ok := t.caches[t.tenantOf(key)]
+	if !ok {
+		return false
+	}
+	before := tc.Bytes()
+	ok = tc.Remove(key)
+	t.nbytes -= before - tc.Bytes()
+	return ok
+}
+
+// RemoveExpired 删除所有租户中已经过期的条目。
+func (t *tenantStore) RemoveExpired() int {
+	n := 0
+	for _, tc := range t.caches {
+		before := tc.Bytes()
+		n += tc.RemoveExpired()
+		t.nbytes -= before - tc.Bytes()
+	}
+	return n
+}
+
+// AddWithExpire 把键值对加入所属租户的缓存，租户超出配额时淘汰该租户最久未访问的条目，
+// 缓存组整体超出限制时从占用内存最多的租户中淘汰。
+func (t *tenantStore) AddWithExpire(key string, value lru.Value, expires time.Time) {
+	tenant := t.tenantOf(key)
+	quota := t.quota(tenant)
+	tc, ok := t.caches[tenant]
+	if !ok {
+		tc = lru.New(quota.MaxBytes, nil, lru.WithClock(t.clock))
+		for _, fn := range t.listeners {
+			tc.AddEvictionListener(fn)
+		}
+		t.caches[tenant] = tc
+	}
+
+	before := tc.Bytes()
+	tc.AddWithExpire(key, value, expires) // 按租户的字节配额淘汰
+	for quota.MaxEntries > 0 && tc.Len() > quota.MaxEntries {
+		tc.RemoveOldest() // 按租户的条目数量配额淘汰
+	}
+	t.nbytes += tc.Bytes() - before
+	t.evict()
+}
+
+// AddEvictionListener 注册一个淘汰监听器，任何租户的条目被淘汰或删除时都会调用。
+func (t *tenantStore) AddEvictionListener(fn lru.EvictionListener) {
+	t.listeners = append(t.listeners, fn)
+	for _, tc := range t.caches {
+		tc.AddEvictionListener(fn)
+	}
+}
+
+// evict 在整体超出内存限制时，不断从占用内存最多的租户中淘汰最久未访问的条目。
+func (t *tenantStore) evict() {
+	for t.maxBytes != 0 && t.maxBytes < t.nbytes {
+		var largest *lru.Cache
+		for _, tc := range t.caches {
+			if largest == nil || tc.Bytes() > largest.Bytes() {
+				largest = tc
+			}
+		}
+		before := largest.Bytes()
+		largest.RemoveOldest()
+		t.nbytes -= before - largest.Bytes()
+	}
+}
+
+// Len 返回所有租户的条目数量之和。
+func (t *tenantStore) Len() int {
+	n := 0
+	for _, tc := range t.caches {
+		n += tc.Len()
+	}
+	return n
+}
+
+// Bytes 返回所有租户已经使用的内存之和。
+func (t *tenantStore) Bytes() int64 {
+	return t.nbytes
+}
+
+// Sample 从每个租户中分别采样，再按租户的条目数量加权合并为最多 n 个条目。
+func (t *tenantStore) Sample(n int) []lru.EntryInfo {
+	samples := make([][]lru.EntryInfo, 0, len(t.caches))
+	sizes := make([]int, 0, len(t.caches))
+	for _, tc := range t.caches {
+		samples = append(samples, tc.Sample(n))
+		sizes = append(sizes, tc.Len())
+	}
+	return lru.MergeSamples(n, samples, sizes)
+}
+
+// Scan 对每个租户使用同一个游标遍历，再合并为一页。
+func (t *tenantStore) Scan(cursor uint64, prefix string, count int) ([]string, uint64) {
+	pages := make([][]string, 0, len(t.caches))
+	nexts := make([]uint64, 0, len(t.caches))
+	for _, tc := range t.caches {
+		keys, next := tc.Scan(cursor, prefix, count)
+		pages = append(pages, keys)
+		nexts = append(nexts, next)
+	}
+	return lru.MergeScans(count, pages, nexts)
+}
+
+// Resize 调整所有租户合计的最大内存，超出部分从占用内存最多的租户中淘汰。
+func (t *tenantStore) Resize(maxBytes int64) {
+	t.maxBytes = maxBytes
+	t.evict()
+}
@@ -0,0 +1,62 @@
+package geecache
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// WithTLSConfig 让节点之间通过 HTTPS 通信：向对等节点发起请求时使用 cfg 校验对端证书，
+// cfg.Certificates 不为空时同时出示客户端证书（双向 TLS）；HTTPServer 返回的服务端也使用 cfg。
+// 启用 TLS 后节点地址应使用 https:// 前缀。同时设置了 WithHTTPClient 时以 WithHTTPClient 为准。
+func WithTLSConfig(cfg *tls.Config) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.tlsConfig = cfg
+	}
+}
+
+// NewHTTPPoolTLS 创建节点之间通过 HTTPS 通信的 HTTPPool，等价于 NewHTTPPool(self, WithTLSConfig(tlsConfig), opts...)。
+func NewHTTPPoolTLS(self string, tlsConfig *tls.Config, opts ...HTTPPoolOption) *HTTPPool {
+	return NewHTTPPool(self, append([]HTTPPoolOption{WithTLSConfig(tlsConfig)}, opts...)...)
+}
+
+// HTTPServer 返回在 addr 上提供本节点服务的 http.Server，已经设置好 Handler、协议以及 TLS 配置。
+// 没有启用 TLS 时调用其 ListenAndServe，服务端同时支持 HTTP/1 和 h2c；
+// 启用 TLS 时调用 ListenAndServeTLS("", "")，证书来自 TLS 配置，
+// 配置中的 ClientAuth 和 ClientCAs 决定是否要求并校验对端的客户端证书。
+func (p *HTTPPool) HTTPServer(addr string) *http.Server {
+	if p.tlsConfig == nil {
+		return &http.Server{Addr: addr, Handler: p, Protocols: PeerProtocols()}
+	}
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	return &http.Server{Addr: addr, Handler: p, Protocols: &protocols, TLSConfig: p.tlsConfig.Clone()}
+}
+
+// LoadMutualTLSConfig 从 PEM 文件加载双向 TLS 配置：certFile 和 keyFile 是本节点的证书和私钥，
+// 同时用作服务端证书和客户端证书；caFile 是签发所有节点证书的 CA，用于校验对端的证书。
+// 返回的配置要求并校验客户端证书，可以同时用于服务端和客户端。
+func LoadMutualTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading key pair: %v", err)
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading CA: %v", err)
+	}
+	cas := x509.NewCertPool()
+	if !cas.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      cas,
+		ClientCAs:    cas,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
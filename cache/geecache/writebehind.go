@@ -0,0 +1,191 @@
+package geecache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// 写回数据源失败时的重试策略：最多尝试 writeBehindAttempts 次，每次重试之前的等待时间翻倍。
+const (
+	writeBehindAttempts = 5
+	writeBehindBackoff  = 100 * time.Millisecond
+)
+
+// ErrWriteQueueFull 表示写回队列已满，这次写入既没有写入缓存，也没有排队写回数据源。
+var ErrWriteQueueFull = errors.New("geecache: write-behind queue is full")
+
+// BatchSetter 由能够一次写入多个键的 Setter 实现，写回队列会优先使用它批量写入。
+type BatchSetter interface {
+	SetMulti(entries map[string][]byte) error
+}
+
+// WithWriteBehind 让 Group.Set 立即写入缓存并返回，由后台 goroutine 把写入分批异步写回数据源 s。
+// 队列中最多有 queueSize 个还没有写回的写入，队列已满时 Set 返回 ErrWriteQueueFull；
+// 每批最多 batchSize 个键，同一批中同一个键只写回最后的值，s 实现了 BatchSetter 时整批一次写入。
+// 写回失败时按指数退避重试，多次失败之后放弃这一批并记录错误日志，缓存中的值不会回滚。
+// 它与 WithWriteThrough 互相替换，以最后设置的为准。
+func WithWriteBehind(s Setter, queueSize, batchSize int) GroupOption {
+	return func(g *Group) {
+		if queueSize <= 0 {
+			queueSize = 1
+		}
+		if batchSize <= 0 {
+			batchSize = 1
+		}
+		g.setter = nil
+		g.writeBehind = newWriteBehind(s, queueSize, batchSize)
+	}
+}
+
+// writeOp 是一次等待写回数据源的写入。
+type writeOp struct {
+	key   string
+	value []byte
+}
+
+// writeBehind 是写回数据源的队列。
+type writeBehind struct {
+	setter    Setter
+	queue     chan writeOp
+	batchSize int
+
+	mu      sync.Mutex
+	pending int           // 已经排队但还没有处理完的写入数量
+	drained chan struct{} // pending 变为 0 时关闭
+}
+
+func newWriteBehind(s Setter, queueSize, batchSize int) *writeBehind {
+	drained := make(chan struct{})
+	close(drained)
+	return &writeBehind{setter: s, queue: make(chan writeOp, queueSize), batchSize: batchSize, drained: drained}
+}
+
+// enqueue 把写入加入队列，队列已满时返回 ErrWriteQueueFull。
+func (w *writeBehind) enqueue(key string, value []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	select {
+	case w.queue <- writeOp{key: key, value: value}:
+	default:
+		return ErrWriteQueueFull
+	}
+	if w.pending == 0 {
+		w.drained = make(chan struct{})
+	}
+	w.pending++
+	return nil
+}
+
+// done 记录 n 个写入已经处理完（写回成功或者放弃）。
+func (w *writeBehind) done(n int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending -= n
+	if w.pending == 0 {
+		close(w.drained)
+	}
+}
+
+// WaitWrites 等待设置了 WithWriteBehind 的缓存组把此前排队的写入全部写回数据源（或者重试失败后放弃），
+// ctx 先结束时返回 ctx 的错误。没有设置 WithWriteBehind 时立即返回。适合在关闭服务之前调用。
+func (g *Group) WaitWrites(ctx context.Context) error {
+	w := g.writeBehind
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	drained := w.drained
+	w.mu.Unlock()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// startWriteBehind 在设置了 WithWriteBehind 时启动写回数据源的 goroutine。
+// 缓存组被销毁后它尝试把队列中剩余的写入各写回一次，然后退出。
+func (g *Group) startWriteBehind() {
+	w := g.writeBehind
+	if w == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-g.done
+		cancel() // 停止重试的等待
+	}()
+	go func() {
+		for {
+			select {
+			case op := <-w.queue:
+				g.persist(ctx, w.collect(op))
+			case <-g.done:
+				for {
+					select {
+					case op := <-w.queue:
+						g.persist(ctx, w.collect(op))
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+}
+
+// writeBatch 是一批写回的写入，同一个键只保留最后的值。
+type writeBatch struct {
+	entries map[string][]byte
+	ops     int // 这一批合并的写入数量
+}
+
+// collect 从 first 开始，把队列中已有的写入合并为一批，最多 batchSize 个写入。
+func (w *writeBehind) collect(first writeOp) writeBatch {
+	b := writeBatch{entries: map[string][]byte{first.key: first.value}, ops: 1}
+	for b.ops < w.batchSize {
+		select {
+		case op := <-w.queue:
+			b.entries[op.key] = op.value
+			b.ops++
+		default:
+			return b
+		}
+	}
+	return b
+}
+
+// persist 把一批写入写回数据源，失败时重试，最终失败时记录错误日志。
+func (g *Group) persist(ctx context.Context, b writeBatch) {
+	w := g.writeBehind
+	defer w.done(b.ops)
+	backoff := writeBehindBackoff
+	for attempt := 1; ; attempt++ {
+		err := w.write(b.entries)
+		if err == nil {
+			return
+		}
+		if attempt >= writeBehindAttempts || !g.sleep(ctx, backoff) {
+			g.logger.Errorf("[GeeCache] group %s failed to write %d keys behind after %d attempts: %v", g.name, len(b.entries), attempt, err)
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// write 把 entries 写入数据源，数据源不支持批量写入时逐个写入，遇到第一个错误时停止。
+// 重试时已经写入成功的键会再写入一次，数据源的写入应当是幂等的。
+func (w *writeBehind) write(entries map[string][]byte) error {
+	if bs, ok := w.setter.(BatchSetter); ok {
+		return bs.SetMulti(entries)
+	}
+	for key, value := range entries {
+		if err := w.setter.Set(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,356 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: geecachepb.proto
+
+package geecachepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Request 是节点间请求的请求体，HTTP 传输层只在写入请求（PUT）中使用，gRPC 传输层的所有方法都使用它。
+// HTTP 请求的 group 和 key 同时出现在请求路径中用于路由，请求体中的值用于校验两者一致。
+type Request struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Value         []byte                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Flags         uint32                 `protobuf:"varint,4,opt,name=flags,proto3" json:"flags,omitempty"` // 描述 value 的标志位，例如是否经过压缩
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Request) Reset() {
+	*x = Request{}
+	mi := &file_geecachepb_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Request) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Request) ProtoMessage() {}
+
+func (x *Request) ProtoReflect() protoreflect.Message {
+	mi := &file_geecachepb_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Request.ProtoReflect.Descriptor instead.
+func (*Request) Descriptor() ([]byte, []int) {
+	return file_geecachepb_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Request) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *Request) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Request) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *Request) GetFlags() uint32 {
+	if x != nil {
+		return x.Flags
+	}
+	return 0
+}
+
+// Response 是节点间读取请求（GET）的响应体，gRPC 传输层的所有方法都使用它。
+type Response struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         []byte                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	TtlMs         int64                  `protobuf:"varint,2,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"` // 条目的剩余有效期（毫秒），0 表示永不过期
+	Flags         uint32                 `protobuf:"varint,3,opt,name=flags,proto3" json:"flags,omitempty"`              // 描述 value 的标志位，例如是否经过压缩
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`               // 流式读取中单个键失败时的错误信息，为空表示成功
+	Token         string                 `protobuf:"bytes,5,opt,name=token,proto3" json:"token,omitempty"`               // 写入类请求返回的一致性令牌
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Response) Reset() {
+	*x = Response{}
+	mi := &file_geecachepb_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Response) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Response) ProtoMessage() {}
+
+func (x *Response) ProtoReflect() protoreflect.Message {
+	mi := &file_geecachepb_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Response.ProtoReflect.Descriptor instead.
+func (*Response) Descriptor() ([]byte, []int) {
+	return file_geecachepb_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Response) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *Response) GetTtlMs() int64 {
+	if x != nil {
+		return x.TtlMs
+	}
+	return 0
+}
+
+func (x *Response) GetFlags() uint32 {
+	if x != nil {
+		return x.Flags
+	}
+	return 0
+}
+
+func (x *Response) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Response) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+// BatchRequest 是批量读取请求的请求体，HTTP 传输层通过 POST <basePath>_batch 发送。
+type BatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Keys          []string               `protobuf:"bytes,2,rep,name=keys,proto3" json:"keys,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchRequest) Reset() {
+	*x = BatchRequest{}
+	mi := &file_geecachepb_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchRequest) ProtoMessage() {}
+
+func (x *BatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_geecachepb_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchRequest.ProtoReflect.Descriptor instead.
+func (*BatchRequest) Descriptor() ([]byte, []int) {
+	return file_geecachepb_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BatchRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *BatchRequest) GetKeys() []string {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+// BatchResponse 是批量读取请求的响应体，results 与请求中的 keys 一一对应，
+// 单个键失败时对应结果的 error 不为空。
+type BatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*Response            `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchResponse) Reset() {
+	*x = BatchResponse{}
+	mi := &file_geecachepb_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchResponse) ProtoMessage() {}
+
+func (x *BatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_geecachepb_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchResponse.ProtoReflect.Descriptor instead.
+func (*BatchResponse) Descriptor() ([]byte, []int) {
+	return file_geecachepb_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BatchResponse) GetResults() []*Response {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+var File_geecachepb_proto protoreflect.FileDescriptor
+
+const file_geecachepb_proto_rawDesc = "" +
+	"\n" +
+	"\x10geecachepb.proto\x12\n" +
+	"geecachepb\"]\n" +
+	"\aRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\fR\x05value\x12\x14\n" +
+	"\x05flags\x18\x04 \x01(\rR\x05flags\"y\n" +
+	"\bResponse\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\fR\x05value\x12\x15\n" +
+	"\x06ttl_ms\x18\x02 \x01(\x03R\x05ttlMs\x12\x14\n" +
+	"\x05flags\x18\x03 \x01(\rR\x05flags\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x12\x14\n" +
+	"\x05token\x18\x05 \x01(\tR\x05token\"8\n" +
+	"\fBatchRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x12\n" +
+	"\x04keys\x18\x02 \x03(\tR\x04keys\"?\n" +
+	"\rBatchResponse\x12.\n" +
+	"\aresults\x18\x01 \x03(\v2\x14.geecachepb.ResponseR\aresults2\xdf\x01\n" +
+	"\bGeeCache\x120\n" +
+	"\x03Get\x12\x13.geecachepb.Request\x1a\x14.geecachepb.Response\x120\n" +
+	"\x03Set\x12\x13.geecachepb.Request\x1a\x14.geecachepb.Response\x123\n" +
+	"\x06Remove\x12\x13.geecachepb.Request\x1a\x14.geecachepb.Response\x12:\n" +
+	"\tGetStream\x12\x13.geecachepb.Request\x1a\x14.geecachepb.Response(\x010\x01B\x1eZ\x1ctestProject/cache/geecachepbb\x06proto3"
+
+var (
+	file_geecachepb_proto_rawDescOnce sync.Once
+	file_geecachepb_proto_rawDescData []byte
+)
+
+func file_geecachepb_proto_rawDescGZIP() []byte {
+	file_geecachepb_proto_rawDescOnce.Do(func() {
+		file_geecachepb_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_geecachepb_proto_rawDesc), len(file_geecachepb_proto_rawDesc)))
+	})
+	return file_geecachepb_proto_rawDescData
+}
+
+var file_geecachepb_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_geecachepb_proto_goTypes = []any{
+	(*Request)(nil),       // 0: geecachepb.Request
+	(*Response)(nil),      // 1: geecachepb.Response
+	(*BatchRequest)(nil),  // 2: geecachepb.BatchRequest
+	(*BatchResponse)(nil), // 3: geecachepb.BatchResponse
+}
+var file_geecachepb_proto_depIdxs = []int32{
+	1, // 0: geecachepb.BatchResponse.results:type_name -> geecachepb.Response
+	0, // 1: geecachepb.GeeCache.Get:input_type -> geecachepb.Request
+	0, // 2: geecachepb.GeeCache.Set:input_type -> geecachepb.Request
+	0, // 3: geecachepb.GeeCache.Remove:input_type -> geecachepb.Request
+	0, // 4: geecachepb.GeeCache.GetStream:input_type -> geecachepb.Request
+	1, // 5: geecachepb.GeeCache.Get:output_type -> geecachepb.Response
+	1, // 6: geecachepb.GeeCache.Set:output_type -> geecachepb.Response
+	1, // 7: geecachepb.GeeCache.Remove:output_type -> geecachepb.Response
+	1, // 8: geecachepb.GeeCache.GetStream:output_type -> geecachepb.Response
+	5, // [5:9] is the sub-list for method output_type
+	1, // [1:5] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_geecachepb_proto_init() }
+func file_geecachepb_proto_init() {
+	if File_geecachepb_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_geecachepb_proto_rawDesc), len(file_geecachepb_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_geecachepb_proto_goTypes,
+		DependencyIndexes: file_geecachepb_proto_depIdxs,
+		MessageInfos:      file_geecachepb_proto_msgTypes,
+	}.Build()
+	File_geecachepb_proto = out.File
+	file_geecachepb_proto_goTypes = nil
+	file_geecachepb_proto_depIdxs = nil
+}
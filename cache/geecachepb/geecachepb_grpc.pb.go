@@ -0,0 +1,242 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: geecachepb.proto
+
+package geecachepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	GeeCache_Get_FullMethodName       = "/geecachepb.GeeCache/Get"
+	GeeCache_Set_FullMethodName       = "/geecachepb.GeeCache/Set"
+	GeeCache_Remove_FullMethodName    = "/geecachepb.GeeCache/Remove"
+	GeeCache_GetStream_FullMethodName = "/geecachepb.GeeCache/GetStream"
+)
+
+// GeeCacheClient is the client API for GeeCache service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// GeeCache 是 gRPC 传输层（见 grpcpool 包）提供的节点间服务。
+type GeeCacheClient interface {
+	// Get 读取一个键，与 HTTP 传输层的 GET 请求相同。
+	Get(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Response, error)
+	// Set 把写入转发给键的所有者，返回所有者签发的一致性令牌。
+	Set(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Response, error)
+	// Remove 删除本节点缓存中的一个键，用于在集群中广播失效。
+	Remove(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Response, error)
+	// GetStream 在一个流上依次读取多个键，响应的顺序与请求的顺序相同。
+	GetStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Request, Response], error)
+}
+
+type geeCacheClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGeeCacheClient(cc grpc.ClientConnInterface) GeeCacheClient {
+	return &geeCacheClient{cc}
+}
+
+func (c *geeCacheClient) Get(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Response, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Response)
+	err := c.cc.Invoke(ctx, GeeCache_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geeCacheClient) Set(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Response, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Response)
+	err := c.cc.Invoke(ctx, GeeCache_Set_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geeCacheClient) Remove(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Response, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Response)
+	err := c.cc.Invoke(ctx, GeeCache_Remove_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geeCacheClient) GetStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Request, Response], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &GeeCache_ServiceDesc.Streams[0], GeeCache_GetStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Request, Response]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GeeCache_GetStreamClient = grpc.BidiStreamingClient[Request, Response]
+
+// GeeCacheServer is the server API for GeeCache service.
+// All implementations must embed UnimplementedGeeCacheServer
+// for forward compatibility.
+//
+// GeeCache 是 gRPC 传输层（见 grpcpool 包）提供的节点间服务。
+type GeeCacheServer interface {
+	// Get 读取一个键，与 HTTP 传输层的 GET 请求相同。
+	Get(context.Context, *Request) (*Response, error)
+	// Set 把写入转发给键的所有者，返回所有者签发的一致性令牌。
+	Set(context.Context, *Request) (*Response, error)
+	// Remove 删除本节点缓存中的一个键，用于在集群中广播失效。
+	Remove(context.Context, *Request) (*Response, error)
+	// GetStream 在一个流上依次读取多个键，响应的顺序与请求的顺序相同。
+	GetStream(grpc.BidiStreamingServer[Request, Response]) error
+	mustEmbedUnimplementedGeeCacheServer()
+}
+
+// UnimplementedGeeCacheServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedGeeCacheServer struct{}
+
+func (UnimplementedGeeCacheServer) Get(context.Context, *Request) (*Response, error) {
+	return nil, status.Error(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedGeeCacheServer) Set(context.Context, *Request) (*Response, error) {
+	return nil, status.Error(codes.Unimplemented, "method Set not implemented")
+}
+func (UnimplementedGeeCacheServer) Remove(context.Context, *Request) (*Response, error) {
+	return nil, status.Error(codes.Unimplemented, "method Remove not implemented")
+}
+func (UnimplementedGeeCacheServer) GetStream(grpc.BidiStreamingServer[Request, Response]) error {
+	return status.Error(codes.Unimplemented, "method GetStream not implemented")
+}
+func (UnimplementedGeeCacheServer) mustEmbedUnimplementedGeeCacheServer() {}
+func (UnimplementedGeeCacheServer) testEmbeddedByValue()                  {}
+
+// UnsafeGeeCacheServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GeeCacheServer will
+// result in compilation errors.
+type UnsafeGeeCacheServer interface {
+	mustEmbedUnimplementedGeeCacheServer()
+}
+
+func RegisterGeeCacheServer(s grpc.ServiceRegistrar, srv GeeCacheServer) {
+	// If the following call panics, it indicates UnimplementedGeeCacheServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&GeeCache_ServiceDesc, srv)
+}
+
+func _GeeCache_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeeCacheServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GeeCache_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeeCacheServer).Get(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GeeCache_Set_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeeCacheServer).Set(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GeeCache_Set_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeeCacheServer).Set(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GeeCache_Remove_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeeCacheServer).Remove(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GeeCache_Remove_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeeCacheServer).Remove(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GeeCache_GetStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(GeeCacheServer).GetStream(&grpc.GenericServerStream[Request, Response]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GeeCache_GetStreamServer = grpc.BidiStreamingServer[Request, Response]
+
+// GeeCache_ServiceDesc is the grpc.ServiceDesc for GeeCache service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var GeeCache_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "geecachepb.GeeCache",
+	HandlerType: (*GeeCacheServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    _GeeCache_Get_Handler,
+		},
+		{
+			MethodName: "Set",
+			Handler:    _GeeCache_Set_Handler,
+		},
+		{
+			MethodName: "Remove",
+			Handler:    _GeeCache_Remove_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetStream",
+			Handler:       _GeeCache_GetStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "geecachepb.proto",
+}
@@ -0,0 +1,4 @@
+// Package geecachepb 定义节点之间通信使用的 protobuf 消息，以及 gRPC 传输层的服务。
+package geecachepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative geecachepb.proto
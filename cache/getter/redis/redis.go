@@ -0,0 +1,145 @@
+// Package redis 让 geecache 作为进程内的热点层放在 Redis 之前：Store 既是从 Redis 加载数据的 Getter，
+// 也可以作为写穿透的 Setter（见 geecache.WithWriteThrough），把 Set 写入的值同步写回 Redis。
+//
+//	store := redis.New(goredis.NewClient(&goredis.Options{Addr: "localhost:6379"}), redis.WithPrefix("scores:"))
+//	group := geecache.NewGroup("scores", 64<<20, store, geecache.WithWriteThrough(store))
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"testProject/cache/codec"
+	"testProject/cache/geecache"
+)
+
+// Client 是 Store 使用的 Redis 命令，*goredis.Client、*goredis.ClusterClient 和 *goredis.Ring 都实现了它。
+type Client interface {
+	Get(ctx context.Context, key string) *goredis.StringCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *goredis.StatusCmd
+	Del(ctx context.Context, keys ...string) *goredis.IntCmd
+}
+
+// Serializer 在 Redis 中保存的格式和缓存中保存的格式之间转换值。
+type Serializer interface {
+	// Decode 把从 Redis 读到的值转换为缓存中保存的值。
+	Decode(data []byte) ([]byte, error)
+	// Encode 把写入缓存的值转换为写回 Redis 的值。
+	Encode(value []byte) ([]byte, error)
+}
+
+// Raw 原样保存值，这是默认的 Serializer。
+var Raw Serializer = rawSerializer{}
+
+type rawSerializer struct{}
+
+func (rawSerializer) Decode(data []byte) ([]byte, error)  { return data, nil }
+func (rawSerializer) Encode(value []byte) ([]byte, error) { return value, nil }
+
+// Transcode 返回在两种编解码器之间转换的 Serializer：Redis 中的值使用 stored 编码，缓存中的值使用 cached 编码，
+// 例如 Redis 中保存 JSON 而缓存组使用 msgpack。newValue 返回用于解码的值的指针，例如 func() interface{} { return new(User) }。
+func Transcode(stored, cached codec.Codec, newValue func() interface{}) Serializer {
+	return transcoder{stored: stored, cached: cached, newValue: newValue}
+}
+
+type transcoder struct {
+	stored, cached codec.Codec
+	newValue       func() interface{}
+}
+
+func (t transcoder) Decode(data []byte) ([]byte, error) {
+	return convert(t.stored, t.cached, t.newValue(), data)
+}
+
+func (t transcoder) Encode(value []byte) ([]byte, error) {
+	return convert(t.cached, t.stored, t.newValue(), value)
+}
+
+// convert 用 from 把 data 解码到 v，再用 to 重新编码。
+func convert(from, to codec.Codec, v interface{}, data []byte) ([]byte, error) {
+	if err := from.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	return to.Marshal(v)
+}
+
+// Store 是以 Redis 为数据源的 Getter 和 Setter。
+type Store struct {
+	client     Client
+	prefix     string        // 缓存中的键加上前缀之后才是 Redis 中的键
+	ttl        time.Duration // 写回 Redis 的键的有效期，0 表示永不过期
+	serializer Serializer
+}
+
+// Option 用于在创建 Store 时定制其配置。
+type Option func(*Store)
+
+// WithPrefix 设置 Redis 中的键的前缀，例如 "scores:"，这样多个缓存组可以共用一个 Redis 而不会冲突。
+func WithPrefix(prefix string) Option {
+	return func(s *Store) {
+		s.prefix = prefix
+	}
+}
+
+// WithTTL 设置写回 Redis 的键的有效期，默认永不过期。
+func WithTTL(ttl time.Duration) Option {
+	return func(s *Store) {
+		s.ttl = ttl
+	}
+}
+
+// WithSerializer 设置 Redis 中的值和缓存中的值之间的转换，默认为 Raw。
+func WithSerializer(serializer Serializer) Option {
+	return func(s *Store) {
+		s.serializer = serializer
+	}
+}
+
+// New 创建从 client 读写数据的 Store。
+func New(client Client, opts ...Option) *Store {
+	s := &Store{client: client, serializer: Raw}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Get 实现 geecache.Getter，见 GetContext。
+func (s *Store) Get(key string) ([]byte, error) {
+	return s.GetContext(context.Background(), key)
+}
+
+// GetContext 实现 geecache.ContextGetter，从 Redis 读取 key 的值。Redis 中没有 key 时返回 geecache.ErrNotFound，
+// 配合 geecache.WithNegativeTTL 可以避免不存在的键反复访问 Redis。
+func (s *Store) GetContext(ctx context.Context, key string) ([]byte, error) {
+	data, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return nil, geecache.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.serializer.Decode(data)
+}
+
+// Set 实现 geecache.Setter，把 key 的新值写回 Redis。
+func (s *Store) Set(key string, value []byte) error {
+	data, err := s.serializer.Encode(value)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(context.Background(), s.prefix+key, data, s.ttl).Err()
+}
+
+// Delete 从 Redis 中删除 key。它只影响 Redis，缓存中的条目需要通过 Group.Remove 删除。
+func (s *Store) Delete(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.prefix+key).Err()
+}
+
+var (
+	_ geecache.ContextGetter = (*Store)(nil)
+	_ geecache.Setter        = (*Store)(nil)
+)
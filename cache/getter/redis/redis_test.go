@@ -0,0 +1,88 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"testProject/cache/codec"
+	"testProject/cache/geecache"
+)
+
+// fakeClient 是保存在内存中的 Client。
+type fakeClient struct {
+	data map[string]string
+	ttls map[string]time.Duration
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{data: make(map[string]string), ttls: make(map[string]time.Duration)}
+}
+
+func (c *fakeClient) Get(ctx context.Context, key string) *goredis.StringCmd {
+	v, ok := c.data[key]
+	if !ok {
+		return goredis.NewStringResult("", goredis.Nil)
+	}
+	return goredis.NewStringResult(v, nil)
+}
+
+func (c *fakeClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *goredis.StatusCmd {
+	c.data[key] = string(value.([]byte))
+	c.ttls[key] = expiration
+	return goredis.NewStatusResult("OK", nil)
+}
+
+func (c *fakeClient) Del(ctx context.Context, keys ...string) *goredis.IntCmd {
+	for _, key := range keys {
+		delete(c.data, key)
+	}
+	return goredis.NewIntResult(int64(len(keys)), nil)
+}
+
+// 测试通过缓存组读取和写穿透 Redis，键带有前缀
+func TestStore(t *testing.T) {
+	client := newFakeClient()
+	client.data["scores:Tom"] = "630"
+	store := New(client, WithPrefix("scores:"), WithTTL(time.Hour))
+	g := geecache.NewGroup("redis", 0, store, geecache.WithWriteThrough(store))
+
+	if v, err := g.Get("Tom"); err != nil || v.String() != "630" {
+		t.Fatalf("Get(Tom) = %q, %v", v.String(), err)
+	}
+	if _, err := g.Get("Bob"); !errors.Is(err, geecache.ErrNotFound) {
+		t.Fatalf("Get(Bob) err = %v, want ErrNotFound", err)
+	}
+	if _, err := g.Set("Jack", []byte("589")); err != nil {
+		t.Fatal(err)
+	}
+	if client.data["scores:Jack"] != "589" || client.ttls["scores:Jack"] != time.Hour {
+		t.Fatalf("redis data = %v, ttls = %v", client.data, client.ttls)
+	}
+	if err := store.Delete(context.Background(), "Jack"); err != nil || len(client.data) != 1 {
+		t.Fatalf("Delete = %v, data = %v", err, client.data)
+	}
+}
+
+// 测试 Redis 中保存 JSON、缓存中保存 msgpack 时的转换
+func TestTranscode(t *testing.T) {
+	type user struct{ Name string }
+	client := newFakeClient()
+	client.data["u1"] = `{"Name":"Tom"}`
+	store := New(client, WithSerializer(Transcode(codec.MustGet(codec.JSON), codec.MustGet(codec.Msgpack), func() interface{} { return new(user) })))
+
+	data, err := store.Get("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var u user
+	if err := codec.MustGet(codec.Msgpack).Unmarshal(data, &u); err != nil || u.Name != "Tom" {
+		t.Fatalf("decoded %+v, %v", u, err)
+	}
+	if err := store.Set("u2", data); err != nil || client.data["u2"] != `{"Name":"Tom"}` {
+		t.Fatalf("Set = %v, stored %q", err, client.data["u2"])
+	}
+}
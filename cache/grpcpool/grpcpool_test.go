@@ -0,0 +1,102 @@
+package grpcpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"testProject/cache/geecache"
+)
+
+// startServer 在随机端口上启动提供 GeeCache 服务的 grpc.Server，返回其地址。
+func startServer(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	New(lis.Addr().String()).Register(s)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+// 测试通过 gRPC 读取、批量读取、写入和删除远程节点的条目
+func TestPool(t *testing.T) {
+	g := geecache.NewGroup("grpc", 0, geecache.ContextGetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		switch key {
+		case "bad":
+			return nil, fmt.Errorf("%s not exist", key)
+		case "slow":
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return []byte(key + "!"), nil
+	}))
+
+	addr := startServer(t)
+	pool := New("self")
+	defer pool.Close()
+	if err := pool.Set(addr); err != nil { // 所有键的所有者都是远程节点
+		t.Fatal(err)
+	}
+	peer, ok := pool.PickPeer("Tom")
+	if !ok {
+		t.Fatal("PickPeer should pick the remote peer")
+	}
+
+	if b, err := peer.Get(context.Background(), "grpc", "Tom"); err != nil || string(b) != "Tom!" {
+		t.Fatalf("Get = %q, %v", b, err)
+	}
+	// 副本读取只返回对端已经缓存的值，不会触发加载
+	peeker := peer.(geecache.PeerPeeker)
+	if b, err := peeker.Peek(context.Background(), "grpc", "Tom"); err != nil || string(b) != "Tom!" {
+		t.Fatalf("Peek = %q, %v", b, err)
+	}
+	if _, err := peeker.Peek(context.Background(), "grpc", "Sam"); !errors.Is(err, geecache.ErrNotCached) {
+		t.Fatalf("Peek of an uncached key: err = %v, want ErrNotCached", err)
+	}
+	if _, err := peer.Get(context.Background(), "grpc", "bad"); err == nil {
+		t.Fatal("Get should return the loader error")
+	}
+	if _, err := peer.Get(context.Background(), "nosuch", "Tom"); err == nil {
+		t.Fatal("Get on an unknown group should fail")
+	}
+	if _, err := peer.Get(geecache.ContextWithHops(context.Background(), defaultMaxHops), "grpc", "Tom"); err == nil {
+		t.Fatal("Get should refuse to exceed the hop limit")
+	}
+
+	// 截止时间由 gRPC 传递给对端，对端的加载随之结束
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := peer.Get(ctx, "grpc", "slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("slow Get: err = %v, want DeadlineExceeded", err)
+	}
+
+	results, err := peer.(MultiGetter).GetMulti(context.Background(), "grpc", []string{"Tom", "bad", "Sam"})
+	if err != nil || len(results) != 3 {
+		t.Fatalf("GetMulti = %v, %v", results, err)
+	}
+	if string(results[0].Value) != "Tom!" || results[1].Err == nil || string(results[2].Value) != "Sam!" {
+		t.Fatalf("GetMulti results = %+v", results)
+	}
+
+	g.RegisterPeers(pool)
+	if token, err := g.Set("k", []byte("v")); err != nil || token == "" {
+		t.Fatalf("Set = %q, %v", token, err)
+	}
+	if _, err := g.Remove("k"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	// 节点列表不变时复用原有连接
+	pool.Set(addr)
+	if again, _ := pool.PickPeer("Tom"); again != peer {
+		t.Fatal("Set should reuse the connection of an existing peer")
+	}
+}
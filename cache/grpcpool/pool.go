@@ -0,0 +1,411 @@
+// Package grpcpool 提供基于 gRPC 的节点间传输层，可以替代 geecache.HTTPPool。
+// 每个远程节点只建立一个长期复用的连接（HTTP/2 多路复用，并开启 keepalive），
+// 调用方的截止时间由 gRPC 原生传递给对端，多个键还可以在一个流上批量读取。
+package grpcpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	consistenthashgo "testProject/cache/consistenthash.go"
+	"testProject/cache/geecache"
+	pb "testProject/cache/geecachepb"
+)
+
+const (
+	defaultReplicas = 50
+	// defaultMaxHops 与 HTTPPool 相同：只允许一次转发，对端不再继续转发。
+	defaultMaxHops = 1
+	// hopsMetadata 是节点间请求中携带转发跳数的元数据键。
+	hopsMetadata = "x-geecache-hops"
+	// tokenMetadata 是节点间请求中携带一致性令牌的元数据键。
+	tokenMetadata = "x-geecache-token"
+	// peekMetadata 标记读取请求只读取对端已经缓存的值，不触发加载，见 geecache.PeerPeeker。
+	peekMetadata = "x-geecache-peek"
+)
+
+// defaultKeepalive 让空闲连接定期发送心跳，及时发现失效的对端并避免连接被中间设备回收。
+var defaultKeepalive = keepalive.ClientParameters{
+	Time:                30 * time.Second,
+	Timeout:             10 * time.Second,
+	PermitWithoutStream: true,
+}
+
+// Option 用于在创建 Pool 时定制其配置。
+type Option func(*Pool)
+
+// WithReplicas 设置一致性哈希中每个真实节点对应的虚拟节点数量。
+func WithReplicas(n int) Option {
+	return func(p *Pool) {
+		if n > 0 {
+			p.replicas = n
+		}
+	}
+}
+
+// WithHashFn 设置一致性哈希使用的散列函数，默认使用 CRC32，也可以使用分布更均匀的 consistenthashgo.XXHash。
+// 同一个集群中的所有节点必须使用相同的散列函数。
+func WithHashFn(fn consistenthashgo.Hash) Option {
+	return func(p *Pool) {
+		p.hashFn = fn
+	}
+}
+
+// WithStrategy 设置把键映射到节点的策略，默认使用哈希环，见 geecache.WithStrategy。
+func WithStrategy(fn consistenthashgo.StrategyFunc) Option {
+	return func(p *Pool) {
+		p.strategy = fn
+	}
+}
+
+// WithPeerWeights 设置节点在一致性哈希中的权重，没有列出的节点权重为 1，见 geecache.WithPeerWeights。
+func WithPeerWeights(weights map[string]int) Option {
+	return func(p *Pool) {
+		p.weights = make(map[string]int, len(weights))
+		for peer, w := range weights {
+			p.weights[peer] = w
+		}
+	}
+}
+
+// WithMaxHops 设置节点间请求允许的最大转发跳数。
+func WithMaxHops(n int) Option {
+	return func(p *Pool) {
+		if n > 0 {
+			p.maxHops = n
+		}
+	}
+}
+
+// WithRegistry 让 Register 注册的服务在 r 中查找请求的缓存组，默认为 geecache.DefaultRegistry。
+func WithRegistry(r *geecache.Registry) Option {
+	return func(p *Pool) {
+		p.registry = r
+	}
+}
+
+// WithDialOptions 追加连接对端时使用的 grpc.DialOption，例如 TLS 凭证。
+// 默认使用明文连接并开启 keepalive，追加的选项会覆盖默认值。
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(p *Pool) {
+		p.dialOpts = append(p.dialOpts, opts...)
+	}
+}
+
+// Pool 实现了 geecache.PeerPicker 和 geecache.PeerLister，通过 gRPC 访问其他节点，
+// 同时通过 Register 在本节点的 grpc.Server 上提供其他节点访问本节点的服务。
+type Pool struct {
+	self     string // 本节点的地址，例如 "localhost:8001"
+	replicas int
+	hashFn   consistenthashgo.Hash
+	weights  map[string]int // 节点的权重，没有列出的节点权重为 1
+	strategy consistenthashgo.StrategyFunc
+	maxHops  int
+	dialOpts []grpc.DialOption
+	registry *geecache.Registry // 本节点的服务查找缓存组的注册表
+
+	mu      sync.Mutex // 保护 peers 和 getters
+	peers   consistenthashgo.Strategy
+	getters map[string]*grpcGetter // 每个远程节点一个，连接在 Set 之间复用
+}
+
+// New 创建一个 Pool，self 是本节点的地址，必须与传给 Set 的地址之一相同。
+func New(self string, opts ...Option) *Pool {
+	p := &Pool{
+		self:     self,
+		replicas: defaultReplicas,
+		maxHops:  defaultMaxHops,
+		dialOpts: []grpc.DialOption{
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithKeepaliveParams(defaultKeepalive),
+		},
+		getters:  make(map[string]*grpcGetter),
+		registry: geecache.DefaultRegistry,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Set 更新节点列表。仍然存在的节点继续使用原有连接，被移除节点的连接会被关闭。
+func (p *Pool) Set(peers ...string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	newRing := p.strategy
+	if newRing == nil {
+		newRing = consistenthashgo.RingStrategy
+	}
+	ring := newRing(p.replicas, p.hashFn)
+	if len(p.weights) == 0 {
+		ring.Add(peers...)
+	} else {
+		for _, peer := range peers {
+			ring.AddWeighted(peer, p.weights[peer])
+		}
+	}
+	getters := make(map[string]*grpcGetter, len(peers))
+	for _, peer := range peers {
+		if peer == p.self {
+			continue
+		}
+		if g, ok := p.getters[peer]; ok {
+			getters[peer] = g
+			continue
+		}
+		conn, err := grpc.NewClient(peer, p.dialOpts...)
+		if err != nil {
+			for addr, g := range getters {
+				if _, old := p.getters[addr]; !old {
+					g.conn.Close() // 只关闭本次新建的连接，保持原有状态不变
+				}
+			}
+			return fmt.Errorf("grpcpool: dial %s: %v", peer, err)
+		}
+		getters[peer] = &grpcGetter{conn: conn, client: pb.NewGeeCacheClient(conn), maxHops: p.maxHops}
+	}
+	for addr, g := range p.getters {
+		if _, ok := getters[addr]; !ok {
+			g.conn.Close()
+		}
+	}
+	p.peers = ring
+	p.getters = getters
+	return nil
+}
+
+// PickPeer 根据 key 选择所有者节点，所有者是本节点时返回 false。
+func (p *Pool) PickPeer(key string) (geecache.PeerGetter, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.peers == nil {
+		return nil, false
+	}
+	if peer := p.peers.Get(key); peer != "" && peer != p.self {
+		return p.getters[peer], true
+	}
+	return nil, false
+}
+
+// PickPeers 实现 geecache.PeerReplicaPicker，返回 key 的前 n 个副本节点中除本节点之外的节点。
+// gRPC 传输不支持推送条目，设置了 geecache.WithReplication 时只能从副本读取，副本上的值由各自节点加载。
+func (p *Pool) PickPeers(key string, n int) []geecache.PeerGetter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.peers == nil {
+		return nil
+	}
+	var peers []geecache.PeerGetter
+	for _, peer := range p.peers.GetN(key, n) {
+		if peer != p.self {
+			peers = append(peers, p.getters[peer])
+		}
+	}
+	return peers
+}
+
+// Peers 返回除本节点之外的所有节点，按地址排序。
+func (p *Pool) Peers() []geecache.PeerGetter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	addrs := make([]string, 0, len(p.getters))
+	for addr := range p.getters {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	peers := make([]geecache.PeerGetter, len(addrs))
+	for i, addr := range addrs {
+		peers[i] = p.getters[addr]
+	}
+	return peers
+}
+
+// Close 关闭到所有远程节点的连接。
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var firstErr error
+	for _, g := range p.getters {
+		if err := g.conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	p.getters = make(map[string]*grpcGetter)
+	p.peers = nil
+	return firstErr
+}
+
+// Result 是批量读取中单个键的结果。
+type Result = geecache.PeerResult
+
+// MultiGetter 由能够在一次往返中读取多个键的 PeerGetter 实现，与 geecache.PeerMultiGetter 相同，
+// Group.GetMulti 通过它为每个节点只发起一次批量读取。
+type MultiGetter = geecache.PeerMultiGetter
+
+// grpcGetter 通过一个复用的 gRPC 连接访问远程节点。
+type grpcGetter struct {
+	conn    *grpc.ClientConn
+	client  pb.GeeCacheClient
+	maxHops int
+}
+
+// outgoing 把转发跳数和一致性令牌写入请求的元数据，截止时间由 gRPC 自动传递。
+func (g *grpcGetter) outgoing(ctx context.Context) (context.Context, error) {
+	hops := geecache.HopsFromContext(ctx) + 1 // 本次转发计入跳数
+	if hops > g.maxHops {
+		return nil, fmt.Errorf("hop limit %d exceeded", g.maxHops)
+	}
+	md := metadata.Pairs(hopsMetadata, strconv.Itoa(hops))
+	if token, ok := geecache.TokenFromContext(ctx); ok {
+		md.Set(tokenMetadata, string(token))
+	}
+	return metadata.NewOutgoingContext(ctx, md), nil
+}
+
+// Get 从远程节点读取 key 的值。
+func (g *grpcGetter) Get(ctx context.Context, group string, key string) ([]byte, error) {
+	ctx, err := g.outgoing(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := g.client.Get(ctx, &pb.Request{Group: group, Key: key})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return res.Value, nil
+}
+
+// Peek 只读取远程节点已经缓存的 key，远程节点没有缓存时返回 geecache.ErrNotCached。
+func (g *grpcGetter) Peek(ctx context.Context, group string, key string) ([]byte, error) {
+	ctx, err := g.outgoing(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, peekMetadata, "1")
+	res, err := g.client.Get(ctx, &pb.Request{Group: group, Key: key})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return res.Value, nil
+}
+
+// Set 把写入转发给远程节点，返回对端签发的一致性令牌。
+func (g *grpcGetter) Set(ctx context.Context, group string, key string, value []byte) (geecache.ConsistencyToken, error) {
+	ctx, err := g.outgoing(ctx)
+	if err != nil {
+		return "", err
+	}
+	res, err := g.client.Set(ctx, &pb.Request{Group: group, Key: key, Value: value})
+	if err != nil {
+		return "", fromStatus(err)
+	}
+	return geecache.ConsistencyToken(res.Token), nil
+}
+
+// Remove 删除远程节点缓存中的条目。
+func (g *grpcGetter) Remove(ctx context.Context, group string, key string) error {
+	ctx, err := g.outgoing(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := g.client.Remove(ctx, &pb.Request{Group: group, Key: key}); err != nil {
+		return fromStatus(err)
+	}
+	return nil
+}
+
+// GetMulti 在一个双向流上发送所有键并依次接收结果。
+func (g *grpcGetter) GetMulti(ctx context.Context, group string, keys []string) ([]Result, error) {
+	ctx, err := g.outgoing(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // 提前返回时结束流，让发送协程退出
+	stream, err := g.client.GetStream(ctx)
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+
+	// 发送和接收并行进行，避免键很多时双方的流控窗口被填满而互相等待。
+	sendErr := make(chan error, 1)
+	go func() {
+		for _, key := range keys {
+			if err := stream.Send(&pb.Request{Group: group, Key: key}); err != nil {
+				sendErr <- err
+				return
+			}
+		}
+		sendErr <- stream.CloseSend()
+	}()
+
+	results := make([]Result, len(keys))
+	for i := range keys {
+		res, err := stream.Recv()
+		if err != nil {
+			return nil, fromStatus(err)
+		}
+		switch res.Error {
+		case "":
+		case geecache.ErrNotFound.Error():
+			results[i].Err = geecache.ErrNotFound
+			continue
+		default:
+			results[i].Err = errors.New(res.Error)
+			continue
+		}
+		results[i].Value = res.Value
+	}
+	if err := <-sendErr; err != nil {
+		return nil, fromStatus(err)
+	}
+	return results, nil
+}
+
+// fromStatus 把 gRPC 状态转换为 geecache 的错误，使重试和冻结判断与 HTTP 传输层一致。
+func fromStatus(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch st.Code() {
+	case codes.Unavailable:
+		return geecache.Retryable(errors.New(st.Message()))
+	case codes.FailedPrecondition:
+		if st.Message() == geecache.ErrFrozen.Error() {
+			return geecache.ErrFrozen
+		}
+	case codes.NotFound:
+		switch st.Message() {
+		case geecache.ErrNotFound.Error():
+			return geecache.ErrNotFound
+		case geecache.ErrNotCached.Error():
+			return geecache.ErrNotCached
+		}
+	case codes.DeadlineExceeded:
+		return context.DeadlineExceeded
+	case codes.Canceled:
+		return context.Canceled
+	}
+	return fmt.Errorf("server returned: %v", st.Message())
+}
+
+var (
+	_ geecache.PeerPicker = (*Pool)(nil)
+	_ geecache.PeerLister = (*Pool)(nil)
+	_ geecache.PeerSetter = (*grpcGetter)(nil)
+	_ geecache.PeerPeeker = (*grpcGetter)(nil)
+	_ MultiGetter         = (*grpcGetter)(nil)
+)
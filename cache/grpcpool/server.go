@@ -0,0 +1,182 @@
+package grpcpool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"testProject/cache/geecache"
+	pb "testProject/cache/geecachepb"
+)
+
+// Register 在 s 上注册本节点的 GeeCache 服务，供其他节点通过 Pool 访问本节点。
+func (p *Pool) Register(s grpc.ServiceRegistrar) {
+	pb.RegisterGeeCacheServer(s, &server{maxHops: p.maxHops, registry: p.registry})
+}
+
+// server 实现 pb.GeeCacheServer，请求会在本节点的缓存组上执行。
+type server struct {
+	pb.UnimplementedGeeCacheServer
+	maxHops  int
+	registry *geecache.Registry
+}
+
+// incoming 从请求的元数据中还原转发跳数和一致性令牌，超过跳数上限说明节点间存在路由环路。
+func (s *server) incoming(ctx context.Context) (context.Context, error) {
+	hops := 0
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(hopsMetadata); len(v) > 0 {
+		n, err := strconv.Atoi(v[0])
+		if err != nil || n < 0 {
+			return nil, status.Error(codes.InvalidArgument, "bad hops metadata")
+		}
+		hops = n
+	}
+	if hops > s.maxHops {
+		return nil, status.Error(codes.Aborted, "hop limit exceeded")
+	}
+	ctx = geecache.ContextWithHops(ctx, hops)
+	if v := md.Get(tokenMetadata); len(v) > 0 {
+		ctx = geecache.ContextWithToken(ctx, geecache.ConsistencyToken(v[0]))
+	}
+	return ctx, nil
+}
+
+// group 返回请求对应的缓存组。
+func (s *server) group(req *pb.Request) (*geecache.Group, error) {
+	if req.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+	g := s.registry.Get(req.Group)
+	if g == nil {
+		return nil, status.Error(codes.NotFound, "no such group: "+req.Group)
+	}
+	return g, nil
+}
+
+// toStatus 把缓存组返回的错误转换为 gRPC 状态，与 HTTP 传输层的状态码一一对应。
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, geecache.ErrNotFound):
+		// 使用固定的消息，与缓存组不存在的 NotFound 区分开。
+		return status.Error(codes.NotFound, geecache.ErrNotFound.Error())
+	case errors.Is(err, geecache.ErrNotCached):
+		return status.Error(codes.NotFound, geecache.ErrNotCached.Error())
+	case errors.Is(err, geecache.ErrFrozen):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case geecache.IsRetryable(err):
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// get 在本节点读取一个键，并附带条目的剩余有效期。
+func (s *server) get(ctx context.Context, req *pb.Request) (*pb.Response, error) {
+	g, err := s.group(req)
+	if err != nil {
+		return nil, err
+	}
+	view, err := g.GetContext(ctx, req.Key)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	res := &pb.Response{Value: view.ByteSlice()}
+	if ttl, err := g.TTL(req.Key); err == nil && ttl > 0 {
+		res.TtlMs = ttl.Milliseconds()
+	}
+	return res, nil
+}
+
+func (s *server) Get(ctx context.Context, req *pb.Request) (*pb.Response, error) {
+	ctx, err := s.incoming(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if md, _ := metadata.FromIncomingContext(ctx); len(md.Get(peekMetadata)) > 0 {
+		return s.peek(req) // 副本读取只读取本地缓存
+	}
+	return s.get(ctx, req)
+}
+
+// peek 只读取本节点已经缓存的 key，不触发加载。
+func (s *server) peek(req *pb.Request) (*pb.Response, error) {
+	g, err := s.group(req)
+	if err != nil {
+		return nil, err
+	}
+	view, err := g.GetLocal(req.Key)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.Response{Value: view.ByteSlice()}, nil
+}
+
+// Set 在本节点作为所有者执行转发来的写入。
+func (s *server) Set(ctx context.Context, req *pb.Request) (*pb.Response, error) {
+	if _, err := s.incoming(ctx); err != nil {
+		return nil, err
+	}
+	g, err := s.group(req)
+	if err != nil {
+		return nil, err
+	}
+	token, err := g.SetLocal(req.Key, req.Value)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.Response{Token: string(token)}, nil
+}
+
+// Remove 处理其他节点广播的删除请求，只删除本地缓存中的条目，不再继续广播。
+func (s *server) Remove(ctx context.Context, req *pb.Request) (*pb.Response, error) {
+	if _, err := s.incoming(ctx); err != nil {
+		return nil, err
+	}
+	g, err := s.group(req)
+	if err != nil {
+		return nil, err
+	}
+	token, err := g.RemoveLocal(req.Key)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.Response{Token: string(token)}, nil
+}
+
+// GetStream 依次读取流上的每个键，单个键失败时在对应的响应中返回错误信息，流继续处理后续的键。
+func (s *server) GetStream(stream grpc.BidiStreamingServer[pb.Request, pb.Response]) error {
+	ctx, err := s.incoming(stream.Context())
+	if err != nil {
+		return err
+	}
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		res, err := s.get(ctx, req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return toStatus(ctx.Err()) // 调用方已经放弃，不再处理后续的键
+			}
+			res = &pb.Response{Error: status.Convert(err).Message()}
+		}
+		if err := stream.Send(res); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,277 @@
+// Package lfu 实现按访问频率淘汰的缓存，接口与 lru.Cache 相同，可以作为 geecache 的淘汰策略。
+// 访问频率差异很大的负载下，偶尔出现的一批冷数据不会把高频的热数据挤出缓存。
+package lfu
+
+import (
+	"container/heap"
+	"math/rand"
+	"time"
+
+	"testProject/cache/clock"
+	"testProject/cache/lru"
+)
+
+// Cache 是按访问频率淘汰的缓存：超出容量时淘汰访问次数最少的条目，次数相同时淘汰最久未访问的条目。
+// Cache 不是并发安全的。
+type Cache struct {
+	maxBytes  int64 //允许使用的最大内存，0 表示不限制
+	nbytes    int64 //当前已经使用的内存大小
+	cache     map[string]*entry
+	heap      entryHeap //按访问次数和最近访问顺序排列的最小堆，堆顶是下一个被淘汰的条目
+	tick      uint64    //单调递增的访问序号，用于在访问次数相同时比较新旧
+	evictions int64     //因超出容量而被淘汰的条目数量
+	clock     clock.Clock
+	listeners []lru.EvictionListener //淘汰监听器，按注册顺序依次调用
+}
+
+// Option 用于在创建 Cache 时定制其行为。
+type Option func(*Cache)
+
+// WithClock 设置 Cache 记录条目写入时间和判断过期使用的时钟，默认使用真实时间。
+func WithClock(c clock.Clock) Option {
+	return func(cache *Cache) {
+		cache.clock = c
+	}
+}
+
+type entry struct {
+	key     string
+	value   lru.Value
+	freq    uint64    //访问次数，写入时为 1
+	tick    uint64    //最近一次访问的序号
+	index   int       //条目在堆中的位置
+	added   time.Time //条目写入（或最近一次被覆盖）的时间
+	expires time.Time //条目的过期时间，零值表示永不过期
+}
+
+// expired 报告条目在 now 时是否已经过期。
+func (e *entry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// New 创建一个 Cache，onEvicted 不为 nil 时会被注册为第一个淘汰监听器。
+func New(maxBytes int64, onEvicted func(string, lru.Value), opts ...Option) *Cache {
+	c := &Cache{
+		maxBytes: maxBytes,
+		cache:    make(map[string]*entry),
+		clock:    clock.Real,
+	}
+	if onEvicted != nil {
+		c.AddEvictionListener(onEvicted)
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// touch 记录一次访问：访问次数加一，并更新条目在堆中的位置。
+func (c *Cache) touch(e *entry) {
+	c.tick++
+	e.freq++
+	e.tick = c.tick
+	heap.Fix(&c.heap, e.index)
+}
+
+// Get 返回 key 对应的值并增加其访问次数。已经过期的条目在这里被惰性删除，视为未命中。
+func (c *Cache) Get(key string) (value lru.Value, ok bool) {
+	e, ok := c.cache[key]
+	if !ok {
+		return nil, false
+	}
+	if e.expired(c.clock.Now()) {
+		c.removeEntry(e)
+		return nil, false
+	}
+	c.touch(e)
+	return e.value, true
+}
+
+// Peek 返回 key 对应的值，但不增加访问次数，不影响条目的淘汰顺序。
+// 已经过期的条目视为不存在，但不会在这里被删除。
+func (c *Cache) Peek(key string) (value lru.Value, ok bool) {
+	if e, ok := c.cache[key]; ok && !e.expired(c.clock.Now()) {
+		return e.value, true
+	}
+	return nil, false
+}
+
+// Contains 报告 key 是否在缓存中且尚未过期，不影响条目的淘汰顺序。
+func (c *Cache) Contains(key string) bool {
+	_, ok := c.Peek(key)
+	return ok
+}
+
+// Expiration 返回 key 对应条目的过期时间，零值表示永不过期。
+// 它不影响条目的淘汰顺序；条目不存在或已经过期时 ok 为 false。
+func (c *Cache) Expiration(key string) (expires time.Time, ok bool) {
+	if e, ok := c.cache[key]; ok && !e.expired(c.clock.Now()) {
+		return e.expires, true
+	}
+	return time.Time{}, false
+}
+
+// Add 将一个永不过期的键值对添加或更新到缓存中。
+func (c *Cache) Add(key string, value lru.Value) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire 将一个键值对添加或更新到缓存中，条目在 expires 之后过期，零值表示永不过期。
+// 覆盖已有的键计为一次访问，条目保留原有的访问次数。
+func (c *Cache) AddWithExpire(key string, value lru.Value, expires time.Time) {
+	if e, ok := c.cache[key]; ok {
+		c.nbytes += int64(value.Len()) - int64(e.value.Len())
+		e.value = value
+		e.added = c.clock.Now()
+		e.expires = expires
+		c.touch(e)
+	} else {
+		c.tick++
+		e := &entry{key: key, value: value, freq: 1, tick: c.tick, added: c.clock.Now(), expires: expires}
+		heap.Push(&c.heap, e)
+		c.cache[key] = e
+		c.nbytes += int64(len(key)) + int64(value.Len())
+	}
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
+		c.RemoveLeastFrequent()
+	}
+}
+
+// RemoveLeastFrequent 淘汰访问次数最少的条目，次数相同时淘汰最久未访问的条目。
+func (c *Cache) RemoveLeastFrequent() {
+	if len(c.heap) > 0 {
+		c.removeEntry(c.heap[0])
+		c.evictions++
+	}
+}
+
+// Victim 返回下一个将被 RemoveLeastFrequent 淘汰的条目的键，缓存为空时 ok 为 false。
+func (c *Cache) Victim() (key string, ok bool) {
+	if len(c.heap) > 0 {
+		return c.heap[0].key, true
+	}
+	return "", false
+}
+
+// Remove 从缓存中删除 key 对应的条目，返回条目是否存在。被删除的条目同样会通知淘汰监听器。
+func (c *Cache) Remove(key string) bool {
+	if e, ok := c.cache[key]; ok {
+		c.removeEntry(e)
+		return true
+	}
+	return false
+}
+
+// RemoveExpired 删除所有已经过期的条目并返回删除的数量，被删除的条目会通知淘汰监听器。
+func (c *Cache) RemoveExpired() int {
+	now := c.clock.Now()
+	var expired []*entry
+	for _, e := range c.cache {
+		if e.expired(now) {
+			expired = append(expired, e)
+		}
+	}
+	for _, e := range expired {
+		c.removeEntry(e)
+	}
+	return len(expired)
+}
+
+// removeEntry 从缓存中删除一个条目，并通知淘汰监听器。
+func (c *Cache) removeEntry(e *entry) {
+	heap.Remove(&c.heap, e.index)
+	delete(c.cache, e.key)
+	c.nbytes -= int64(len(e.key)) + int64(e.value.Len())
+	for _, fn := range c.listeners {
+		fn(e.key, e.value)
+	}
+}
+
+// AddEvictionListener 注册一个淘汰监听器，条目被淘汰或删除时所有监听器按注册顺序被调用。
+func (c *Cache) AddEvictionListener(fn lru.EvictionListener) {
+	c.listeners = append(c.listeners, fn)
+}
+
+// Evictions 返回因超出容量而被淘汰的条目数量，不包括过期和被显式删除的条目。
+func (c *Cache) Evictions() int64 {
+	return c.evictions
+}
+
+// Len 返回缓存中的条目数量。
+func (c *Cache) Len() int {
+	return len(c.cache)
+}
+
+// Bytes 返回当前已经使用的内存大小，即所有条目的键和值的长度之和。
+func (c *Cache) Bytes() int64 {
+	return c.nbytes
+}
+
+// Resize 调整允许使用的最大内存，并立即淘汰访问次数最少的条目直到不超过新的限制，0 表示不限制。
+func (c *Cache) Resize(maxBytes int64) {
+	c.maxBytes = maxBytes
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
+		c.RemoveLeastFrequent()
+	}
+}
+
+// Sample 随机返回最多 n 个未过期的条目，不影响条目的淘汰顺序。
+func (c *Cache) Sample(n int) []lru.EntryInfo {
+	if n <= 0 {
+		return nil
+	}
+	sample := make([]lru.EntryInfo, 0, n)
+	now := c.clock.Now()
+	i := 0
+	for _, e := range c.cache {
+		if e.expired(now) {
+			continue
+		}
+		info := lru.EntryInfo{Key: e.key, Value: e.value, Added: e.added, Expires: e.expires}
+		if i < n {
+			sample = append(sample, info)
+		} else if j := rand.Intn(i + 1); j < n {
+			sample[j] = info
+		}
+		i++
+	}
+	return sample
+}
+
+// Scan 与 lru.Cache.Scan 相同：按 lru.KeyHash 的顺序分页遍历以 prefix 开头的键，
+// 返回的游标为 0 表示遍历结束，因此两种缓存的结果可以用 lru.MergeScans 合并。
+func (c *Cache) Scan(cursor uint64, prefix string, count int) (keys []string, next uint64) {
+	now := c.clock.Now()
+	return lru.ScanKeys(cursor, prefix, count, func(yield func(string) bool) {
+		for key, e := range c.cache {
+			if !e.expired(now) && !yield(key) {
+				return
+			}
+		}
+	})
+}
+
+// entryHeap 是按访问次数、再按最近访问顺序排列的最小堆。
+type entryHeap []*entry
+
+func (h entryHeap) Len() int { return len(h) }
+func (h entryHeap) Less(i, j int) bool {
+	return h[i].freq < h[j].freq || (h[i].freq == h[j].freq && h[i].tick < h[j].tick)
+}
+func (h entryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *entryHeap) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+func (h *entryHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
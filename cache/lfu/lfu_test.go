@@ -0,0 +1,74 @@
+package lfu
+
+import (
+	"testing"
+	"time"
+
+	"testProject/cache/clock"
+	"testProject/cache/lru"
+)
+
+type String string
+
+func (d String) Len() int {
+	return len(d)
+}
+
+// 测试超出容量时淘汰访问次数最少的条目，次数相同时淘汰最久未访问的条目
+func TestEviction(t *testing.T) {
+	var evicted []string
+	c := New(int64(6), func(key string, _ lru.Value) { evicted = append(evicted, key) })
+	c.Add("a", String("1"))
+	c.Add("b", String("2"))
+	c.Add("c", String("3"))
+	c.Get("a")
+	c.Get("a")
+	c.Get("b")
+	c.Add("d", String("4")) // c 只被写入过一次
+	if _, ok := c.Peek("c"); ok || c.Len() != 3 {
+		t.Fatalf("least frequent entry was not evicted: %v", evicted)
+	}
+	c.Add("e", String("5")) // d 和 e 都只访问过一次，淘汰更早写入的 d
+	if _, ok := c.Peek("d"); ok {
+		t.Fatalf("evicted = %v, want d evicted", evicted)
+	}
+	if c.Evictions() != 2 || c.Bytes() != 6 {
+		t.Fatalf("Evictions = %d, Bytes = %d", c.Evictions(), c.Bytes())
+	}
+}
+
+// 测试过期的条目视为未命中并被删除
+func TestExpiration(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := New(0, nil, WithClock(clk))
+	c.AddWithExpire("k", String("v"), clk.Now().Add(time.Second))
+	c.Add("forever", String("v"))
+	if _, ok := c.Get("k"); !ok {
+		t.Fatal("entry expired early")
+	}
+	clk.Advance(time.Second)
+	if c.Contains("k") || c.RemoveExpired() != 1 || c.Len() != 1 {
+		t.Fatalf("expired entry still present, Len = %d", c.Len())
+	}
+}
+
+// 测试 Scan 的分页结果可以与 lru.Cache 合并
+func TestScan(t *testing.T) {
+	c := New(0, nil)
+	for _, k := range []string{"user:1", "user:2", "user:3", "order:1"} {
+		c.Add(k, String("v"))
+	}
+	var all []string
+	cursor := uint64(0)
+	for {
+		keys, next := c.Scan(cursor, "user:", 2)
+		all = append(all, keys...)
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+	if len(all) != 3 {
+		t.Fatalf("Scan returned %v", all)
+	}
+}
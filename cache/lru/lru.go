@@ -1,95 +1,613 @@
 package lru
 
-import "container/list"
+import (
+	"container/heap"
+	"container/list"
+	"hash/fnv"
+	"iter"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"testProject/cache/clock"
+)
 
 type Cache struct {
-	maxBytes int64 //允许使用的最大内存
-	nbytes   int64 //当前已经使用的内存大小
-	ll       *list.List
-	cache    map[string]*list.Element
+	maxBytes   int64      //允许使用的最大内存
+	maxEntries int        //允许保存的最大条目数量，0 表示不限制
+	nbytes     int64      //当前已经使用的内存大小
+	overhead   int64      //每个条目额外计入的字节数，见 WithEntryOverhead
+	ll         *list.List //分段时是试用段，见 WithSegments
+	cache      map[string]*list.Element
+
+	protected      *list.List //保护段，为 nil 时不分段
+	protectedRatio float64    //保护段最多占用 maxBytes 的比例
+	pbytes         int64      //保护段已经使用的内存大小
+
+	low, high   *list.List //低优先级和高优先级的条目，见 AddWithPriority
+	pinned      *list.List //被固定的条目，不会被淘汰，见 Pin
+	pinnedBytes int64      //被固定的条目占用的内存，不计入 maxBytes
+	pinBudget   int64      //被固定的条目最多占用的内存，0 表示不限制
+
+	listeners []EvictionListener //淘汰监听器，按注册顺序依次调用
+	evictions int64              //因超出容量而被淘汰的条目数量
+	clock     clock.Clock        //记录条目写入时间和判断过期使用的时钟
+
+	evictQueue  chan eviction //异步淘汰回调的队列，为 nil 时同步执行回调
+	queuePolicy QueuePolicy   //队列已满时的处理方式
+	dropped     int           //因队列已满而被丢弃的淘汰事件数量
+	queueDone   chan struct{} //后台回调 goroutine 退出时关闭
+}
+
+// EvictionListener 在条目被淘汰时调用，参数为被淘汰条目的键和值。
+type EvictionListener func(key string, value Value)
+
+// eviction 是一次淘汰事件，记录被淘汰的条目以及淘汰发生时已注册的监听器。
+type eviction struct {
+	kv        *entry
+	listeners []EvictionListener
+}
+
+// QueuePolicy 决定异步淘汰队列已满时如何处理新的淘汰事件。
+type QueuePolicy int
 
-	OnEvicted func(key string, value Value)
+const (
+	// BlockWhenFull 在队列已满时阻塞写入，直到回调消费掉队列中的事件，保证事件不丢失。
+	BlockWhenFull QueuePolicy = iota
+	// DropWhenFull 在队列已满时丢弃新的淘汰事件，保证写入永远不会被回调拖慢。
+	DropWhenFull
+)
+
+// Option 用于在创建 Cache 时定制其行为。
+type Option func(*Cache)
+
+// WithAsyncEviction 让淘汰监听器在后台 goroutine 中异步执行，
+// 淘汰事件经过容量为 size 的队列投递，队列已满时按 policy 处理。
+// 这样较慢的回调（例如写磁盘）不会拖慢 Add 和 RemoveOldest。
+// 使用异步回调的 Cache 不再使用时需要调用 Close 释放后台 goroutine。
+func WithAsyncEviction(size int, policy QueuePolicy) Option {
+	return func(c *Cache) {
+		c.evictQueue = make(chan eviction, size)
+		c.queuePolicy = policy
+		c.queueDone = make(chan struct{})
+		go c.deliverEvictions()
+	}
+}
+
+// WithClock 设置 Cache 记录条目写入时间和判断过期使用的时钟，默认使用真实时间。
+func WithClock(c clock.Clock) Option {
+	return func(cache *Cache) {
+		cache.clock = c
+	}
+}
+
+// WithMaxEntries 限制缓存中的条目数量，与 maxBytes 同时生效，任意一个超出限制时都会淘汰最久未访问的条目。
+// 值的大小比较均匀时，按条目数量限制比按字节数更直观。n <= 0 表示不限制。
+func WithMaxEntries(n int) Option {
+	return func(c *Cache) {
+		if n > 0 {
+			c.maxEntries = n
+		}
+	}
+}
+
+// EntryOverhead 是每个条目在键和值之外大致占用的内存：链表节点、entry 结构体以及 map 中的槽位。
+// 它是 64 位平台上的估计值，可以传给 WithEntryOverhead。
+const EntryOverhead = 128
+
+// WithEntryOverhead 让每个条目在 len(key)+value.Len() 之外额外计入 n 个字节，
+// 使 maxBytes 限制的是进程实际占用的内存，而不仅是键和值的长度。值很小、条目很多时差别尤其明显。
+// 默认为 0，即只计算键和值的长度。
+func WithEntryOverhead(n int64) Option {
+	return func(c *Cache) {
+		if n > 0 {
+			c.overhead = n
+		}
+	}
 }
 
 type entry struct {
-	key   string
-	value Value
+	key       string
+	value     Value
+	added     time.Time //条目写入（或最近一次被覆盖）的时间
+	expires   time.Time //条目的过期时间，零值表示永不过期
+	protected bool      //条目位于保护段
+	priority  Priority  //条目的优先级
+	pinned    bool      //条目被固定
+}
+
+// expired 报告条目在 now 时是否已经过期。
+func (e *entry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// EntryInfo 描述缓存中的一个条目，用于采样等只读的检查操作。
+type EntryInfo struct {
+	Key     string
+	Value   Value
+	Added   time.Time // 条目写入（或最近一次被覆盖）的时间
+	Expires time.Time // 条目的过期时间，零值表示永不过期
 }
 
 type Value interface {
 	Len() int
 }
 
-func New(maxBytes int64, onEvicted func(string, Value)) *Cache {
-	return &Cache{
-		maxBytes:  maxBytes,
-		ll:        list.New(),
-		cache:     make(map[string]*list.Element),
-		OnEvicted: onEvicted,
+// New 创建一个 Cache，onEvicted 不为 nil 时会被注册为第一个淘汰监听器。
+func New(maxBytes int64, onEvicted func(string, Value), opts ...Option) *Cache {
+	c := &Cache{
+		maxBytes: maxBytes,
+		ll:       list.New(),
+		low:      list.New(),
+		high:     list.New(),
+		pinned:   list.New(),
+		cache:    make(map[string]*list.Element),
+		clock:    clock.Real,
+	}
+	if onEvicted != nil {
+		c.AddEvictionListener(onEvicted)
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 //第一步是从字典中找到对应的双向链表的节点，第二步，将该节点移动到队尾。
 //如果键对应的链表节点存在，则将对应节点移动到队尾，并返回查找到的值
+//已经过期的条目在这里被惰性删除，视为未命中
 func (c *Cache) Get(key string) (value Value, ok bool) {
 	if ele, ok := c.cache[key]; ok {
-		c.ll.MoveToFront(ele)
 		kv := ele.Value.(*entry)
+		if kv.expired(c.clock.Now()) {
+			c.removeElement(ele)
+			return nil, false
+		}
+		c.touch(ele)
 		return kv.value, true
 	}
 	return
 }
 
+// Peek 返回 key 对应的值，但不把条目移动到队尾，不影响条目的淘汰顺序。
+// 已经过期的条目视为不存在，但不会在这里被删除。
+func (c *Cache) Peek(key string) (value Value, ok bool) {
+	if ele, ok := c.cache[key]; ok {
+		kv := ele.Value.(*entry)
+		if !kv.expired(c.clock.Now()) {
+			return kv.value, true
+		}
+	}
+	return
+}
+
+// Contains 报告 key 是否在缓存中且尚未过期，不影响条目的淘汰顺序。
+func (c *Cache) Contains(key string) bool {
+	_, ok := c.Peek(key)
+	return ok
+}
+
+// Expiration 返回 key 对应条目的过期时间，零值表示永不过期。
+// 它不影响条目的访问顺序；条目不存在或已经过期时 ok 为 false。
+func (c *Cache) Expiration(key string) (expires time.Time, ok bool) {
+	if ele, ok := c.cache[key]; ok {
+		kv := ele.Value.(*entry)
+		if !kv.expired(c.clock.Now()) {
+			return kv.expires, true
+		}
+	}
+	return
+}
+
+// RemoveExpired 删除所有已经过期的条目并返回删除的数量，被删除的条目会通知淘汰监听器。
+// 它会遍历全部条目，由调用方在后台定期调用，清理那些过期后再也没有被访问的条目。
+func (c *Cache) RemoveExpired() int {
+	now := c.clock.Now()
+	n := 0
+	for _, l := range []*list.List{c.low, c.ll, c.protected, c.high, c.pinned} {
+		if l == nil {
+			continue
+		}
+		for ele := l.Back(); ele != nil; {
+			prev := ele.Prev()
+			if ele.Value.(*entry).expired(now) {
+				c.removeElement(ele)
+				n++
+			}
+			ele = prev
+		}
+	}
+	return n
+}
+
 //缓存淘汰。即移除最近最少访问的节点（队首）
 // RemoveOldest 从缓存中淘汰最不常访问的元素，即位于队首的元素。
 func (c *Cache) RemoveOldest() {
 	// 获取队尾元素（最不常访问的元素）
-	ele := c.ll.Back()
+	ele := c.oldest()
 	if ele != nil {
-		// 从双向链表中移除队尾元素
-		c.ll.Remove(ele)
-		// 通过队尾元素获取其对应的键值对（entry）
-		kv := ele.Value.(*entry)
-		// 从缓存映射表中删除对应的键
-		delete(c.cache, kv.key)
-		// 减去被移除元素的大小以更新当前已使用的内存大小
-		c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len())
-		// 如果定义了回调函数 OnEvicted，执行它，并传递被淘汰元素的键和值作为参数
-		if c.OnEvicted != nil {
-			c.OnEvicted(kv.key, kv.value)
+		c.removeElement(ele)
+		c.evictions++
+	}
+}
+
+// Victim 返回下一个将被 RemoveOldest 淘汰的条目的键，缓存为空时 ok 为 false。
+func (c *Cache) Victim() (key string, ok bool) {
+	if ele := c.oldest(); ele != nil {
+		return ele.Value.(*entry).key, true
+	}
+	return "", false
+}
+
+// Evictions 返回通过 RemoveOldest 淘汰的条目数量（包括超出容量时的自动淘汰），
+// 不包括过期和被显式删除的条目。
+func (c *Cache) Evictions() int64 {
+	return c.evictions
+}
+
+// Remove 从缓存中删除 key 对应的条目，返回条目是否存在。被删除的条目同样会通知淘汰监听器，
+// 使监听器（例如释放磁盘空间）不需要区分条目是被淘汰还是被显式删除。
+func (c *Cache) Remove(key string) bool {
+	if ele, ok := c.cache[key]; ok {
+		c.removeElement(ele)
+		return true
+	}
+	return false
+}
+
+// removeElement 从缓存中删除一个节点，并通知淘汰监听器。
+func (c *Cache) removeElement(ele *list.Element) {
+	// 通过元素获取其对应的键值对（entry）
+	kv := ele.Value.(*entry)
+	// 从所在的双向链表中移除该元素
+	c.listOf(kv).Remove(ele)
+	if kv.protected {
+		c.pbytes -= c.cost(kv.key, kv.value)
+	}
+	if kv.pinned {
+		c.pinnedBytes -= c.cost(kv.key, kv.value)
+	}
+	// 从缓存映射表中删除对应的键
+	delete(c.cache, kv.key)
+	// 减去被移除元素的大小以更新当前已使用的内存大小
+	c.nbytes -= c.cost(kv.key, kv.value)
+	// 如果注册了淘汰监听器，通知它们被淘汰元素的键和值
+	if len(c.listeners) > 0 {
+		c.evicted(eviction{kv: kv, listeners: c.listeners})
+	}
+}
+
+// AddEvictionListener 注册一个淘汰监听器，条目被淘汰时所有监听器按注册顺序被调用。
+// 适用于指标统计、溢出落盘、失效广播等多个订阅方，无需手工把它们组合成一个回调。
+func (c *Cache) AddEvictionListener(fn EvictionListener) {
+	// 复制一份新的切片，已经入队的淘汰事件持有的旧切片不受影响。
+	listeners := make([]EvictionListener, len(c.listeners), len(c.listeners)+1)
+	copy(listeners, c.listeners)
+	c.listeners = append(listeners, fn)
+}
+
+// notify 依次调用淘汰事件中的所有监听器。
+func (ev eviction) notify() {
+	for _, fn := range ev.listeners {
+		fn(ev.kv.key, ev.kv.value)
+	}
+}
+
+// evicted 把淘汰事件交给监听器：未开启异步回调时直接调用，否则放入队列由后台 goroutine 执行。
+func (c *Cache) evicted(ev eviction) {
+	if c.evictQueue == nil {
+		ev.notify()
+		return
+	}
+	if c.queuePolicy == DropWhenFull {
+		select {
+		case c.evictQueue <- ev:
+		default:
+			c.dropped++ // 队列已满，丢弃该事件
 		}
+		return
 	}
+	c.evictQueue <- ev
 }
 
-// Add 将一个键值对添加或更新到缓存中。
+// deliverEvictions 在后台 goroutine 中依次执行队列中的淘汰回调，直到队列被关闭。
+func (c *Cache) deliverEvictions() {
+	defer close(c.queueDone)
+	for ev := range c.evictQueue {
+		ev.notify()
+	}
+}
+
+// Dropped 返回因异步淘汰队列已满而被丢弃的淘汰事件数量。
+func (c *Cache) Dropped() int {
+	return c.dropped
+}
+
+// Close 关闭异步淘汰队列，并等待队列中剩余的回调执行完毕。
+// 未开启异步回调时 Close 什么也不做。Close 之后不应再向 Cache 写入数据。
+func (c *Cache) Close() {
+	if c.evictQueue == nil {
+		return
+	}
+	close(c.evictQueue)
+	<-c.queueDone
+	c.evictQueue = nil
+}
+
+// Add 将一个永不过期的键值对添加或更新到缓存中。
 func (c *Cache) Add(key string, value Value) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire 将一个键值对添加或更新到缓存中，条目在 expires 之后过期，零值表示永不过期。
+// 新条目的优先级为 PriorityNormal，已有的条目保留原来的优先级。
+func (c *Cache) AddWithExpire(key string, value Value, expires time.Time) {
+	c.add(key, value, expires, PriorityNormal, false)
+}
+
+// add 添加或更新一个条目，setPriority 为 true 时已有条目的优先级也改为 priority。
+func (c *Cache) add(key string, value Value, expires time.Time, priority Priority, setPriority bool) {
 	// 检查键是否已存在于缓存中
 	if ele, ok := c.cache[key]; ok {
-		// 如果存在，将对应的节点移动到队首，表示最近访问过
-		c.ll.MoveToFront(ele)
+		if setPriority {
+			ele = c.setPriority(ele, priority)
+		}
 		// 获取节点对应的键值对
 		kv := ele.Value.(*entry)
 		// 更新缓存占用的内存大小，减去旧值大小并加上新值大小
-		c.nbytes += int64(value.Len()) - int64(kv.value.Len())
+		delta := int64(value.Len()) - int64(kv.value.Len())
+		if kv.pinned && c.pinBudget != 0 && c.pinnedBytes+delta > c.pinBudget {
+			ele = c.unpin(ele) // 更新后的值超出固定预算，条目重新参与淘汰
+		}
+		c.nbytes += delta
+		if kv.protected {
+			c.pbytes += delta
+		}
+		if kv.pinned {
+			c.pinnedBytes += delta
+		}
 		// 更新节点的值为新的值
 		kv.value = value
+		kv.added = c.clock.Now()
+		kv.expires = expires
+		// 将对应的节点移动到队首，表示最近访问过
+		c.touch(ele)
 	} else {
-		// 如果键不存在，创建一个新的节点并添加到队首
-		ele := c.ll.PushFront(&entry{key, value})
+		// 如果键不存在，创建一个新的节点并添加到所属链表的队首
+		kv := &entry{key: key, value: value, added: c.clock.Now(), expires: expires, priority: priority}
+		ele := c.listOf(kv).PushFront(kv)
 		// 在缓存映射表中添加新的键值对映射
 		c.cache[key] = ele
-		// 更新缓存占用的内存大小，加上新键和新值的大小
-		c.nbytes += int64(len(key)) + int64(value.Len())
+		// 更新缓存占用的内存大小，加上新键和新值的大小以及条目本身的开销
+		c.nbytes += c.cost(key, value)
 	}
 
-	// 如果设置了最大内存限制且当前内存占用超过了限制
-	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
+	// 如果当前内存占用或条目数量超过了限制
+	for c.overCapacity() {
 		// 执行淘汰操作，移除最不常访问的元素
 		c.RemoveOldest()
 	}
 }
 
+// cost 返回一个条目计入 nbytes 的字节数。
+func (c *Cache) cost(key string, value Value) int64 {
+	return int64(len(key)) + int64(value.Len()) + c.overhead
+}
+
+// overCapacity 报告缓存是否超过了内存限制或条目数量限制，被固定的条目不计入。
+func (c *Cache) overCapacity() bool {
+	return (c.maxBytes != 0 && c.maxBytes < c.nbytes-c.pinnedBytes) ||
+		(c.maxEntries != 0 && c.maxEntries < c.Len()-c.pinned.Len())
+}
+
 //获取添加了多少条数据
 func (c *Cache) Len() int {
-	return c.ll.Len()
+	return len(c.cache)
+}
+
+// Range 按从最近访问到最久未访问的顺序（即与淘汰相反的顺序，被固定的条目在最前）对每个未过期的条目调用 fn，
+// fn 返回 false 时停止遍历。Range 不影响条目的访问顺序，但 fn 中不能修改缓存。
+func (c *Cache) Range(fn func(key string, value Value) bool) {
+	now := c.clock.Now()
+	for _, l := range []*list.List{c.pinned, c.high, c.protected, c.ll, c.low} {
+		if l == nil {
+			continue
+		}
+		for ele := l.Front(); ele != nil; ele = ele.Next() {
+			kv := ele.Value.(*entry)
+			if kv.expired(now) {
+				continue
+			}
+			if !fn(kv.key, kv.value) {
+				return
+			}
+		}
+	}
+}
+
+// Keys 返回所有未过期条目的键，顺序与 Range 相同，不影响条目的访问顺序。
+func (c *Cache) Keys() []string {
+	keys := make([]string, 0, c.Len())
+	c.Range(func(key string, _ Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// Resize 调整允许使用的最大内存，并立即淘汰最久未访问的条目直到不超过新的限制，0 表示不限制。
+func (c *Cache) Resize(maxBytes int64) {
+	c.maxBytes = maxBytes
+	for c.overCapacity() {
+		c.RemoveOldest()
+	}
+}
+
+// Sample 随机返回最多 n 个未过期的条目，不影响条目的访问顺序。
+// 采样会遍历全部条目（水塘抽样），耗时与条目数量成正比，适合运维检查而不是请求路径。
+func (c *Cache) Sample(n int) []EntryInfo {
+	if n <= 0 {
+		return nil
+	}
+	sample := make([]EntryInfo, 0, n)
+	now := c.clock.Now()
+	i := 0
+	for _, ele := range c.cache {
+		kv := ele.Value.(*entry)
+		if kv.expired(now) {
+			continue
+		}
+		info := EntryInfo{Key: kv.key, Value: kv.value, Added: kv.added, Expires: kv.expires}
+		if i < n {
+			sample = append(sample, info)
+		} else if j := rand.Intn(i + 1); j < n {
+			sample[j] = info
+		}
+		i++
+	}
+	return sample
+}
+
+// MergeSamples 把从多个缓存中分别采样得到的结果合并为最多 n 个条目的样本。
+// sizes[i] 是 samples[i] 所来自的缓存的条目总数，条目越多的缓存在合并结果中的占比越大，
+// 使合并后的样本近似于对所有缓存的条目做均匀采样。
+func MergeSamples(n int, samples [][]EntryInfo, sizes []int) []EntryInfo {
+	type weighted struct {
+		info EntryInfo
+		key  float64
+	}
+	var all []weighted
+	for i, sample := range samples {
+		if len(sample) == 0 {
+			continue
+		}
+		w := float64(sizes[i]) / float64(len(sample)) // 每个样本代表的条目数量
+		for _, info := range sample {
+			// 加权水塘抽样（A-ES）：键为 u^(1/w)，取键最大的 n 个
+			all = append(all, weighted{info: info, key: math.Pow(rand.Float64(), 1/w)})
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].key > all[j].key })
+	if len(all) > n {
+		all = all[:n]
+	}
+	merged := make([]EntryInfo, len(all))
+	for i, w := range all {
+		merged[i] = w.info
+	}
+	return merged
+}
+
+// KeyHash 返回 Scan 游标所使用的键的 64 位哈希值。
+func KeyHash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// Scan 按键的哈希值顺序分页遍历以 prefix 开头的键，返回哈希值不小于 cursor 的最多 count 个键，
+// 以及下一页的游标；返回的游标为 0 表示遍历结束。第一次调用时 cursor 传 0。
+// 与 Redis 的 SCAN 类似：遍历期间一直存在的键一定会被返回，遍历期间增删的键可能返回也可能不返回。
+// 每次调用都会检查全部条目，但调用之间不需要持有任何状态。
+func (c *Cache) Scan(cursor uint64, prefix string, count int) (keys []string, next uint64) {
+	now := c.clock.Now()
+	return ScanKeys(cursor, prefix, count, func(yield func(string) bool) {
+		for key, ele := range c.cache {
+			if !ele.Value.(*entry).expired(now) && !yield(key) {
+				return
+			}
+		}
+	})
+}
+
+// ScanKeys 实现 Scan 的分页：从 keys 中选出以 prefix 开头、哈希值不小于 cursor 的最多 count 个键。
+// 其他淘汰策略的缓存用它实现与 Cache.Scan 相同的游标，结果可以用 MergeScans 合并。
+func ScanKeys(cursor uint64, prefix string, count int, keys iter.Seq[string]) ([]string, uint64) {
+	if count <= 0 {
+		return nil, cursor
+	}
+	h := make(scanHeap, 0, count)
+	for key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		k := scanKey{hash: KeyHash(key), key: key}
+		if k.hash < cursor {
+			continue
+		}
+		if len(h) < count {
+			heap.Push(&h, k)
+		} else if k.less(h[0]) {
+			h[0] = k // 替换掉当前页中哈希值最大的键
+			heap.Fix(&h, 0)
+		}
+	}
+	sort.Slice(h, func(i, j int) bool { return h[i].less(h[j]) })
+	return h.page(count)
+}
+
+// MergeScans 合并对多个缓存使用同一个游标调用 Scan 得到的结果，返回最多 count 个键以及下一页的游标。
+func MergeScans(count int, pages [][]string, nexts []uint64) (keys []string, next uint64) {
+	var all scanHeap
+	more := false
+	for i, page := range pages {
+		for _, key := range page {
+			all = append(all, scanKey{hash: KeyHash(key), key: key})
+		}
+		more = more || nexts[i] != 0
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].less(all[j]) })
+	keys, next = all.page(count)
+	if !more && len(all) <= count {
+		next = 0 // 所有缓存都已遍历结束
+	}
+	return keys, next
+}
+
+// scanKey 是 Scan 遍历到的键及其哈希值。
+type scanKey struct {
+	hash uint64
+	key  string
+}
+
+// less 按哈希值、再按键本身排序。
+func (k scanKey) less(o scanKey) bool {
+	return k.hash < o.hash || (k.hash == o.hash && k.key < o.key)
+}
+
+// scanHeap 是按 scanKey 排序的最大堆，用于在遍历时保留哈希值最小的 count 个键。
+type scanHeap []scanKey
+
+func (h scanHeap) Len() int            { return len(h) }
+func (h scanHeap) Less(i, j int) bool  { return h[j].less(h[i]) }
+func (h scanHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *scanHeap) Push(x interface{}) { *h = append(*h, x.(scanKey)) }
+func (h *scanHeap) Pop() interface{} {
+	old := *h
+	k := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return k
+}
+
+// page 从已排序的键中取出最多 count 个作为一页，并计算下一页的游标。
+// 键的数量不足 count 时说明遍历已经结束，游标为 0。
+func (h scanHeap) page(count int) (keys []string, next uint64) {
+	if len(h) > count {
+		h = h[:count]
+	}
+	keys = make([]string, len(h))
+	for i, k := range h {
+		keys[i] = k.key
+	}
+	if len(h) < count {
+		return keys, 0
+	}
+	return keys, h[len(h)-1].hash + 1 // 哈希值为最大值时加一溢出为 0，同样表示遍历结束
+}
+
+// Bytes 返回当前已经使用的内存大小，即所有条目的键和值的长度之和，加上 WithEntryOverhead 设置的每个条目的开销。
+func (c *Cache) Bytes() int64 {
+	return c.nbytes
 }
@@ -2,7 +2,11 @@ package lru
 
 import (
 	"reflect"
+	"strconv"
 	"testing"
+	"time"
+
+	"testProject/cache/clock"
 )
 
 type String string
@@ -38,6 +42,141 @@ func TestRemoveoldest(t *testing.T) {
 	}
 }
 
+// 测试分段 LRU 中被再次访问过的条目不会被一次性扫描挤出缓存，保护段超限时降级最久未访问的条目
+func TestSegments(t *testing.T) {
+	lru := New(int64(8), nil, WithSegments(0.5))
+	lru.Add("a", String("1"))
+	lru.Add("b", String("2"))
+	lru.Get("a")
+	lru.Get("b")
+	for _, k := range []string{"c", "d", "e", "f"} {
+		lru.Add(k, String("x"))
+	}
+	if !lru.Contains("a") || !lru.Contains("b") || lru.Len() != 4 || lru.Bytes() != 8 {
+		t.Fatalf("protected entries evicted by a scan: Len = %d", lru.Len())
+	}
+	lru.Get("f") // 晋升 f，保护段超出 4 字节，a 降级到试用段
+	if key, _ := lru.Victim(); key != "e" {
+		t.Fatalf("Victim = %q, want e", key)
+	}
+	lru.RemoveOldest()
+	lru.RemoveOldest()
+	if lru.Contains("a") || !lru.Contains("b") || !lru.Contains("f") {
+		t.Fatal("demoted entry was not evicted from the probation segment")
+	}
+}
+
+// 测试被固定的条目和高优先级条目不会被批量写入挤出缓存
+func TestPinAndPriority(t *testing.T) {
+	lru := New(int64(12), nil, WithPinBudget(4))
+	lru.Add("cfg", String("1"))
+	if !lru.Pin("cfg") || lru.PinnedBytes() != 4 {
+		t.Fatalf("Pin failed, PinnedBytes = %d", lru.PinnedBytes())
+	}
+	lru.Add("tok", String("2"))
+	if lru.Pin("tok") {
+		t.Fatal("Pin exceeded the pin budget")
+	}
+	lru.AddWithPriority("tok", String("2"), time.Time{}, PriorityHigh)
+	lru.AddWithPriority("bulk", String("x"), time.Time{}, PriorityLow)
+	lru.Add("a", String("x"))
+	lru.Get("bulk") // 访问过的低优先级条目仍然先于普通条目被淘汰
+	if key, _ := lru.Victim(); key != "bulk" {
+		t.Fatalf("Victim = %q, want the low priority entry", key)
+	}
+	for _, k := range []string{"b", "c", "d", "e"} {
+		lru.Add(k, String("x"))
+	}
+	if !lru.Contains("cfg") || !lru.Contains("tok") || lru.Contains("bulk") || lru.Contains("a") {
+		t.Fatal("pinned or high priority entry evicted before normal entries")
+	}
+	lru.Unpin("cfg") // cfg 重新计入容量，最久未访问的普通条目被淘汰
+	if !lru.Contains("cfg") || lru.Contains("b") || lru.PinnedBytes() != 0 || lru.Bytes() > 12 {
+		t.Fatalf("Bytes = %d after Unpin, want the cache back within its limit", lru.Bytes())
+	}
+}
+
+// 测试被固定的条目更新为更大的值并超出固定预算时会被取消固定
+func TestPinBudgetOnUpdate(t *testing.T) {
+	lru := New(int64(12), nil, WithPinBudget(5))
+	lru.Add("cfg", String("1"))
+	if !lru.Pin("cfg") || lru.PinnedBytes() != 4 {
+		t.Fatalf("Pin failed, PinnedBytes = %d", lru.PinnedBytes())
+	}
+	lru.Add("cfg", String("22"))
+	if lru.PinnedBytes() != 5 {
+		t.Fatalf("PinnedBytes = %d after growing within the budget, want 5", lru.PinnedBytes())
+	}
+	lru.Add("cfg", String("333"))
+	if lru.PinnedBytes() != 0 || lru.Unpin("cfg") {
+		t.Fatalf("PinnedBytes = %d, want the entry unpinned once it exceeds the budget", lru.PinnedBytes())
+	}
+	for _, k := range []string{"a", "b", "c", "d"} {
+		lru.Add(k, String("x"))
+	}
+	if lru.Contains("cfg") || lru.Bytes() > 12 {
+		t.Fatal("unpinned entry was not evicted like a normal entry")
+	}
+}
+
+// 测试 Keys 和 Range 按从最近到最久的顺序遍历，且不影响淘汰顺序
+func TestKeysAndRange(t *testing.T) {
+	lru := New(int64(0), nil)
+	lru.Add("k1", String("1"))
+	lru.Add("k2", String("2"))
+	lru.Add("k3", String("3"))
+	lru.Get("k1")
+	if keys := lru.Keys(); !reflect.DeepEqual(keys, []string{"k1", "k3", "k2"}) {
+		t.Fatalf("Keys = %v", keys)
+	}
+	n := 0
+	lru.Range(func(key string, value Value) bool {
+		n++
+		return key != "k3"
+	})
+	if n != 2 {
+		t.Fatalf("Range visited %d entries after fn returned false", n)
+	}
+	if key, _ := lru.Victim(); key != "k2" {
+		t.Fatalf("Victim = %q, Range changed the eviction order", key)
+	}
+}
+
+// 测试每个条目的额外开销计入内存占用
+func TestEntryOverhead(t *testing.T) {
+	lru := New(int64(3*(2+EntryOverhead)), nil, WithEntryOverhead(EntryOverhead))
+	lru.Add("k1", String("v"))
+	if lru.Bytes() != 3+EntryOverhead {
+		t.Fatalf("Bytes = %d, want %d", lru.Bytes(), 3+EntryOverhead)
+	}
+	lru.Add("k1", String("v1")) // 覆盖已有的键不重复计入开销
+	lru.Add("k2", String("v2"))
+	lru.Add("k3", String("v3"))
+	if lru.Len() != 2 || lru.Bytes() != 2*(4+EntryOverhead) {
+		t.Fatalf("Len = %d, Bytes = %d", lru.Len(), lru.Bytes())
+	}
+	lru.Remove("k2")
+	lru.Remove("k3")
+	if lru.Bytes() != 0 {
+		t.Fatalf("Bytes = %d after removing every entry", lru.Bytes())
+	}
+}
+
+// 测试条目数量和内存限制同时生效，任意一个超出时都会淘汰最久未访问的条目
+func TestMaxEntries(t *testing.T) {
+	lru := New(int64(10), nil, WithMaxEntries(2))
+	lru.Add("a", String("1"))
+	lru.Add("b", String("2"))
+	lru.Add("c", String("3"))
+	if _, ok := lru.Get("a"); ok || lru.Len() != 2 {
+		t.Fatalf("entry limit: Len = %d, a still cached = %v", lru.Len(), ok)
+	}
+	lru.Add("d", String("123456789"))
+	if lru.Len() != 1 || lru.Bytes() > 10 {
+		t.Fatalf("byte limit: Len = %d, Bytes = %d", lru.Len(), lru.Bytes())
+	}
+}
+
 // 回调函数能否被调用
 func TestOnEvicted(t *testing.T) {
 	keys := make([]string, 0)
@@ -56,3 +195,164 @@ func TestOnEvicted(t *testing.T) {
 		t.Fatalf("Call OnEvicted failed, expect keys equals to %s", expect)
 	}
 }
+
+// 异步回调不会阻塞写入，队列满时按策略丢弃事件
+func TestAsyncEviction(t *testing.T) {
+	release := make(chan struct{})
+	var keys []string
+	callback := func(key string, value Value) {
+		<-release
+		keys = append(keys, key)
+	}
+	lru := New(int64(10), callback, WithAsyncEviction(1, DropWhenFull))
+	lru.Add("key1", String("123456"))
+	lru.Add("k2", String("k2"))
+	lru.Add("k3", String("k3"))
+	lru.Add("k4", String("k4"))
+	lru.Add("k5", String("k5"))
+
+	// 后台 goroutine 最多取走一个事件阻塞在回调中，队列中还能再缓存一个，其余被丢弃
+	if lru.Dropped() < 1 {
+		t.Fatalf("Dropped() = %d, want at least 1", lru.Dropped())
+	}
+	close(release)
+	lru.Close()
+	if len(keys)+lru.Dropped() != 3 {
+		t.Fatalf("delivered %v and dropped %d, want 3 evictions in total", keys, lru.Dropped())
+	}
+}
+
+// 多个淘汰监听器都能收到淘汰事件
+func TestEvictionListeners(t *testing.T) {
+	var first, second []string
+	lru := New(int64(10), nil)
+	lru.AddEvictionListener(func(key string, value Value) {
+		first = append(first, key)
+	})
+	lru.AddEvictionListener(func(key string, value Value) {
+		second = append(second, key)
+	})
+	lru.Add("key1", String("123456"))
+	lru.Add("k2", String("k2"))
+
+	expect := []string{"key1"}
+	if !reflect.DeepEqual(expect, first) || !reflect.DeepEqual(expect, second) {
+		t.Fatalf("listeners got %v and %v, expect both %v", first, second, expect)
+	}
+}
+
+// 采样返回不重复的条目，且不超过条目总数
+func TestSample(t *testing.T) {
+	lru := New(int64(0), nil)
+	for i := 0; i < 10; i++ {
+		lru.Add(strconv.Itoa(i), String("v"))
+	}
+	if got := lru.Sample(20); len(got) != 10 {
+		t.Fatalf("Sample(20) returned %d entries, want 10", len(got))
+	}
+	seen := make(map[string]bool)
+	for _, e := range lru.Sample(5) {
+		if seen[e.Key] || e.Added.IsZero() {
+			t.Fatalf("Sample(5) returned duplicate or undated entry %q", e.Key)
+		}
+		seen[e.Key] = true
+	}
+	if len(seen) != 5 {
+		t.Fatalf("Sample(5) returned %d entries", len(seen))
+	}
+}
+
+// 按游标分页遍历能恰好返回每个匹配前缀的键一次
+func TestScan(t *testing.T) {
+	lru := New(int64(0), nil)
+	for i := 0; i < 25; i++ {
+		lru.Add("user:"+strconv.Itoa(i), String("v"))
+		lru.Add("order:"+strconv.Itoa(i), String("v"))
+	}
+	seen := make(map[string]int)
+	var cursor uint64
+	for pages := 0; ; pages++ {
+		if pages > 25 {
+			t.Fatal("Scan did not terminate")
+		}
+		keys, next := lru.Scan(cursor, "user:", 7)
+		for _, key := range keys {
+			seen[key]++
+		}
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+	if len(seen) != 25 {
+		t.Fatalf("Scan returned %d distinct keys, want 25", len(seen))
+	}
+	for key, n := range seen {
+		if n != 1 {
+			t.Fatalf("Scan returned %q %d times", key, n)
+		}
+	}
+}
+
+// 过期的条目在 Get 时被惰性删除，RemoveExpired 清理其余过期条目并通知监听器
+func TestExpiration(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	var evicted []string
+	lru := New(int64(0), func(key string, value Value) {
+		evicted = append(evicted, key)
+	}, WithClock(clk))
+	lru.AddWithExpire("k1", String("v"), clk.Now().Add(time.Second))
+	lru.AddWithExpire("k2", String("v"), clk.Now().Add(time.Second))
+	lru.Add("k3", String("v"))
+
+	if exp, ok := lru.Expiration("k1"); !ok || !exp.Equal(clk.Now().Add(time.Second)) {
+		t.Fatalf("Expiration(k1) = %v, %v", exp, ok)
+	}
+	clk.Advance(time.Second)
+	if _, ok := lru.Get("k1"); ok {
+		t.Fatal("expired entry k1 should miss")
+	}
+	if n := lru.RemoveExpired(); n != 1 || lru.Len() != 1 {
+		t.Fatalf("RemoveExpired() = %d, Len() = %d; want 1, 1", n, lru.Len())
+	}
+	if !reflect.DeepEqual(evicted, []string{"k1", "k2"}) || lru.Bytes() != int64(len("k3")+1) {
+		t.Fatalf("evicted %v, bytes %d", evicted, lru.Bytes())
+	}
+}
+
+// Remove 删除条目并通知监听器
+func TestRemove(t *testing.T) {
+	var evicted []string
+	lru := New(int64(0), func(key string, value Value) {
+		evicted = append(evicted, key)
+	})
+	lru.Add("k1", String("v1"))
+	if !lru.Remove("k1") || lru.Remove("k1") {
+		t.Fatal("Remove should report whether the key existed")
+	}
+	if lru.Len() != 0 || lru.Bytes() != 0 || !reflect.DeepEqual(evicted, []string{"k1"}) {
+		t.Fatalf("after Remove: len %d, bytes %d, evicted %v", lru.Len(), lru.Bytes(), evicted)
+	}
+}
+
+// 测试 Peek 和 Contains 不影响淘汰顺序
+func TestPeek(t *testing.T) {
+	k1, k2, k3 := "key1", "key2", "k3"
+	v1, v2, v3 := "value1", "value2", "v3"
+	cap := len(k1 + k2 + v1 + v2)
+	lru := New(int64(cap), nil)
+	lru.Add(k1, String(v1))
+	lru.Add(k2, String(v2))
+
+	if v, ok := lru.Peek(k1); !ok || string(v.(String)) != v1 {
+		t.Fatalf("Peek(%s) = %v, %v", k1, v, ok)
+	}
+	if !lru.Contains(k1) || lru.Contains(k3) {
+		t.Fatal("Contains should report whether the key is cached")
+	}
+	// k1 没有被 Peek 和 Contains 提升，仍然最先被淘汰
+	lru.Add(k3, String(v3))
+	if lru.Contains(k1) || !lru.Contains(k2) {
+		t.Fatal("Peek and Contains should not affect the eviction order")
+	}
+}
@@ -0,0 +1,127 @@
+package lru
+
+import (
+	"container/list"
+	"time"
+)
+
+// Priority 是条目的优先级。超出容量时先淘汰全部低优先级条目，再淘汰普通条目，最后才淘汰高优先级条目；
+// 同一优先级内按最久未访问的顺序淘汰。
+type Priority int
+
+const (
+	// PriorityNormal 是 Add 和 AddWithExpire 写入的条目的默认优先级。
+	PriorityNormal Priority = iota
+	// PriorityLow 适用于可以随时重新加载的批量数据，总是最先被淘汰。
+	PriorityLow
+	// PriorityHigh 适用于配置、令牌等关键条目，只有其他条目都被淘汰后才会被淘汰。
+	PriorityHigh
+)
+
+// WithPinBudget 设置被固定的条目最多占用的内存，超出时 Pin 返回 false。默认不限制。
+func WithPinBudget(n int64) Option {
+	return func(c *Cache) {
+		if n > 0 {
+			c.pinBudget = n
+		}
+	}
+}
+
+// AddWithPriority 与 AddWithExpire 相同，同时把条目的优先级设置为 priority。
+func (c *Cache) AddWithPriority(key string, value Value, expires time.Time, priority Priority) {
+	c.add(key, value, expires, priority, true)
+}
+
+// Pin 固定 key 对应的条目，使它不会因超出容量被淘汰，返回是否固定成功。
+// 被固定的条目占用的内存单独计入固定预算，不计入 maxBytes 和 WithMaxEntries 的限制；
+// 它仍然会过期，也可以被 Remove 删除。条目不存在或固定后会超出预算时返回 false。
+// 被固定的条目之后被更新为更大的值、使固定的条目超出预算时，它会被取消固定。
+func (c *Cache) Pin(key string) bool {
+	ele, ok := c.cache[key]
+	if !ok {
+		return false
+	}
+	kv := ele.Value.(*entry)
+	if kv.pinned {
+		return true
+	}
+	n := c.cost(kv.key, kv.value)
+	if c.pinBudget != 0 && c.pinnedBytes+n > c.pinBudget {
+		return false
+	}
+	c.listOf(kv).Remove(ele)
+	if kv.protected {
+		kv.protected = false
+		c.pbytes -= n
+	}
+	kv.pinned = true
+	c.pinnedBytes += n
+	c.cache[key] = c.pinned.PushFront(kv)
+	return true
+}
+
+// Unpin 取消固定 key 对应的条目，条目重新按优先级参与淘汰，返回条目是否曾被固定。
+// 取消固定后缓存可能超出容量，此时会立即淘汰条目。
+func (c *Cache) Unpin(key string) bool {
+	ele, ok := c.cache[key]
+	if !ok || !ele.Value.(*entry).pinned {
+		return false
+	}
+	c.unpin(ele)
+	for c.overCapacity() {
+		c.RemoveOldest()
+	}
+	return true
+}
+
+// unpin 把被固定的条目移回它的优先级对应的链表，返回条目新的节点。调用方负责之后的淘汰。
+func (c *Cache) unpin(ele *list.Element) *list.Element {
+	kv := ele.Value.(*entry)
+	c.pinned.Remove(ele)
+	kv.pinned = false
+	c.pinnedBytes -= c.cost(kv.key, kv.value)
+	ele = c.listOf(kv).PushFront(kv)
+	c.cache[kv.key] = ele
+	return ele
+}
+
+// PinnedBytes 返回被固定的条目占用的内存，它包含在 Bytes 中。
+func (c *Cache) PinnedBytes() int64 {
+	return c.pinnedBytes
+}
+
+// setPriority 修改条目的优先级，必要时把它移到新优先级对应的链表，返回条目新的节点。
+func (c *Cache) setPriority(ele *list.Element, priority Priority) *list.Element {
+	kv := ele.Value.(*entry)
+	if kv.priority == priority {
+		return ele
+	}
+	if kv.pinned {
+		kv.priority = priority // 取消固定后按新的优先级参与淘汰
+		return ele
+	}
+	c.listOf(kv).Remove(ele)
+	if kv.protected {
+		kv.protected = false
+		c.pbytes -= c.cost(kv.key, kv.value)
+	}
+	kv.priority = priority
+	ele = c.listOf(kv).PushFront(kv)
+	c.cache[kv.key] = ele
+	return ele
+}
+
+// listOf 返回条目所在的链表。
+func (c *Cache) listOf(kv *entry) *list.List {
+	switch {
+	case kv.pinned:
+		return c.pinned
+	case kv.priority == PriorityLow:
+		return c.low
+	case kv.priority == PriorityHigh:
+		return c.high
+	case kv.protected:
+		return c.protected
+	}
+	return c.ll
+}
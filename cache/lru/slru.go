@@ -0,0 +1,57 @@
+package lru
+
+import "container/list"
+
+// WithSegments 把缓存划分为试用段和保护段，即分段 LRU（SLRU）：
+// 新写入的条目进入试用段，在试用段中再次被访问时晋升到保护段；
+// 保护段最多占用 maxBytes 的 protectedRatio，超出时保护段中最久未访问的条目降级回试用段；
+// 淘汰总是先从试用段开始。一次性扫描大量键时这些键只会挤占试用段，
+// 被反复访问的条目留在保护段中，命中率明显高于普通 LRU。protectedRatio 通常取 0.8，
+// 不在 (0, 1) 范围内时不分段。
+func WithSegments(protectedRatio float64) Option {
+	return func(c *Cache) {
+		if protectedRatio > 0 && protectedRatio < 1 {
+			c.protected = list.New()
+			c.protectedRatio = protectedRatio
+		}
+	}
+}
+
+// touch 记录一次访问：不分段或条目已在保护段时移动到所在链表的队首，
+// 条目在试用段时晋升到保护段。
+func (c *Cache) touch(ele *list.Element) {
+	kv := ele.Value.(*entry)
+	if c.protected == nil || kv.pinned || kv.priority != PriorityNormal {
+		c.listOf(kv).MoveToFront(ele)
+		return
+	}
+	if kv.protected {
+		c.protected.MoveToFront(ele)
+	} else {
+		c.ll.Remove(ele)
+		kv.protected = true
+		c.cache[kv.key] = c.protected.PushFront(kv)
+		c.pbytes += c.cost(kv.key, kv.value)
+	}
+	// 保护段超出上限时，把最久未访问的条目降级到试用段的队首，至少保留刚访问的条目
+	limit := int64(float64(c.maxBytes) * c.protectedRatio)
+	for c.maxBytes != 0 && c.pbytes > limit && c.protected.Len() > 1 {
+		back := c.protected.Back()
+		old := back.Value.(*entry)
+		c.protected.Remove(back)
+		old.protected = false
+		c.pbytes -= c.cost(old.key, old.value)
+		c.cache[old.key] = c.ll.PushFront(old)
+	}
+}
+
+// oldest 返回下一个将被淘汰的节点：依次是低优先级条目、试用段、保护段、高优先级条目的队尾，
+// 被固定的条目不会被淘汰。
+func (c *Cache) oldest() *list.Element {
+	for _, l := range []*list.List{c.low, c.ll, c.protected, c.high} {
+		if l != nil && l.Len() > 0 {
+			return l.Back()
+		}
+	}
+	return nil
+}
@@ -1,11 +1,18 @@
 package main
 
 import (
+	"crypto/tls"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"strings"
 	"testProject/cache/geecache"
+	"testProject/cache/grpcpool"
+
+	"google.golang.org/grpc"
 )
 
 var db = map[string]string{
@@ -21,7 +28,7 @@ func createGroup() *geecache.Group {
 			if v, ok := db[key]; ok {
 				return []byte(v), nil
 			}
-			return nil, fmt.Errorf("%s not exist", key)
+			return nil, fmt.Errorf("%s: %w", key, geecache.ErrNotFound)
 		}))
 }
 
@@ -30,7 +37,46 @@ func startCacheServer(addr string, addrs []string, gee *geecache.Group) {
 	peers.Set(addrs...)
 	gee.RegisterPeers(peers)
 	log.Println("geecache is running at", addr)
-	log.Fatal(http.ListenAndServe(addr[7:], peers))
+	// 节点之间使用 h2c 通信，Server 同时启用 HTTP/1 和明文 HTTP/2。
+	log.Fatal(geecache.NewServer(addr[7:], peers).ListenAndServe())
+}
+
+// startTLSCacheServer 与 startCacheServer 相同，但节点之间通过双向 TLS 通信，节点地址使用 https:// 前缀。
+func startTLSCacheServer(addr string, addrs []string, gee *geecache.Group, tlsConfig *tls.Config) {
+	addr = "https://" + strings.TrimPrefix(addr, "http://")
+	peerAddrs := make([]string, len(addrs))
+	for i, a := range addrs {
+		peerAddrs[i] = "https://" + strings.TrimPrefix(a, "http://")
+	}
+	peers := geecache.NewHTTPPoolTLS(addr, tlsConfig)
+	peers.Set(peerAddrs...)
+	gee.RegisterPeers(peers)
+	log.Println("geecache is running at", addr)
+	log.Fatal(geecache.NewServer(strings.TrimPrefix(addr, "https://"), peers).ListenAndServe())
+}
+
+// startGRPCCacheServer 与 startCacheServer 相同，但节点之间通过 gRPC 通信。
+// gRPC 的地址不带协议前缀，例如 "localhost:8001"。
+func startGRPCCacheServer(addr string, addrs []string, gee *geecache.Group) {
+	addr = strings.TrimPrefix(addr, "http://")
+	peerAddrs := make([]string, len(addrs))
+	for i, a := range addrs {
+		peerAddrs[i] = strings.TrimPrefix(a, "http://")
+	}
+	peers := grpcpool.New(addr)
+	if err := peers.Set(peerAddrs...); err != nil {
+		log.Fatal(err)
+	}
+	defer peers.Close()
+	gee.RegisterPeers(peers)
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	server := grpc.NewServer()
+	peers.Register(server)
+	log.Println("geecache is running at", addr, "over gRPC")
+	log.Fatal(server.Serve(lis))
 }
 
 func startAPIServer(apiAddr string, gee *geecache.Group) {
@@ -38,6 +84,10 @@ func startAPIServer(apiAddr string, gee *geecache.Group) {
 		func(w http.ResponseWriter, r *http.Request) {
 			key := r.URL.Query().Get("key")
 			view, err := gee.Get(key)
+			if errors.Is(err, geecache.ErrNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound) // 数据源中不存在 key，不是服务故障
+				return
+			}
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
@@ -46,6 +96,7 @@ func startAPIServer(apiAddr string, gee *geecache.Group) {
 			w.Write(view.ByteSlice())
 
 		}))
+	http.Handle("/metrics", geecache.MetricsHandler())
 	log.Println("fontend server is running at", apiAddr)
 	log.Fatal(http.ListenAndServe(apiAddr[7:], nil))
 
@@ -54,8 +105,14 @@ func startAPIServer(apiAddr string, gee *geecache.Group) {
 func main() {
 	var port int
 	var api bool
+	var transport string
+	var certFile, keyFile, caFile string
 	flag.IntVar(&port, "port", 8001, "Geecache server port")
 	flag.BoolVar(&api, "api", false, "Start a api server?")
+	flag.StringVar(&transport, "transport", "http", "Peer transport: http or grpc")
+	flag.StringVar(&certFile, "tls-cert", "", "Node certificate for mutual TLS between peers (http transport)")
+	flag.StringVar(&keyFile, "tls-key", "", "Node private key for mutual TLS between peers")
+	flag.StringVar(&caFile, "tls-ca", "", "CA certificate used to verify peers")
 	flag.Parse()
 
 	apiAddr := "http://localhost:9999"
@@ -74,5 +131,20 @@ func main() {
 	if api {
 		go startAPIServer(apiAddr, gee)
 	}
-	startCacheServer(addrMap[port], []string(addrs), gee)
+	switch transport {
+	case "http":
+		if certFile != "" {
+			tlsConfig, err := geecache.LoadMutualTLSConfig(certFile, keyFile, caFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+			startTLSCacheServer(addrMap[port], []string(addrs), gee, tlsConfig)
+			break
+		}
+		startCacheServer(addrMap[port], []string(addrs), gee)
+	case "grpc":
+		startGRPCCacheServer(addrMap[port], []string(addrs), gee)
+	default:
+		log.Fatalf("unknown transport %q, want http or grpc", transport)
+	}
 }
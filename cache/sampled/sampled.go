@@ -0,0 +1,286 @@
+// Package sampled 实现按采样近似 LRU 淘汰的缓存（与 Redis 的 allkeys-lru 相同的思路），接口与 lru.Cache 相同，
+// 可以作为 geecache 的淘汰策略。条目不维护访问顺序链表，只记录最近一次访问的序号；
+// 需要淘汰时随机抽取若干个条目，淘汰其中最久未访问的一个。
+// 读取只更新条目上的原子计数，不移动任何指针，适合条目数量很大、读取并发很高的缓存。
+package sampled
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"testProject/cache/clock"
+	"testProject/cache/lru"
+)
+
+// defaultSamples 是每次淘汰时默认抽取的条目数量，与 Redis 的 maxmemory-samples 默认值相同。
+const defaultSamples = 5
+
+// Cache 是按采样近似 LRU 淘汰的缓存。除 Touch 之外，Cache 不是并发安全的。
+type Cache struct {
+	maxBytes  int64 //允许使用的最大内存，0 表示不限制
+	nbytes    int64 //当前已经使用的内存大小
+	samples   int   //每次淘汰时抽取的条目数量
+	cache     map[string]*entry
+	entries   []*entry      //所有条目，用于随机抽样，删除时用最后一个条目填补空位
+	tick      atomic.Uint64 //单调递增的访问序号
+	evictions int64         //因超出容量而被淘汰的条目数量
+	clock     clock.Clock
+	listeners []lru.EvictionListener //淘汰监听器，按注册顺序依次调用
+}
+
+// Option 用于在创建 Cache 时定制其行为。
+type Option func(*Cache)
+
+// WithClock 设置 Cache 记录条目写入时间和判断过期使用的时钟，默认使用真实时间。
+func WithClock(c clock.Clock) Option {
+	return func(cache *Cache) {
+		cache.clock = c
+	}
+}
+
+// WithSamples 设置每次淘汰时抽取的条目数量，默认为 5。抽取得越多越接近精确的 LRU，淘汰的开销也越大。
+func WithSamples(n int) Option {
+	return func(c *Cache) {
+		if n > 0 {
+			c.samples = n
+		}
+	}
+}
+
+type entry struct {
+	key     string
+	value   lru.Value
+	index   int           //条目在 entries 中的位置
+	access  atomic.Uint64 //最近一次访问的序号
+	added   time.Time     //条目写入（或最近一次被覆盖）的时间
+	expires time.Time     //条目的过期时间，零值表示永不过期
+}
+
+// expired 报告条目在 now 时是否已经过期。
+func (e *entry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// New 创建一个 Cache，onEvicted 不为 nil 时会被注册为第一个淘汰监听器。
+func New(maxBytes int64, onEvicted func(string, lru.Value), opts ...Option) *Cache {
+	c := &Cache{
+		maxBytes: maxBytes,
+		samples:  defaultSamples,
+		cache:    make(map[string]*entry),
+		clock:    clock.Real,
+	}
+	if onEvicted != nil {
+		c.AddEvictionListener(onEvicted)
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Get 返回 key 对应的值并记录一次访问。已经过期的条目在这里被惰性删除，视为未命中。
+func (c *Cache) Get(key string) (value lru.Value, ok bool) {
+	e, ok := c.cache[key]
+	if !ok {
+		return nil, false
+	}
+	if e.expired(c.clock.Now()) {
+		c.removeEntry(e)
+		return nil, false
+	}
+	e.access.Store(c.tick.Add(1))
+	return e.value, true
+}
+
+// Touch 记录对 key 的一次访问，不返回值，也不删除过期的条目。
+// 它只更新原子计数，可以与 Touch、Peek、Contains 和 Expiration 并发调用，不能与修改缓存的方法并发调用。
+func (c *Cache) Touch(key string) {
+	if e, ok := c.cache[key]; ok {
+		e.access.Store(c.tick.Add(1))
+	}
+}
+
+// Peek 返回 key 对应的值，但不记录访问，不影响条目的淘汰顺序。
+// 已经过期的条目视为不存在，但不会在这里被删除。
+func (c *Cache) Peek(key string) (value lru.Value, ok bool) {
+	if e, ok := c.cache[key]; ok && !e.expired(c.clock.Now()) {
+		return e.value, true
+	}
+	return nil, false
+}
+
+// Contains 报告 key 是否在缓存中且尚未过期，不影响条目的淘汰顺序。
+func (c *Cache) Contains(key string) bool {
+	_, ok := c.Peek(key)
+	return ok
+}
+
+// Expiration 返回 key 对应条目的过期时间，零值表示永不过期。
+// 它不影响条目的淘汰顺序；条目不存在或已经过期时 ok 为 false。
+func (c *Cache) Expiration(key string) (expires time.Time, ok bool) {
+	if e, ok := c.cache[key]; ok && !e.expired(c.clock.Now()) {
+		return e.expires, true
+	}
+	return time.Time{}, false
+}
+
+// Add 将一个永不过期的键值对添加或更新到缓存中。
+func (c *Cache) Add(key string, value lru.Value) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire 将一个键值对添加或更新到缓存中，条目在 expires 之后过期，零值表示永不过期。
+// 覆盖已有的键计为一次访问。
+func (c *Cache) AddWithExpire(key string, value lru.Value, expires time.Time) {
+	e, ok := c.cache[key]
+	if ok {
+		c.nbytes += int64(value.Len()) - int64(e.value.Len())
+		e.value = value
+	} else {
+		e = &entry{key: key, value: value, index: len(c.entries)}
+		c.entries = append(c.entries, e)
+		c.cache[key] = e
+		c.nbytes += int64(len(key)) + int64(value.Len())
+	}
+	e.added = c.clock.Now()
+	e.expires = expires
+	e.access.Store(c.tick.Add(1))
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
+		c.RemoveOldest()
+	}
+}
+
+// RemoveOldest 随机抽取若干个条目，淘汰其中已经过期或者最久未访问的一个。
+func (c *Cache) RemoveOldest() {
+	if victim := c.victim(); victim != nil {
+		c.removeEntry(victim)
+		c.evictions++
+	}
+}
+
+// victim 随机抽取 c.samples 个条目并返回其中应当被淘汰的一个，缓存为空时返回 nil。
+// 条目数量不超过 c.samples 时检查所有条目。
+func (c *Cache) victim() *entry {
+	n := len(c.entries)
+	if n == 0 {
+		return nil
+	}
+	now := c.clock.Now()
+	var victim *entry
+	for i := 0; i < min(c.samples, n); i++ {
+		e := c.entries[i]
+		if n > c.samples {
+			e = c.entries[rand.Intn(n)]
+		}
+		if e.expired(now) {
+			return e // 已经过期的条目总是优先淘汰
+		}
+		if victim == nil || e.access.Load() < victim.access.Load() {
+			victim = e
+		}
+	}
+	return victim
+}
+
+// Remove 从缓存中删除 key 对应的条目，返回条目是否存在。被删除的条目同样会通知淘汰监听器。
+func (c *Cache) Remove(key string) bool {
+	if e, ok := c.cache[key]; ok {
+		c.removeEntry(e)
+		return true
+	}
+	return false
+}
+
+// RemoveExpired 删除所有已经过期的条目并返回删除的数量，被删除的条目会通知淘汰监听器。
+func (c *Cache) RemoveExpired() int {
+	now := c.clock.Now()
+	var expired []*entry
+	for _, e := range c.entries {
+		if e.expired(now) {
+			expired = append(expired, e)
+		}
+	}
+	for _, e := range expired {
+		c.removeEntry(e)
+	}
+	return len(expired)
+}
+
+// removeEntry 从缓存中删除一个条目，并通知淘汰监听器。
+func (c *Cache) removeEntry(e *entry) {
+	last := c.entries[len(c.entries)-1]
+	c.entries[e.index] = last
+	last.index = e.index
+	c.entries[len(c.entries)-1] = nil
+	c.entries = c.entries[:len(c.entries)-1]
+	delete(c.cache, e.key)
+	c.nbytes -= int64(len(e.key)) + int64(e.value.Len())
+	for _, fn := range c.listeners {
+		fn(e.key, e.value)
+	}
+}
+
+// AddEvictionListener 注册一个淘汰监听器，条目被淘汰或删除时所有监听器按注册顺序被调用。
+func (c *Cache) AddEvictionListener(fn lru.EvictionListener) {
+	c.listeners = append(c.listeners, fn)
+}
+
+// Evictions 返回因超出容量而被淘汰的条目数量，不包括过期和被显式删除的条目。
+func (c *Cache) Evictions() int64 {
+	return c.evictions
+}
+
+// Len 返回缓存中的条目数量。
+func (c *Cache) Len() int {
+	return len(c.entries)
+}
+
+// Bytes 返回当前已经使用的内存大小，即所有条目的键和值的长度之和。
+func (c *Cache) Bytes() int64 {
+	return c.nbytes
+}
+
+// Resize 调整允许使用的最大内存，并立即按采样淘汰条目直到不超过新的限制，0 表示不限制。
+func (c *Cache) Resize(maxBytes int64) {
+	c.maxBytes = maxBytes
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
+		c.RemoveOldest()
+	}
+}
+
+// Sample 随机返回最多 n 个未过期的条目，不影响条目的淘汰顺序。
+func (c *Cache) Sample(n int) []lru.EntryInfo {
+	if n <= 0 {
+		return nil
+	}
+	sample := make([]lru.EntryInfo, 0, n)
+	now := c.clock.Now()
+	i := 0
+	for _, e := range c.entries {
+		if e.expired(now) {
+			continue
+		}
+		info := lru.EntryInfo{Key: e.key, Value: e.value, Added: e.added, Expires: e.expires}
+		if i < n {
+			sample = append(sample, info)
+		} else if j := rand.Intn(i + 1); j < n {
+			sample[j] = info
+		}
+		i++
+	}
+	return sample
+}
+
+// Scan 与 lru.Cache.Scan 相同：按 lru.KeyHash 的顺序分页遍历以 prefix 开头的键，
+// 返回的游标为 0 表示遍历结束，因此两种缓存的结果可以用 lru.MergeScans 合并。
+func (c *Cache) Scan(cursor uint64, prefix string, count int) (keys []string, next uint64) {
+	now := c.clock.Now()
+	return lru.ScanKeys(cursor, prefix, count, func(yield func(string) bool) {
+		for _, e := range c.entries {
+			if !e.expired(now) && !yield(e.key) {
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package sampled
+
+import (
+	"testing"
+	"time"
+
+	"testProject/cache/clock"
+	"testProject/cache/lru"
+)
+
+type String string
+
+func (d String) Len() int {
+	return len(d)
+}
+
+// 测试抽样覆盖所有条目时淘汰最久未访问的条目，Touch 与 Get 一样记录访问
+func TestEviction(t *testing.T) {
+	var evicted []string
+	c := New(int64(6), func(key string, _ lru.Value) { evicted = append(evicted, key) }, WithSamples(10))
+	c.Add("a", String("1"))
+	c.Add("b", String("2"))
+	c.Add("c", String("3"))
+	c.Get("a")
+	c.Touch("b")
+	c.Add("d", String("4")) // c 最久未访问
+	if c.Contains("c") || c.Len() != 3 {
+		t.Fatalf("evicted = %v, want c evicted", evicted)
+	}
+	c.Remove("a") // 删除时用最后一个条目填补空位
+	c.Add("e", String("5"))
+	c.Add("f", String("6")) // b 最久未访问
+	if c.Contains("b") || !c.Contains("d") || !c.Contains("e") || !c.Contains("f") {
+		t.Fatalf("evicted = %v, want b evicted", evicted)
+	}
+	if c.Evictions() != 2 || c.Bytes() != 6 {
+		t.Fatalf("Evictions = %d, Bytes = %d", c.Evictions(), c.Bytes())
+	}
+}
+
+// 测试过期的条目视为未命中，并且在淘汰时优先于未过期的条目
+func TestExpiration(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := New(4, nil, WithClock(clk), WithSamples(3))
+	c.AddWithExpire("k", String("v"), clk.Now().Add(time.Second))
+	c.Add("x", String("v"))
+	c.Get("x")
+	clk.Advance(time.Second)
+	if c.Contains("k") {
+		t.Fatal("expired entry should not be visible")
+	}
+	c.Add("y", String("v")) // k 虽然更晚被访问，但已经过期
+	if c.Len() != 2 || !c.Contains("x") {
+		t.Fatalf("expired entry should be evicted first, Len = %d", c.Len())
+	}
+	if c.RemoveExpired() != 0 {
+		t.Fatal("no expired entry should remain")
+	}
+}
@@ -1,24 +1,139 @@
 package singleflight
 
-import "sync"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"testProject/cache/clock"
+)
+
+// PanicError 表示 fn 在执行过程中发生了 panic。
+// fn 在单独的 goroutine 中执行，panic 无法传回调用方的 goroutine，
+// 因此被恢复并以 PanicError 返回给所有等待的调用方，而不是让整个进程退出。
+type PanicError struct {
+	Value interface{} // recover 得到的值
+	Stack []byte      // 发生 panic 时的调用栈
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("singleflight: fn panicked: %v\n\n%s", p.Value, p.Stack)
+}
+
+// Unwrap 在 panic 的值是 error 时返回它，便于使用 errors.Is 判断。
+func (p *PanicError) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
+}
+
+var (
+	// ErrTimeout 表示 DoTimeout 在等待调用结果时超时。
+	ErrTimeout = errors.New("singleflight: timed out waiting for call")
+	// ErrTooManyWaiters 表示同一个 key 的调用已经有 WithMaxWaiters 个调用方在等待，新的调用方直接失败。
+	ErrTooManyWaiters = errors.New("singleflight: too many waiters")
+	// ErrGoexit 表示 fn 调用了 runtime.Goexit（例如测试中的 t.FailNow），没有返回结果。
+	ErrGoexit = errors.New("singleflight: fn called runtime.Goexit")
+)
 
 //call 代表正在进行中，或已经结束的请求
 type call struct {
-	wg  sync.WaitGroup
-	val interface{}
-	err error
+	key     string
+	done    chan struct{} // 请求结束时关闭
+	val     interface{}
+	err     error
+	waiters int                // 仍在等待结果的调用方数量
+	cancel  context.CancelFunc // 所有调用方都放弃等待时取消请求
 }
 
 // singleflight 的主数据结构，管理不同 key 的请求(call)
+// 零值可以直接使用，等价于不带选项的 New()。
 type Group struct {
-	mu sync.Mutex // protects m
-	m  map[string]*call
+	mu     sync.Mutex // protects m
+	m      map[string]*call
+	window time.Duration // 成功的结果在请求结束之后继续共享的时长，见 WithShareWindow
+	clock  clock.Clock   // 共享期间计时使用的时钟，为 nil 时使用 clock.Real，见 WithClock
+	// maxWaiters 是同一个调用最多的等待方数量（包括发起调用的一方），0 表示不限制，见 WithMaxWaiters
+	maxWaiters int
+
+	calls    atomic.Int64 // 执行 fn 的次数
+	shared   atomic.Int64 // 共享其他调用方的结果而没有执行 fn 的次数
+	rejected atomic.Int64 // 因为 ErrTooManyWaiters 直接失败的次数
+}
+
+// Stats 是 Group 的运行统计，由 Group.Stats 返回。计数类字段从 Group 创建时开始累计。
+type Stats struct {
+	Calls    int64 // 执行 fn 的次数
+	Shared   int64 // 共享其他调用方的结果而没有执行 fn 的次数，包括共享期间内的结果
+	Rejected int64 // 超过 WithMaxWaiters 上限而直接失败的次数
+	InFlight int   // 当前正在执行的调用数量
+}
+
+// DedupRatio 返回被合并的调用方所占的比例，即 Shared / (Calls + Shared)，没有任何调用时为 0。
+// 例如 0.75 表示每 4 个调用方中只有 1 个真正执行了 fn。
+func (s Stats) DedupRatio() float64 {
+	if total := s.Calls + s.Shared; total > 0 {
+		return float64(s.Shared) / float64(total)
+	}
+	return 0
+}
+
+// Option 用于在创建 Group 时定制其配置。
+type Option func(*Group)
+
+// WithShareWindow 让成功的结果在请求结束之后继续共享 d 时长：这段时间内到达的同一个 key 的调用直接得到该结果，
+// 而不是再执行一次 fn，这样紧跟在请求结束之后到达的大量调用也只会执行一次 fn。
+// 失败的结果不会共享，之后的调用会重新执行 fn。结果在共享期间可能已经过时，调用方可以通过 Forget 提前丢弃。
+func WithShareWindow(d time.Duration) Option {
+	return func(g *Group) {
+		g.window = d
+	}
+}
+
+// WithClock 设置共享期间（见 WithShareWindow）计时使用的时钟，默认为 clock.Real，测试中可以使用 clock.Fake。
+func WithClock(c clock.Clock) Option {
+	return func(g *Group) {
+		g.clock = c
+	}
+}
+
+// WithMaxWaiters 限制同一个 key 同时等待一次调用的调用方最多为 n 个（包括发起调用的一方），
+// 超出的调用方立即得到 ErrTooManyWaiters 而不是继续排队。后端卡住时，这样可以避免无限多的 goroutine
+// 阻塞在同一个调用上。n <= 0 表示不限制，这是默认行为。已经结束、仍在共享期间的结果不受限制。
+func WithMaxWaiters(n int) Option {
+	return func(g *Group) {
+		g.maxWaiters = n
+	}
+}
+
+// New 创建一个 Group。
+func New(opts ...Option) *Group {
+	g := &Group{}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
 }
 
 // Do 方法接受一个键值（key）和一个函数（fn）作为参数，用于处理缓存请求。
 // 如果缓存中已经有该键值的调用，它会等待调用结果并返回结果。
 // 如果缓存中没有该键值的调用，它会执行提供的函数 fn，并将结果存储在缓存中。
 func (g *Group) Do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	return g.DoContext(context.Background(), key, func(context.Context) (interface{}, error) {
+		return fn()
+	})
+}
+
+// DoContext 与 Do 相同，但调用方可以通过 ctx 放弃等待：ctx 结束时立即返回 ctx 的错误。
+// 同一个 key 的并发调用共享一次 fn 的执行，fn 收到的 context 携带第一个调用方 ctx 中的值和截止时间，
+// 但不会因为某一个调用方主动取消而被取消；只有所有调用方都放弃等待时才会被取消，
+// 这样一个调用方放弃不会让其他仍在等待的调用方一起失败。
+// fn 发生 panic 时所有调用方都会收到 *PanicError，调用 runtime.Goexit 时都会收到 ErrGoexit。
+func (g *Group) DoContext(ctx context.Context, key string, fn func(context.Context) (interface{}, error)) (interface{}, error) {
 	g.mu.Lock() // 加锁以确保在并发访问中的安全性
 
 	// 如果缓存 map 为空，初始化它
@@ -28,24 +143,158 @@ func (g *Group) Do(key string, fn func() (interface{}, error)) (interface{}, err
 
 	// 检查缓存中是否已经存在该键值的调用
 	if c, ok := g.m[key]; ok {
+		if g.maxWaiters > 0 && c.waiters >= g.maxWaiters && !c.finished() {
+			g.mu.Unlock()
+			g.rejected.Add(1)
+			return nil, ErrTooManyWaiters
+		}
+		g.shared.Add(1)
+		c.waiters++
 		g.mu.Unlock() // 解锁
-		c.wg.Wait()   // 等待调用结果
-		return c.val, c.err
+		return g.wait(ctx, c)
 	}
 
 	// 如果缓存中没有该键值的调用，创建一个新的调用并存储在缓存中
-	c := new(call)
-	c.wg.Add(1)
+	base := context.WithoutCancel(ctx)
+	callCtx, cancel := context.WithCancel(base)
+	if deadline, ok := ctx.Deadline(); ok {
+		cancel()
+		callCtx, cancel = context.WithDeadline(base, deadline)
+	}
+	c := &call{key: key, done: make(chan struct{}), waiters: 1, cancel: cancel}
 	g.m[key] = c
 	g.mu.Unlock() // 解锁
+	g.calls.Add(1)
+
+	go func() {
+		normalReturn := false // panic 在 g.call 中被恢复，fn 调用 runtime.Goexit 时不会返回
+		defer func() {
+			if !normalReturn {
+				c.val, c.err = nil, ErrGoexit
+			}
+			g.finish(c)
+		}()
+		// 执行提供的函数 fn，获取结果
+		c.val, c.err = g.call(callCtx, fn)
+		normalReturn = true
+	}()
+
+	return g.wait(ctx, c)
+}
+
+// finish 在 fn 结束之后（包括 panic 和 runtime.Goexit）通知等待方，并按共享期间的配置从 m 中删除调用。
+func (g *Group) finish(c *call) {
+	c.cancel()
+
+	g.mu.Lock() // 再次加锁以进行最后的处理
+	share := g.window > 0 && c.err == nil && g.m[c.key] == c
+	if !share {
+		g.forget(c) // 从缓存中删除调用结果
+	}
+	g.mu.Unlock()
+	close(c.done) // 通知调用已经完成
+	if share {
+		clk := g.clock
+		if clk == nil {
+			clk = clock.Real // 零值的 Group 没有经过 New
+		}
+		clk.AfterFunc(g.window, func() {
+			g.mu.Lock()
+			g.forget(c) // 共享时间结束
+			g.mu.Unlock()
+		})
+	}
+}
+
+// DoTimeout 与 Do 相同，但最多等待 d：调用 d 时间之后还没有结束时返回 ErrTimeout。
+// 放弃等待的规则与 DoContext 相同，所有调用方都超时之后 fn 收到的 context 才会被取消。
+func (g *Group) DoTimeout(key string, d time.Duration, fn func() (interface{}, error)) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	v, err := g.DoContext(ctx, key, func(context.Context) (interface{}, error) {
+		return fn()
+	})
+	if err != nil && err == ctx.Err() {
+		return nil, fmt.Errorf("%w after %v", ErrTimeout, d)
+	}
+	return v, err
+}
+
+// Stats 返回 Group 当前的运行统计。
+func (g *Group) Stats() Stats {
+	g.mu.Lock()
+	var inFlight int
+	for _, c := range g.m {
+		if !c.finished() {
+			inFlight++
+		}
+	}
+	g.mu.Unlock()
+	return Stats{Calls: g.calls.Load(), Shared: g.shared.Load(), Rejected: g.rejected.Load(), InFlight: inFlight}
+}
+
+// InFlight 返回当前正在执行的调用的 key，按字典序排列，用于排查卡住的加载。
+func (g *Group) InFlight() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var keys []string
+	for key, c := range g.m {
+		if !c.finished() {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// finished 报告调用是否已经结束。
+func (c *call) finished() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
 
-	// 执行提供的函数 fn，获取结果
-	c.val, c.err = fn()
-	c.wg.Done() // 通知调用已经完成
+// call 执行 fn，并把 fn 中的 panic 转换为 PanicError。
+func (g *Group) call(ctx context.Context, fn func(context.Context) (interface{}, error)) (val interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			val, err = nil, &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn(ctx)
+}
 
-	g.mu.Lock()      // 再次加锁以进行最后的处理
-	delete(g.m, key) // 从缓存中删除调用结果
-	g.mu.Unlock()    // 解锁
+// wait 等待调用结束，或者在 ctx 结束时放弃等待。最后一个放弃等待的调用方会取消该调用。
+func (g *Group) wait(ctx context.Context, c *call) (interface{}, error) {
+	select {
+	case <-c.done:
+		return c.val, c.err // 返回调用结果
+	case <-ctx.Done():
+		g.mu.Lock()
+		c.waiters--
+		if c.waiters == 0 {
+			c.cancel()
+			g.forget(c) // 之后的调用方不应再加入已经被取消的调用
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
 
-	return c.val, c.err // 返回调用结果
+// Forget 丢弃 key 对应的调用：之后同一个 key 的调用会重新执行 fn，而不是等待进行中的调用
+// 或者得到仍在共享期间的结果。已经在等待的调用方不受影响。
+func (g *Group) Forget(key string) {
+	g.mu.Lock()
+	delete(g.m, key)
+	g.mu.Unlock()
+}
+
+// forget 在 key 仍然对应调用 c 时把它从 map 中删除。调用方需要持有 g.mu。
+func (g *Group) forget(c *call) {
+	if g.m[c.key] == c {
+		delete(g.m, c.key)
+	}
 }
@@ -0,0 +1,194 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"testProject/cache/clock"
+)
+
+// 并发的同一个 key 只执行一次
+func TestDo(t *testing.T) {
+	var g Group
+	var mu sync.Mutex
+	calls := 0
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := g.Do("key", func() (interface{}, error) {
+				mu.Lock()
+				calls++
+				mu.Unlock()
+				<-release
+				return "bar", nil
+			})
+			if v != "bar" || err != nil {
+				t.Errorf("Do = %v, %v", v, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls != 1 {
+		t.Fatalf("fn called %d times, want 1", calls)
+	}
+	if s := g.Stats(); s.Calls != 1 || s.Shared != 9 || s.InFlight != 0 || s.DedupRatio() != 0.9 {
+		t.Fatalf("Stats = %+v, ratio %v; want 1 call shared by 9", s, s.DedupRatio())
+	}
+}
+
+// 一个调用方放弃等待不会取消其他调用方仍在等待的调用，所有调用方都放弃后调用被取消
+func TestDoContextCancel(t *testing.T) {
+	var g Group
+	started := make(chan struct{})
+	release := make(chan struct{})
+	cancelled := make(chan struct{})
+	fn := func(ctx context.Context) (interface{}, error) {
+		close(started)
+		select {
+		case <-release:
+			return "bar", nil
+		case <-ctx.Done():
+			close(cancelled)
+			return nil, ctx.Err()
+		}
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := g.DoContext(ctx1, "key", fn)
+		errc <- err
+	}()
+	<-started
+	done := make(chan interface{}, 1)
+	go func() {
+		v, _ := g.DoContext(context.Background(), "key", fn)
+		done <- v
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	cancel1()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("abandoned caller got %v, want context.Canceled", err)
+	}
+	close(release)
+	if v := <-done; v != "bar" {
+		t.Fatalf("remaining caller got %v, want bar", v)
+	}
+
+	// 唯一的调用方放弃后调用被取消
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	started, release = make(chan struct{}), make(chan struct{})
+	go g.DoContext(ctx2, "other", fn)
+	<-started
+	cancel2()
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("call was not cancelled after all callers gave up")
+	}
+}
+
+// fn 发生 panic 时所有调用方都收到 PanicError，之后的调用不受影响
+func TestDoPanic(t *testing.T) {
+	var g Group
+	_, err := g.Do("key", func() (interface{}, error) {
+		panic("boom")
+	})
+	var pe *PanicError
+	if !errors.As(err, &pe) || pe.Value != "boom" {
+		t.Fatalf("Do err = %v, want PanicError", err)
+	}
+	if v, err := g.Do("key", func() (interface{}, error) { return "ok", nil }); v != "ok" || err != nil {
+		t.Fatalf("Do after panic = %v, %v", v, err)
+	}
+}
+
+// fn 调用 runtime.Goexit 时所有调用方都收到 ErrGoexit，之后的调用不会卡在残留的调用上
+func TestDoGoexit(t *testing.T) {
+	var g Group
+	if _, err := g.Do("key", func() (interface{}, error) {
+		runtime.Goexit()
+		return nil, nil
+	}); err != ErrGoexit {
+		t.Fatalf("Do err = %v, want ErrGoexit", err)
+	}
+	if v, err := g.DoTimeout("key", time.Second, func() (interface{}, error) { return "ok", nil }); v != "ok" || err != nil {
+		t.Fatalf("Do after Goexit = %v, %v", v, err)
+	}
+}
+
+// 共享时间内到达的调用直接得到上一次成功的结果，失败的结果不共享，Forget 提前丢弃结果
+func TestShareWindow(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := New(WithShareWindow(50*time.Millisecond), WithClock(clk))
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+	g.Do("key", fn)
+	if v, _ := g.Do("key", fn); v != 1 || calls != 1 {
+		t.Fatalf("Do within the window = %v, calls %d; want the shared result", v, calls)
+	}
+	g.Forget("key")
+	if v, _ := g.Do("key", fn); v != 2 {
+		t.Fatalf("Do after Forget = %v, want a new call", v)
+	}
+	clk.Advance(50 * time.Millisecond)
+	if v, _ := g.Do("key", fn); v != 3 {
+		t.Fatalf("Do after the window = %v, want a new call", v)
+	}
+
+	fails := 0
+	failing := func() (interface{}, error) {
+		fails++
+		return nil, errors.New("backend down")
+	}
+	g.Do("bad", failing)
+	g.Do("bad", failing)
+	if fails != 2 {
+		t.Fatalf("failing fn called %d times, want errors not shared", fails)
+	}
+}
+
+// 调用超时时等待方得到 ErrTimeout，超过等待方上限的调用方立即失败
+func TestDoTimeoutAndMaxWaiters(t *testing.T) {
+	g := New(WithMaxWaiters(2))
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	go g.Do("key", func() (interface{}, error) {
+		close(started)
+		<-release
+		return "bar", nil
+	})
+	<-started
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := g.DoTimeout("key", 100*time.Millisecond, func() (interface{}, error) { return nil, nil })
+		errs <- err
+	}()
+	for g.Stats().Shared == 0 {
+		runtime.Gosched() // 等待 DoTimeout 加入正在执行的调用，Shared 在加入时计数
+	}
+	if _, err := g.Do("key", func() (interface{}, error) { return nil, nil }); err != ErrTooManyWaiters {
+		t.Fatalf("third waiter: err = %v, want ErrTooManyWaiters", err)
+	}
+	if keys := g.InFlight(); len(keys) != 1 || keys[0] != "key" {
+		t.Fatalf("InFlight = %v, want [key]", keys)
+	}
+	if err := <-errs; !errors.Is(err, ErrTimeout) {
+		t.Fatalf("DoTimeout: err = %v, want ErrTimeout", err)
+	}
+}
@@ -0,0 +1,113 @@
+// Package tinylfu 实现 TinyLFU 准入过滤器：用 count-min sketch 近似统计每个键最近的访问频率，
+// 缓存已满时只有比将被淘汰的条目更常被访问的新条目才会被接纳，只访问一次的键不会挤走有价值的条目。
+package tinylfu
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"testProject/cache/bloom"
+)
+
+const (
+	depth      = 4  // count-min sketch 的行数
+	maxCounter = 15 // 计数器的上限，与论文中 4 位计数器的取值范围相同
+)
+
+// Filter 是 TinyLFU 准入过滤器，所有方法都可以并发调用。
+// 键第一次被访问时只记录在 doorkeeper（布隆过滤器）中，再次访问才进入 sketch 计数，
+// 大量只访问一次的键因此不会占满 sketch。记录的访问次数达到 samples 后，
+// 所有计数减半并清空 doorkeeper，使频率反映的是最近的访问情况。
+type Filter struct {
+	mu         sync.Mutex
+	counters   [depth][]uint8 // count-min sketch，每行 width 个计数器
+	mask       uint64         // width-1，width 是 2 的幂
+	doorkeeper *bloom.Filter
+	samples    int // 两次衰减之间记录的访问次数
+	n          int // 上次衰减以来记录的访问次数
+}
+
+// New 创建一个 TinyLFU 过滤器，samples 是衰减周期内的访问次数，通常取缓存条目数量的 10 倍左右。
+func New(samples int) *Filter {
+	if samples < 16 {
+		samples = 16
+	}
+	width := 1
+	for width < samples/8 { // 每个计数器平均对应不超过 8 次访问
+		width <<= 1
+	}
+	f := &Filter{mask: uint64(width - 1), samples: samples}
+	for i := range f.counters {
+		f.counters[i] = make([]uint8, width)
+	}
+	f.doorkeeper = bloom.New(samples, 0.01)
+	return f
+}
+
+// Record 记录 key 的一次访问。
+func (f *Filter) Record(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.doorkeeper.MayContain(key) {
+		f.doorkeeper.Add(key)
+	} else {
+		h1, h2 := hashes(key)
+		for i := range f.counters {
+			c := &f.counters[i][(h1+uint64(i)*h2)&f.mask]
+			if *c < maxCounter {
+				*c++
+			}
+		}
+	}
+	if f.n++; f.n >= f.samples {
+		f.reset()
+	}
+}
+
+// Estimate 返回 key 在衰减周期内的估计访问次数，可能偏大但不会偏小。
+func (f *Filter) Estimate(key string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.estimate(key)
+}
+
+// Admit 报告缓存已满时 candidate 能否替换掉将被淘汰的 victim：candidate 的估计访问次数必须更高。
+func (f *Filter) Admit(candidate, victim string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.estimate(candidate) > f.estimate(victim)
+}
+
+// estimate 返回 key 的估计访问次数，调用方需要持有 f.mu。
+func (f *Filter) estimate(key string) int {
+	h1, h2 := hashes(key)
+	min := uint8(maxCounter)
+	for i := range f.counters {
+		if c := f.counters[i][(h1+uint64(i)*h2)&f.mask]; c < min {
+			min = c
+		}
+	}
+	if f.doorkeeper.MayContain(key) {
+		return int(min) + 1 // 加上 doorkeeper 中记录的那一次访问
+	}
+	return int(min)
+}
+
+// reset 把所有计数减半并清空 doorkeeper，调用方需要持有 f.mu。
+func (f *Filter) reset() {
+	for i := range f.counters {
+		for j := range f.counters[i] {
+			f.counters[i][j] >>= 1
+		}
+	}
+	f.doorkeeper = bloom.New(f.samples, 0.01)
+	f.n = 0
+}
+
+// hashes 返回用于双重哈希的两个哈希值，第 i 行的位置为 h1 + i·h2。
+func hashes(key string) (h1, h2 uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	return sum, sum>>32 | 1
+}
@@ -0,0 +1,39 @@
+package tinylfu
+
+import (
+	"strconv"
+	"testing"
+)
+
+// 测试频繁访问的键的估计次数更高，只访问一次的键不能替换它
+func TestAdmit(t *testing.T) {
+	f := New(1000)
+	for i := 0; i < 5; i++ {
+		f.Record("hot")
+	}
+	f.Record("once")
+	if got := f.Estimate("hot"); got < 5 {
+		t.Fatalf("Estimate(hot) = %d, want at least 5", got)
+	}
+	if f.Estimate("never") != 0 {
+		t.Fatal("a key that was never recorded has a non-zero estimate")
+	}
+	if f.Admit("once", "hot") || !f.Admit("hot", "once") {
+		t.Fatal("admission should favour the more frequent key")
+	}
+}
+
+// 测试达到衰减周期后计数减半
+func TestReset(t *testing.T) {
+	f := New(100)
+	for i := 0; i < 9; i++ {
+		f.Record("hot")
+	}
+	before := f.Estimate("hot")
+	for i := 0; f.n != 0 || i == 0; i++ {
+		f.Record("k" + strconv.Itoa(i))
+	}
+	if after := f.Estimate("hot"); after != before/2 {
+		t.Fatalf("Estimate after reset = %d, before = %d", after, before)
+	}
+}
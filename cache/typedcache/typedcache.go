@@ -0,0 +1,90 @@
+// Package typedcache 在 geecache.Group 之上提供类型化的缓存组：
+// 数据源返回 T 类型的值，调用方读取和写入的也是 T 类型的值，
+// 值与缓存中字节之间的转换由缓存组的编解码器（见 geecache.WithCodec 和 codec 包）负责。
+package typedcache
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"testProject/cache/codec"
+	"testProject/cache/geecache"
+)
+
+// GetterFunc 是类型化缓存组的数据源，缓存未命中时调用。
+type GetterFunc[T any] func(ctx context.Context, key string) (T, error)
+
+// Group 是值类型为 T 的缓存组，所有操作都委托给底层的 geecache.Group。
+type Group[T any] struct {
+	group *geecache.Group
+}
+
+// New 创建名为 name 的类型化缓存组，opts 传给 geecache.NewGroup，
+// 其中 geecache.WithCodec 决定值的编码方式，默认为 JSON。
+func New[T any](name string, cacheBytes int64, getter GetterFunc[T], opts ...geecache.GroupOption) *Group[T] {
+	g := &Group[T]{}
+	g.group = geecache.NewGroup(name, cacheBytes, geecache.ContextGetterFunc(
+		func(ctx context.Context, key string) ([]byte, error) {
+			v, err := getter(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+			return g.group.Codec().Marshal(v)
+		}), opts...)
+	return g
+}
+
+// Wrap 把已有的缓存组包装为类型化缓存组，缓存组中的值必须是用其编解码器编码的 T。
+func Wrap[T any](g *geecache.Group) *Group[T] {
+	return &Group[T]{group: g}
+}
+
+// Untyped 返回底层的 geecache.Group，用于注册节点、查看统计等与值类型无关的操作。
+func (g *Group[T]) Untyped() *geecache.Group {
+	return g.group
+}
+
+// Get 读取 key 对应的值并解码为 T，缓存未命中时从其他节点或数据源加载。
+func (g *Group[T]) Get(ctx context.Context, key string) (T, error) {
+	v, target := newValue[T]()
+	if err := g.group.GetIntoContext(ctx, key, codecSink{g.group.Codec(), target}); err != nil {
+		var zero T
+		return zero, err
+	}
+	return *v, nil
+}
+
+// Set 把 v 编码后写入 key，见 geecache.Group.SetContext。
+func (g *Group[T]) Set(ctx context.Context, key string, v T) (geecache.ConsistencyToken, error) {
+	b, err := g.group.Codec().Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("typedcache: encoding %q: %v", key, err)
+	}
+	return g.group.SetContext(ctx, key, b)
+}
+
+// Remove 删除 key，见 geecache.Group.RemoveContext。
+func (g *Group[T]) Remove(ctx context.Context, key string) (geecache.ConsistencyToken, error) {
+	return g.group.RemoveContext(ctx, key)
+}
+
+// newValue 分配用于解码的 T 类型的值，返回指向它的指针以及解码的目标。
+// T 是指针类型（例如 protobuf 消息）时同时分配它指向的值并直接解码到其中，否则解码到 *v。
+func newValue[T any]() (v *T, target interface{}) {
+	v = new(T)
+	if t := reflect.TypeFor[T](); t.Kind() == reflect.Pointer {
+		*v = reflect.New(t.Elem()).Interface().(T)
+		return v, *v
+	}
+	return v, v
+}
+
+// codecSink 使用编解码器把缓存中的数据直接解码到 target 中。
+type codecSink struct {
+	codec  codec.Codec
+	target interface{}
+}
+
+func (s codecSink) SetBytes(b []byte) error  { return s.codec.Unmarshal(b, s.target) }
+func (s codecSink) SetString(v string) error { return s.codec.Unmarshal([]byte(v), s.target) }
@@ -0,0 +1,54 @@
+package typedcache
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"testProject/cache/codec"
+	"testProject/cache/geecache"
+)
+
+type score struct {
+	Name  string
+	Score int
+}
+
+// 测试类型化缓存组读取、写入结构体，数据源只在未命中时调用
+func TestGroup(t *testing.T) {
+	ctx := context.Background()
+	var loads int
+	g := New("typed-scores", 2<<10, func(ctx context.Context, key string) (score, error) {
+		loads++
+		return score{key, 630}, nil
+	}, geecache.WithCodec(codec.Msgpack))
+
+	for i := 0; i < 2; i++ {
+		if v, err := g.Get(ctx, "Tom"); err != nil || v != (score{"Tom", 630}) || loads != 1 {
+			t.Fatalf("Get = %+v, %v (loads %d)", v, err, loads)
+		}
+	}
+	if _, err := g.Set(ctx, "Jack", score{"Jack", 589}); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := g.Get(ctx, "Jack"); err != nil || v != (score{"Jack", 589}) || loads != 1 {
+		t.Fatalf("Get after Set = %+v, %v (loads %d)", v, err, loads)
+	}
+}
+
+// 测试值类型为指针的 protobuf 消息时每次读取都得到新分配的消息
+func TestProtoGroup(t *testing.T) {
+	g := New("typed-proto", 2<<10, func(ctx context.Context, key string) (*wrapperspb.StringValue, error) {
+		return wrapperspb.String(key + "!"), nil
+	}, geecache.WithCodec(codec.Proto))
+	a, err := g.Get(context.Background(), "Tom")
+	if err != nil || a.GetValue() != "Tom!" {
+		t.Fatalf("Get = %v, %v", a, err)
+	}
+	b, _ := g.Get(context.Background(), "Tom")
+	if a == b || !proto.Equal(a, b) {
+		t.Fatal("each Get should decode into a fresh message")
+	}
+}